@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	a read-only http api over what's being tracked, so that other tools can
+	use the data without talking grpc to the database service. With
+	API_ADDRESS set, it serves json at:
+
+		GET /api/posts                 the tracked posts and their current scores, most upvoted first.
+		                               ?subreddit=<name> narrows them down, ?sort=upvotes|comments|created|age
+		                               picks the order and ?limit=<n> keeps the first n
+		GET /api/posts/<id>            a post and its vote history, by fullname (t3_62sjuh) or post id (62sjuh).
+		                               Posts that aren't tracked anymore only have their history
+		GET /api/posts/<id>/chart.svg  the post's vote history as a line chart, or chart.png (see chart.go)
+		GET /api/subreddits            a summary of each subreddit being watched
+		GET /api/triggers              tracked posts past some upvotes, for automation services to poll (see triggers.go)
+		GET /dump                      stored snapshots as ndjson, for jq, pandas or duckdb (see dump.go)
+		GET /openapi.json              the api's OpenAPI 3 description (see openapi.go)
+
+	if API_TOKEN is set, requests (other than /openapi.json, and charts with
+	API_PUBLIC_URL set) need an "Authorization: Bearer <API_TOKEN>" header. Without it anyone who can reach API_ADDRESS can read everything
+*/
+
+// how long looking up a post's history may take
+const historyTimeout = 10 * time.Second
+
+// the reddit api handler, as far as the api is concerned
+type trackedSource interface {
+	GetTrackedPosts() reddit.ContentGroup
+	SubredditRefreshPeriods() map[string]uint64
+}
+
+// the part of database.Store that the api needs
+type historySource interface {
+	GetHistory(ctx context.Context, ID reddit.Fullname) ([]database.Snapshot, error)
+	RecieveHistoryPage(ctx context.Context, query database.ListingsQuery) ([]database.ListingHistory, string, error)
+}
+
+type Server struct {
+	tracked      trackedSource
+	history      historySource
+	token        string
+	publicCharts bool // API_PUBLIC_URL is set
+}
+
+// a tracked post, as the api returns it
+type Post struct {
+	Id        reddit.Fullname `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Title     string          `json:"title"`
+	Author    string          `json:"author,omitempty"`
+	Url       string          `json:"url,omitempty"`
+	Created   uint64          `json:"created"`
+	Queried   uint64          `json:"queried"` // when the scores were fetched
+	Upvotes   int             `json:"upvotes"`
+	Comments  int             `json:"comments"`
+
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"` // gained since the update before, see reddit.VelocityBetween
+	CommentsPerHour float64 `json:"comments_per_hour"`
+}
+
+type PostHistory struct {
+	Id      reddit.Fullname     `json:"id"`
+	Tracked bool                `json:"tracked"`
+	Post    *Post               `json:"post,omitempty"` // only for tracked posts
+	Entries []database.Snapshot `json:"entries"`
+}
+
+type SubredditSummary struct {
+	Subreddit     string          `json:"subreddit"`
+	RefreshPeriod uint64          `json:"refresh_period,omitempty"` // its own, see subreddits.json.template
+	Tracked       int             `json:"tracked"`
+	Upvotes       int             `json:"upvotes"` // of every tracked post
+	Comments      int             `json:"comments"`
+	AverageScore  float64         `json:"average_upvotes"`
+	Top           reddit.Fullname `json:"top,omitempty"` // the most upvoted tracked post
+}
+
+// reads API_TOKEN and API_PUBLIC_URL
+func New(tracked trackedSource, history historySource) *Server {
+	return &Server{
+		tracked:      tracked,
+		history:      history,
+		token:        os.Getenv("API_TOKEN"),
+		publicCharts: strings.TrimSpace(os.Getenv("API_PUBLIC_URL")) != "",
+	}
+}
+
+// serves the endpoints described above on address (eg: ":9102"). Only returns if the server fails
+func (s *Server) Serve(address string) error {
+	return http.ListenAndServe(address, s.Handler())
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts", s.listPosts)
+	mux.HandleFunc("/api/posts/", s.getPost)
+	mux.HandleFunc("/api/subreddits", s.listSubreddits)
+	mux.HandleFunc("/api/triggers", s.listTriggers)
+	mux.HandleFunc("/dump", s.dumpSnapshots)
+	mux.HandleFunc("/openapi.json", serveOpenAPI)
+
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+
+		if s.token != "" && !s.isPublic(r.URL.Path) {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or wrong API_TOKEN")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// what's served without API_TOKEN
+func (s *Server) isPublic(path string) bool {
+	if path == "/openapi.json" {
+		return true
+	}
+	return s.publicCharts && strings.HasPrefix(path, "/api/posts/") && strings.Contains(path, "/chart.")
+}
+
+func (s *Server) listPosts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subreddit := strings.TrimPrefix(query.Get("subreddit"), "r/")
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, "limit should be a positive number")
+			return
+		}
+	}
+
+	less, err := postOrder(query.Get("sort"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	posts := make([]Post, 0)
+	for _, listing := range s.tracked.GetTrackedPosts() {
+		if subreddit != "" && !strings.EqualFold(listing.Subreddit, subreddit) {
+			continue
+		}
+		posts = append(posts, toPost(listing))
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return less(posts[i], posts[j])
+	})
+	if limit > 0 && len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	writeJSON(w, posts)
+}
+
+// the order of ?sort=, ties broken by id so that pages of the same data come out the same
+func postOrder(sortBy string) (func(a, b Post) bool, error) {
+	var key func(Post) int64
+	switch sortBy {
+	case "", "upvotes":
+		key = func(p Post) int64 { return int64(p.Upvotes) }
+	case "comments":
+		key = func(p Post) int64 { return int64(p.Comments) }
+	case "created":
+		key = func(p Post) int64 { return int64(p.Created) }
+	case "age":
+		key = func(p Post) int64 { return -int64(p.Created) }
+	default:
+		return nil, errors.New("sort should be upvotes, comments, created or age")
+	}
+
+	return func(a, b Post) bool {
+		if key(a) != key(b) {
+			return key(a) > key(b)
+		}
+		return a.Id < b.Id
+	}, nil
+}
+
+func (s *Server) getPost(w http.ResponseWriter, r *http.Request) {
+	value := strings.TrimPrefix(r.URL.Path, "/api/posts/")
+	if value, format, isChart := strings.Cut(value, "/chart."); isChart {
+		s.getChart(w, r, value, format)
+		return
+	}
+
+	ID, valid := parseID(value)
+	if !valid {
+		writeError(w, http.StatusBadRequest, "expected a fullname (eg: t3_62sjuh) or post id (eg: 62sjuh)")
+		return
+	}
+
+	result := PostHistory{Id: ID}
+	if listing, tracked := s.tracked.GetTrackedPosts()[ID]; tracked {
+		post := toPost(listing)
+		result.Tracked = true
+		result.Post = &post
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), historyTimeout)
+	defer cancel()
+
+	entries, err := s.history.GetHistory(ctx, ID)
+	switch {
+	case errors.Is(err, database.ErrListingNotFound) && !result.Tracked:
+		writeError(w, http.StatusNotFound, string(ID)+" isn't tracked or stored")
+		return
+	case errors.Is(err, database.ErrListingNotFound):
+		// tracked, but not saved yet
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "error getting history:\n"+err.Error())
+		return
+	}
+	result.Entries = entries
+	if result.Entries == nil {
+		result.Entries = make([]database.Snapshot, 0)
+	}
+
+	writeJSON(w, result)
+}
+
+// posts can be given by their id alone, as it appears in their url
+func parseID(value string) (reddit.Fullname, bool) {
+	ID := reddit.Fullname(value)
+	if !ID.IsValid() {
+		ID = reddit.Fullname("t3_" + value)
+	}
+	return ID, ID.IsValid()
+}
+
+func (s *Server) listSubreddits(w http.ResponseWriter, r *http.Request) {
+	summaries := make(map[string]*SubredditSummary)
+	summary := func(name string) *SubredditSummary {
+		key := strings.ToLower(name)
+		if _, exists := summaries[key]; !exists {
+			summaries[key] = &SubredditSummary{Subreddit: name}
+		}
+		return summaries[key]
+	}
+
+	//every watched subreddit is listed, even without any tracked posts
+	for name, period := range s.tracked.SubredditRefreshPeriods() {
+		summary(name).RefreshPeriod = period
+	}
+
+	top := make(map[string]int)
+	for ID, listing := range s.tracked.GetTrackedPosts() {
+		sub := summary(listing.Subreddit)
+		sub.Tracked += 1
+		sub.Upvotes += listing.Upvotes
+		sub.Comments += listing.Comments
+
+		key := strings.ToLower(listing.Subreddit)
+		if best, exists := top[key]; !exists || listing.Upvotes > best || (listing.Upvotes == best && ID < sub.Top) {
+			top[key] = listing.Upvotes
+			sub.Top = ID
+		}
+	}
+
+	result := make([]SubredditSummary, 0, len(summaries))
+	for _, sub := range summaries {
+		if sub.Tracked > 0 {
+			sub.AverageScore = float64(sub.Upvotes) / float64(sub.Tracked)
+		}
+		result = append(result, *sub)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Subreddit) < strings.ToLower(result[j].Subreddit)
+	})
+
+	writeJSON(w, result)
+}
+
+func toPost(listing reddit.RedditContent) Post {
+	return Post{
+		Id:        listing.FullId(),
+		Subreddit: listing.Subreddit,
+		Title:     listing.Title,
+		Author:    listing.Author,
+		Url:       listing.Url,
+		Created:   listing.Date,
+		Queried:   listing.QueryDate,
+		Upvotes:   listing.Upvotes,
+		Comments:  listing.Comments,
+
+		UpvotesPerHour:  listing.UpvotesPerHour,
+		CommentsPerHour: listing.CommentsPerHour,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jtyrmn/reddit-votewatch/chart"
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	a post's vote history as a line chart image (see the chart package):
+
+		GET /api/posts/<id>/chart.svg
+		GET /api/posts/<id>/chart.png      ?width=<pixels>&height=<pixels>, 600x300 by default
+
+	so that a dashboard can show it in an <img>, and notifications can embed
+	it. Chat services fetch images on their own, without API_TOKEN, so with
+	API_PUBLIC_URL set (the url they can reach API_ADDRESS at) charts are
+	served to anyone, and discord and slack notifications link to them (see
+	notify.Notification.ChartURL)
+*/
+
+var chartContentTypes = map[string]string{
+	"svg": "image/svg+xml",
+	"png": "image/png",
+}
+
+func (s *Server) getChart(w http.ResponseWriter, r *http.Request, value string, format string) {
+	contentType, known := chartContentTypes[format]
+	if !known {
+		writeError(w, http.StatusNotFound, "charts are chart.svg or chart.png")
+		return
+	}
+	ID, valid := parseID(value)
+	if !valid {
+		writeError(w, http.StatusBadRequest, "expected a fullname (eg: t3_62sjuh) or post id (eg: 62sjuh)")
+		return
+	}
+
+	var options chart.Options
+	for _, size := range []struct {
+		name  string
+		value *int
+	}{{"width", &options.Width}, {"height", &options.Height}} {
+		if text := r.URL.Query().Get(size.name); text != "" {
+			var err error
+			*size.value, err = strconv.Atoi(text)
+			if err != nil || *size.value <= 0 {
+				writeError(w, http.StatusBadRequest, size.name+" should be a positive number of pixels")
+				return
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), historyTimeout)
+	defer cancel()
+
+	listing, tracked := s.tracked.GetTrackedPosts()[ID]
+	entries, err := s.history.GetHistory(ctx, ID)
+	switch {
+	case errors.Is(err, database.ErrListingNotFound) && !tracked:
+		writeError(w, http.StatusNotFound, string(ID)+" isn't tracked or stored")
+		return
+	case errors.Is(err, database.ErrListingNotFound):
+		// tracked, but not saved yet
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "error getting history:\n"+err.Error())
+		return
+	}
+	if tracked {
+		entries = append(entries, listing.Snapshot())
+	}
+
+	var image bytes.Buffer
+	history := reddit.ListingHistory{Listing: listing, Entries: entries}
+	if format == "svg" {
+		err = chart.SVG(&image, history, options)
+	} else {
+		err = chart.PNG(&image, history, options)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "error drawing chart:\n"+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "max-age=60")
+	w.Write(image.Bytes())
+}
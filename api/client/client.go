@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	a typed go client for votewatch's http api, following api/openapi.json
+	(GET /openapi.json on a running instance). It only needs the standard
+	library, so other programs can import it without pulling in votewatch's
+	dependencies:
+
+		c := client.New("http://localhost:9102", os.Getenv("API_TOKEN"))
+		posts, err := c.ListPosts(ctx, client.ListPostsOptions{Subreddit: "golang", Limit: 10})
+		history, err := c.GetPost(ctx, "t3_62sjuh")
+
+	/dump is read a snapshot at a time, as it streams in (see Dump). Other
+	errors from the api (a 4xx or 5xx with {"error": ...}) come back as *Error
+*/
+
+const defaultTimeout = 30 * time.Second
+
+type Client struct {
+	BaseURL    string // eg: http://localhost:9102
+	Token      string // API_TOKEN, if the api needs one
+	HTTPClient *http.Client
+}
+
+// a tracked post and its current scores
+type Post struct {
+	Id        string `json:"id"` // fullname, eg: t3_62sjuh
+	Subreddit string `json:"subreddit"`
+	Title     string `json:"title"`
+	Author    string `json:"author,omitempty"`
+	Url       string `json:"url,omitempty"`
+	Created   uint64 `json:"created"` // unix time
+	Queried   uint64 `json:"queried"` // when the scores were fetched
+	Upvotes   int    `json:"upvotes"`
+	Comments  int    `json:"comments"`
+
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"` // gained since the update before, 0 until the first update
+	CommentsPerHour float64 `json:"comments_per_hour"`
+}
+
+type Snapshot struct {
+	Upvotes  int    `json:"upvotes"`
+	Comments int    `json:"comments"`
+	Date     uint64 `json:"date"` // time of recording
+
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"` // gained since the snapshot before
+	CommentsPerHour float64 `json:"comments_per_hour"`
+}
+
+type PostHistory struct {
+	Id      string     `json:"id"`
+	Tracked bool       `json:"tracked"`
+	Post    *Post      `json:"post,omitempty"` // only for tracked posts
+	Entries []Snapshot `json:"entries"`        // oldest first
+}
+
+type SubredditSummary struct {
+	Subreddit      string  `json:"subreddit"`
+	RefreshPeriod  uint64  `json:"refresh_period,omitempty"` // seconds, if it has its own
+	Tracked        int     `json:"tracked"`
+	Upvotes        int     `json:"upvotes"` // of every tracked post
+	Comments       int     `json:"comments"`
+	AverageUpvotes float64 `json:"average_upvotes"`
+	Top            string  `json:"top,omitempty"` // the most upvoted tracked post
+}
+
+// a post returned by /api/triggers
+type AutomationEvent struct {
+	Id        string  `json:"id"`   // the same for a post and rule between polls, eg: t3_62sjuh-upvotes-10000
+	Kind      string  `json:"kind"` // upvotes or velocity
+	Text      string  `json:"text"`
+	Time      string  `json:"time"` // RFC 3339
+	PostId    string  `json:"post_id,omitempty"`
+	Subreddit string  `json:"subreddit,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Author    string  `json:"author,omitempty"`
+	Url       string  `json:"url,omitempty"`
+	Link      string  `json:"link,omitempty"`
+	Created   string  `json:"created,omitempty"` // RFC 3339
+	Upvotes   int     `json:"upvotes"`
+	Comments  int     `json:"comments"`
+	Velocity  float64 `json:"upvotes_per_hour"`
+	Threshold float64 `json:"threshold,omitempty"`
+	RemovedBy string  `json:"removed_by,omitempty"`
+}
+
+// a line of /dump
+type DumpRow struct {
+	Id        string `json:"id"`
+	Subreddit string `json:"subreddit"`
+	Title     string `json:"title"`
+	Created   uint64 `json:"created"` // when the post was created
+	Date      uint64 `json:"date"`    // when the snapshot was recorded
+	Upvotes   int    `json:"upvotes"`
+	Comments  int    `json:"comments"`
+
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"` // gained since the snapshot before
+	CommentsPerHour float64 `json:"comments_per_hour"`
+}
+
+type ListPostsOptions struct {
+	Subreddit string // with or without the r/
+	Sort      string // upvotes (the default), comments, created or age
+	Limit     int    // 0 for every post
+}
+
+// one of MinUpvotes and MinVelocity must be set
+type TriggersOptions struct {
+	MinUpvotes  *int
+	MinVelocity *float64 // upvotes an hour
+	Subreddit   string
+	Limit       int // 0 for the api's default (100)
+}
+
+type DumpOptions struct {
+	Since      string // an age (eg: 7d) or a date (eg: 2026-10-01). The api defaults to 1d, all for everything
+	Until      string
+	Subreddit  string
+	Posts      []string // fullnames or post ids
+	MinUpvotes int
+}
+
+// the rows of /dump as they come in
+type DumpStream struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+// an error response from the api
+type Error struct {
+	Status  int // http status code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("votewatch api error (%d): %s", e.Status, e.Message)
+}
+
+// a client for the api at baseURL. token can be empty if the api doesn't require API_TOKEN
+func New(baseURL string, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// GET /api/posts
+func (c *Client) ListPosts(ctx context.Context, options ListPostsOptions) ([]Post, error) {
+	query := url.Values{}
+	if options.Subreddit != "" {
+		query.Set("subreddit", options.Subreddit)
+	}
+	if options.Sort != "" {
+		query.Set("sort", options.Sort)
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+
+	var posts []Post
+	return posts, c.get(ctx, "/api/posts", query, &posts)
+}
+
+// GET /api/posts/<id>, by fullname (t3_62sjuh) or post id (62sjuh)
+func (c *Client) GetPost(ctx context.Context, id string) (*PostHistory, error) {
+	var history PostHistory
+	if err := c.get(ctx, "/api/posts/"+url.PathEscape(id), nil, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// GET /api/subreddits
+func (c *Client) ListSubreddits(ctx context.Context) ([]SubredditSummary, error) {
+	var summaries []SubredditSummary
+	return summaries, c.get(ctx, "/api/subreddits", nil, &summaries)
+}
+
+// GET /api/triggers
+func (c *Client) ListTriggers(ctx context.Context, options TriggersOptions) ([]AutomationEvent, error) {
+	query := url.Values{}
+	if options.MinUpvotes != nil {
+		query.Set("min_upvotes", strconv.Itoa(*options.MinUpvotes))
+	}
+	if options.MinVelocity != nil {
+		query.Set("min_velocity", strconv.FormatFloat(*options.MinVelocity, 'f', -1, 64))
+	}
+	if options.Subreddit != "" {
+		query.Set("subreddit", options.Subreddit)
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+
+	var events []AutomationEvent
+	return events, c.get(ctx, "/api/triggers", query, &events)
+}
+
+// GET /dump. The stream must be closed. HTTPClient's timeout (30s with New) covers reading all of it, a big dump needs a
+// longer one, or none and a deadline on ctx instead
+func (c *Client) Dump(ctx context.Context, options DumpOptions) (*DumpStream, error) {
+	query := url.Values{}
+	if options.Since != "" {
+		query.Set("since", options.Since)
+	}
+	if options.Until != "" {
+		query.Set("until", options.Until)
+	}
+	if options.Subreddit != "" {
+		query.Set("subreddit", options.Subreddit)
+	}
+	for _, post := range options.Posts {
+		query.Add("post", post)
+	}
+	if options.MinUpvotes > 0 {
+		query.Set("min_upvotes", strconv.Itoa(options.MinUpvotes))
+	}
+
+	response, err := c.do(ctx, "/dump", query)
+	if err != nil {
+		return nil, err
+	}
+	return &DumpStream{body: response.Body, decoder: json.NewDecoder(response.Body)}, nil
+}
+
+// the next row, or io.EOF after the last one. An error the api ran into partway through is returned as an *Error
+func (d *DumpStream) Next() (DumpRow, error) {
+	var line struct {
+		DumpRow
+		Error string `json:"error"`
+	}
+	if err := d.decoder.Decode(&line); err != nil {
+		return DumpRow{}, err
+	}
+	if line.Error != "" {
+		return DumpRow{}, &Error{Status: http.StatusOK, Message: line.Error}
+	}
+	return line.DumpRow, nil
+}
+
+func (d *DumpStream) Close() error {
+	return d.body.Close()
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	response, err := c.do(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("error parsing the response of %s:\n%s", path, err)
+	}
+	return nil
+}
+
+// sends a GET request. Anything but a 200 is returned as an *Error, otherwise the body must be closed
+func (c *Client) do(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(response.Body).Decode(&body) != nil || body.Error == "" {
+			body.Error = response.Status
+		}
+		return nil, &Error{Status: response.StatusCode, Message: body.Error}
+	}
+	return response, nil
+}
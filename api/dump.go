@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/dump"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	the stored snapshots as newline-delimited json, streamed straight from the
+	database so that they can be piped into jq, pandas or duckdb:
+
+		curl "localhost:9102/dump?subreddit=golang&since=7d" | jq 'select(.upvotes > 1000)'
+		duckdb -c "select * from read_ndjson_auto('http://localhost:9102/dump?since=1d')"
+
+	each line is an entry recorded under a post: {"id", "subreddit", "title",
+	"created", "date", "upvotes", "comments"}, the same as the csv export's
+	rows. The snapshots can be narrowed down with:
+
+		?since=<age or date>     recorded since then, eg: 7d, 12h or 2026-10-01. Defaults to 1d, all for everything
+		?until=<age or date>     recorded before then
+		?subreddit=<name>
+		?post=<id>               only this post's, by fullname or post id. Can be given more than once
+		?min_upvotes=<n>         only snapshots with at least n upvotes
+
+	the status is sent before the first snapshot, so an error after that is
+	sent as a last line of its own: {"error": "..."}. votewatch dump does the
+	same from the command line
+*/
+
+const defaultDumpSince = "1d"
+
+func (s *Server) dumpSnapshots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	since := query.Get("since")
+	if since == "" {
+		since = defaultDumpSince
+	}
+	var from time.Time
+	if since != "all" {
+		var err error
+		from, err = dump.ParseTime(since, now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since: "+err.Error())
+			return
+		}
+	}
+	until := now.Add(time.Second) //until is exclusive, so that what was just recorded is included
+	if value := query.Get("until"); value != "" {
+		var err error
+		until, err = dump.ParseTime(value, now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "until: "+err.Error())
+			return
+		}
+	}
+	if !until.After(from) {
+		writeError(w, http.StatusBadRequest, "until should be after since")
+		return
+	}
+
+	filter := dump.SnapshotFilter{From: uint64(from.Unix()), Until: uint64(until.Unix())}
+	if value := query.Get("min_upvotes"); value != "" {
+		var err error
+		filter.MinUpvotes, err = strconv.Atoi(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "min_upvotes should be a whole number of upvotes")
+			return
+		}
+	}
+	for _, value := range query["post"] {
+		ID, valid := parseID(value)
+		if !valid {
+			writeError(w, http.StatusBadRequest, "post should be a fullname (eg: t3_62sjuh) or post id (eg: 62sjuh)")
+			return
+		}
+		if filter.Posts == nil {
+			filter.Posts = make(map[reddit.Fullname]bool)
+		}
+		filter.Posts[ID] = true
+	}
+
+	listings := dump.SnapshotsQuery(now, from, until, strings.TrimPrefix(query.Get("subreddit"), "r/"))
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	written, err := dump.ExportSnapshots(r.Context(), s.history, listings, filter, dump.NDJSON, w)
+	if err != nil && written == 0 {
+		//nothing's been sent yet, there's still time for a proper status
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+}
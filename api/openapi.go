@@ -0,0 +1,25 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+/*
+	the api described as an OpenAPI 3 document (openapi.json), served at
+	GET /openapi.json so that integrators can generate a client in their own
+	language, or load it into swagger ui / postman. It's served without
+	API_TOKEN, there's nothing in it that isn't in the source. Go programs can
+	use the client package (api/client) instead.
+
+	openapi.json is written by hand, so it has to be changed along with the
+	endpoints and their types
+*/
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
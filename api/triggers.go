@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/notify"
+)
+
+/*
+	a polling trigger for automation services (a zapier "retrieve poll", or
+	anything else that polls for new items):
+
+		GET /api/triggers?min_upvotes=10000          every tracked post with at least 10000 upvotes
+		GET /api/triggers?min_velocity=500           every tracked post gaining at least 500 upvotes an hour
+		                                             (see notify.Velocity)
+
+	?subreddit=<name> narrows them down. The posts come newest first, as
+	notify.AutomationEvent objects, the same as ZAPIER_WEBHOOK_URL is sent. Each
+	post's id stays the same between polls (eg: t3_62sjuh-upvotes-10000), so
+	services that deduplicate on it trigger once per post. Only the first
+	?limit=<n> (defaults to 100) are listed
+*/
+
+const defaultTriggersLimit = 100
+
+func (s *Server) listTriggers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subreddit := strings.TrimPrefix(query.Get("subreddit"), "r/")
+
+	limit := defaultTriggersLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "limit should be a positive number")
+			return
+		}
+	}
+
+	minUpvotes, upvotesErr := strconv.Atoi(query.Get("min_upvotes"))
+	minVelocity, velocityErr := strconv.ParseFloat(query.Get("min_velocity"), 64)
+	var kind notify.Kind
+	var threshold float64
+	switch {
+	case query.Has("min_upvotes") == query.Has("min_velocity"):
+		writeError(w, http.StatusBadRequest, "expected either ?min_upvotes=<n> or ?min_velocity=<upvotes an hour>")
+		return
+	case query.Has("min_upvotes") && (upvotesErr != nil || minUpvotes < 0):
+		writeError(w, http.StatusBadRequest, "min_upvotes should be a whole number of upvotes")
+		return
+	case query.Has("min_velocity") && (velocityErr != nil || minVelocity < 0):
+		writeError(w, http.StatusBadRequest, "min_velocity should be a number of upvotes an hour")
+		return
+	case query.Has("min_upvotes"):
+		kind, threshold = notify.UpvotesReached, float64(minUpvotes)
+	default:
+		kind, threshold = notify.VelocityReached, minVelocity
+	}
+
+	now := time.Now()
+	events := make([]notify.AutomationEvent, 0)
+	created := make(map[string]uint64)
+	for _, listing := range s.tracked.GetTrackedPosts() {
+		if subreddit != "" && !strings.EqualFold(listing.Subreddit, subreddit) {
+			continue
+		}
+
+		velocity := notify.Velocity(listing)
+		if (kind == notify.UpvotesReached && float64(listing.Upvotes) < threshold) ||
+			(kind == notify.VelocityReached && velocity < threshold) {
+			continue
+		}
+
+		event := notify.AutomationEventOf(notify.Notification{
+			Kind:      kind,
+			Time:      now,
+			Post:      listing,
+			Threshold: threshold,
+			Velocity:  velocity,
+		})
+		created[event.Id] = listing.Date
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if created[events[i].Id] != created[events[j].Id] {
+			return created[events[i].Id] > created[events[j].Id]
+		}
+		return events[i].Id < events[j].Id
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	writeJSON(w, events)
+}
@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/dump"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	This package backs the database up to object storage (S3, Google Cloud
+	Storage, minio...) in case the database service loses its data. A backup is
+	a gzipped json export of every listing and its history (see the dump
+	package), uploaded twice: once under a timestamped key that's kept, and once
+	as <prefix>/latest.jsonl.gz, which is what gets restored.
+
+	backups are taken by the scheduler every BACKUP_REFRESH_PERIOD seconds, and
+	restored at startup (only into an empty database) if BACKUP_RESTORE_ON_START
+	is set, or with the restore subcommand. See .env.template
+*/
+
+var errObjectNotFound = errors.New("object not found")
+
+// the part of database.Store that Backup needs
+type historySource interface {
+	RecieveHistoryPage(context.Context, database.ListingsQuery) ([]database.ListingHistory, string, error)
+}
+
+// the part of database.Store that Restore needs
+type historySink interface {
+	SaveListings(context.Context, reddit.ContentGroup) error
+	RecordNewData(context.Context, reddit.ContentGroup) error
+	RecieveListingsPage(context.Context, reddit.ContentGroup, database.ListingsQuery) (string, int, error)
+}
+
+type Config struct {
+	client *s3Client
+	prefix string
+}
+
+// reads the BACKUP_* env variables. Returns nil if BACKUP_BUCKET isn't set
+func ConfigFromEnv() (*Config, error) {
+	bucket := util.GetEnvOptional("BACKUP_BUCKET", "")
+	if bucket == "" {
+		return nil, nil
+	}
+
+	accessKeyID, err := util.GetEnv("BACKUP_ACCESS_KEY_ID")
+	if err != nil {
+		return nil, err
+	}
+	secretAccessKey, err := util.GetEnv("BACKUP_SECRET_ACCESS_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := util.GetEnvOptional("BACKUP_ENDPOINT", awsEndpoint)
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	region := util.GetEnvOptional("BACKUP_REGION", "us-east-1")
+
+	return &Config{
+		client: newS3Client(strings.TrimSuffix(endpoint, "/"), region, bucket, accessKeyID, secretAccessKey),
+		prefix: strings.Trim(util.GetEnvOptional("BACKUP_PREFIX", "votewatch"), "/"),
+	}, nil
+}
+
+// the key that always holds the most recent backup
+func (c *Config) LatestKey() string {
+	return c.prefix + "/latest.jsonl.gz"
+}
+
+// exports every listing in store and uploads it. Returns # of listings backed up
+func (c *Config) Backup(ctx context.Context, store historySource) (int, error) {
+	temp, err := os.CreateTemp("", "votewatch-backup-*.jsonl.gz")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary file:\n%s", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	query := database.ListingsQuery{
+		MaxAge: time.Now().Unix(), //every listing since the epoch
+		Sort:   database.OldestFirst,
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	writer := bufio.NewWriter(temp)
+	compressor := gzip.NewWriter(writer)
+	count, err := dump.Export(ctx, store, query, dump.JSON, compressor)
+	if err != nil {
+		return 0, err
+	}
+	err = compressor.Close()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing backup:\n%s", err)
+	}
+
+	key := fmt.Sprintf("%s/votewatch-%s.jsonl.gz", c.prefix, time.Now().UTC().Format("20060102T150405Z"))
+	for _, key := range []string{key, c.LatestKey()} {
+		err = c.client.put(ctx, key, temp.Name())
+		if err != nil {
+			return 0, fmt.Errorf("error uploading backup to %s:\n%s", key, err)
+		}
+	}
+
+	return count, nil
+}
+
+// downloads the backup at key and imports it into store. Returns # of listings restored
+func (c *Config) Restore(ctx context.Context, store historySink, key string) (int, error) {
+	body, err := c.client.get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading backup %s:\n%w", key, err) // %w so that RestoreIfEmpty can tell a missing backup apart
+	}
+	defer body.Close()
+
+	decompressor, err := gzip.NewReader(bufio.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error reading backup %s:\n%s", key, err)
+	}
+
+	batchSize := util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000)
+	return dump.Import(ctx, store, dump.JSON, decompressor, batchSize)
+}
+
+// restores the latest backup, but only if store is empty (restoring into a database that has data would duplicate entries).
+// Returns # of listings restored
+func (c *Config) RestoreIfEmpty(ctx context.Context, store historySink) (int, error) {
+	_, count, err := store.RecieveListingsPage(ctx, make(reddit.ContentGroup), database.ListingsQuery{MaxAge: time.Now().Unix(), Limit: 1})
+	if err != nil {
+		return 0, fmt.Errorf("error checking if the database is empty:\n%s", err)
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	restored, err := c.Restore(ctx, store, c.LatestKey())
+	if errors.Is(err, errObjectNotFound) {
+		// nothing was ever backed up
+		return 0, nil
+	}
+	return restored, err
+}
@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/dump"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	cold storage keeps the database small without losing data: instead of
+	culling old listings (CULL_MODE=coldstorage, see .env.template), they're
+	exported with their full history to a gzipped json file under
+	<prefix>/archive/ in the backup bucket, and only deleted from the database
+	once the upload succeeded. The files can be loaded back with
+	"votewatch restore --key <key>" or read directly, see the dump package
+*/
+
+// the part of database.Store that ColdStore needs
+type coldStorageSource interface {
+	historySource
+	DeleteListings(context.Context, []reddit.Fullname) (int, error)
+}
+
+// records the IDs of every listing exported through it, so that exactly those get deleted. Listings newer than
+// newest (created after it, unix time) stop the export, the store didn't filter them out like it was asked to
+type exportedIDs struct {
+	historySource
+	newest uint64
+	IDs    []reddit.Fullname
+}
+
+func (e *exportedIDs) RecieveHistoryPage(ctx context.Context, query database.ListingsQuery) ([]database.ListingHistory, string, error) {
+	page, next, err := e.historySource.RecieveHistoryPage(ctx, query)
+	for _, history := range page {
+		if history.Listing.Date > e.newest {
+			return nil, "", fmt.Errorf("the database returned %s, which isn't old enough to be moved to cold storage. Not archiving anything", history.Listing.FullId())
+		}
+		e.IDs = append(e.IDs, history.Listing.FullId())
+	}
+	return page, next, err
+}
+
+// moves every listing over maxAge seconds old from store to object storage. Returns # of listings moved
+func (c *Config) ColdStore(ctx context.Context, store coldStorageSource, maxAge uint64) (int, error) {
+	//an older database service ignores MinAge, everything would be archived and then deleted
+	if service, ok := store.(interface{ SupportsMinAge() bool }); ok && !service.SupportsMinAge() {
+		return 0, errors.New("the database service is too old to pick listings by age (it needs schema version 6), not moving anything to cold storage")
+	}
+
+	temp, err := os.CreateTemp("", "votewatch-archive-*.jsonl.gz")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary file:\n%s", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	query := database.ListingsQuery{
+		MaxAge: time.Now().Unix(), //since the epoch
+		MinAge: int64(maxAge),
+		Sort:   database.OldestFirst,
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	source := &exportedIDs{historySource: store, newest: uint64(time.Now().Unix()) - maxAge}
+	writer := bufio.NewWriter(temp)
+	compressor := gzip.NewWriter(writer)
+	count, err := dump.Export(ctx, source, query, dump.JSON, compressor)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	err = compressor.Close()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing archive:\n%s", err)
+	}
+
+	key := fmt.Sprintf("%s/archive/votewatch-archive-%s.jsonl.gz", c.prefix, time.Now().UTC().Format("20060102T150405Z"))
+	err = c.client.put(ctx, key, temp.Name())
+	if err != nil {
+		return 0, fmt.Errorf("error uploading archive to %s:\n%s", key, err)
+	}
+
+	deleted, err := store.DeleteListings(ctx, source.IDs)
+	if err != nil {
+		return 0, fmt.Errorf("listings were archived to %s, but not deleted from the database:\n%s", key, err)
+	}
+
+	return deleted, nil
+}
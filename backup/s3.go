@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+/*
+	whole objects put and got with the AWS SDK's S3 client. Google Cloud
+	Storage speaks the same api (with HMAC keys, see
+	https://cloud.google.com/storage/docs/interoperability), as do minio and
+	most other object stores, so this is the only client needed
+*/
+
+// amazon's own endpoint, where the SDK picks the bucket's regional endpoint itself
+const awsEndpoint = "https://s3.amazonaws.com"
+
+type s3Client struct {
+	client *s3.Client
+	bucket string
+}
+
+// endpoint is eg: https://s3.amazonaws.com. Buckets on other endpoints are addressed path-style, which is what minio
+// and the like expect
+func newS3Client(endpoint string, region string, bucket string, accessKeyID string, secretAccessKey string) *s3Client {
+	options := s3.Options{
+		Region: region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}, nil
+		}),
+	}
+	if endpoint != awsEndpoint {
+		options.BaseEndpoint = aws.String(endpoint)
+		options.UsePathStyle = true
+	}
+	return &s3Client{client: s3.New(options), bucket: bucket}
+}
+
+// uploads the file at path as the object at key
+func (c *s3Client) put(ctx context.Context, key string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = c.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	})
+	return err
+}
+
+// downloads the object at key. The caller must close the returned body
+func (c *s3Client) get(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		//any store's 404, not only amazon's NoSuchKey
+		var responseError *awshttp.ResponseError
+		if errors.As(err, &responseError) && responseError.HTTPStatusCode() == http.StatusNotFound {
+			return nil, errObjectNotFound
+		}
+		return nil, err
+	}
+	return output.Body, nil
+}
@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/dump"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	the recorded snapshots, exported for analytics every EXPORT_REFRESH_PERIOD
+	(eg: 1h) as parquet (or csv, EXPORT_FORMAT=csv) files partitioned by the
+	day they were recorded, the way duckdb, spark... expect partitions:
+
+		<EXPORT_PATH>/date=2026-10-16/snapshots-20261016T1300Z.parquet
+
+	or in the backup bucket under <BACKUP_PREFIX>/snapshots/ with
+	EXPORT_TO_BUCKET=true. Each file holds the entries recorded during one
+	period. Periods line up with the clock (an hourly export covers 13:00 to
+	14:00 UTC) and name the files, so exporting one again overwrites its file.
+	A period is exported a minute after it ends, to let the writes in flight
+	land. Periods when votewatch wasn't running are skipped, "votewatch
+	export" covers everything
+*/
+
+// how long after a period ends before it's exported
+const snapshotExportLag = time.Minute
+
+type SnapshotExport struct {
+	period time.Duration
+	format string
+	dir    string  // where files go, if they're written locally
+	bucket *Config // where files go otherwise
+
+	exportedUntil time.Time // the end of the last period exported
+}
+
+// reads the EXPORT_* env variables. Returns nil if EXPORT_REFRESH_PERIOD isn't set or is 0
+func SnapshotExportFromEnv() (*SnapshotExport, error) {
+	if value, _ := os.LookupEnv("EXPORT_REFRESH_PERIOD"); strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	period, err := util.GetEnvDuration("EXPORT_REFRESH_PERIOD", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if period == 0 {
+		return nil, nil
+	}
+	if period < time.Minute || (24*time.Hour)%period != 0 {
+		return nil, fmt.Errorf("EXPORT_REFRESH_PERIOD=%s should divide a day evenly (eg: 15m, 1h, 6h, 24h), so that each file falls within one date", period)
+	}
+
+	e := &SnapshotExport{period: period}
+
+	switch format := strings.ToLower(strings.TrimSpace(os.Getenv("EXPORT_FORMAT"))); format {
+	case "", dump.PARQUET:
+		e.format = dump.PARQUET
+	case dump.CSV:
+		e.format = dump.CSV
+	default:
+		return nil, fmt.Errorf("EXPORT_FORMAT=%s should be %s or %s", format, dump.PARQUET, dump.CSV)
+	}
+
+	toBucket, err := util.GetEnvBool("EXPORT_TO_BUCKET", false)
+	if err != nil {
+		return nil, err
+	}
+	if toBucket {
+		e.bucket, err = ConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if e.bucket == nil {
+			return nil, fmt.Errorf("EXPORT_TO_BUCKET needs BACKUP_BUCKET, the bucket to export to")
+		}
+		return e, nil
+	}
+
+	e.dir = strings.TrimSpace(os.Getenv("EXPORT_PATH"))
+	if e.dir == "" {
+		e.dir = "./exports"
+	}
+	return e, nil
+}
+
+func (e *SnapshotExport) Period() time.Duration {
+	return e.period
+}
+
+// exports every period that ended since the last export (only the latest one the first time). Returns # of snapshots
+// exported and where to
+func (e *SnapshotExport) Export(ctx context.Context, store historySource, now time.Time) (int, []string, error) {
+	until := now.Add(-snapshotExportLag).Truncate(e.period)
+	from := until.Add(-e.period)
+	if !e.exportedUntil.IsZero() {
+		from = e.exportedUntil
+	}
+
+	count := 0
+	written := make([]string, 0)
+	for start := from; start.Before(until); start = start.Add(e.period) {
+		n, location, err := e.exportPeriod(ctx, store, now, start)
+		if err != nil {
+			return count, written, err
+		}
+		count += n
+		if location != "" {
+			written = append(written, location)
+		}
+		e.exportedUntil = start.Add(e.period)
+	}
+	return count, written, nil
+}
+
+// the file's path under EXPORT_PATH, or key under <BACKUP_PREFIX>/snapshots/
+func (e *SnapshotExport) name(start time.Time) string {
+	start = start.UTC()
+	return fmt.Sprintf("date=%s/snapshots-%s.%s", start.Format("2006-01-02"), start.Format("20060102T1504Z"), e.format)
+}
+
+// exports the entries recorded from start to start + period. Returns # of entries, and where they went (nothing if there
+// weren't any)
+func (e *SnapshotExport) exportPeriod(ctx context.Context, store historySource, now time.Time, start time.Time) (int, string, error) {
+	end := start.Add(e.period)
+
+	//listings stop being updated MAX_TRACKING_AGE seconds after they're posted, so older ones can't have entries in
+	//the period. An extra period covers MAX_TRACKING_AGE having changed a little since
+	maxTrackingAge := time.Duration(util.GetEnvIntDefault("MAX_TRACKING_AGE", 86400)) * time.Second
+	query := database.ListingsQuery{
+		MaxAge: int64(now.Sub(start.Add(-maxTrackingAge - e.period)).Seconds()),
+		Sort:   database.OldestFirst,
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	//written locally next to the destination then renamed, so that readers never see half a file
+	name := e.name(start)
+	path := filepath.Join(e.dir, filepath.FromSlash(name))
+	tempDir := filepath.Dir(path)
+	if e.bucket != nil {
+		tempDir = ""
+	} else if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return 0, "", fmt.Errorf("error creating %s:\n%s", tempDir, err)
+	}
+	temp, err := os.CreateTemp(tempDir, ".votewatch-snapshots-*."+e.format)
+	if err != nil {
+		return 0, "", fmt.Errorf("error creating temporary file:\n%s", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	writer := bufio.NewWriter(temp)
+	count, err := dump.ExportSnapshots(ctx, store, query, dump.SnapshotFilter{From: uint64(start.Unix()), Until: uint64(end.Unix())}, e.format, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = temp.Close()
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("error writing export:\n%s", err)
+	}
+	if count == 0 {
+		return 0, "", nil
+	}
+
+	if e.bucket != nil {
+		key := e.bucket.prefix + "/snapshots/" + name
+		err = e.bucket.client.put(ctx, key, temp.Name())
+		if err != nil {
+			return 0, "", fmt.Errorf("error uploading export to %s:\n%s", key, err)
+		}
+		return count, key, nil
+	}
+
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		return 0, "", fmt.Errorf("error writing export to %s:\n%s", path, err)
+	}
+	return count, path, nil
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+	the tracked posts mirrored into redis (REDIS_URL), so that a restarted
+	instance, or a standby taking over from a leader that went away, picks
+	them up from redis instead of pulling every one from the database
+	service. Several instances (eg: one per shard) can share the same redis,
+	each only takes the posts of its own subreddits back.
+
+		<REDIS_KEY_PREFIX>post:<id>    the post as json, eg: votewatch:post:t3_62sjuh. It expires when the post
+		                               gets older than MAX_TRACKING_AGE, and would stop being tracked anyway
+		<REDIS_KEY_PREFIX>tracked      a sorted set of the ids, by when the posts were created
+
+	posts are written as they start being tracked (and again when they're
+	removed), in batches every second, and all at once after they're pulled
+	from the database on startup. Posts that stop being tracked before
+	they're too old (eg: evicted, see MAX_TRACKED_POSTS) stay until they
+	expire, and are evicted again after they're taken back. If redis is empty
+	or can't be reached, the posts are pulled from the database like usual
+*/
+
+const (
+	maxQueued   = 8192
+	writePeriod = time.Second
+	timeout     = 10 * time.Second
+
+	// posts per MGET when loading
+	loadChunk = 500
+)
+
+type Cache struct {
+	client *redis.Client
+	prefix string
+	maxAge int64 // MAX_TRACKING_AGE
+
+	posts   chan reddit.RedditContent
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// reads REDIS_URL, REDIS_KEY_PREFIX and MAX_TRACKING_AGE. nil if REDIS_URL isn't set
+func FromEnv() (*Cache, error) {
+	raw := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(raw, "redis://") && !strings.HasPrefix(raw, "rediss://") {
+		return nil, errors.New("REDIS_URL should be a redis url, eg: redis://localhost:6379/0, or rediss:// for TLS")
+	}
+
+	maxAge, err := util.GetEnvInt("MAX_TRACKING_AGE")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := os.Getenv("REDIS_KEY_PREFIX")
+	if prefix == "" {
+		prefix = "votewatch:"
+	}
+	if strings.ContainsAny(prefix, " \t\r\n") {
+		return nil, fmt.Errorf("REDIS_KEY_PREFIX=%q shouldn't have spaces", prefix)
+	}
+
+	client, err := NewClient(raw)
+	if err != nil {
+		return nil, errors.New("REDIS_URL is wrong:\n" + err.Error())
+	}
+
+	return &Cache{
+		client:  client,
+		prefix:  prefix,
+		maxAge:  int64(maxAge),
+		posts:   make(chan reddit.RedditContent, maxQueued),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// starts mirroring the tracked posts. Must be called before the scheduler starts (see scheduler.OnEvent)
+func (c *Cache) Setup() {
+	scheduler.OnEvent(scheduler.PostTracked, c.onEvent)
+	scheduler.OnEvent(scheduler.PostRemoved, c.onEvent)
+	go c.run()
+}
+
+// writes whatever's still queued and closes the connection
+func (c *Cache) Shutdown() {
+	close(c.stop)
+	<-c.stopped
+	c.client.Close()
+}
+
+// posts that pile up faster than they can be written (eg: redis is down) are dropped, they'll come from the database
+// on the next start if they're missing
+func (c *Cache) onEvent(event scheduler.Event) {
+	select {
+	case c.posts <- event.Post:
+	default:
+	}
+}
+
+func (c *Cache) run() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(writePeriod)
+	defer ticker.Stop()
+
+	batch := make(reddit.ContentGroup)
+	for {
+		select {
+		case post := <-c.posts:
+			batch[post.FullId()] = post
+			continue
+		case <-ticker.C:
+		case <-c.stop:
+			for len(c.posts) > 0 {
+				post := <-c.posts
+				batch[post.FullId()] = post
+			}
+		}
+
+		if len(batch) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err := c.Store(ctx, batch)
+			cancel()
+			if err != nil {
+				fmt.Printf("warning: error caching %d posts in redis:\n%s\n", len(batch), err)
+			}
+			batch = make(reddit.ContentGroup)
+		}
+
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+	}
+}
+
+// writes the posts, each expiring once it's older than MAX_TRACKING_AGE. Posts that are already too old are skipped
+func (c *Cache) Store(ctx context.Context, posts reddit.ContentGroup) error {
+	now := time.Now().Unix()
+	pipeline := c.client.Pipeline()
+	for ID, post := range posts {
+		ttl := int64(post.Date) + c.maxAge - now
+		if ttl <= 0 {
+			continue
+		}
+
+		data, err := json.Marshal(post)
+		if err != nil {
+			return err
+		}
+		pipeline.Set(ctx, c.prefix+"post:"+string(ID), data, time.Duration(ttl)*time.Second)
+		pipeline.ZAdd(ctx, c.prefix+"tracked", redis.Z{Score: float64(post.Date), Member: string(ID)})
+	}
+	if pipeline.Len() == 0 {
+		return nil
+	}
+	//the ids of the posts that expired go too
+	pipeline.ZRemRangeByScore(ctx, c.prefix+"tracked", "-inf", "("+strconv.FormatInt(now-c.maxAge, 10))
+
+	//the first command that failed, if any
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// every cached post that isn't too old to track
+func (c *Cache) Load(ctx context.Context) (reddit.ContentGroup, error) {
+	oldest := strconv.FormatInt(time.Now().Unix()-c.maxAge, 10)
+	IDs, err := c.client.ZRangeByScore(ctx, c.prefix+"tracked", &redis.ZRangeBy{Min: oldest, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(reddit.ContentGroup, len(IDs))
+	for start := 0; start < len(IDs); start += loadChunk {
+		end := start + loadChunk
+		if end > len(IDs) {
+			end = len(IDs)
+		}
+
+		keys := make([]string, 0, end-start)
+		for _, ID := range IDs[start:end] {
+			keys = append(keys, c.prefix+"post:"+ID)
+		}
+		values, err := c.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for idx, value := range values {
+			data, exists := value.(string)
+			if !exists {
+				continue //expired since
+			}
+			var post reddit.RedditContent
+			if err := json.Unmarshal([]byte(data), &post); err != nil {
+				return nil, errors.New("error parsing a cached post:\n" + err.Error())
+			}
+			//RedditContent's json is reddit's, where the kind isn't part of the post
+			post.ContentType, _, _ = strings.Cut(IDs[start+idx], "_")
+			posts[post.FullId()] = post
+		}
+	}
+
+	return posts, nil
+}
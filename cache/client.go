@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// a go-redis client for a redis url: redis://[[user]:password@]host[:port][/database], or rediss:// over TLS. The
+// port defaults to 6379. It connects on its first command, and again after the connection is lost
+func NewClient(raw string) (*redis.Client, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "redis://" + raw
+	}
+	options, err := redis.ParseURL(raw)
+	if err != nil {
+		return nil, fmt.Errorf("not a redis url:\n%s", err)
+	}
+	//redis://password@host, the way some hosted redis services hand out their urls
+	if options.Password == "" && options.Username != "" {
+		options.Password, options.Username = options.Username, ""
+	}
+	return redis.NewClient(options), nil
+}
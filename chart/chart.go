@@ -0,0 +1,202 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	a post's vote history drawn as a line chart, for the api to serve
+	(/api/posts/<id>/chart.svg and .png) so that notifications and dashboards
+	can show an image instead of a sparkline. The upvotes and comments are
+	drawn by time since the post was created, over a grid, with a legend.
+
+	both formats are drawn here with the standard library (see svg.go and
+	png.go) from the same layout. The svg has the post's title on top, the png
+	doesn't: its text is drawn with a tiny built in font that only has what
+	the axes and legend need (see font.go)
+*/
+
+const (
+	DefaultWidth  = 600
+	DefaultHeight = 300
+
+	minWidth  = 200
+	maxWidth  = 2000
+	minHeight = 100
+	maxHeight = 1200
+)
+
+const (
+	upvotesColor  = "#ff4500"
+	commentsColor = "#1f77b4"
+	gridColor     = "#e5e5e5"
+	axisColor     = "#999999"
+	textColor     = "#444444"
+)
+
+type Options struct {
+	Width  int // in pixels, DefaultWidth if 0. Clamped to 200-2000
+	Height int // in pixels, DefaultHeight if 0. Clamped to 100-1200
+}
+
+func (o Options) size() (int, int) {
+	clamp := func(value, def, low, high int) int {
+		switch {
+		case value == 0:
+			return def
+		case value < low:
+			return low
+		case value > high:
+			return high
+		}
+		return value
+	}
+	return clamp(o.Width, DefaultWidth, minWidth, maxWidth), clamp(o.Height, DefaultHeight, minHeight, maxHeight)
+}
+
+type point struct {
+	x, y float64
+}
+
+type tick struct {
+	at    float64 // in pixels
+	label string
+}
+
+type series struct {
+	name   string
+	color  string
+	points []point // in pixels
+}
+
+// where everything goes, in pixels from the top left
+type layout struct {
+	width, height            int
+	scale                    int // of the png's font and lines, 2 on big charts
+	left, top, right, bottom float64
+	xTicks, yTicks           []tick
+	series                   []series
+}
+
+// history's entries (oldest first), by time since the post was created. Entries from before then (they shouldn't
+// exist) are drawn from the first one instead
+func newLayout(history reddit.ListingHistory, options Options) layout {
+	l := layout{scale: 1}
+	l.width, l.height = options.size()
+	if l.height >= 500 {
+		l.scale = 2
+	}
+	s := float64(l.scale)
+	l.left, l.top, l.right, l.bottom = 44*s, 30*s, float64(l.width)-12*s, float64(l.height)-22*s
+
+	entries := history.Entries
+	start := history.Listing.Date
+	if len(entries) > 0 && (start == 0 || entries[0].Date < start) {
+		start = entries[0].Date
+	}
+
+	span, most := uint64(0), 0
+	for _, entry := range entries {
+		if entry.Date-start > span {
+			span = entry.Date - start
+		}
+		if entry.Upvotes > most {
+			most = entry.Upvotes
+		}
+		if entry.Comments > most {
+			most = entry.Comments
+		}
+	}
+
+	step := timeStep(time.Duration(span) * time.Second)
+	if span < uint64(step.Seconds()) {
+		span = uint64(step.Seconds())
+	}
+	top, yStep := yScale(most)
+
+	x := func(seconds uint64) float64 {
+		return l.left + (l.right-l.left)*float64(seconds)/float64(span)
+	}
+	y := func(value int) float64 {
+		return l.bottom - (l.bottom-l.top)*float64(value)/float64(top)
+	}
+
+	for at := time.Duration(0); at.Seconds() <= float64(span); at += step {
+		l.xTicks = append(l.xTicks, tick{at: x(uint64(at.Seconds())), label: durationLabel(at)})
+	}
+	for value := 0; value <= top; value += yStep {
+		l.yTicks = append(l.yTicks, tick{at: y(value), label: countLabel(value)})
+	}
+
+	upvotes := series{name: "upvotes", color: upvotesColor}
+	comments := series{name: "comments", color: commentsColor}
+	for _, entry := range entries {
+		upvotes.points = append(upvotes.points, point{x(entry.Date - start), y(entry.Upvotes)})
+		comments.points = append(comments.points, point{x(entry.Date - start), y(entry.Comments)})
+	}
+	l.series = []series{upvotes, comments}
+
+	return l
+}
+
+// the top of the y axis and the step between its ticks: a round step (1, 2 or 5 times a power of 10) that fits most in
+// at most 5 of them
+func yScale(most int) (int, int) {
+	if most < 1 {
+		most = 1
+	}
+	raw := float64(most) / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, multiple := range []float64{1, 2, 5} {
+		if multiple*magnitude >= raw {
+			step = multiple * magnitude
+			break
+		}
+	}
+	whole := int(math.Max(1, math.Round(step)))
+	return whole * ((most + whole - 1) / whole), whole
+}
+
+// the first time between ticks that fits span in at most 6 of them
+func timeStep(span time.Duration) time.Duration {
+	steps := []time.Duration{
+		10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+		24 * time.Hour, 48 * time.Hour, 7 * 24 * time.Hour,
+	}
+	for _, step := range steps {
+		if span <= 6*step {
+			return step
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
+// eg: 0, 30m, 6h, 2d
+func durationLabel(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// eg: 800, 1.2k, 40k
+func countLabel(value int) string {
+	if value < 1000 {
+		return fmt.Sprint(value)
+	}
+	if value%1000 == 0 {
+		return fmt.Sprintf("%dk", value/1000)
+	}
+	return fmt.Sprintf("%.1fk", float64(value)/1000)
+}
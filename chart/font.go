@@ -0,0 +1,49 @@
+package chart
+
+/*
+	a 5x7 pixel font for the png's axes and legend, so that drawing them
+	doesn't need a font file or a font rendering library. It only has what
+	the labels are made of: digits, the units (k, m, h, d) and the legend's
+	words. Anything else is drawn as a space
+*/
+
+// pixels from one character to the next, at a scale of 1
+const glyphAdvance = 6
+
+// the pixels of each character, top to bottom, # for the ones that are drawn
+var glyphs = map[rune][7]string{
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'.': {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	'-': {".....", ".....", ".....", ".###.", ".....", ".....", "....."},
+	'c': {".....", ".....", ".###.", "#....", "#....", "#...#", ".###."},
+	'd': {"....#", "....#", ".##.#", "#..##", "#...#", "#...#", ".####"},
+	'e': {".....", ".....", ".###.", "#...#", "#####", "#....", ".###."},
+	'h': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "#...#"},
+	'k': {"#....", "#....", "#..#.", "#.#..", "##...", "#.#..", "#..#."},
+	'm': {".....", ".....", "##.#.", "#.#.#", "#.#.#", "#...#", "#...#"},
+	'n': {".....", ".....", "#.##.", "##..#", "#...#", "#...#", "#...#"},
+	'o': {".....", ".....", ".###.", "#...#", "#...#", "#...#", ".###."},
+	'p': {".....", ".....", "####.", "#...#", "####.", "#....", "#...."},
+	's': {".....", ".....", ".####", "#....", ".###.", "....#", "####."},
+	't': {".#...", ".#...", "###..", ".#...", ".#...", ".#..#", "..##."},
+	'u': {".....", ".....", "#...#", "#...#", "#...#", "#..##", ".##.#"},
+	'v': {".....", ".....", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+}
+
+// the width of text in pixels
+func textWidth(text string, scale int) int {
+	count := len([]rune(text))
+	if count == 0 {
+		return 0
+	}
+	return (count*glyphAdvance - 1) * scale
+}
@@ -0,0 +1,113 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+// writes history's chart as a png image
+func PNG(out io.Writer, history reddit.ListingHistory, options Options) error {
+	l := newLayout(history, options)
+	c := canvas{image.NewRGBA(image.Rect(0, 0, l.width, l.height))}
+	s := float64(l.scale)
+
+	c.fill(parseColor("#ffffff"))
+	grid, axis, text := parseColor(gridColor), parseColor(axisColor), parseColor(textColor)
+
+	for _, t := range l.yTicks {
+		c.line(point{l.left, t.at}, point{l.right, t.at}, 1, grid)
+		c.text(t.label, l.left-5*s-float64(textWidth(t.label, l.scale)), t.at-3.5*s, l.scale, text)
+	}
+	for _, t := range l.xTicks {
+		c.line(point{t.at, l.top}, point{t.at, l.bottom}, 1, grid)
+		c.text(t.label, t.at-float64(textWidth(t.label, l.scale))/2, l.bottom+6*s, l.scale, text)
+	}
+	c.line(point{l.left, l.top}, point{l.left, l.bottom}, 1, axis)
+	c.line(point{l.left, l.bottom}, point{l.right, l.bottom}, 1, axis)
+
+	legend := l.right
+	for idx := len(l.series) - 1; idx >= 0; idx -= 1 {
+		series := l.series[idx]
+		lineColor := parseColor(series.color)
+		for i := 1; i < len(series.points); i += 1 {
+			c.line(series.points[i-1], series.points[i], 2*l.scale, lineColor)
+		}
+		if len(series.points) == 1 {
+			c.line(series.points[0], series.points[0], 3*l.scale, lineColor)
+		}
+
+		legend -= float64(textWidth(series.name, l.scale))
+		c.text(series.name, legend, l.top-13*s, l.scale, text)
+		legend -= 14 * s
+		c.line(point{legend, l.top - 10*s}, point{legend + 10*s, l.top - 10*s}, 3*l.scale, lineColor)
+		legend -= 12 * s
+	}
+
+	return png.Encode(out, c.img)
+}
+
+type canvas struct {
+	img *image.RGBA
+}
+
+func (c canvas) fill(col color.RGBA) {
+	for idx := 0; idx < len(c.img.Pix); idx += 4 {
+		c.img.Pix[idx], c.img.Pix[idx+1], c.img.Pix[idx+2], c.img.Pix[idx+3] = col.R, col.G, col.B, col.A
+	}
+}
+
+// a square of size pixels centered on x, y
+func (c canvas) dot(x, y int, size int, col color.RGBA) {
+	for dy := -size / 2; dy < size-size/2; dy += 1 {
+		for dx := -size / 2; dx < size-size/2; dx += 1 {
+			c.img.SetRGBA(x+dx, y+dy, col)
+		}
+	}
+}
+
+// a line width pixels wide, a dot every pixel along it
+func (c canvas) line(from, to point, width int, col color.RGBA) {
+	steps := int(math.Max(math.Abs(to.x-from.x), math.Abs(to.y-from.y)))
+	for step := 0; step <= steps; step += 1 {
+		t := 0.0
+		if steps > 0 {
+			t = float64(step) / float64(steps)
+		}
+		x := from.x + (to.x-from.x)*t
+		y := from.y + (to.y-from.y)*t
+		c.dot(int(math.Round(x)), int(math.Round(y)), width, col)
+	}
+}
+
+// text with its top left corner at x, y, in font.go's font scaled by scale
+func (c canvas) text(text string, x, y float64, scale int, col color.RGBA) {
+	left, top := int(math.Round(x)), int(math.Round(y))
+	for _, char := range text {
+		rows := glyphs[char]
+		for row, line := range rows {
+			for column, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				for dy := 0; dy < scale; dy += 1 {
+					for dx := 0; dx < scale; dx += 1 {
+						c.img.SetRGBA(left+column*scale+dx, top+row*scale+dy, col)
+					}
+				}
+			}
+		}
+		left += glyphAdvance * scale
+	}
+}
+
+// #rrggbb, opaque
+func parseColor(hex string) color.RGBA {
+	value, _ := strconv.ParseUint(hex[1:], 16, 32)
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}
+}
@@ -0,0 +1,73 @@
+package chart
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+// the longest title drawn on a chart, in characters. Longer ones (or ones wider than the chart) are cut short
+const titleLimit = 80
+
+// writes history's chart as an svg image
+func SVG(out io.Writer, history reddit.ListingHistory, options Options) error {
+	l := newLayout(history, options)
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		l.width, l.height, l.width, l.height)
+	fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	title, limit := history.Listing.Title, titleLimit
+	if fits := int((l.right - l.left) / 7); fits < limit {
+		limit = fits
+	}
+	if len([]rune(title)) > limit {
+		title = string([]rune(title)[:limit-1]) + "…"
+	}
+	if title != "" {
+		fmt.Fprintf(w, `<text x="%.1f" y="14" fill="%s" font-size="12" font-weight="bold">%s</text>`+"\n", l.left, textColor, escape(title))
+	}
+
+	for _, t := range l.yTicks {
+		fmt.Fprintf(w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", l.left, t.at, l.right, t.at, gridColor)
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="end">%s</text>`+"\n", l.left-5, t.at+4, textColor, t.label)
+	}
+	for _, t := range l.xTicks {
+		fmt.Fprintf(w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", t.at, l.top, t.at, l.bottom, gridColor)
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="middle">%s</text>`+"\n", t.at, l.bottom+15, textColor, t.label)
+	}
+	fmt.Fprintf(w, `<polyline points="%.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="none" stroke="%s"/>`+"\n",
+		l.left, l.top, l.left, l.bottom, l.right, l.bottom, axisColor)
+
+	legend := l.right
+	for idx := len(l.series) - 1; idx >= 0; idx -= 1 {
+		s := l.series[idx]
+		if len(s.points) > 0 {
+			points := make([]string, len(s.points))
+			for i, p := range s.points {
+				points[i] = fmt.Sprintf("%.1f,%.1f", p.x, p.y)
+			}
+			fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2" stroke-linejoin="round"/>`+"\n",
+				strings.Join(points, " "), s.color)
+		}
+
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="end">%s</text>`+"\n", legend, l.top-6, textColor, s.name)
+		legend -= 6.5*float64(len(s.name)) + 4
+		fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="10" height="3" fill="%s"/>`+"\n", legend-10, l.top-10, s.color)
+		legend -= 22
+	}
+
+	fmt.Fprintln(w, "</svg>")
+	return w.Flush()
+}
+
+func escape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
@@ -0,0 +1,744 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/backup"
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/dedupe"
+	"github.com/jtyrmn/reddit-votewatch/dump"
+	"github.com/jtyrmn/reddit-votewatch/migrate"
+	"github.com/jtyrmn/reddit-votewatch/notify"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/jtyrmn/reddit-votewatch/version"
+)
+
+// subcommands, eg: votewatch export --format csv --since 7d. Each takes its own flags, see votewatch <command> --help
+var commands = map[string]func(args []string){
+	"run":     runTracker,
+	"check":   runCheck,
+	"export":  runExport,
+	"import":  runImport,
+	"show":    runShow,
+	"stats":   runStats,
+	"report":  runReport,
+	"dump":    runDump,
+	"cull":    runCull,
+	"purge":   runPurge,
+	"migrate": runMigrate,
+	"dedupe":  runDedupe,
+	"restore": runRestore,
+}
+
+// what each subcommand does, in the order votewatch help lists them
+var commandDescriptions = [][2]string{
+	{"run", "track posts until stopped. The default when no command is given"},
+	{"check", "check the env variables, reddit, each subreddit and the database, then exit"},
+	{"config", "config init: write a starting .env and subreddits file with every setting"},
+	{"export", "write stored listings and their vote history to a csv or json file"},
+	{"import", "save listings from a file written by export"},
+	{"show", "print a post's current state and vote history, with a sparkline, eg: votewatch show t3_62sjuh"},
+	{"stats", "print aggregate stats of stored posts, eg: votewatch stats --subreddit golang --since 30d"},
+	{"report", "write a standalone html report of stored posts, with charts, eg: votewatch report --since 7d"},
+	{"dump", "stream stored snapshots as ndjson, eg: votewatch dump --since 7d | jq ..."},
+	{"cull", "cull listings past CULLING_AGE once, as the culling job does"},
+	{"purge", "permanently delete archived listings"},
+	{"migrate", "upgrade stored listings to the latest data version"},
+	{"dedupe", "merge listings that are re-submissions of the same content"},
+	{"restore", "load a backup from object storage into the database"},
+	{"version", "print the version, commit and build date of this binary"},
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "usage: votewatch <command> [flags]")
+	fmt.Fprintln(out, "\ncommands:")
+	for _, command := range commandDescriptions {
+		fmt.Fprintf(out, "  %-8s %s\n", command[0], command[1])
+	}
+	fmt.Fprintln(out, "\nevery setting in .env.template can also be given as a flag, overriding the env, eg:")
+	fmt.Fprintln(out, "  votewatch --new-posts-period 2m --subreddits golang,programming --debug-http")
+}
+
+// dumps listings and their vote history from the database to a file. See the dump package
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", dump.CSV, "output format, csv, json or parquet")
+	since := flags.String("since", "", "only export listings created within this long ago, eg: 7d, 12h. Exports everything if not set")
+	subreddit := flags.String("subreddit", "", "only export listings from this subreddit")
+	outPath := flags.String("out", "", "file to write to, or - for stdout. Defaults to votewatch-export.<format>")
+	flags.Parse(args)
+
+	if *format != dump.CSV && *format != dump.JSON && *format != dump.PARQUET {
+		log.Fatalf("unknown format \"%s\", expected %s, %s or %s", *format, dump.CSV, dump.JSON, dump.PARQUET)
+	}
+
+	//no age limit means everything since the epoch
+	maxAge := time.Now().Unix()
+	if *since != "" {
+		age, err := dump.ParseAge(*since)
+		if err != nil {
+			log.Fatal(err)
+		}
+		maxAge = int64(age.Seconds())
+	}
+
+	query := database.ListingsQuery{
+		MaxAge:    maxAge,
+		Subreddit: *subreddit,
+		Sort:      database.OldestFirst,
+		Limit:     util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		if *outPath == "" {
+			*outPath = "votewatch-export." + *format
+		}
+
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal("error creating output file:\n" + err.Error())
+		}
+		defer file.Close()
+		out = file
+	}
+
+	count, err := dump.Export(context.Background(), store, query, *format, out)
+	if err != nil {
+		log.Fatal("error exporting:\n" + err.Error())
+	}
+
+	//stdout is the output in that case, don't mix anything else into it
+	if *outPath != "-" {
+		fmt.Printf("exported %d listings to %s\n", count, *outPath)
+	}
+}
+
+// saves a file written by the export subcommand (or in the same format) to the database
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "input format, csv or json. Guessed from the file extension if not set")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: votewatch import [--format csv|json] <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *format != dump.CSV && *format != dump.JSON {
+		log.Fatalf("unknown format \"%s\", expected %s or %s", *format, dump.CSV, dump.JSON)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal("error opening input file:\n" + err.Error())
+	}
+	defer file.Close()
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	batchSize := util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000)
+	count, err := dump.Import(context.Background(), store, *format, bufio.NewReader(file), batchSize)
+	if err != nil {
+		log.Fatalf("error importing after %d listings:\n%s", count, err)
+	}
+
+	fmt.Printf("imported %d listings from %s\n", count, path)
+}
+
+// prints a post's current state on reddit and every entry recorded under it, oldest first, with a sparkline of its upvotes.
+// Handy for checking that a post is being tracked
+func runShow(args []string) {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	format := flags.String("format", "text", "output format, text or json")
+	offline := flags.Bool("offline", false, "only print the stored history, without asking reddit for the post's current state")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: votewatch show [--format text|json] [--offline] <fullname, eg: t3_62sjuh>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	//posts can be given by their id alone, as it appears in their url
+	ID := reddit.Fullname(flags.Arg(0))
+	if !ID.IsValid() {
+		ID = reddit.Fullname("t3_" + flags.Arg(0))
+	}
+	if !ID.IsValid() {
+		log.Fatalf("\"%s\" isn't a reddit fullname (eg: t3_62sjuh) or post id (eg: 62sjuh)", flags.Arg(0))
+	}
+	if *format != "text" && *format != dump.JSON {
+		log.Fatalf("unknown format \"%s\", expected text or %s", *format, dump.JSON)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	//the post as reddit has it now. Not being able to reach reddit still leaves the history to show
+	var current *reddit.RedditContent
+	if !*offline {
+		r, err := reddit.Connect()
+		if err == nil {
+			var posts *reddit.ContentGroup
+			posts, err = r.FetchPosts(ctx, []reddit.Fullname{ID})
+			if err == nil {
+				if post, exists := (*posts)[ID]; exists {
+					current = &post
+				}
+			}
+		}
+		if err != nil {
+			log.Println("warning: couldn't get the post from reddit:\n" + err.Error())
+		}
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	history, err := store.GetHistory(ctx, ID)
+	if errors.Is(err, database.ErrListingNotFound) && current == nil {
+		log.Fatalf("%s isn't stored", ID)
+	}
+	if err != nil && !errors.Is(err, database.ErrListingNotFound) {
+		log.Fatal("error getting history:\n" + err.Error())
+	}
+
+	if *format == dump.JSON {
+		output := map[string]interface{}{"id": ID, "entries": history}
+		if current != nil {
+			output["post"] = current
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(output)
+		return
+	}
+
+	if current != nil {
+		fmt.Printf("%s in r/%s by u/%s: %s\n", ID, current.Subreddit, current.Author, current.Title)
+		fmt.Printf("now %d upvotes, %d comments, posted %s ago", current.Upvotes, current.Comments,
+			time.Since(time.Unix(int64(current.Date), 0)).Round(time.Minute))
+		if current.RemovedBy != "" {
+			fmt.Printf(", removed (%s)", current.RemovedBy)
+		}
+		fmt.Println()
+	}
+
+	if len(history) == 0 {
+		fmt.Println("nothing recorded yet, it isn't stored")
+		return
+	}
+
+	fmt.Printf("%d entries\n", len(history))
+	if chart := notify.ChartSummary(history); chart != "" {
+		fmt.Println(chart)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "recorded\tupvotes\tcomments")
+	for _, entry := range history {
+		fmt.Fprintf(writer, "%s\t%d\t%d\n", time.Unix(int64(entry.Date), 0).UTC().Format(time.RFC3339), entry.Upvotes, entry.Comments)
+	}
+	writer.Flush()
+}
+
+// the days of posting volume stats prints, the json output has all of them
+const statsDaysShown = 31
+
+// prints aggregate stats of the stored listings (or an export of them): peak scores, time to peak and posting volume.
+// See dump/stats.go
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	subreddit := flags.String("subreddit", "", "only listings from this subreddit")
+	since := flags.String("since", "30d", "only listings created within this long ago, eg: 30d, 12h. all for every listing")
+	from := flags.String("from", "", "a file written by export to read the listings from, instead of the database")
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Parse(args)
+
+	if *format != "text" && *format != dump.JSON {
+		log.Fatalf("unknown format \"%s\", expected text or %s", *format, dump.JSON)
+	}
+	*subreddit = strings.TrimPrefix(*subreddit, "r/")
+
+	maxAge := time.Now().Unix()
+	if *since != "all" {
+		age, err := dump.ParseAge(*since)
+		if err != nil {
+			log.Fatal(err)
+		}
+		maxAge = int64(age.Seconds())
+	}
+
+	var stats dump.Stats
+	if *from != "" {
+		file, err := os.Open(*from)
+		if err != nil {
+			log.Fatal("error opening export:\n" + err.Error())
+		}
+		defer file.Close()
+
+		stats, err = dump.StatsFromDump(strings.TrimPrefix(strings.ToLower(filepath.Ext(*from)), "."), bufio.NewReader(file), maxAge, *subreddit)
+		if err != nil {
+			log.Fatal("error reading export:\n" + err.Error())
+		}
+	} else {
+		store, err := database.Connect()
+		if err != nil {
+			log.Fatal("error connecting to database:\n" + err.Error())
+		}
+		defer store.Close()
+
+		stats, err = dump.StatsFromStore(context.Background(), store, database.ListingsQuery{
+			MaxAge:    maxAge,
+			Subreddit: *subreddit,
+			Sort:      database.OldestFirst,
+			Limit:     util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+		})
+		if err != nil {
+			log.Fatal("error getting listings:\n" + err.Error())
+		}
+	}
+	if *format == dump.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(stats)
+		return
+	}
+
+	where := "every subreddit"
+	if *subreddit != "" {
+		where = "r/" + *subreddit
+	}
+	if stats.Listings == 0 {
+		fmt.Printf("no posts from %s within %s\n", where, *since)
+		return
+	}
+
+	fmt.Printf("%s: %d posts created %s to %s, %d snapshots\n", where, stats.Listings,
+		stats.First.Format("2006-01-02"), stats.Last.Format("2006-01-02"), stats.Snapshots)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "peak score\tmedian %d, mean %.1f\n", stats.MedianPeak, stats.MeanPeak)
+	fmt.Fprintf(writer, "highest\t%d, %s in r/%s: %s\n", stats.Top.Peak, stats.Top.Id, stats.Top.Subreddit, stats.Top.Title)
+	if stats.AverageTimeToPeak > 0 {
+		fmt.Fprintf(writer, "time to peak\tmedian %s, average %s\n", strings.TrimSuffix(stats.MedianTimeToPeak.Round(time.Minute).String(), "0s"),
+			strings.TrimSuffix(stats.AverageTimeToPeak.Round(time.Minute).String(), "0s"))
+	}
+	fmt.Fprintf(writer, "posts per day\tmean %.1f, busiest on %ss\n", stats.MeanPerDay, stats.BusiestWeekday)
+	writer.Flush()
+
+	days := stats.PerDay
+	if len(days) > statsDaysShown {
+		fmt.Printf("\nlast %d days:\n", statsDaysShown)
+		days = days[len(days)-statsDaysShown:]
+	} else {
+		fmt.Println()
+	}
+	writer = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "day\tposts")
+	for _, day := range days {
+		fmt.Fprintf(writer, "%s\t%d\n", day.Day, day.Listings)
+	}
+	writer.Flush()
+}
+
+// writes a self-contained html report of the stored listings (or an export of them) created within a time range, with a
+// section per subreddit. See dump/report.go
+func runReport(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	since := flags.String("since", "7d", "only listings created within this long ago, eg: 7d, 12h. all for every listing")
+	until := flags.String("until", "", "only listings created at least this long ago, eg: 7d with --since 14d for the week before last")
+	subreddit := flags.String("subreddit", "", "only listings from this subreddit")
+	top := flags.Int("top", dump.DefaultReportTop, "# of top posts charted per subreddit")
+	title := flags.String("title", "", "the report's title. Defaults to \"votewatch report\" and the dates")
+	from := flags.String("from", "", "a file written by export to read the listings from, instead of the database")
+	outPath := flags.String("out", "", "file to write to, or - for stdout. Defaults to votewatch-report-<date>.html")
+	flags.Parse(args)
+	*subreddit = strings.TrimPrefix(*subreddit, "r/")
+
+	now := time.Now().UTC()
+	query := database.ListingsQuery{
+		MaxAge:    now.Unix(),
+		Subreddit: *subreddit,
+		Sort:      database.OldestFirst,
+		Limit:     util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+	var start time.Time
+	if *since != "all" {
+		age, err := dump.ParseAge(*since)
+		if err != nil {
+			log.Fatal(err)
+		}
+		query.MaxAge = int64(age.Seconds())
+		start = now.Add(-age)
+	}
+	end := now
+	if *until != "" {
+		age, err := dump.ParseAge(*until)
+		if err != nil {
+			log.Fatal(err)
+		}
+		query.MinAge = int64(age.Seconds())
+		end = now.Add(-age)
+	}
+	if !start.IsZero() && !end.After(start) {
+		log.Fatalf("--until %s should be more recent than --since %s", *until, *since)
+	}
+
+	collector := dump.NewReportCollector(*top)
+	if *from != "" {
+		file, err := os.Open(*from)
+		if err != nil {
+			log.Fatal("error opening export:\n" + err.Error())
+		}
+		defer file.Close()
+
+		err = dump.ReportFromDump(strings.TrimPrefix(strings.ToLower(filepath.Ext(*from)), "."), bufio.NewReader(file), query, collector)
+		if err != nil {
+			log.Fatal("error reading export:\n" + err.Error())
+		}
+	} else {
+		store, err := database.Connect()
+		if err != nil {
+			log.Fatal("error connecting to database:\n" + err.Error())
+		}
+		defer store.Close()
+
+		err = dump.ReportFromStore(context.Background(), store, query, collector)
+		if err != nil {
+			log.Fatal("error getting listings:\n" + err.Error())
+		}
+	}
+
+	if *title == "" {
+		*title = "votewatch report"
+		if *subreddit != "" {
+			*title += " for r/" + *subreddit
+		}
+		if !start.IsZero() {
+			*title += ", " + start.Format("Jan 2") + " to " + end.Format("Jan 2 2006")
+		}
+	}
+	report := collector.Report(*title, start, end)
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		if *outPath == "" {
+			*outPath = "votewatch-report-" + end.Format("2006-01-02") + ".html"
+		}
+
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal("error creating output file:\n" + err.Error())
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := report.WriteHTML(out); err != nil {
+		log.Fatal("error writing report:\n" + err.Error())
+	}
+
+	//stdout is the output in that case, don't mix anything else into it
+	if *outPath != "-" {
+		fmt.Printf("wrote a report of %d posts in %d subreddit(s) to %s\n", report.Overall.Listings, len(report.Subreddits), *outPath)
+	}
+}
+
+// streams the entries recorded within a time range to stdout as ndjson (or csv/parquet), the same as the api's /dump.
+// See api/dump.go
+func runDump(args []string) {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	since := flags.String("since", "1d", "only snapshots recorded since then, an age (eg: 7d, 12h) or a date (eg: 2026-10-01). all for every snapshot")
+	until := flags.String("until", "", "only snapshots recorded before then, an age or a date")
+	subreddit := flags.String("subreddit", "", "only snapshots of posts from this subreddit")
+	post := flags.String("post", "", "only snapshots of these posts, by fullname or post id, separated by commas")
+	minUpvotes := flags.Int("min-upvotes", 0, "only snapshots with at least this many upvotes")
+	format := flags.String("format", dump.NDJSON, "output format, ndjson, csv or parquet")
+	outPath := flags.String("out", "-", "file to write to, or - for stdout")
+	flags.Parse(args)
+
+	now := time.Now()
+	var from time.Time
+	if *since != "all" {
+		var err error
+		from, err = dump.ParseTime(*since, now)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	to := now.Add(time.Second) //until is exclusive, so that what was just recorded is included
+	if *until != "" {
+		var err error
+		to, err = dump.ParseTime(*until, now)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !to.After(from) {
+		log.Fatalf("--until %s should be after --since %s", *until, *since)
+	}
+
+	filter := dump.SnapshotFilter{From: uint64(from.Unix()), Until: uint64(to.Unix()), MinUpvotes: *minUpvotes}
+	if *post != "" {
+		filter.Posts = make(map[reddit.Fullname]bool)
+		for _, value := range strings.Split(*post, ",") {
+			//posts can be given by their id alone, as it appears in their url
+			ID := reddit.Fullname(strings.TrimSpace(value))
+			if !ID.IsValid() {
+				ID = reddit.Fullname("t3_" + strings.TrimSpace(value))
+			}
+			filter.Posts[ID] = true
+		}
+	}
+	query := dump.SnapshotsQuery(now, from, to, strings.TrimPrefix(*subreddit, "r/"))
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal("error creating output file:\n" + err.Error())
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+
+	count, err := dump.ExportSnapshots(context.Background(), store, query, filter, *format, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		log.Fatal("error dumping snapshots:\n" + err.Error())
+	}
+
+	//stdout is the output in that case, don't mix anything else into it
+	if *outPath != "-" {
+		fmt.Printf("wrote %d snapshots to %s\n", count, *outPath)
+	}
+}
+
+// culls the database once, eg: after lowering CULLING_AGE. See CULL_MODE in .env.template
+func runCull(args []string) {
+	flags := flag.NewFlagSet("cull", flag.ExitOnError)
+	flags.Parse(args)
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	err = scheduler.Cull(context.Background(), store)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// permanently deletes listings that were archived by culling (see CULL_MODE in .env.template)
+func runPurge(args []string) {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "only purge archived listings created longer ago than this, eg: 30d. Purges every archived listing if not set")
+	flags.Parse(args)
+
+	var maxAge uint64
+	if *olderThan != "" {
+		age, err := dump.ParseAge(*olderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		maxAge = uint64(age.Seconds())
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	count, err := store.PurgeListings(context.Background(), maxAge)
+	if err != nil {
+		log.Fatal("error purging listings:\n" + err.Error())
+	}
+
+	fmt.Printf("purged %d archived listings\n", count)
+}
+
+// upgrades stored listings to the latest data version. See the migrate package
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.Int("from", 0, "data version the stored listings are at. Every migration after it is run")
+	to := flags.Int("to", migrate.Latest(), "data version to migrate to")
+	dryRun := flags.Bool("dry-run", false, "only count the listings that would be changed, without writing anything")
+	flags.Parse(args)
+
+	descriptions := migrate.Describe(*from)
+	if len(descriptions) == 0 || *to <= *from {
+		fmt.Printf("nothing to migrate, latest data version is %d\n", migrate.Latest())
+		return
+	}
+	fmt.Println("running migrations:")
+	for _, description := range descriptions {
+		fmt.Println("  " + description)
+	}
+
+	query := database.ListingsQuery{
+		MaxAge: time.Now().Unix(), //every listing since the epoch
+		Sort:   database.OldestFirst,
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	result, err := migrate.Run(context.Background(), store, query, *from, *to, *dryRun)
+	if err != nil {
+		log.Fatalf("error migrating after %d listings:\n%s", result.Scanned, err)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d of %d listings would be migrated\n", result.Migrated, result.Scanned)
+	} else {
+		fmt.Printf("migrated %d of %d listings\n", result.Migrated, result.Scanned)
+	}
+}
+
+// merges listings that are re-submissions/crossposts of the same content. See the dedupe package
+func runDedupe(args []string) {
+	flags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the duplicates, without merging anything")
+	flags.Parse(args)
+
+	query := database.ListingsQuery{
+		MaxAge: time.Now().Unix(), //every listing since the epoch
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	groups, err := dedupe.Find(context.Background(), store, query)
+	if err != nil {
+		log.Fatal("error finding duplicates:\n" + err.Error())
+	}
+
+	duplicates := 0
+	for _, group := range groups {
+		IDs := make([]string, len(group.Duplicates))
+		for idx, duplicate := range group.Duplicates {
+			IDs[idx] = string(duplicate.FullId())
+		}
+		fmt.Printf("%s \"%s\": %s\n", group.Kept.FullId(), group.Kept.Title, strings.Join(IDs, ", "))
+		duplicates += len(group.Duplicates)
+	}
+
+	if *dryRun {
+		fmt.Printf("found %d duplicates of %d listings\n", duplicates, len(groups))
+		return
+	}
+
+	deleted, err := dedupe.Merge(context.Background(), store, groups)
+	if err != nil {
+		log.Fatalf("error merging duplicates after %d were merged:\n%s", deleted, err)
+	}
+
+	fmt.Printf("merged %d duplicates into %d listings\n", deleted, len(groups))
+}
+
+// loads a backup from object storage into the database. See the backup package
+func runRestore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	key := flags.String("key", "", "key of the backup to restore. Defaults to the latest backup")
+	flags.Parse(args)
+
+	backups, err := backup.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if backups == nil {
+		log.Fatal("BACKUP_BUCKET isn't set, see .env.template")
+	}
+	if *key == "" {
+		*key = backups.LatestKey()
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		log.Fatal("error connecting to database:\n" + err.Error())
+	}
+	defer store.Close()
+
+	count, err := backups.Restore(context.Background(), store, *key)
+	if err != nil {
+		log.Fatalf("error restoring after %d listings:\n%s", count, err)
+	}
+
+	fmt.Printf("restored %d listings from %s\n", count, *key)
+}
+
+// prints which build this is, see the version package
+func runVersion(args []string) {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Parse(args)
+
+	switch *format {
+	case "text":
+		fmt.Println(version.String())
+	case dump.JSON:
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		out.Encode(version.Get())
+	default:
+		log.Fatalf("unknown format \"%s\", expected text or %s", *format, dump.JSON)
+	}
+}
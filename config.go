@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/backup"
+	"github.com/jtyrmn/reddit-votewatch/cache"
+	"github.com/jtyrmn/reddit-votewatch/notify"
+	"github.com/jtyrmn/reddit-votewatch/publish"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/jtyrmn/reddit-votewatch/webhooks"
+)
+
+/*
+	settings are read where they're used, so a missing or malformed one only
+	shows up once something needs it, one at a time (util.GetEnv halts the
+	program on the first missing one). validateConfig checks all of them up
+	front, before anything connects, and reports every problem at once:
+	missing required variables, numbers that don't parse or are out of range,
+	unknown values for the settings that pick between a few options, and files
+	that can't be found
+*/
+
+// env variables that must be set, whatever else is configured
+var requiredSettings = []string{
+	"REDDIT_CLIENT_ID", "REDDIT_CLIENT_SECRET", "REDDIT_USERNAME", "REDDIT_PASSWORD",
+	"MAX_TRACKING_AGE", "CULLING_AGE", "UNTRACK_POSTS_REFRESH_PERIOD",
+}
+
+// settings that can be read from a file instead, with NAME_FILE. See util/secrets.go
+var secretSettings = []string{
+	"REDDIT_CLIENT_ID", "REDDIT_CLIENT_SECRET", "REDDIT_USERNAME", "REDDIT_PASSWORD",
+	"MONGODB_CONNECTION_STRING", "DATABASE_API_KEY", "DATABASE_BEARER_TOKEN",
+	"BACKUP_ACCESS_KEY_ID", "BACKUP_SECRET_ACCESS_KEY",
+	"CONSUL_HTTP_TOKEN", "OTEL_EXPORTER_OTLP_HEADERS", "ALERT_WEBHOOK_URL", "API_TOKEN",
+	"DISCORD_WEBHOOK_URL", "SLACK_WEBHOOK_URL", "EMAIL_SMTP_PASSWORD", "TELEGRAM_BOT_TOKEN",
+	"KAFKA_REST_PASSWORD", "NATS_URL", "MQTT_URL", "WEBHOOK_SECRET",
+	"INFLUX_TOKEN", "TIMESCALE_URL", "ZAPIER_WEBHOOK_URL", "IFTTT_WEBHOOK_KEY",
+	"MATRIX_ACCESS_TOKEN", "NTFY_TOKEN", "PUSHOVER_APP_TOKEN", "PUSHOVER_USER_KEY",
+	"CONTROL_TOKEN", "PROMETHEUS_REMOTE_WRITE_PASSWORD",
+	"ELASTICSEARCH_API_KEY", "ELASTICSEARCH_PASSWORD", "REDIS_URL",
+}
+
+// integer settings and the lowest value each can take, when they're set. Lengths of time (periods, ages, timeouts) can't be
+// negative, and the ones where 0 doesn't mean disabled have to be positive
+var integerSettings = []struct {
+	name string
+	min  int
+}{
+	{"MAX_TRACKING_AGE", 1},
+	{"CULLING_AGE", 1},
+	{"COMPACT_HISTORY_AGE", 1},
+	{"COMPACT_HISTORY_REFRESH_PERIOD", 0},
+	{"COMPACT_HISTORY_RESOLUTION", 1},
+	{"BACKUP_REFRESH_PERIOD", 0},
+	{"UPDATE_RETRY_PERIOD", 0},
+	{"UPDATE_RETRY_QUEUE_SIZE", 0},
+	{"UPDATE_BATCH_SIZE", 1},
+	{"RATE_LIMIT_REPORT_PERIOD", 0},
+	{"REPORT_CYCLES", 0},
+	{"CATCH_UP_DEPTH", 0},
+	{"MAX_TRACKED_POSTS", 0},
+	{"SCHEDULER_WORKERS", 1},
+	{"JOB_TIMEOUT", 0},
+	{"NEW_POSTS_TIMEOUT", 0},
+	{"UPDATE_TRACKED_POSTS_TIMEOUT", 0},
+	{"CULL_POSTS_TIMEOUT", 0},
+	{"WATCHDOG_INTERVAL_MULTIPLE", 0},
+	{"SHUTDOWN_TIMEOUT", 1},
+	{"REDDIT_BACKOFF_THRESHOLD", 0},
+	{"REDDIT_BACKOFF_BASE", 1},
+	{"REDDIT_BACKOFF_MAX", 1},
+	{"ALERT_FAILURES", 0},
+	{"ALERT_NO_WRITES", 0},
+	{"DATABASE_TIMEOUT", 1},
+	{"DATABASE_RETRIEVE_PAGE_SIZE", 1},
+	{"DATABASE_STREAM_CHUNK_SIZE", 1},
+	{"DATABASE_RETRY_ATTEMPTS", 1},
+	{"DATABASE_RETRY_BACKOFF_MS", 0},
+	{"DATABASE_RETRY_MAX_BACKOFF_MS", 0},
+	{"DATABASE_HEALTH_CHECK_PERIOD", 1},
+	{"DATABASE_HEALTH_CHECK_TIMEOUT", 1},
+	{"DATABASE_HEALTH_CHECK_FAILURES", 1},
+	{"DATABASE_KEEPALIVE_TIME", 0},
+	{"DATABASE_KEEPALIVE_TIMEOUT", 1},
+	{"DATABASE_DISCOVERY_REFRESH", 1},
+	{"SHARD_COUNT", 1},
+	{"SHARD_INDEX", 0},
+	{"LEADER_ELECTION_TTL", 10},
+	{"WEBHOOK_RETRIES", 0},
+}
+
+// periods that can also be given as a duration (eg: 90s, 5m), see util.GetEnvDuration. They have to be positive
+var durationSettings = []string{
+	"NEW_POSTS_REFRESH_PERIOD", "UPDATE_TRACKED_POSTS_REFRESH_PERIOD", "CULL_POSTS_REFRESH_PERIOD", "UNTRACK_POSTS_REFRESH_PERIOD",
+}
+
+// true/false settings, see util.GetEnvBool
+var flagSettings = []string{
+	"CACHE_ACCESS_TOKEN", "DATABASE_TLS", "DATABASE_VERSION_CHECK", "DATABASE_KEEPALIVE_PERMIT_WITHOUT_STREAM",
+	"STATSD_TAGS", "DEBUG_HTTP", "BACKUP_RESTORE_ON_START", "RUN_ONCE", "STARTUP_DIAGNOSTICS",
+	"DISCORD_REMOVED", "DISCORD_ERRORS", "SLACK_REMOVED", "SLACK_ERRORS", "EMAIL_ERRORS",
+	"TELEGRAM_REMOVED", "TELEGRAM_ERRORS", "TELEGRAM_COMMANDS", "NATS_JETSTREAM",
+	"ZAPIER_REMOVED", "ZAPIER_ERRORS", "IFTTT_REMOVED", "IFTTT_ERRORS", "MATRIX_REMOVED", "MATRIX_ERRORS",
+	"NTFY_REMOVED", "NTFY_ERRORS", "PUSHOVER_REMOVED", "PUSHOVER_ERRORS",
+	"MQTT_RETAIN", "EXPORT_TO_BUCKET", "TIMESCALE_SETUP",
+}
+
+// settings that pick between a few options, and the options. Case insensitive, like wherever they're read
+var optionSettings = []struct {
+	name    string
+	options []string
+}{
+	{"STORAGE_BACKEND", []string{"grpc", "mongo", "mongodb", "bolt", "embedded", "memory"}},
+	{"CULL_MODE", []string{"archive", "delete", "coldstorage"}},
+	{"DATABASE_COMPRESSION", []string{"none", "gzip", ""}},
+	{"LOG_FORMAT", []string{"text", "json", ""}},
+	{"LEADER_ELECTION", []string{"consul", ""}},
+}
+
+type configProblems []string
+
+func (p *configProblems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// checks every setting, returning an error listing all of the problems found. See the top of this file
+func validateConfig() error {
+	var problems configProblems
+
+	required := append([]string{}, requiredSettings...)
+
+	//a job's period is only needed when it doesn't have a cron schedule
+	for _, job := range []struct{ schedule, period string }{
+		{"NEW_POSTS_SCHEDULE", "NEW_POSTS_REFRESH_PERIOD"},
+		{"UPDATE_TRACKED_POSTS_SCHEDULE", "UPDATE_TRACKED_POSTS_REFRESH_PERIOD"},
+		{"CULL_POSTS_SCHEDULE", "CULL_POSTS_REFRESH_PERIOD"},
+	} {
+		if value, exists := os.LookupEnv(job.schedule); !exists || strings.TrimSpace(value) == "" {
+			required = append(required, job.period)
+		}
+	}
+
+	//SUBREDDITS replaces the subreddits file, see reddit/subreddit.go
+	subreddits, _ := os.LookupEnv("SUBREDDITS")
+	subredditsFile := strings.TrimSpace(subreddits) == ""
+	if subredditsFile {
+		required = append(required, "SUBREDDITS_PATH")
+	}
+
+	if period, exists := os.LookupEnv("COMPACT_HISTORY_REFRESH_PERIOD"); exists && strings.TrimSpace(period) != "" && strings.TrimSpace(period) != "0" {
+		required = append(required, "COMPACT_HISTORY_AGE")
+	}
+
+	backend, _ := os.LookupEnv("STORAGE_BACKEND")
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", "grpc":
+		required = append(required, "SUBREDDIT_LOGGER_DATABASE_LOCATION")
+	case "mongo", "mongodb":
+		required = append(required, "MONGODB_CONNECTION_STRING", "MONGODB_DATABASE_NAME")
+	}
+
+	cacheToken, _ := util.GetEnvBool("CACHE_ACCESS_TOKEN", true)
+	if cacheToken {
+		required = append(required, "ACCESS_TOKEN_PATH")
+	}
+
+	if bucket, _ := os.LookupEnv("BACKUP_BUCKET"); strings.TrimSpace(bucket) != "" {
+		required = append(required, "BACKUP_ACCESS_KEY_ID", "BACKUP_SECRET_ACCESS_KEY")
+	}
+
+	var missing []string
+	for _, name := range required {
+		if _, exists := os.LookupEnv(name); !exists {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		problems.add("missing %s", strings.Join(missing, ", "))
+	}
+
+	//numbers
+	integers := make(map[string]int)
+	for _, setting := range integerSettings {
+		value, exists := os.LookupEnv(setting.name)
+		if !exists || strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			problems.add("%s=%s isn't a whole number", setting.name, value)
+			continue
+		}
+		if int(i) < setting.min {
+			problems.add("%s=%d must be at least %d", setting.name, i, setting.min)
+			continue
+		}
+		integers[setting.name] = int(i)
+	}
+
+	for _, name := range durationSettings {
+		value, exists := os.LookupEnv(name)
+		if !exists || strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		period, err := util.GetEnvDuration(name, time.Second)
+		if err != nil {
+			problems.add("%s=%s isn't a number of seconds or a duration like 90s", name, value)
+		} else if period <= 0 {
+			problems.add("%s=%s must be positive", name, value)
+		}
+	}
+
+	if count, exists := integers["SHARD_COUNT"]; exists {
+		if index := integers["SHARD_INDEX"]; index >= count {
+			problems.add("SHARD_INDEX=%d must be under SHARD_COUNT=%d", index, count)
+		}
+	}
+
+	//how far into the access token's lifetime it's refreshed, see reddit/api.go
+	if value, exists := os.LookupEnv("TOKEN_REFRESH_LENIENCY"); exists && strings.TrimSpace(value) != "" {
+		leniency, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			problems.add("TOKEN_REFRESH_LENIENCY=%s isn't a number", value)
+		} else if leniency <= 0 || leniency > 1 {
+			problems.add("TOKEN_REFRESH_LENIENCY=%s must be over 0 and at most 1", value)
+		}
+	}
+
+	for _, name := range flagSettings {
+		if _, err := util.GetEnvBool(name, false); err != nil {
+			value, _ := os.LookupEnv(name)
+			problems.add("%s=%s should be true or false", name, value)
+		}
+	}
+
+	for _, setting := range optionSettings {
+		value, exists := os.LookupEnv(setting.name)
+		if !exists {
+			continue
+		}
+		if !contains(setting.options, strings.ToLower(strings.TrimSpace(value))) {
+			problems.add("%s=%s should be one of %s", setting.name, value, strings.Join(nonEmpty(setting.options), ", "))
+		}
+	}
+
+	if value, exists := os.LookupEnv("TRACKED_POSTS_EVICTION"); exists {
+		if _, err := reddit.ParseEvictionPolicy(value); err != nil {
+			problems.add("TRACKED_POSTS_EVICTION: %s", err)
+		}
+	}
+
+	//notification rules and templates, the brokers to publish to and the webhook rules. See the notify, publish and webhooks packages
+	for _, err := range notify.CheckConfig() {
+		problems.add("%s", err)
+	}
+	for _, err := range publish.CheckConfig() {
+		problems.add("%s", err)
+	}
+	for _, err := range webhooks.CheckConfig() {
+		problems.add("%s", err)
+	}
+	if _, err := cache.FromEnv(); err != nil {
+		problems.add("%s", err)
+	}
+	if _, err := backup.SnapshotExportFromEnv(); err != nil {
+		problems.add("%s", err)
+	}
+	if _, err := watchGroups(); err != nil {
+		problems.add("%s", err)
+	}
+
+	//files
+	if path, exists := os.LookupEnv("SUBREDDITS_PATH"); exists && subredditsFile {
+		if _, err := os.Stat(path); err != nil {
+			problems.add("SUBREDDITS_PATH: %s", err)
+		}
+	}
+	if path, exists := os.LookupEnv("DATABASE_TLS_CA_FILE"); exists && path != "" {
+		if _, err := os.Stat(path); err != nil {
+			problems.add("DATABASE_TLS_CA_FILE: %s", err)
+		}
+	}
+
+	//files that are written to only need their directory to exist
+	directories := map[string]bool{"ACCESS_TOKEN_PATH": cacheToken}
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "bolt", "embedded":
+		directories["EMBEDDED_DATABASE_PATH"] = true
+	}
+	for _, name := range []string{"ACCESS_TOKEN_PATH", "EMBEDDED_DATABASE_PATH"} {
+		path, exists := os.LookupEnv(name)
+		if !exists || !directories[name] {
+			continue
+		}
+		if info, err := os.Stat(filepath.Dir(path)); err != nil {
+			problems.add("%s: %s", name, err)
+		} else if !info.IsDir() {
+			problems.add("%s: %s isn't a directory", name, filepath.Dir(path))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration (see .env.template):\n  %s", strings.Join(problems, "\n  "))
+}
+
+// checks the settings in env (see candidateEnv) like validateConfig does, without setting them in this process, whose
+// jobs read its env variables while they run. They're checked by `votewatch [--profile p] check --config` run with them
+func checkEnv(env map[string]string, p string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.New("error finding this program to check the configuration with:\n" + err.Error())
+	}
+
+	var args []string
+	if p != "" {
+		args = append(args, "--profile", p)
+	}
+	command := exec.Command(executable, append(args, "check", "--config")...)
+	command.Env = make([]string, 0, len(env))
+	for name, value := range env {
+		command.Env = append(command.Env, name+"="+value)
+	}
+
+	//the problems are printed to stdout, anything that stops the check before it gets to them (eg: an unreadable env file) to stderr
+	output, err := command.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if len(bytes.TrimSpace(output)) == 0 {
+			output = exitErr.Stderr
+		}
+		return errors.New(strings.TrimSpace(string(output)))
+	}
+	if err != nil {
+		return errors.New("error checking the configuration:\n" + err.Error())
+	}
+	return nil
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(options []string) []string {
+	var result []string
+	for _, option := range options {
+		if option != "" {
+			result = append(result, option)
+		}
+	}
+	return result
+}
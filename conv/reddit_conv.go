@@ -10,7 +10,7 @@ import (
 	grpc pb.RedditContent structs
 */
 
-func ToRedditContent(pb pb.RedditContent) reddit.RedditContent {
+func ToRedditContent(pb *pb.RedditContent) reddit.RedditContent {
 	rc := reddit.RedditContent{
 		Id:          pb.MetaData.Id,
 		ContentType: pb.MetaData.ContentType,
@@ -19,6 +19,12 @@ func ToRedditContent(pb pb.RedditContent) reddit.RedditContent {
 		Comments:    int(pb.MetaData.Comments),
 		Date:        pb.MetaData.DateCreated,
 		QueryDate:   pb.MetaData.DateQueried,
+		Subreddit:   pb.MetaData.Subreddit,
+		Author:      pb.MetaData.Author,
+		Url:         pb.MetaData.Url,
+
+		UpvotesPerHour:  pb.MetaData.UpvotesPerHour,
+		CommentsPerHour: pb.MetaData.CommentsPerHour,
 	}
 
 	return rc
@@ -35,8 +41,59 @@ func ToGrpc(rc reddit.RedditContent) pb.RedditContent {
 			Comments: uint32(rc.Comments),
 			DateCreated: rc.Date,
 			DateQueried: rc.QueryDate,
+			Subreddit: rc.Subreddit,
+			Author: rc.Author,
+			Url: rc.Url,
+			UpvotesPerHour: rc.UpvotesPerHour,
+			CommentsPerHour: rc.CommentsPerHour,
 		},
 		Entries: make([]*pb.RedditContent_ListingEntry, 0), // reddit.RedditContents have no entries by default
 		// allocating for an empty array might be expensive but leaving it null is sketchy
 	}
-} 
\ No newline at end of file
+} 
+//same as ToRedditContent, but keeps the entries recorded under the listing
+func ToListingHistory(pb *pb.RedditContent) reddit.ListingHistory {
+	return reddit.ListingHistory{
+		Listing: ToRedditContent(pb),
+		Entries: ToSnapshots(pb.Entries),
+	}
+}
+
+//same as ToGrpc, but sends the listing's entries along with it
+func HistoryToGrpc(history reddit.ListingHistory) *pb.RedditContent {
+	rc := ToGrpc(history.Listing)
+	rc.Entries = SnapshotsToGrpc(history.Entries)
+	return &rc
+}
+
+func ToSnapshots(entries []*pb.RedditContent_ListingEntry) []reddit.Snapshot {
+	snapshots := make([]reddit.Snapshot, len(entries))
+	for idx, entry := range entries {
+		snapshots[idx] = reddit.Snapshot{
+			Upvotes:  int(entry.Upvotes),
+			Comments: int(entry.Comments),
+			Date:     entry.DateQueried,
+
+			UpvotesPerHour:  entry.UpvotesPerHour,
+			CommentsPerHour: entry.CommentsPerHour,
+		}
+	}
+
+	return snapshots
+}
+
+func SnapshotsToGrpc(snapshots []reddit.Snapshot) []*pb.RedditContent_ListingEntry {
+	entries := make([]*pb.RedditContent_ListingEntry, len(snapshots))
+	for idx, snapshot := range snapshots {
+		entries[idx] = &pb.RedditContent_ListingEntry{
+			Upvotes:     uint32(snapshot.Upvotes),
+			Comments:    uint32(snapshot.Comments),
+			DateQueried: snapshot.Date,
+
+			UpvotesPerHour:  snapshot.UpvotesPerHour,
+			CommentsPerHour: snapshot.CommentsPerHour,
+		}
+	}
+
+	return entries
+}
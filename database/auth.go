@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+/*
+	this file handles authenticating with subreddit-logger-database so that the
+	service can be exposed beyond localhost. Every call carries an api key and/or
+	bearer token as grpc metadata, and the connection itself can be secured with
+	TLS so those secrets aren't sent in the clear
+*/
+
+// attaches the configured secrets to every call as metadata
+type tokenCredentials struct {
+	apiKey      string
+	bearerToken string
+	secure      bool // whether or not the connection uses TLS
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := make(map[string]string)
+	if t.apiKey != "" {
+		md["x-api-key"] = t.apiKey
+	}
+	if t.bearerToken != "" {
+		md["authorization"] = "Bearer " + t.bearerToken
+	}
+
+	return md, nil
+}
+
+// the token is allowed over plaintext so that local setups keep working. authDialOptions() warns about it instead
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// builds the dial options for TLS and per-call authentication from the DATABASE_* env variables
+func authDialOptions() ([]grpc.DialOption, error) {
+	opts := make([]grpc.DialOption, 0, 2)
+
+	useTLS, err := util.GetEnvBool("DATABASE_TLS", false)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		//a custom CA is needed for self-signed certificates. Otherwise the system's CAs are used
+		if caPath, exists := os.LookupEnv("DATABASE_TLS_CA_FILE"); exists && caPath != "" {
+			ca, err := os.ReadFile(caPath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading DATABASE_TLS_CA_FILE:\n%s", err)
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("DATABASE_TLS_CA_FILE contains no PEM certificates")
+			}
+			config.RootCAs = pool
+		}
+
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	token := tokenCredentials{
+		apiKey:      os.Getenv("DATABASE_API_KEY"),
+		bearerToken: os.Getenv("DATABASE_BEARER_TOKEN"),
+		secure:      useTLS,
+	}
+	if token.apiKey != "" || token.bearerToken != "" {
+		if !token.secure {
+			fmt.Println("warning: sending database credentials without TLS. Set DATABASE_TLS=true unless the database service is on localhost")
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(token))
+	}
+
+	return opts, nil
+}
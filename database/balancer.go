@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/health" // enables the health checks in the service config below
+	"google.golang.org/grpc/resolver"
+)
+
+/*
+	subreddit-logger-database can run as several replicas, so that the watcher
+	stays up while one of them restarts. SUBREDDIT_LOGGER_DATABASE_LOCATION can
+	either be a comma separated list of addresses (eg: "db1:8080,db2:8080") or a
+	name that resolves to several addresses (see discovery.go). Calls are then spread over
+	the replicas with grpc's round_robin balancer, which skips replicas that
+	fail the standard grpc health check (see health.go for the protocol).
+*/
+
+// scheme of the resolver that serves a comma separated list of addresses
+const replicasScheme = "replicas"
+
+// returns the target to dial for location, along with the dial options that set up load balancing
+func balancerDialOptions(location string) (string, []grpc.DialOption) {
+	target := location
+	opts := make([]grpc.DialOption, 0)
+	balanced := true
+
+	switch {
+	case strings.HasPrefix(location, "dns:///"):
+	case strings.HasPrefix(location, kubernetesScheme+":///"):
+		target = kubernetesTarget(location)
+	case strings.HasPrefix(location, consulScheme+":///"):
+		opts = append(opts, grpc.WithResolvers(consulResolverBuilder{}))
+	case strings.Contains(location, ","):
+		addresses := make([]resolver.Address, 0)
+		for _, address := range strings.Split(location, ",") {
+			address = strings.TrimSpace(address)
+			if address == "" {
+				continue
+			}
+
+			// each replica's TLS certificate is checked against its own host name
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				host = address
+			}
+			addresses = append(addresses, resolver.Address{Addr: address, ServerName: host})
+		}
+
+		target = replicasScheme + ":///" + strings.ReplaceAll(location, " ", "")
+		opts = append(opts, grpc.WithResolvers(&staticResolverBuilder{addresses: addresses}))
+	default:
+		balanced = false
+	}
+
+	policy := strings.ToLower(util.GetEnvOptional("DATABASE_LOAD_BALANCING", ""))
+	switch policy {
+	case "":
+		if !balanced {
+			return target, opts
+		}
+		policy = "round_robin"
+	case "round_robin", "pick_first":
+	default:
+		fmt.Printf("warning: unknown DATABASE_LOAD_BALANCING \"%s\", using round_robin...\n", policy)
+		policy = "round_robin"
+	}
+
+	serviceConfig := fmt.Sprintf(`{"loadBalancingConfig": [{"%s": {}}], "healthCheckConfig": {"serviceName": ""}}`, policy)
+	opts = append(opts, grpc.WithDefaultServiceConfig(serviceConfig))
+
+	return target, opts
+}
+
+// resolves every target to the same fixed list of addresses
+type staticResolverBuilder struct {
+	addresses []resolver.Address
+}
+
+func (b *staticResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	err := cc.UpdateState(resolver.State{Addresses: b.addresses})
+	if err != nil {
+		return nil, err
+	}
+
+	return staticResolver{}, nil
+}
+
+func (b *staticResolverBuilder) Scheme() string {
+	return replicasScheme
+}
+
+// the addresses never change, so there's nothing to do
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (staticResolver) Close() {}
@@ -0,0 +1,528 @@
+package database
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	bolt "go.etcd.io/bbolt"
+)
+
+/*
+	This file contains an embedded Store (STORAGE_BACKEND=bolt) for
+	ultra-light deployments that don't want to run any database at all.
+	Everything is kept in a single bbolt file at EMBEDDED_DATABASE_PATH, with
+	two buckets:
+
+		listings - fullname -> {"listing":{...},"archived":true}
+		entries  - fullname -> a bucket of the listing's entries, appended
+		           under increasing sequence numbers, so oldest first
+
+	only the listings being read or written are loaded into memory, and every
+	write is committed (and synced) before the call returns. Only one process
+	can have the file open at a time
+*/
+
+var (
+	boltListings = []byte("listings")
+	boltEntries  = []byte("entries")
+)
+
+type boltStore struct {
+	db *bolt.DB
+}
+
+// a value of the listings bucket
+type boltListing struct {
+	Listing  plainContent `json:"listing"`
+	Archived bool         `json:"archived,omitempty"`
+}
+
+/*
+reddit.RedditContent has a custom UnmarshalJSON for parsing reddit's api
+responses, which doesn't round-trip with its own json tags. Converting to
+this type drops the method so the standard encoding is used both ways
+*/
+type plainContent reddit.RedditContent
+
+// opens (or creates) the database file at EMBEDDED_DATABASE_PATH
+func connectBolt() (*boltStore, error) {
+	path := util.GetEnvOptional("EMBEDDED_DATABASE_PATH", "./votewatch.db")
+
+	//another process holding the file makes Open wait, give up instead of hanging
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s:\n%s", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltListings, boltEntries} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error setting up %s:\n%s", path, err)
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Close() {
+	s.db.Close()
+}
+
+func (s *boltStore) Healthy() bool {
+	return true
+}
+
+// the stored listing of ID, or nil if it isn't stored
+func getBoltListing(tx *bolt.Tx, ID reddit.Fullname) (*boltListing, error) {
+	value := tx.Bucket(boltListings).Get([]byte(ID))
+	if value == nil {
+		return nil, nil
+	}
+
+	var stored boltListing
+	err := json.Unmarshal(value, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("error reading listing %s:\n%s", ID, err)
+	}
+
+	return &stored, nil
+}
+
+func putBoltListing(tx *bolt.Tx, stored boltListing) error {
+	value, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(boltListings).Put([]byte(reddit.RedditContent(stored.Listing).FullId()), value)
+}
+
+// appends entries to the listing's entries, after the ones already there
+func appendBoltEntries(tx *bolt.Tx, ID reddit.Fullname, entries []Snapshot) error {
+	bucket, err := tx.Bucket(boltEntries).CreateBucketIfNotExists([]byte(ID))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, sequence)
+
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		err = bucket.Put(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// every entry of the listing, oldest first
+func getBoltEntries(tx *bolt.Tx, ID reddit.Fullname) ([]Snapshot, error) {
+	entries := make([]Snapshot, 0)
+	bucket := tx.Bucket(boltEntries).Bucket([]byte(ID))
+	if bucket == nil {
+		return entries, nil
+	}
+
+	err := bucket.ForEach(func(key, value []byte) error {
+		var entry Snapshot
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			return fmt.Errorf("error reading an entry of %s:\n%s", ID, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries, err
+}
+
+// replaces every entry of the listing
+func replaceBoltEntries(tx *bolt.Tx, ID reddit.Fullname, entries []Snapshot) error {
+	err := tx.Bucket(boltEntries).DeleteBucket([]byte(ID))
+	if err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+
+	return appendBoltEntries(tx, ID, entries)
+}
+
+// deletes the listing along with its entries
+func deleteBoltListing(tx *bolt.Tx, ID reddit.Fullname) error {
+	err := tx.Bucket(boltListings).Delete([]byte(ID))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(boltEntries).DeleteBucket([]byte(ID))
+	if err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// every stored listing. Buckets can't be changed while they're iterated over, so the callers that change listings go
+// through this list instead
+func allBoltListings(tx *bolt.Tx) ([]boltListing, error) {
+	listings := make([]boltListing, 0)
+	err := tx.Bucket(boltListings).ForEach(func(key, value []byte) error {
+		var stored boltListing
+		err := json.Unmarshal(value, &stored)
+		if err != nil {
+			return fmt.Errorf("error reading listing %s:\n%s", key, err)
+		}
+		listings = append(listings, stored)
+		return nil
+	})
+
+	return listings, err
+}
+
+func (s *boltStore) SaveListings(ctx context.Context, listings reddit.ContentGroup) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for ID, listing := range listings {
+			if tx.Bucket(boltListings).Get([]byte(ID)) != nil {
+				continue
+			}
+
+			err := putBoltListing(tx, boltListing{Listing: plainContent(listing)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error saving listings:\n%s", err)
+	}
+
+	return nil
+}
+
+func (s *boltStore) RecordNewData(ctx context.Context, newData reddit.ContentGroup) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for ID, listing := range newData {
+			stored, err := getBoltListing(tx, ID)
+			if err != nil {
+				return err
+			}
+			if stored == nil {
+				continue
+			}
+
+			stored.Listing = plainContent(listing)
+			err = putBoltListing(tx, *stored)
+			if err != nil {
+				return err
+			}
+			err = appendBoltEntries(tx, ID, []Snapshot{listing.Snapshot()})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error recording new data:\n%s", err)
+	}
+
+	return nil
+}
+
+func (s *boltStore) ReplaceListings(ctx context.Context, histories []ListingHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, history := range histories {
+			ID := history.Listing.FullId()
+			stored, err := getBoltListing(tx, ID)
+			if err != nil {
+				return err
+			}
+
+			//archived listings stay archived
+			replaced := boltListing{Listing: plainContent(history.Listing)}
+			if stored != nil {
+				replaced.Archived = stored.Archived
+			}
+			err = putBoltListing(tx, replaced)
+			if err != nil {
+				return err
+			}
+			err = replaceBoltEntries(tx, ID, history.Entries)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error replacing listings:\n%s", err)
+	}
+
+	return nil
+}
+
+// the listings of the page described by query, as read within tx. Returns the next page's cursor as well
+func boltPage(tx *bolt.Tx, query ListingsQuery) ([]reddit.RedditContent, string, error) {
+	stored, err := allBoltListings(tx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	listings := make([]reddit.RedditContent, len(stored))
+	for idx, listing := range stored {
+		listings[idx] = reddit.RedditContent(listing.Listing)
+	}
+
+	return paginate(listings, query)
+}
+
+func (s *boltStore) RecieveListingsPage(ctx context.Context, set reddit.ContentGroup, query ListingsQuery) (string, int, error) {
+	if err := ctx.Err(); err != nil {
+		return "", 0, err
+	}
+
+	var page []reddit.RedditContent
+	var next string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		page, next, err = boltPage(tx, query)
+		return err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, listing := range page {
+		set[listing.FullId()] = listing
+	}
+
+	return next, len(page), nil
+}
+
+func (s *boltStore) RecieveHistoryPage(ctx context.Context, query ListingsQuery) ([]ListingHistory, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var histories []ListingHistory
+	var next string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		page, cursor, err := boltPage(tx, query)
+		if err != nil {
+			return err
+		}
+
+		histories = make([]ListingHistory, len(page))
+		for idx, listing := range page {
+			entries, err := getBoltEntries(tx, listing.FullId())
+			if err != nil {
+				return err
+			}
+			histories[idx] = ListingHistory{Listing: listing, Entries: entries}
+		}
+
+		next = cursor
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return histories, next, nil
+}
+
+func (s *boltStore) GetHistory(ctx context.Context, ID reddit.Fullname) ([]Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltListings).Get([]byte(ID)) == nil {
+			return ErrListingNotFound
+		}
+
+		var err error
+		entries, err = getBoltEntries(tx, ID)
+		return err
+	})
+
+	return entries, err
+}
+
+func (s *boltStore) CullListings(ctx context.Context, maxAge uint64, archive bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	oldest := uint64(time.Now().Unix()) - maxAge
+	culled := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		listings, err := allBoltListings(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, stored := range listings {
+			if stored.Listing.Date >= oldest {
+				continue
+			}
+
+			if !archive {
+				err = deleteBoltListing(tx, reddit.RedditContent(stored.Listing).FullId())
+			} else if !stored.Archived {
+				stored.Archived = true
+				err = putBoltListing(tx, stored)
+			} else {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			culled += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error culling listings:\n%s", err)
+	}
+
+	return culled, nil
+}
+
+func (s *boltStore) PurgeListings(ctx context.Context, maxAge uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	oldest := uint64(time.Now().Unix()) - maxAge
+	purged := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		listings, err := allBoltListings(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, stored := range listings {
+			if !stored.Archived || stored.Listing.Date >= oldest {
+				continue
+			}
+
+			err = deleteBoltListing(tx, reddit.RedditContent(stored.Listing).FullId())
+			if err != nil {
+				return err
+			}
+			purged += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error purging listings:\n%s", err)
+	}
+
+	return purged, nil
+}
+
+func (s *boltStore) CompactHistory(ctx context.Context, maxAge uint64, resolution uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	cutoff := uint64(time.Now().Unix()) - maxAge
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		IDs := make([]reddit.Fullname, 0)
+		err := tx.Bucket(boltEntries).ForEach(func(key, value []byte) error {
+			IDs = append(IDs, reddit.Fullname(key))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, ID := range IDs {
+			entries, err := getBoltEntries(tx, ID)
+			if err != nil {
+				return err
+			}
+
+			kept := downsample(entries, cutoff, resolution)
+			if len(kept) == len(entries) {
+				continue
+			}
+
+			err = replaceBoltEntries(tx, ID, kept)
+			if err != nil {
+				return err
+			}
+			removed += len(entries) - len(kept)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error compacting history:\n%s", err)
+	}
+
+	return removed, nil
+}
+
+func (s *boltStore) DeleteListings(ctx context.Context, IDs []reddit.Fullname) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, ID := range IDs {
+			if tx.Bucket(boltListings).Get([]byte(ID)) == nil {
+				continue
+			}
+
+			err := deleteBoltListing(tx, ID)
+			if err != nil {
+				return err
+			}
+			deleted += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error deleting listings:\n%s", err)
+	}
+
+	return deleted, nil
+}
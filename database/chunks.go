@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+
+	"github.com/jtyrmn/reddit-votewatch/conv"
+	"github.com/jtyrmn/reddit-votewatch/pb"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc/metadata"
+)
+
+/*
+	SaveListings, UpdateListings and ReplaceListings can carry tens of thousands of listings in
+	a single cycle. Instead of one huge stream, the listings are sent as a
+	series of smaller streams (chunks) that are each acknowledged by the
+	service. A failed chunk is retried on its own (see retry.go), so a failure
+	late in a batch doesn't restart it from the beginning, and callers can
+	follow along through a progress callback (see WithProgress).
+
+	Every chunk carries a random batch-id header. When a connection drops after
+	the service received a chunk but before its acknowledgement arrived, the
+	retried chunk has the same batch-id, so the service can recognize it and
+	not insert or count it twice. Services that don't know the header ignore it
+*/
+
+// called after every acknowledged chunk with the # of listings acknowledged so far and the total
+type ProgressFunc func(sent, total int)
+
+type progressKey struct{}
+
+// returns a copy of ctx that makes SaveListings and RecordNewData report their progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// returned when a chunked call fails partway through. The first Sent listings were acknowledged by the service
+type PartialError struct {
+	Sent  int
+	Total int
+	Err   error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("failed after %d/%d listings were acknowledged:\n%s", e.Sent, e.Total, e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// how many listings are sent per stream. 0 sends every listing in a single stream
+func chunkSize() int {
+	size := util.GetEnvIntDefault("DATABASE_STREAM_CHUNK_SIZE", 500)
+	if size < 0 {
+		fmt.Printf("warning: DATABASE_STREAM_CHUNK_SIZE must not be negative. Defaulting to 0...\n")
+		size = 0
+	}
+
+	return size
+}
+
+// headers of a SaveListings/UpdateListings/ReplaceListings stream sending chunk
+func chunkMetadata(chunk []*pb.RedditContent) (metadata.MD, error) {
+	batchID, err := newBatchID()
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.New(map[string]string{
+		"listings-count": strconv.Itoa(len(chunk)),
+		"batch-id":       batchID,
+	}), nil
+}
+
+// returns a random (version 4) uuid
+func newBatchID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("error generating batch-id:\n%s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// converts listings for sendChunked
+func toGrpcListings(listings reddit.ContentGroup) []*pb.RedditContent {
+	converted := make([]*pb.RedditContent, 0, len(listings))
+	for _, listing := range listings {
+		toSend := conv.ToGrpc(listing)
+		converted = append(converted, &toSend)
+	}
+
+	return converted
+}
+
+// sends listings through send (one stream per call) in chunks of c.chunkSize. A chunk that fails transiently is sent
+// again, with the same headers. name is the call, for logging
+func (c *connection) sendChunked(ctx context.Context, name string, listings []*pb.RedditContent, send func(context.Context, []*pb.RedditContent) error) error {
+	total := len(listings)
+	size := c.chunkSize
+	if size == 0 || size > total {
+		size = total
+	}
+
+	progress := progressFrom(ctx)
+	chunk := make([]*pb.RedditContent, 0, size)
+	sent := 0
+
+	flush := func() error {
+		// the listings-count and batch-id headers
+		md, err := chunkMetadata(chunk)
+		if err != nil {
+			return err
+		}
+		chunkCtx := metadata.NewOutgoingContext(ctx, md)
+
+		err = c.retry.do(chunkCtx, name, func() error {
+			return send(chunkCtx, chunk)
+		})
+		if err != nil {
+			if sent == 0 {
+				return err
+			}
+			return &PartialError{Sent: sent, Total: total, Err: err}
+		}
+
+		sent += len(chunk)
+		chunk = chunk[:0]
+		if progress != nil {
+			progress(sent, total)
+		}
+		return nil
+	}
+
+	for _, listing := range listings {
+		chunk = append(chunk, listing)
+
+		if len(chunk) == size {
+			err := flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(chunk) > 0 {
+		return flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// a retry policy that doesn't make tests wait
+func testRetryPolicy() retryPolicy {
+	return retryPolicy{
+		codes:          []codes.Code{codes.Unavailable},
+		maxAttempts:    3,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     time.Millisecond,
+	}
+}
+
+func testListings(count int) []*pb.RedditContent {
+	listings := make([]*pb.RedditContent, count)
+	for idx := range listings {
+		listings[idx] = &pb.RedditContent{Id: fmt.Sprintf("t3_%d", idx)}
+	}
+	return listings
+}
+
+// what a send func was called with
+type sentChunk struct {
+	IDs     []string
+	count   string // the listings-count header
+	batchID string
+}
+
+// a send func for sendChunked that records its calls, and returns the errors in fails in order, then nil
+func recordingSend(sent *[]sentChunk, fails ...error) func(context.Context, []*pb.RedditContent) error {
+	return func(ctx context.Context, chunk []*pb.RedditContent) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		call := sentChunk{}
+		for _, listing := range chunk {
+			call.IDs = append(call.IDs, listing.Id)
+		}
+		if values := md.Get("listings-count"); len(values) == 1 {
+			call.count = values[0]
+		}
+		if values := md.Get("batch-id"); len(values) == 1 {
+			call.batchID = values[0]
+		}
+		*sent = append(*sent, call)
+
+		if len(fails) > 0 {
+			err := fails[0]
+			fails = fails[1:]
+			return err
+		}
+		return nil
+	}
+}
+
+func TestSendChunked(t *testing.T) {
+	tests := []struct {
+		listings  int
+		chunkSize int
+		chunks    []int
+	}{
+		{listings: 10, chunkSize: 3, chunks: []int{3, 3, 3, 1}},
+		{listings: 9, chunkSize: 3, chunks: []int{3, 3, 3}},
+		{listings: 2, chunkSize: 500, chunks: []int{2}},
+		{listings: 7, chunkSize: 0, chunks: []int{7}}, // 0 sends everything at once
+		{listings: 0, chunkSize: 3, chunks: nil},
+	}
+
+	for _, test := range tests {
+		c := &connection{chunkSize: test.chunkSize, retry: testRetryPolicy()}
+		listings := testListings(test.listings)
+
+		var progress [][2]int
+		ctx := WithProgress(context.Background(), func(sent, total int) {
+			progress = append(progress, [2]int{sent, total})
+		})
+
+		var sent []sentChunk
+		err := c.sendChunked(ctx, "test", listings, recordingSend(&sent))
+		if err != nil {
+			t.Errorf("%d listings in chunks of %d: %s", test.listings, test.chunkSize, err)
+			continue
+		}
+
+		var sizes []int
+		var IDs []string
+		var expectedProgress [][2]int
+		for _, call := range sent {
+			sizes = append(sizes, len(call.IDs))
+			IDs = append(IDs, call.IDs...)
+			if call.count != strconv.Itoa(len(call.IDs)) {
+				t.Errorf("%d listings in chunks of %d: listings-count header is %q for %d listings", test.listings, test.chunkSize, call.count, len(call.IDs))
+			}
+			expectedProgress = append(expectedProgress, [2]int{len(IDs), test.listings})
+		}
+		if !reflect.DeepEqual(sizes, test.chunks) {
+			t.Errorf("%d listings in chunks of %d were sent as %v, expected %v", test.listings, test.chunkSize, sizes, test.chunks)
+		}
+		for idx, ID := range IDs {
+			if ID != listings[idx].Id {
+				t.Errorf("%d listings in chunks of %d: listing %d sent was %s, expected %s", test.listings, test.chunkSize, idx, ID, listings[idx].Id)
+				break
+			}
+		}
+		if !reflect.DeepEqual(progress, expectedProgress) {
+			t.Errorf("%d listings in chunks of %d reported progress %v, expected %v", test.listings, test.chunkSize, progress, expectedProgress)
+		}
+	}
+}
+
+func TestSendChunkedFailures(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "service restarting")
+	invalid := status.Error(codes.InvalidArgument, "bad listing")
+
+	tests := []struct {
+		name  string
+		fails []error
+		calls int
+		sent  int // listings acknowledged before the failure, -1 if it succeeds
+	}{
+		{name: "transient failure", fails: []error{nil, unavailable}, calls: 5, sent: -1},
+		{name: "first chunk fails", fails: []error{invalid}, calls: 1, sent: 0},
+		{name: "third chunk fails", fails: []error{nil, nil, invalid}, calls: 3, sent: 6},
+		{name: "out of attempts", fails: []error{nil, unavailable, unavailable, unavailable}, calls: 4, sent: 3},
+	}
+
+	for _, test := range tests {
+		c := &connection{chunkSize: 3, retry: testRetryPolicy()}
+		var sent []sentChunk
+		err := c.sendChunked(context.Background(), "test", testListings(10), recordingSend(&sent, test.fails...))
+
+		if len(sent) != test.calls {
+			t.Errorf("%s: sent %d times, expected %d", test.name, len(sent), test.calls)
+		}
+
+		var partial *PartialError
+		switch {
+		case test.sent == -1:
+			if err != nil {
+				t.Errorf("%s: %s", test.name, err)
+			}
+		case err == nil:
+			t.Errorf("%s didn't fail", test.name)
+		case test.sent == 0:
+			if errors.As(err, &partial) {
+				t.Errorf("%s: failing before anything was acknowledged returned %s", test.name, err)
+			}
+		case !errors.As(err, &partial):
+			t.Errorf("%s: %s, expected a PartialError", test.name, err)
+		case partial.Sent != test.sent || partial.Total != 10:
+			t.Errorf("%s: %d/%d listings acknowledged, expected %d/10", test.name, partial.Sent, partial.Total, test.sent)
+		}
+	}
+}
+
+func TestNewBatchID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for idx := 0; idx < 100; idx++ {
+		ID, err := newBatchID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !uuid.MatchString(ID) {
+			t.Errorf("%s isn't a version 4 uuid", ID)
+		}
+		if seen[ID] {
+			t.Errorf("%s was generated twice", ID)
+		}
+		seen[ID] = true
+	}
+}
+
+func TestSendChunkedBatchIDs(t *testing.T) {
+	c := &connection{chunkSize: 3, retry: testRetryPolicy()}
+	unavailable := status.Error(codes.Unavailable, "connection dropped")
+
+	var sent []sentChunk
+	err := c.sendChunked(context.Background(), "test", testListings(7), recordingSend(&sent, nil, unavailable, unavailable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second chunk is sent 3 times
+	if len(sent) != 5 {
+		t.Fatalf("sent %d times, expected 5", len(sent))
+	}
+	if sent[1].batchID == "" || sent[1].batchID != sent[2].batchID || sent[2].batchID != sent[3].batchID {
+		t.Errorf("a retried chunk was sent with batch-ids %q, %q and %q, expected the same one every time", sent[1].batchID, sent[2].batchID, sent[3].batchID)
+	}
+	if sent[0].batchID == sent[1].batchID || sent[3].batchID == sent[4].batchID {
+		t.Errorf("different chunks were sent with the same batch-id: %q, %q, %q", sent[0].batchID, sent[1].batchID, sent[4].batchID)
+	}
+}
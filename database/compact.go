@@ -0,0 +1,47 @@
+package database
+
+import "github.com/jtyrmn/reddit-votewatch/reddit"
+
+/*
+	a listing gets an entry every UPDATE_TRACKED_POSTS_REFRESH_PERIOD for as
+	long as it's tracked, and archived listings keep their entries forever.
+	Old history is only interesting for long-term trends, so CompactHistory
+	thins it out to a coarser resolution (eg: hourly or daily) to keep storage
+	bounded. Every backend downsamples the same way, through downsample()
+*/
+
+// returns entries (oldest first) with the ones dated before cutoff thinned out to the
+// latest entry of every resolution second window. Entries from cutoff on are kept as is.
+// An entry whose previous entry was thinned out gets its velocity from the entry kept before it
+func downsample(entries []Snapshot, cutoff uint64, resolution uint64) []Snapshot {
+	if resolution == 0 {
+		return entries
+	}
+
+	kept := make([]Snapshot, 0, len(entries))
+	dropped := false
+	keep := func(entry Snapshot) {
+		if dropped && len(kept) > 0 {
+			entry.UpvotesPerHour, entry.CommentsPerHour = reddit.VelocityBetween(kept[len(kept)-1], entry)
+		}
+		kept = append(kept, entry)
+		dropped = false
+	}
+
+	for idx, entry := range entries {
+		if entry.Date >= cutoff {
+			keep(entry)
+			continue
+		}
+
+		// keep it if it's the last entry of its window
+		last := idx == len(entries)-1 || entries[idx+1].Date >= cutoff || entries[idx+1].Date/resolution != entry.Date/resolution
+		if last {
+			keep(entry)
+		} else {
+			dropped = true
+		}
+	}
+
+	return kept
+}
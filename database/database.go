@@ -4,166 +4,417 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jtyrmn/reddit-votewatch/conv"
 	"github.com/jtyrmn/reddit-votewatch/pb"
 	"github.com/jtyrmn/reddit-votewatch/reddit"
 	"github.com/jtyrmn/reddit-votewatch/util"
-	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 /*
-This module used to contain mongodb interfacing code, but now serves as a
-grpc client to the subreddit-logger-database service. All mongodb code was
-moved over there.
+This file serves as a grpc client to the subreddit-logger-database service,
+which is the default storage backend. Small deployments that don't want to
+run the service can talk to mongodb directly instead, see mongo.go
 */
 type connection struct {
-	connection grpc.ClientConn
+	// needed to re-dial the service, see health.go
+	target   string
+	dialOpts []grpc.DialOption
+
+	// guards connection, client and healthy, which get swapped out on re-dial
+	mu         sync.RWMutex
+	connection *grpc.ClientConn
 	client     pb.ListingsDatabaseClient
-}
+	healthy    bool
+
+	// closed by Close() to stop the health monitor
+	stop chan struct{}
+
+	// deadline of every call, see callTimeout()
+	timeout time.Duration
 
-//note: a listing is just a piece of media from reddit. A comment or a post or a link, etc
+	// extra options for the calls that stream many listings, see streamCallOptions()
+	streamOpts []grpc.CallOption
 
-// this template struct describes how each listing is represented in the db
-type document struct {
-	Id      reddit.Fullname      `bson:"_id"`
-	Listing reddit.RedditContent `bson:"listing"`
+	// # of listings per SaveListings/UpdateListings stream, see chunks.go
+	chunkSize int
+
+	// how the chunks of those streams are retried, see retry.go
+	retry retryPolicy
+
+	// the service's schema version, -1 if it wasn't checked. See version.go
+	serviceVersion int
 }
 
 // call this function to establish a new connection with subreddit-logger-db
-func Connect() (*connection, error) {
-	conn, err := grpc.Dial(util.GetEnv("SUBREDDIT_LOGGER_DATABASE_LOCATION"),  grpc.WithTransportCredentials(insecure.NewCredentials()))
-	// TODO: figure out credentials
+func connectService() (*connection, error) {
+	// transient failures (eg: the database service restarting) are retried. See retry.go
+	retry := retryPolicyFromEnv()
+
+	// TLS and api key/token, see auth.go
+	authOpts, err := authDialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// one or more replicas of the service, see balancer.go
+	location, err := util.GetEnv("SUBREDDIT_LOGGER_DATABASE_LOCATION")
+	if err != nil {
+		return nil, err
+	}
+	target, balancerOpts := balancerDialOptions(location)
+
+	dialOpts := append(authOpts, keepaliveDialOptions()...)
+	dialOpts = append(dialOpts, balancerOpts...)
+
+	c := &connection{
+		target: target,
+		dialOpts: append(dialOpts,
+			// calls are traced and measured outside of the retries, see tracing.go and metrics.go
+			grpc.WithChainUnaryInterceptor(tracingUnaryInterceptor(), retry.unaryInterceptor()),
+			grpc.WithChainStreamInterceptor(tracingStreamInterceptor(), metricsStreamInterceptor(), retry.streamInterceptor()),
+		),
+		healthy:    true, // assume the best until the first health check says otherwise
+		stop:       make(chan struct{}),
+		timeout:    callTimeout(),
+		streamOpts: streamCallOptions(),
+		chunkSize:  chunkSize(),
+		retry:      retry,
+	}
+
+	err = c.dial()
 	if err != nil {
 		return nil, fmt.Errorf("error establishing connection:\n%s", err)
 	}
 
-	client := pb.NewListingsDatabaseClient(conn)
+	// see version.go
+	err = c.checkVersion(context.Background())
+	if err != nil {
+		c.connection.Close()
+		return nil, err
+	}
+
+	go c.monitor(healthCheckConfigFromEnv())
+
+	return c, nil
+}
+
+/*
+update cycles stream thousands of listings to the service, so those calls can
+be compressed to save bandwidth. DATABASE_COMPRESSION picks the compressor
+(see .env.template). The service compresses its responses the same way
+*/
+func streamCallOptions() []grpc.CallOption {
+	compression := strings.ToLower(util.GetEnvOptional("DATABASE_COMPRESSION", "none"))
+
+	switch compression {
+	case "gzip":
+		return []grpc.CallOption{grpc.UseCompressor(gzip.Name)}
+	case "none", "":
+		return nil
+	default:
+		fmt.Printf("warning: unknown DATABASE_COMPRESSION \"%s\", not compressing...\n", compression)
+		return nil
+	}
+}
+
+/*
+the scheduler can leave the connection idle for minutes between ticks, long
+enough for a NAT or firewall to silently drop it. DATABASE_KEEPALIVE_TIME pings
+the service after that many idle seconds to keep the connection alive (and to
+notice sooner when it's gone). See .env.template
+*/
+func keepaliveDialOptions() []grpc.DialOption {
+	period := util.GetEnvIntDefault("DATABASE_KEEPALIVE_TIME", 0)
+	if period <= 0 {
+		return nil
+	}
 
-	return &connection{connection: *conn, client: client}, nil
+	permitWithoutStream, err := util.GetEnvBool("DATABASE_KEEPALIVE_PERMIT_WITHOUT_STREAM", true)
+	if err != nil {
+		fmt.Printf("warning: %s, defaulting to true...\n", err)
+	}
+
+	params := keepalive.ClientParameters{
+		Time:                time.Second * time.Duration(period),
+		Timeout:             time.Second * time.Duration(util.GetEnvIntDefault("DATABASE_KEEPALIVE_TIMEOUT", 20)),
+		PermitWithoutStream: permitWithoutStream,
+	}
+
+	return []grpc.DialOption{grpc.WithKeepaliveParams(params)}
 }
 
 /*
 the connection will be active the entire program, but try to close it when
 the program terminates
 */
-func (c connection) Close() {
+func (c *connection) Close() {
+	close(c.stop)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.connection.Close()
 }
 
 // saves the listings to the database. Note that Fullname IDs in ContentGroup are treated as unique keys so duplicates will not be inserted
 // as a result, you should use this function to save listings that were recently created on reddit (probably not in the database yet)
-func (c connection) SaveListings(listings reddit.ContentGroup) error {
-	// SaveListings requires a listings-count header
-	md := metadata.New(map[string]string{"listings-count": strconv.Itoa(len(listings))})
-	ctx := metadata.NewOutgoingContext(context.Background(), md)
+func (c *connection) SaveListings(ctx context.Context, listings reddit.ContentGroup) error {
+	// sent in chunks, see chunks.go
+	return c.sendChunked(ctx, "SaveListings", toGrpcListings(listings), func(ctx context.Context, chunk []*pb.RedditContent) error {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
 
-	// start streaming
-	stream, err := c.client.SaveListings(ctx)
-	if err != nil {
-		return fmt.Errorf("error creating stream:\n%s", err)
-	}
+		// start streaming. The headers are set by sendChunked
+		stream, err := c.getClient().SaveListings(ctx, c.streamOpts...)
+		if err != nil {
+			return fmt.Errorf("error creating stream:\n%w", err)
+		}
 
-	for ID, listing := range listings {
-		toSend := conv.ToGrpc(listing)
-		err = stream.Send(&toSend)
+		for _, listing := range chunk {
+			err = stream.Send(listing)
+			if err == io.EOF {
+				// the stream was aborted, CloseAndRecv returns why
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("error streaming listing of ID \"%s\":\n%w", listing.Id, err)
+			}
+		}
+
+		// recieve response
+		_, err = stream.CloseAndRecv()
 		if err != nil {
-			return fmt.Errorf("error streaming listing of ID \"%s\":\n%s", ID, err)
+			return fmt.Errorf("error from server response:\n%w", err)
 		}
-	}
 
-	// recieve response
-	_, err = stream.CloseAndRecv()
+		return nil
+	})
+}
+
+// pulls a page of listings matching query from the database and places it into the set parameter.
+// returns the cursor of the next page ("" if there are no more) and # of listings inserted into set
+func (c *connection) RecieveListingsPage(ctx context.Context, set reddit.ContentGroup, query ListingsQuery) (string, int, error) {
+	return c.retrievePage(ctx, query, false, func(recieved *pb.RedditContent) {
+		listing := conv.ToRedditContent(recieved)
+		set[listing.FullId()] = listing
+	})
+}
+
+// pulls a page of listings matching query from the database, along with their entries
+func (c *connection) RecieveHistoryPage(ctx context.Context, query ListingsQuery) ([]ListingHistory, string, error) {
+	histories := make([]ListingHistory, 0)
+	next, _, err := c.retrievePage(ctx, query, true, func(recieved *pb.RedditContent) {
+		histories = append(histories, conv.ToListingHistory(recieved))
+	})
 	if err != nil {
-		return fmt.Errorf("error from server response:\n%s", err)
+		return nil, "", err
 	}
 
-	return nil
+	return histories, next, nil
 }
 
-// pulls *all* the listings from the database and places it into the set parameter.
-// doesn't replace pre-existing duplicate, probably more up-to-date, listings in set however
-// maxAge: only recieve posts that are at most maxAge seconds old
-// returns # of listings inserted into set
-func (c connection) RecieveListings(set reddit.ContentGroup, maxAge int64) (int, error) {
-	request := pb.RetrieveListingsRequest{MaxAge: uint64(maxAge)}
-	stream, err := c.client.RetrieveListings(context.Background(), &request)
+// streams a page of listings from the RetrieveListings rpc, calling handle on each one.
+// returns the cursor of the next page ("" if there are no more) and # of listings recieved
+func (c *connection) retrievePage(ctx context.Context, query ListingsQuery, includeEntries bool, handle func(*pb.RedditContent)) (string, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	request := pb.RetrieveListingsRequest{
+		MaxAge:         uint64(query.MaxAge),
+		MinAge:         uint64(query.MinAge),
+		Subreddit:      query.Subreddit,
+		Limit:          uint32(query.Limit),
+		Cursor:         query.Cursor,
+		Sort:           pb.ListingsSort(query.Sort),
+		IncludeEntries: includeEntries,
+	}
+	stream, err := c.getClient().RetrieveListings(ctx, &request, c.streamOpts...)
 	if err != nil {
-		return 0, fmt.Errorf("error calling database service:\n%s", err)
+		return "", 0, fmt.Errorf("error calling database service:\n%s", err)
 	}
 
 	recievedCount := 0
-	// recieve listings from stream and put them into set
 	for {
 		recieved, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return 0, fmt.Errorf("error reading from stream:\n%s", err)
+			return "", 0, fmt.Errorf("error reading from stream:\n%s", err)
 		}
 
-		listing := conv.ToRedditContent(*recieved)
-		set[listing.FullId()] = listing
+		handle(recieved)
 		recievedCount += 1
 	}
 
-	return recievedCount, nil
+	// the service sends the next page's cursor after the last listing
+	next := ""
+	if values := stream.Trailer().Get("next-cursor"); len(values) > 0 {
+		next = values[0]
+	}
+
+	return next, recievedCount, nil
 }
 
-// Records all the listings in newData as entries in the database under their respective listings
-func (c connection) RecordNewData(newData reddit.ContentGroup) error {
-	// UpdateListings requires a listings-count header
-	md := metadata.New(map[string]string{"listings-count": strconv.Itoa(len(newData))})
-	ctx := metadata.NewOutgoingContext(context.Background(), md)
+// returns the time series of entries recorded under a single listing
+func (c *connection) GetHistory(ctx context.Context, ID reddit.Fullname) ([]Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
-	// start streaming
-	stream, err := c.client.UpdateListings(ctx)
+	request := pb.GetHistoryRequest{Id: string(ID)}
+	response, err := c.getClient().GetHistory(ctx, &request)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrListingNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("error creating stream:\n%s", err)
+		return nil, fmt.Errorf("error calling database service:\n%s", err)
 	}
 
-	for ID, listing := range newData {
-		toSend := conv.ToGrpc(listing)
-		err = stream.Send(&toSend)
+	return conv.ToSnapshots(response.Entries), nil
+}
+
+// Records all the listings in newData as entries in the database under their respective listings
+func (c *connection) RecordNewData(ctx context.Context, newData reddit.ContentGroup) error {
+	// sent in chunks, see chunks.go
+	return c.sendChunked(ctx, "UpdateListings", toGrpcListings(newData), func(ctx context.Context, chunk []*pb.RedditContent) error {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		// start streaming. The headers are set by sendChunked
+		stream, err := c.getClient().UpdateListings(ctx, c.streamOpts...)
 		if err != nil {
-			return fmt.Errorf("error streaming listing of ID \"%s\":\n%s", ID, err)
+			return fmt.Errorf("error creating stream:\n%w", err)
+		}
+
+		for _, listing := range chunk {
+			err = stream.Send(listing)
+			if err == io.EOF {
+				// the stream was aborted, CloseAndRecv returns why
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("error streaming listing of ID \"%s\":\n%w", listing.Id, err)
+			}
 		}
+
+		// recieve response
+		_, err = stream.CloseAndRecv()
+		if err != nil {
+			return fmt.Errorf("error from server response:\n%w", err)
+		}
+
+		return nil
+	})
+}
+
+// overwrites the listings in the database (entries included), inserting the ones that aren't in the database yet
+func (c *connection) ReplaceListings(ctx context.Context, histories []ListingHistory) error {
+	listings := make([]*pb.RedditContent, len(histories))
+	for idx, history := range histories {
+		listings[idx] = conv.HistoryToGrpc(history)
 	}
 
-	// recieve response
-	_, err = stream.CloseAndRecv()
+	// sent in chunks, see chunks.go
+	return c.sendChunked(ctx, "ReplaceListings", listings, func(ctx context.Context, chunk []*pb.RedditContent) error {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		// start streaming. The headers are set by sendChunked
+		stream, err := c.getClient().ReplaceListings(ctx, c.streamOpts...)
+		if err != nil {
+			return fmt.Errorf("error creating stream:\n%w", err)
+		}
+
+		for _, listing := range chunk {
+			err = stream.Send(listing)
+			if err == io.EOF {
+				// the stream was aborted, CloseAndRecv returns why
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("error streaming listing of ID \"%s\":\n%w", listing.Id, err)
+			}
+		}
+
+		// recieve response
+		_, err = stream.CloseAndRecv()
+		if err != nil {
+			return fmt.Errorf("error from server response:\n%w", err)
+		}
+
+		return nil
+	})
+}
+
+// all posts in the database that are past maxAge seconds old get deleted, or marked as archived if archive is set
+// returns # of listings deleted/archived
+func (c *connection) CullListings(ctx context.Context, maxAge uint64, archive bool) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	request := pb.CullListingsRequest{MaxAge: maxAge, Archive: archive}
+	response, err := c.getClient().CullListings(ctx, &request)
 	if err != nil {
-		return fmt.Errorf("error from server response:\n%s", err)
+		return 0, fmt.Errorf("error calling database service:\n%s", err)
 	}
 
-	return nil
+	return int(response.NumDeleted), nil
 }
 
-func isDuplicateKeyError(err error) bool {
-	conv, ok := err.(mongo.BulkWriteException)
-	if !ok {
-		return false
+// archived posts in the database that are past maxAge seconds old get deleted for good
+// returns # of listings deleted
+func (c *connection) PurgeListings(ctx context.Context, maxAge uint64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	request := pb.PurgeListingsRequest{MaxAge: maxAge}
+	response, err := c.getClient().PurgeListings(ctx, &request)
+	if err != nil {
+		return 0, fmt.Errorf("error calling database service:\n%s", err)
 	}
 
-	for _, writeError := range conv.WriteErrors {
-		if writeError.Code == 11000 { //mongodb error code for duplicate key
-			return true
-		}
+	return int(response.NumDeleted), nil
+}
+
+// entries in the database recorded over maxAge seconds ago get downsampled to one per resolution seconds
+// returns # of entries removed
+func (c *connection) CompactHistory(ctx context.Context, maxAge uint64, resolution uint64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	request := pb.CompactHistoryRequest{MaxAge: maxAge, Resolution: resolution}
+	response, err := c.getClient().CompactHistory(ctx, &request)
+	if err != nil {
+		return 0, fmt.Errorf("error calling database service:\n%s", err)
 	}
 
-	return false
+	return int(response.NumRemoved), nil
 }
 
-// all posts in the database that are past maxAge seconds old get deleted
+// deletes specific listings from the database, regardless of their age
 // returns # of listings deleted
-func (c connection) CullListings(maxAge uint64) (int, error) {
-	request := pb.CullListingsRequest{MaxAge: maxAge}
-	response, err := c.client.CullListings(context.Background(), &request)
+func (c *connection) DeleteListings(ctx context.Context, IDs []reddit.Fullname) (int, error) {
+	if len(IDs) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	request := pb.DeleteListingsRequest{Ids: make([]string, len(IDs))}
+	for idx, ID := range IDs {
+		request.Ids[idx] = string(ID)
+	}
+
+	response, err := c.getClient().DeleteListings(ctx, &request)
 	if err != nil {
 		return 0, fmt.Errorf("error calling database service:\n%s", err)
 	}
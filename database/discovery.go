@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc/resolver"
+)
+
+/*
+	besides a fixed address, SUBREDDIT_LOGGER_DATABASE_LOCATION can name the
+	database service in a service registry:
+
+	dns:///host:port          - every address host resolves to (grpc's built in resolver)
+	kubernetes:///svc.ns:port - the pods behind a kubernetes headless service, looked up
+	                            through the cluster's dns (svc.ns.svc.cluster.local)
+	consul:///service         - the instances of a consul service that pass their health checks
+
+	grpc asks the resolver to resolve again whenever a connection to one of the
+	addresses fails, so replicas that moved are picked up without a restart.
+	The consul resolver also polls every DATABASE_DISCOVERY_REFRESH seconds
+*/
+
+const (
+	kubernetesScheme = "kubernetes"
+	consulScheme     = "consul"
+)
+
+// rewrites kubernetes:/// targets into the dns:/// target grpc already knows how to resolve
+func kubernetesTarget(location string) string {
+	service := strings.TrimPrefix(location, kubernetesScheme+":///")
+	host, port, err := net.SplitHostPort(service)
+	if err != nil {
+		host, port = service, "80"
+	}
+
+	domain := util.GetEnvOptional("KUBERNETES_CLUSTER_DOMAIN", "cluster.local")
+	return "dns:///" + net.JoinHostPort(host+".svc."+domain, port)
+}
+
+// resolves consul:///service targets through consul's health api
+type consulResolverBuilder struct{}
+
+func (consulResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	address := util.GetEnvOptional("CONSUL_HTTP_ADDR", "http://127.0.0.1:8500")
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	r := &consulResolver{
+		service: strings.TrimPrefix(target.URL.Path, "/"),
+		api:     strings.TrimSuffix(address, "/"),
+		token:   util.GetEnvOptional("CONSUL_HTTP_TOKEN", ""),
+		cc:      cc,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		resolve: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	if r.service == "" {
+		return nil, fmt.Errorf("missing service name in consul target \"%s\"", target.URL.String())
+	}
+
+	r.wg.Add(1)
+	go r.watch(time.Second * time.Duration(util.GetEnvIntDefault("DATABASE_DISCOVERY_REFRESH", 30)))
+
+	return r, nil
+}
+
+func (consulResolverBuilder) Scheme() string {
+	return consulScheme
+}
+
+type consulResolver struct {
+	service string
+	api     string // base url of the consul http api
+	token   string
+
+	cc     resolver.ClientConn
+	client *http.Client
+
+	// ResolveNow() requests a lookup through here, Close() stops watch() through stop
+	resolve chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *consulResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default: // a lookup is already pending
+	}
+}
+
+func (r *consulResolver) Close() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+// looks the service up right away, then whenever grpc asks for it and every refresh
+func (r *consulResolver) watch(refresh time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		addresses, err := r.lookup()
+		if err != nil {
+			r.cc.ReportError(err)
+		} else {
+			r.cc.UpdateState(resolver.State{Addresses: addresses})
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-r.resolve:
+		case <-ticker.C:
+		}
+	}
+}
+
+// the part of a /v1/health/service response that's needed
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// returns the addresses of the service's instances that pass their health checks
+func (r *consulResolver) lookup() ([]resolver.Address, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.client.Timeout)
+	defer cancel()
+
+	endpoint := r.api + "/v1/health/service/" + url.PathEscape(r.service) + "?passing=true"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.token != "" {
+		request.Header.Set("X-Consul-Token", r.token)
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up \"%s\" in consul:\n%s", r.service, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error looking up \"%s\" in consul: %s", r.service, response.Status)
+	}
+
+	var entries []consulServiceEntry
+	err = json.NewDecoder(response.Body).Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing consul response:\n%s", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no healthy instances of \"%s\" registered in consul", r.service)
+	}
+
+	addresses := make([]resolver.Address, len(entries))
+	for idx, entry := range entries {
+		// the service address is optional in consul, it defaults to the node's
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		addresses[idx] = resolver.Address{Addr: net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)), ServerName: host}
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/pb"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	this file keeps an eye on the connection to subreddit-logger-database.
+	The connection is dialed once at startup, so if the service restarts or the
+	network drops, something has to notice and recover. The monitor goroutine
+	periodically runs the standard grpc health check protocol
+	(https://github.com/grpc/grpc/blob/master/doc/health-checking.md) and
+	re-dials the service with backoff after too many consecutive failures.
+*/
+
+type healthCheckConfig struct {
+	period  time.Duration // time between health checks
+	timeout time.Duration // how long a single health check may take
+
+	// consecutive failed checks before the connection is torn down and re-dialed
+	failureThreshold int
+
+	// delay between re-dial attempts, doubling up to maxRedialBackoff
+	redialBackoff    time.Duration
+	maxRedialBackoff time.Duration
+}
+
+func healthCheckConfigFromEnv() healthCheckConfig {
+	return healthCheckConfig{
+		period:           time.Second * time.Duration(util.GetEnvIntDefault("DATABASE_HEALTH_CHECK_PERIOD", 15)),
+		timeout:          time.Second * time.Duration(util.GetEnvIntDefault("DATABASE_HEALTH_CHECK_TIMEOUT", 5)),
+		failureThreshold: util.GetEnvIntDefault("DATABASE_HEALTH_CHECK_FAILURES", 3),
+		redialBackoff:    time.Second,
+		maxRedialBackoff: time.Minute,
+	}
+}
+
+// whether or not the database service was reachable and serving at the last health check
+func (c *connection) Healthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.healthy
+}
+
+func (c *connection) getClient() pb.ListingsDatabaseClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+func (c *connection) setHealthy(healthy bool) {
+	c.mu.Lock()
+	changed := c.healthy != healthy
+	c.healthy = healthy
+	c.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		fmt.Println("database service is healthy again")
+	} else {
+		fmt.Println("warning: database service is unhealthy")
+	}
+}
+
+// (re)establishes the grpc connection, replacing any previous one
+func (c *connection) dial() error {
+	conn, err := grpc.Dial(c.target, c.dialOpts...)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	old := c.connection
+	c.connection = conn
+	c.client = pb.NewListingsDatabaseClient(conn)
+	c.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	go watchState(conn)
+
+	return nil
+}
+
+// logs connectivity state transitions of conn until it gets shut down
+func watchState(conn *grpc.ClientConn) {
+	state := conn.GetState()
+	for state != connectivity.Shutdown {
+		if !conn.WaitForStateChange(context.Background(), state) {
+			return
+		}
+
+		newState := conn.GetState()
+		if newState == connectivity.TransientFailure || state == connectivity.TransientFailure {
+			fmt.Printf("database connection state: %s -> %s\n", state, newState)
+		}
+		state = newState
+	}
+}
+
+// runs a single health check against the service
+func (c *connection) check(timeout time.Duration) error {
+	c.mu.RLock()
+	conn := c.connection
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		// service doesn't implement the health protocol. Best we can do is look at the connection itself
+		if state := conn.GetState(); state != connectivity.Ready && state != connectivity.Idle {
+			return fmt.Errorf("connection is %s", state)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service reports %s", response.Status)
+	}
+
+	return nil
+}
+
+// the forever-loop that checks the service's health and re-dials it when needed. Stops when Close() is called
+func (c *connection) monitor(config healthCheckConfig) {
+	ticker := time.NewTicker(config.period)
+	defer ticker.Stop()
+
+	failures := 0
+	backoff := config.redialBackoff
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := c.check(config.timeout)
+		if err == nil {
+			failures = 0
+			backoff = config.redialBackoff
+			c.setHealthy(true)
+			continue
+		}
+
+		failures += 1
+		c.setHealthy(false)
+		fmt.Printf("warning: database health check failed (%d/%d):\n%s\n", failures, config.failureThreshold, err)
+
+		if failures < config.failureThreshold {
+			continue
+		}
+
+		// too many failures, start over with a fresh connection
+		fmt.Printf("re-dialing database service at %s...\n", c.target)
+		err = c.dial()
+		if err != nil {
+			fmt.Printf("warning: error re-dialing database service:\n%s\n", err)
+		}
+
+		// don't hammer the service while it's down
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > config.maxRedialBackoff {
+			backoff = config.maxRedialBackoff
+		}
+		failures = 0
+	}
+}
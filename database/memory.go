@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+NewMemoryStore returns a Store that keeps everything in memory and writes
+nothing to disk. Everything is lost when the program exits, which makes it
+useful for tests and dry runs (see the --dry-run flag in main.go).
+
+Listings that should survive a restart without running a database belong in
+the bolt store (see bolt.go).
+*/
+func NewMemoryStore() Store {
+	return &memoryStore{documents: make(map[reddit.Fullname]*document)}
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	documents map[reddit.Fullname]*document
+}
+
+func (s *memoryStore) Close() {}
+
+func (s *memoryStore) Healthy() bool {
+	return true
+}
+
+func (s *memoryStore) SaveListings(ctx context.Context, listings reddit.ContentGroup) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ID, listing := range listings {
+		if _, exists := s.documents[ID]; exists {
+			continue
+		}
+		s.documents[ID] = &document{Id: ID, Listing: listing, Entries: make([]Snapshot, 0)}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) RecordNewData(ctx context.Context, newData reddit.ContentGroup) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ID, listing := range newData {
+		doc, exists := s.documents[ID]
+		if !exists {
+			continue
+		}
+		doc.Listing = listing
+		doc.Entries = append(doc.Entries, listing.Snapshot())
+	}
+
+	return nil
+}
+
+func (s *memoryStore) ReplaceListings(ctx context.Context, histories []ListingHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, history := range histories {
+		ID := history.Listing.FullId()
+		doc, exists := s.documents[ID]
+		if !exists {
+			doc = &document{Id: ID}
+			s.documents[ID] = doc
+		}
+		doc.Listing = history.Listing
+		doc.Entries = append(make([]Snapshot, 0, len(history.Entries)), history.Entries...)
+	}
+
+	return nil
+}
+
+func (s *memoryStore) RecieveListingsPage(ctx context.Context, set reddit.ContentGroup, query ListingsQuery) (string, int, error) {
+	if err := ctx.Err(); err != nil {
+		return "", 0, err
+	}
+
+	s.mu.Lock()
+	listings := make([]reddit.RedditContent, 0, len(s.documents))
+	for _, doc := range s.documents {
+		listings = append(listings, doc.Listing)
+	}
+	s.mu.Unlock()
+
+	page, next, err := paginate(listings, query)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, listing := range page {
+		set[listing.FullId()] = listing
+	}
+
+	return next, len(page), nil
+}
+
+func (s *memoryStore) RecieveHistoryPage(ctx context.Context, query ListingsQuery) ([]ListingHistory, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listings := make([]reddit.RedditContent, 0, len(s.documents))
+	for _, doc := range s.documents {
+		listings = append(listings, doc.Listing)
+	}
+
+	page, next, err := paginate(listings, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	histories := make([]ListingHistory, len(page))
+	for idx, listing := range page {
+		// copied so that the caller can't race with RecordNewData appending to them
+		entries := s.documents[listing.FullId()].Entries
+		histories[idx] = ListingHistory{Listing: listing, Entries: append([]Snapshot(nil), entries...)}
+	}
+
+	return histories, next, nil
+}
+
+func (s *memoryStore) GetHistory(ctx context.Context, ID reddit.Fullname) ([]Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, exists := s.documents[ID]
+	if !exists {
+		return nil, ErrListingNotFound
+	}
+
+	return append([]Snapshot(nil), doc.Entries...), nil
+}
+
+func (s *memoryStore) CullListings(ctx context.Context, maxAge uint64, archive bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldest := uint64(time.Now().Unix()) - maxAge
+	culled := 0
+	for ID, doc := range s.documents {
+		if doc.Listing.Date >= oldest {
+			continue
+		}
+
+		if !archive {
+			delete(s.documents, ID)
+			culled += 1
+		} else if !doc.Archived {
+			doc.Archived = true
+			culled += 1
+		}
+	}
+
+	return culled, nil
+}
+
+func (s *memoryStore) PurgeListings(ctx context.Context, maxAge uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldest := uint64(time.Now().Unix()) - maxAge
+	purged := 0
+	for ID, doc := range s.documents {
+		if doc.Archived && doc.Listing.Date < oldest {
+			delete(s.documents, ID)
+			purged += 1
+		}
+	}
+
+	return purged, nil
+}
+
+func (s *memoryStore) CompactHistory(ctx context.Context, maxAge uint64, resolution uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := uint64(time.Now().Unix()) - maxAge
+	removed := 0
+	for _, doc := range s.documents {
+		kept := downsample(doc.Entries, cutoff, resolution)
+		removed += len(doc.Entries) - len(kept)
+		doc.Entries = kept
+	}
+
+	return removed, nil
+}
+
+func (s *memoryStore) DeleteListings(ctx context.Context, IDs []reddit.Fullname) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, ID := range IDs {
+		if _, exists := s.documents[ID]; exists {
+			delete(s.documents, ID)
+			deleted += 1
+		}
+	}
+
+	return deleted, nil
+}
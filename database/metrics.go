@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+/*
+	throughput of the streaming calls to subreddit-logger-database (SaveListings,
+	UpdateListings, RetrieveListings...), exposed through the metrics package.
+	The interceptor sits outside of the retry interceptor (see retry.go), so a
+	retried RetrieveListings counts once, and its duration includes the
+	retries. The chunks of the other streams are retried by sendChunked, each
+	attempt counts on its own
+*/
+
+var (
+	streamListingsSent     = metrics.NewCounter("votewatch_db_listings_sent_total", "listings streamed to the database service", "method")
+	streamListingsRecieved = metrics.NewCounter("votewatch_db_listings_received_total", "listings streamed from the database service", "method")
+	streamBytesSent        = metrics.NewCounter("votewatch_db_stream_sent_bytes_total", "bytes (before compression) streamed to the database service", "method")
+	streamBytesRecieved    = metrics.NewCounter("votewatch_db_stream_received_bytes_total", "bytes (after decompression) streamed from the database service", "method")
+	streamErrors           = metrics.NewCounter("votewatch_db_stream_errors_total", "streams to/from the database service that failed", "method")
+	streamDuration         = metrics.NewSummary("votewatch_db_stream_duration_seconds", "time from opening a stream to the database service until it finished", "method")
+)
+
+func metricsStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		// eg: /ListingsDatabase/SaveListings -> SaveListings
+		name := method[strings.LastIndex(method, "/")+1:]
+
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			streamErrors.With(name).Inc()
+			streamDuration.With(name).Observe(time.Since(start).Seconds())
+			return nil, err
+		}
+
+		return &measuredClientStream{ClientStream: stream, method: name, serverStreams: desc.ServerStreams, start: start}, nil
+	}
+}
+
+// counts the messages and bytes going through a stream
+type measuredClientStream struct {
+	grpc.ClientStream
+	method        string
+	serverStreams bool
+	start         time.Time
+
+	once sync.Once // the stream finishes once
+}
+
+func (s *measuredClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+
+	streamListingsSent.With(s.method).Inc()
+	if message, ok := m.(proto.Message); ok {
+		streamBytesSent.With(s.method).Add(float64(proto.Size(message)))
+	}
+	return nil
+}
+
+func (s *measuredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+
+	if s.serverStreams {
+		streamListingsRecieved.With(s.method).Inc()
+	} else {
+		// the single response of a client stream ends it
+		s.finish(nil)
+	}
+	if message, ok := m.(proto.Message); ok {
+		streamBytesRecieved.With(s.method).Add(float64(proto.Size(message)))
+	}
+	return nil
+}
+
+// records the duration of the stream, and whether it failed. io.EOF is the end of a server stream, not a failure
+func (s *measuredClientStream) finish(err error) {
+	s.once.Do(func() {
+		if err != nil && err != io.EOF {
+			streamErrors.With(s.method).Inc()
+		}
+		streamDuration.With(s.method).Observe(time.Since(s.start).Seconds())
+	})
+}
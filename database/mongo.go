@@ -0,0 +1,414 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+	This file contains a Store that talks to mongodb directly instead of going
+	through the subreddit-logger-database service, for deployments that don't want
+	to run a second process. It uses the same document schema as the service, so
+	both backends can be pointed at the same database.
+*/
+
+// this template struct describes how each listing is represented in the db
+type document struct {
+	Id      reddit.Fullname      `bson:"_id"`
+	Listing reddit.RedditContent `bson:"listing"`
+	Entries []Snapshot           `bson:"entries"`
+
+	// set by CullListings in archive mode, see Store.CullListings
+	Archived bool `bson:"archived,omitempty"`
+}
+
+type mongoStore struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+
+	timeout time.Duration // see callTimeout()
+}
+
+// connects to the mongodb server at MONGODB_CONNECTION_STRING
+func connectMongo() (*mongoStore, error) {
+	connectionString, err := util.GetEnv("MONGODB_CONNECTION_STRING")
+	if err != nil {
+		return nil, err
+	}
+	databaseName, err := util.GetEnv("MONGODB_DATABASE_NAME")
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := callTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to mongodb:\n%s", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error pinging mongodb:\n%s", err)
+	}
+
+	//several watchers (see WATCH_GROUPS) can share a database, each with a collection of its own
+	collectionName := strings.TrimSpace(os.Getenv("MONGODB_COLLECTION_NAME"))
+	if collectionName == "" {
+		collectionName = "listings"
+	}
+	collection := client.Database(databaseName).Collection(collectionName)
+	return &mongoStore{client: client, collection: collection, timeout: timeout}, nil
+}
+
+func (m *mongoStore) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	m.client.Disconnect(ctx)
+}
+
+func (m *mongoStore) Healthy() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return m.client.Ping(ctx, nil) == nil
+}
+
+// see Store.SaveListings. Fullname IDs are unique keys in the collection so duplicates will not be inserted
+func (m *mongoStore) SaveListings(ctx context.Context, listings reddit.ContentGroup) error {
+	if len(listings) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(listings))
+	for ID, listing := range listings {
+		documents = append(documents, document{Id: ID, Listing: listing, Entries: make([]Snapshot, 0)})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	//unordered so that one duplicate doesn't stop the rest of the listings from being inserted
+	_, err := m.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !isDuplicateKeyError(err) {
+		return fmt.Errorf("error inserting listings:\n%s", err)
+	}
+
+	return nil
+}
+
+func (m *mongoStore) RecordNewData(ctx context.Context, newData reddit.ContentGroup) error {
+	if len(newData) == 0 {
+		return nil
+	}
+
+	updates := make([]mongo.WriteModel, 0, len(newData))
+	for ID, listing := range newData {
+		update := bson.M{
+			"$set":  bson.M{"listing": listing},
+			"$push": bson.M{"entries": listing.Snapshot()},
+		}
+		updates = append(updates, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": ID}).SetUpdate(update))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error recording new data:\n%s", err)
+	}
+
+	return nil
+}
+
+func (m *mongoStore) ReplaceListings(ctx context.Context, histories []ListingHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+
+	// $set rather than a replacement so that the archived flag is kept
+	updates := make([]mongo.WriteModel, len(histories))
+	for idx, history := range histories {
+		entries := history.Entries
+		if entries == nil {
+			entries = make([]Snapshot, 0)
+		}
+		update := bson.M{"$set": bson.M{"listing": history.Listing, "entries": entries}}
+		updates[idx] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": history.Listing.FullId()}).SetUpdate(update).SetUpsert(true)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error replacing listings:\n%s", err)
+	}
+
+	return nil
+}
+
+func (m *mongoStore) RecieveListingsPage(ctx context.Context, set reddit.ContentGroup, query ListingsQuery) (string, int, error) {
+	page, next, err := m.findPage(ctx, query, false)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, doc := range page {
+		set[doc.Listing.FullId()] = doc.Listing
+	}
+
+	return next, len(page), nil
+}
+
+func (m *mongoStore) RecieveHistoryPage(ctx context.Context, query ListingsQuery) ([]ListingHistory, string, error) {
+	page, next, err := m.findPage(ctx, query, true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	histories := make([]ListingHistory, len(page))
+	for idx, doc := range page {
+		histories[idx] = ListingHistory{Listing: doc.Listing, Entries: doc.Entries}
+	}
+
+	return histories, next, nil
+}
+
+// finds the page of documents described by query. Entries are only pulled if withEntries is set, as they make up most of a document
+func (m *mongoStore) findPage(ctx context.Context, query ListingsQuery, withEntries bool) ([]document, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	conditions := bson.A{bson.M{"listing.date": bson.M{"$gte": time.Now().Unix() - query.MaxAge}}}
+	if query.MinAge > 0 {
+		conditions = append(conditions, bson.M{"listing.date": bson.M{"$lte": time.Now().Unix() - query.MinAge}})
+	}
+	if query.Subreddit != "" {
+		conditions = append(conditions, bson.M{"listing.subreddit": query.Subreddit})
+	}
+
+	findOptions := options.Find()
+	if !withEntries {
+		findOptions.SetProjection(bson.M{"entries": 0})
+	}
+	if query.Limit > 0 {
+		// same ordering and cursors as paging.go, so that cursors work across backends
+		dateOrder, comparison := 1, "$gt"
+		if query.Sort == NewestFirst {
+			dateOrder, comparison = -1, "$lt"
+		}
+
+		if query.Sort == Unsorted {
+			findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
+		} else {
+			findOptions.SetSort(bson.D{{Key: "listing.date", Value: dateOrder}, {Key: "_id", Value: 1}})
+		}
+		findOptions.SetLimit(int64(query.Limit) + 1) // one extra to see if there's another page
+
+		if query.Cursor != "" {
+			date, ID, err := parseCursor(query.Cursor)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if query.Sort == Unsorted {
+				conditions = append(conditions, bson.M{"_id": bson.M{"$gt": ID}})
+			} else {
+				conditions = append(conditions, bson.M{"$or": bson.A{
+					bson.M{"listing.date": bson.M{comparison: date}},
+					bson.M{"listing.date": date, "_id": bson.M{"$gt": ID}},
+				}})
+			}
+		}
+	}
+
+	cursor, err := m.collection.Find(ctx, bson.M{"$and": conditions}, findOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("error querying listings:\n%s", err)
+	}
+	defer cursor.Close(ctx)
+
+	page := make([]document, 0)
+	for cursor.Next(ctx) {
+		var doc document
+		err = cursor.Decode(&doc)
+		if err != nil {
+			return nil, "", fmt.Errorf("error decoding listing:\n%s", err)
+		}
+		page = append(page, doc)
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("error reading listings:\n%s", err)
+	}
+
+	next := ""
+	if query.Limit > 0 && len(page) > query.Limit {
+		page = page[:query.Limit]
+		next = makeCursor(page[len(page)-1].Listing)
+	}
+
+	return page, next, nil
+}
+
+func (m *mongoStore) GetHistory(ctx context.Context, ID reddit.Fullname) ([]Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var doc document
+	findOptions := options.FindOne().SetProjection(bson.M{"entries": 1})
+	err := m.collection.FindOne(ctx, bson.M{"_id": ID}, findOptions).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying listing:\n%s", err)
+	}
+
+	return doc.Entries, nil
+}
+
+func (m *mongoStore) CullListings(ctx context.Context, maxAge uint64, archive bool) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	filter := bson.M{"listing.date": bson.M{"$lt": uint64(time.Now().Unix()) - maxAge}}
+
+	if archive {
+		filter["archived"] = bson.M{"$ne": true}
+		result, err := m.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"archived": true}})
+		if err != nil {
+			return 0, fmt.Errorf("error archiving listings:\n%s", err)
+		}
+		return int(result.ModifiedCount), nil
+	}
+
+	result, err := m.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting listings:\n%s", err)
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+func (m *mongoStore) PurgeListings(ctx context.Context, maxAge uint64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	filter := bson.M{"archived": true, "listing.date": bson.M{"$lt": uint64(time.Now().Unix()) - maxAge}}
+	result, err := m.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error purging listings:\n%s", err)
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+func (m *mongoStore) CompactHistory(ctx context.Context, maxAge uint64, resolution uint64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	// entries are oldest first, so only documents whose first entry is old enough can have anything to compact
+	cutoff := uint64(time.Now().Unix()) - maxAge
+	findOptions := options.Find().SetProjection(bson.M{"entries": 1})
+	cursor, err := m.collection.Find(ctx, bson.M{"entries.0.date": bson.M{"$lt": cutoff}}, findOptions)
+	if err != nil {
+		return 0, fmt.Errorf("error querying listings:\n%s", err)
+	}
+	defer cursor.Close(ctx)
+
+	removed := 0
+	updates := make([]mongo.WriteModel, 0)
+	for cursor.Next(ctx) {
+		var doc document
+		err = cursor.Decode(&doc)
+		if err != nil {
+			return 0, fmt.Errorf("error decoding listing:\n%s", err)
+		}
+
+		kept := downsample(doc.Entries, cutoff, resolution)
+		if len(kept) == len(doc.Entries) {
+			continue
+		}
+
+		/*
+			the dropped entries are pulled rather than the kept ones being $set, so
+			that entries recorded in the meantime aren't lost. Dates shared with a
+			kept entry can't be told apart, so those are left alone
+		*/
+		keptDates := make(map[uint64]bool, len(kept))
+		for _, entry := range kept {
+			keptDates[entry.Date] = true
+		}
+		dropped := make([]uint64, 0, len(doc.Entries)-len(kept))
+		for _, entry := range doc.Entries {
+			if entry.Date < cutoff && !keptDates[entry.Date] {
+				dropped = append(dropped, entry.Date)
+			}
+		}
+		if len(dropped) == 0 {
+			continue
+		}
+
+		update := bson.M{"$pull": bson.M{"entries": bson.M{"date": bson.M{"$in": dropped}}}}
+		updates = append(updates, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc.Id}).SetUpdate(update))
+		removed += len(dropped)
+	}
+	if err = cursor.Err(); err != nil {
+		return 0, fmt.Errorf("error reading listings:\n%s", err)
+	}
+
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	_, err = m.collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("error compacting history:\n%s", err)
+	}
+
+	return removed, nil
+}
+
+func (m *mongoStore) DeleteListings(ctx context.Context, IDs []reddit.Fullname) (int, error) {
+	if len(IDs) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	result, err := m.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": IDs}})
+	if err != nil {
+		return 0, fmt.Errorf("error deleting listings:\n%s", err)
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+func isDuplicateKeyError(err error) bool {
+	var conv mongo.BulkWriteException
+	if !errors.As(err, &conv) {
+		return false
+	}
+
+	for _, writeError := range conv.WriteErrors {
+		if writeError.Code != 11000 { //mongodb error code for duplicate key
+			return false
+		}
+	}
+
+	return true
+}
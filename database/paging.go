@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	pagination for the backends that don't get it for free from a database
+	server (the embedded and in-memory stores).
+
+	cursors are "<date created>:<fullname>" of the last listing in the previous
+	page. Listings are always ordered by fullname within the same date (and
+	only by fullname when unsorted) so that pages are stable.
+*/
+
+func makeCursor(listing reddit.RedditContent) string {
+	return fmt.Sprintf("%d:%s", listing.Date, listing.FullId())
+}
+
+func parseCursor(cursor string) (uint64, reddit.Fullname, error) {
+	date, ID, found := strings.Cut(cursor, ":")
+	if !found {
+		return 0, "", fmt.Errorf("malformed cursor \"%s\"", cursor)
+	}
+
+	d, err := strconv.ParseUint(date, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed cursor \"%s\"", cursor)
+	}
+
+	return d, reddit.Fullname(ID), nil
+}
+
+// whether a should come before b in the given sort order
+func listingLess(a, b reddit.RedditContent, order ListingsSort) bool {
+	if a.Date != b.Date {
+		switch order {
+		case NewestFirst:
+			return a.Date > b.Date
+		case OldestFirst:
+			return a.Date < b.Date
+		}
+	}
+
+	return a.FullId() < b.FullId()
+}
+
+// filters, sorts and cuts listings down to the page described by query. Returns the page and the next page's cursor
+func paginate(listings []reddit.RedditContent, query ListingsQuery) ([]reddit.RedditContent, string, error) {
+	oldest := uint64(time.Now().Unix() - query.MaxAge)
+	newest := uint64(time.Now().Unix() - query.MinAge)
+
+	var after *reddit.RedditContent
+	if query.Cursor != "" {
+		date, ID, err := parseCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &reddit.RedditContent{Date: date}
+		after.ContentType, after.Id, _ = strings.Cut(string(ID), "_")
+	}
+
+	page := make([]reddit.RedditContent, 0)
+	for _, listing := range listings {
+		if listing.Date < oldest || (query.MinAge > 0 && listing.Date > newest) {
+			continue
+		}
+		if query.Subreddit != "" && !strings.EqualFold(listing.Subreddit, query.Subreddit) {
+			continue
+		}
+		if after != nil && !listingLess(*after, listing, query.Sort) {
+			continue
+		}
+		page = append(page, listing)
+	}
+
+	//no limit means no pages, so no need to sort either
+	if query.Limit <= 0 {
+		return page, "", nil
+	}
+
+	sort.Slice(page, func(i, j int) bool {
+		return listingLess(page[i], page[j], query.Sort)
+	})
+
+	if len(page) <= query.Limit {
+		return page, "", nil
+	}
+
+	page = page[:query.Limit]
+	return page, makeCursor(page[len(page)-1]), nil
+}
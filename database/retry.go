@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	this file contains the client interceptors that retry grpc calls to
+	subreddit-logger-database when they fail for transient reasons (the service
+	restarting, a dropped connection, etc). Without these, a brief blip during
+	SaveListings or UpdateListings loses an entire cycle's worth of data.
+
+	only calls that are safe to repeat are retried (see idempotentMethods), a
+	retried CullListings or DeleteListings could have already gone through.
+	The streams that send listings are retried a chunk at a time by
+	sendChunked (see chunks.go), which still has the whole chunk to send again
+*/
+
+// calls that can be repeated without changing the outcome: reads, and the streams that insert or overwrite listings by ID.
+// UpdateListings appends entries, it's only safe to repeat because of its batch-id header (see chunks.go)
+var idempotentMethods = map[string]bool{
+	"/ListingsDatabase/Version":          true,
+	"/ListingsDatabase/ManyListings":     true,
+	"/ListingsDatabase/RetrieveListings": true,
+	"/ListingsDatabase/FetchListing":     true,
+	"/ListingsDatabase/GetHistory":       true,
+	"/ListingsDatabase/SaveListings":     true, // duplicate IDs aren't inserted
+	"/ListingsDatabase/ReplaceListings":  true,
+	"/ListingsDatabase/UpdateListings":   true,
+}
+
+// describes which calls get retried and how long to wait between attempts
+type retryPolicy struct {
+	// grpc status codes that are considered transient
+	codes []codes.Code
+
+	// total attempts, including the first one. 1 disables retrying
+	maxAttempts int
+
+	// delay before the first retry. Doubled after every failed attempt up to maxBackoff
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// reads the retry policy from the DATABASE_RETRY_* env variables. See .env.template
+func retryPolicyFromEnv() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:    util.GetEnvIntDefault("DATABASE_RETRY_ATTEMPTS", 4),
+		initialBackoff: time.Millisecond * time.Duration(util.GetEnvIntDefault("DATABASE_RETRY_BACKOFF_MS", 500)),
+		maxBackoff:     time.Millisecond * time.Duration(util.GetEnvIntDefault("DATABASE_RETRY_MAX_BACKOFF_MS", 10000)),
+	}
+
+	if policy.maxAttempts < 1 {
+		fmt.Printf("warning: DATABASE_RETRY_ATTEMPTS must be at least 1. Defaulting to 1...\n")
+		policy.maxAttempts = 1
+	}
+
+	codeNames, err := util.GetEnvStringSlice("DATABASE_RETRY_CODES")
+	if err != nil {
+		codeNames = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED", "ABORTED"}
+	}
+	for _, name := range codeNames {
+		name = strings.ToUpper(name)
+
+		var code codes.Code
+		err := code.UnmarshalJSON([]byte(`"` + name + `"`))
+		if err != nil {
+			fmt.Printf("warning: unknown grpc code \"%s\" in DATABASE_RETRY_CODES, ignoring...\n", name)
+			continue
+		}
+		policy.codes = append(policy.codes, code)
+	}
+
+	return policy
+}
+
+// whether or not err is worth retrying under this policy
+func (p retryPolicy) retryable(err error) bool {
+	if err == nil || errors.Is(err, io.EOF) {
+		return false
+	}
+
+	// the status of a grpc error wrapped with %w, eg: by the calls in database.go
+	code := status.Code(err)
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		code = grpcErr.GRPCStatus().Code()
+	}
+
+	for _, c := range p.codes {
+		if code == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// how long to sleep before retry # attempt (1-indexed, attempt 1 is the first retry)
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.initialBackoff
+	for i := 1; i < attempt; i += 1 {
+		delay *= 2
+		if delay >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+
+	return delay
+}
+
+// sleeps for the backoff of the given attempt. Returns early with an error if ctx is done first
+func (p retryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// calls call until it succeeds, fails for good or runs out of attempts. name is for logging
+func (p retryPolicy) do(ctx context.Context, name string, call func() error) error {
+	var err error
+	for attempt := 0; attempt < p.maxAttempts; attempt += 1 {
+		if attempt > 0 {
+			fmt.Printf("warning: retrying %s (attempt %d/%d) after error:\n%s\n", name, attempt+1, p.maxAttempts, err)
+			if waitErr := p.wait(ctx, attempt); waitErr != nil {
+				return err
+			}
+		}
+
+		err = call()
+		if !p.retryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// interceptor for unary calls (FetchListing, GetHistory, etc). Calls that aren't idempotent are made once
+func (p retryPolicy) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !idempotentMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return p.do(ctx, method, func() error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// interceptor for server-side streams, see retryingClientStream. Client-side streams are retried a chunk at a time by
+// sendChunked instead
+func (p retryPolicy) streamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if desc.ClientStreams || !idempotentMethods[method] {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		newStream := func() (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		// opening the stream itself can fail transiently too
+		var stream grpc.ClientStream
+		err := p.do(ctx, method, func() error {
+			var err error
+			stream, err = newStream()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &retryingClientStream{
+			ClientStream: stream,
+			ctx:          ctx,
+			method:       method,
+			policy:       p,
+			newStream:    newStream,
+		}, nil
+	}
+}
+
+/*
+wraps a server-side stream (RetrieveListings) and transparently re-opens it on
+transient failures, as long as nothing has been recieved yet. Otherwise the
+caller would see duplicate messages. The request is kept so that it can be sent
+again on the new stream
+*/
+type retryingClientStream struct {
+	grpc.ClientStream
+
+	ctx       context.Context
+	method    string
+	policy    retryPolicy
+	newStream func() (grpc.ClientStream, error)
+
+	sent       []interface{} // the request, for replaying
+	closedSend bool
+	recieved   bool
+}
+
+// grpc returns io.EOF when the stream was aborted, and the caller goes on to RecvMsg for the actual status. That's
+// where the retrying happens
+func (s *retryingClientStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryingClientStream) CloseSend() error {
+	s.closedSend = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *retryingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	for attempt := 1; attempt < s.policy.maxAttempts && s.policy.retryable(err) && !s.recieved; attempt += 1 {
+		fmt.Printf("warning: retrying %s (attempt %d/%d) after error:\n%s\n", s.method, attempt+1, s.policy.maxAttempts, err)
+		if waitErr := s.policy.wait(s.ctx, attempt); waitErr != nil {
+			break
+		}
+
+		err = s.replay()
+		if err != nil {
+			continue
+		}
+
+		err = s.ClientStream.RecvMsg(m)
+	}
+
+	if err == nil {
+		s.recieved = true
+	}
+
+	return err
+}
+
+// opens a new stream and re-sends the request that was sent on the old one
+func (s *retryingClientStream) replay() error {
+	stream, err := s.newStream()
+	if err != nil {
+		return err
+	}
+	s.ClientStream = stream
+
+	for _, m := range s.sent {
+		// on io.EOF, the status comes from RecvMsg
+		err = stream.SendMsg(m)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	if s.closedSend {
+		return stream.CloseSend()
+	}
+
+	return nil
+}
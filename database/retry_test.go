@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryable(t *testing.T) {
+	p := retryPolicy{codes: []codes.Code{codes.Unavailable, codes.Aborted}}
+
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{io.EOF, false},
+		{status.Error(codes.Unavailable, "restarting"), true},
+		{status.Error(codes.Aborted, "conflict"), true},
+		{status.Error(codes.InvalidArgument, "bad request"), false},
+		{status.Error(codes.DeadlineExceeded, "too slow"), false},
+		{fmt.Errorf("error saving listings: %w", status.Error(codes.Unavailable, "restarting")), true},
+		{fmt.Errorf("error saving listings: %w", io.EOF), false},
+		{errors.New("not a grpc error"), false},
+	}
+
+	for _, test := range tests {
+		if retryable := p.retryable(test.err); retryable != test.retryable {
+			t.Errorf("retryable(%v) = %t, expected %t", test.err, retryable, test.retryable)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := retryPolicy{initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+
+	for _, test := range tests {
+		if delay := p.backoff(test.attempt); delay != test.delay {
+			t.Errorf("backoff(%d) = %s, expected %s", test.attempt, delay, test.delay)
+		}
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	t.Setenv("DATABASE_RETRY_BACKOFF_MS", "50")
+	t.Setenv("DATABASE_RETRY_MAX_BACKOFF_MS", "2000")
+
+	tests := []struct {
+		attempts    string
+		codes       string
+		maxAttempts int
+		expected    []codes.Code
+	}{
+		{"4", "UNAVAILABLE,ABORTED", 4, []codes.Code{codes.Unavailable, codes.Aborted}},
+		{"2", "unavailable, deadline_exceeded", 2, []codes.Code{codes.Unavailable, codes.DeadlineExceeded}},
+		{"3", "UNAVAILABLE,NOT_A_CODE", 3, []codes.Code{codes.Unavailable}},
+		{"0", "UNAVAILABLE", 1, []codes.Code{codes.Unavailable}},
+	}
+
+	for _, test := range tests {
+		t.Setenv("DATABASE_RETRY_ATTEMPTS", test.attempts)
+		t.Setenv("DATABASE_RETRY_CODES", test.codes)
+		p := retryPolicyFromEnv()
+
+		if p.maxAttempts != test.maxAttempts {
+			t.Errorf("DATABASE_RETRY_ATTEMPTS=%s: %d attempts, expected %d", test.attempts, p.maxAttempts, test.maxAttempts)
+		}
+		if fmt.Sprint(p.codes) != fmt.Sprint(test.expected) {
+			t.Errorf("DATABASE_RETRY_CODES=%s: retrying %v, expected %v", test.codes, p.codes, test.expected)
+		}
+		if p.initialBackoff != 50*time.Millisecond || p.maxBackoff != 2*time.Second {
+			t.Errorf("backoff of %s up to %s, expected 50ms up to 2s", p.initialBackoff, p.maxBackoff)
+		}
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "restarting")
+	invalid := status.Error(codes.InvalidArgument, "bad request")
+
+	tests := []struct {
+		method string
+		fails  []error
+		calls  int
+		err    error
+	}{
+		{"/ListingsDatabase/GetHistory", nil, 1, nil},
+		{"/ListingsDatabase/GetHistory", []error{unavailable, unavailable}, 3, nil},
+		{"/ListingsDatabase/GetHistory", []error{unavailable, unavailable, unavailable}, 3, unavailable},
+		{"/ListingsDatabase/GetHistory", []error{invalid}, 1, invalid},
+		{"/ListingsDatabase/FetchListing", []error{unavailable}, 2, nil},
+		{"/ListingsDatabase/Version", []error{unavailable}, 2, nil},
+
+		// a lost reply would make a retried cull or delete report 0 listings
+		{"/ListingsDatabase/CullListings", []error{unavailable}, 1, unavailable},
+		{"/ListingsDatabase/DeleteListings", []error{unavailable}, 1, unavailable},
+		{"/ListingsDatabase/PurgeListings", []error{unavailable}, 1, unavailable},
+	}
+
+	interceptor := testRetryPolicy().unaryInterceptor()
+	for _, test := range tests {
+		calls := 0
+		fails := test.fails
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls += 1
+			if len(fails) > 0 {
+				err := fails[0]
+				fails = fails[1:]
+				return err
+			}
+			return nil
+		}
+
+		err := interceptor(context.Background(), test.method, nil, nil, nil, invoker)
+		if calls != test.calls {
+			t.Errorf("%s failing with %v: called %d times, expected %d", test.method, test.fails, calls, test.calls)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s failing with %v: returned %v, expected %v", test.method, test.fails, err, test.err)
+		}
+	}
+}
+
+func TestUnaryInterceptorCancelled(t *testing.T) {
+	p := testRetryPolicy()
+	p.initialBackoff = time.Hour
+	p.maxBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls += 1
+		cancel()
+		return status.Error(codes.Unavailable, "restarting")
+	}
+
+	err := p.unaryInterceptor()(ctx, "/ListingsDatabase/GetHistory", nil, nil, nil, invoker)
+	if calls != 1 || status.Code(err) != codes.Unavailable {
+		t.Errorf("cancelled while waiting to retry: called %d times and returned %v, expected 1 call and the last error", calls, err)
+	}
+}
+
+// a server-side stream that sends the errors in recvErrors from RecvMsg in order, then nil. SendMsg returns sendErr
+type fakeClientStream struct {
+	sent       []interface{}
+	closedSend bool
+	recvErrors []error
+	sendErr    error
+}
+
+func (s *fakeClientStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *fakeClientStream) Trailer() metadata.MD         { return nil }
+func (s *fakeClientStream) Context() context.Context     { return context.Background() }
+
+func (s *fakeClientStream) CloseSend() error {
+	s.closedSend = true
+	return nil
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return s.sendErr
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	if len(s.recvErrors) > 0 {
+		err := s.recvErrors[0]
+		s.recvErrors = s.recvErrors[1:]
+		return err
+	}
+	return nil
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "restarting")
+	invalid := status.Error(codes.InvalidArgument, "bad request")
+	serverStream := &grpc.StreamDesc{ServerStreams: true}
+
+	tests := []struct {
+		name    string
+		method  string
+		desc    *grpc.StreamDesc
+		streams [][]error // what each opened stream's RecvMsg returns
+		recvs   int       // RecvMsg calls by the caller
+		opened  int
+		err     error // from the last RecvMsg
+	}{
+		{"no failures", "/ListingsDatabase/RetrieveListings", serverStream, [][]error{nil}, 2, 1, nil},
+		{"fails before recieving", "/ListingsDatabase/RetrieveListings", serverStream, [][]error{{unavailable}, {unavailable}, nil}, 1, 3, nil},
+		{"out of attempts", "/ListingsDatabase/RetrieveListings", serverStream, [][]error{{unavailable}, {unavailable}, {unavailable}}, 1, 3, unavailable},
+		{"not transient", "/ListingsDatabase/RetrieveListings", serverStream, [][]error{{invalid}}, 1, 1, invalid},
+		// a retry would send the messages already recieved again
+		{"fails after recieving", "/ListingsDatabase/RetrieveListings", serverStream, [][]error{{nil, unavailable}, nil}, 2, 1, unavailable},
+		{"client stream", "/ListingsDatabase/SaveListings", &grpc.StreamDesc{ClientStreams: true}, [][]error{{unavailable}, nil}, 1, 1, unavailable},
+		{"not idempotent", "/ListingsDatabase/Unknown", serverStream, [][]error{{unavailable}, nil}, 1, 1, unavailable},
+	}
+
+	for _, test := range tests {
+		var opened []*fakeClientStream
+		streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			stream := &fakeClientStream{recvErrors: test.streams[len(opened)]}
+			opened = append(opened, stream)
+			return stream, nil
+		}
+
+		stream, err := testRetryPolicy().streamInterceptor()(context.Background(), test.desc, nil, test.method, streamer)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		request := "request"
+		if err := stream.SendMsg(request); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		}
+		stream.CloseSend()
+
+		for idx := 0; idx < test.recvs; idx++ {
+			err = stream.RecvMsg(nil)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: RecvMsg returned %v, expected %v", test.name, err, test.err)
+		}
+		if len(opened) != test.opened {
+			t.Errorf("%s: opened %d streams, expected %d", test.name, len(opened), test.opened)
+		}
+
+		// every stream gets the whole request
+		for idx, s := range opened {
+			if len(s.sent) != 1 || s.sent[0] != request || !s.closedSend {
+				t.Errorf("%s: stream %d was sent %v (closed: %t), expected the request", test.name, idx, s.sent, s.closedSend)
+			}
+		}
+	}
+}
+
+func TestStreamInterceptorSendEOF(t *testing.T) {
+	// io.EOF from SendMsg means the stream was aborted, the caller has to get the status from RecvMsg
+	unavailable := status.Error(codes.Unavailable, "restarting")
+	var opened []*fakeClientStream
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream := &fakeClientStream{}
+		if len(opened) == 0 {
+			stream.sendErr = io.EOF
+			stream.recvErrors = []error{unavailable}
+		}
+		opened = append(opened, stream)
+		return stream, nil
+	}
+
+	stream, err := testRetryPolicy().streamInterceptor()(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/ListingsDatabase/RetrieveListings", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg("request"); err != io.EOF {
+		t.Errorf("SendMsg returned %v, expected io.EOF", err)
+	}
+	stream.CloseSend()
+
+	if err := stream.RecvMsg(nil); err != nil {
+		t.Errorf("RecvMsg after io.EOF: %s", err)
+	}
+	if len(opened) != 2 || len(opened[1].sent) != 1 {
+		t.Errorf("opened %d streams, expected the request to be replayed on a second one", len(opened))
+	}
+}
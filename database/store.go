@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+//note: a listing is just a piece of media from reddit. A comment or a post or a link, etc
+
+// Store is implemented by every storage backend the listings can be saved to.
+// every call is abandoned when its context is done, or after DATABASE_TIMEOUT seconds (see callTimeout())
+type Store interface {
+	// saves newly discovered listings. Listings that are already stored are left alone
+	SaveListings(context.Context, reddit.ContentGroup) error
+
+	// records the current upvotes/comments of each listing as a new entry under the stored listing
+	RecordNewData(context.Context, reddit.ContentGroup) error
+
+	// overwrites stored listings along with all of their entries, inserting the ones that aren't stored yet.
+	// Archived listings stay archived. Used by migrations (see the migrate package)
+	ReplaceListings(context.Context, []ListingHistory) error
+
+	// pulls a single page of stored listings matching query into set.
+	// returns the cursor of the next page ("" if this was the last page) and # of listings pulled
+	RecieveListingsPage(ctx context.Context, set reddit.ContentGroup, query ListingsQuery) (string, int, error)
+
+	// same as RecieveListingsPage, but also pulls every entry recorded under each listing.
+	// returns the page and the cursor of the next page ("" if this was the last page)
+	RecieveHistoryPage(ctx context.Context, query ListingsQuery) ([]ListingHistory, string, error)
+
+	// deletes every stored listing over maxAge seconds old, or only marks them as archived if archive is set.
+	// Returns # of listings deleted/archived
+	CullListings(ctx context.Context, maxAge uint64, archive bool) (int, error)
+
+	// permanently deletes archived listings over maxAge seconds old (all of them if maxAge is 0). Returns # of listings deleted
+	PurgeListings(ctx context.Context, maxAge uint64) (int, error)
+
+	// downsamples the entries recorded over maxAge seconds ago to one per resolution seconds (see compact.go).
+	// Returns # of entries removed
+	CompactHistory(ctx context.Context, maxAge uint64, resolution uint64) (int, error)
+
+	// returns every entry recorded under the listing, oldest first. Returns ErrListingNotFound if the listing isn't stored
+	GetHistory(ctx context.Context, ID reddit.Fullname) ([]Snapshot, error)
+
+	// deletes the given listings (and their entries) regardless of age, eg: posts that were removed from reddit.
+	// IDs that aren't stored are ignored. Returns # of listings deleted
+	DeleteListings(ctx context.Context, IDs []reddit.Fullname) (int, error)
+
+	// whether or not the backend is currently reachable
+	Healthy() bool
+
+	Close()
+}
+
+// returned when a requested listing isn't stored
+var ErrListingNotFound = errors.New("listing not found")
+
+// a single recording of a listing's upvotes and comments at some point in time. Defined in the reddit package so that conv can convert it
+type Snapshot = reddit.Snapshot
+
+// a stored listing along with every entry recorded under it, oldest first
+type ListingHistory = reddit.ListingHistory
+
+// narrows down which listings RecieveListingsPage and RecieveHistoryPage pull
+type ListingsQuery struct {
+	MaxAge    int64  // only listings at most MaxAge seconds old
+	MinAge    int64  // only listings at least MinAge seconds old, if set
+	Subreddit string // only listings from this subreddit, if set
+
+	Sort   ListingsSort
+	Limit  int    // max # of listings in the page. 0 means no limit (and no pagination)
+	Cursor string // returned by the previous page. Empty for the first page
+}
+
+type ListingsSort int
+
+const (
+	Unsorted    ListingsSort = iota // in whatever order is fastest for the backend
+	NewestFirst                     // by date created
+	OldestFirst
+)
+
+// how long a single call to the storage backend may take before it's abandoned
+func callTimeout() time.Duration {
+	return time.Second * time.Duration(util.GetEnvIntDefault("DATABASE_TIMEOUT", 60))
+}
+
+// connects to the storage backend chosen by the STORAGE_BACKEND env variable
+func Connect() (Store, error) {
+	backend := strings.ToLower(util.GetEnvOptional("STORAGE_BACKEND", "grpc"))
+
+	var store Store
+	var err error
+	switch backend {
+	case "grpc":
+		store, err = connectService()
+	case "mongo", "mongodb":
+		store, err = connectMongo()
+	case "bolt", "embedded":
+		store, err = connectBolt()
+	case "memory":
+		store = NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND \"%s\"", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jtyrmn/reddit-votewatch/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+/*
+	every call to subreddit-logger-database gets a span (see the tracing
+	package) under whatever the scheduler was doing at the time, and passes the
+	trace on in a traceparent header so that the service can add its own spans
+	to it. Like the metrics, a retried call is one span covering every attempt.
+	A stream's span ends once it's finished, not when it's opened
+*/
+
+func tracingUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startCallSpan(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		span.End(err)
+		return err
+	}
+}
+
+func tracingStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startCallSpan(ctx, method)
+		if span == nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			span.End(err)
+			return nil, err
+		}
+
+		return &tracedClientStream{ClientStream: stream, span: span, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// starts the span of a call, and adds its traceparent to the call's metadata
+func startCallSpan(ctx context.Context, method string) (context.Context, *tracing.Span) {
+	// eg: /ListingsDatabase/SaveListings -> SaveListings
+	name := method[strings.LastIndex(method, "/")+1:]
+
+	ctx, span := tracing.StartKind(ctx, "database "+name, tracing.Client)
+	if span == nil {
+		return ctx, nil
+	}
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", name)
+
+	return metadata.AppendToOutgoingContext(ctx, "traceparent", tracing.Traceparent(ctx)), span
+}
+
+// ends the span of a stream once it's finished. io.EOF is the end of a server stream, not a failure
+type tracedClientStream struct {
+	grpc.ClientStream
+	span          *tracing.Span
+	serverStreams bool
+
+	messages int
+	mu       sync.Mutex
+}
+
+func (s *tracedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+
+	s.count()
+	return nil
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+
+	if s.serverStreams {
+		s.count()
+	} else {
+		// the single response of a client stream ends it
+		s.finish(nil)
+	}
+	return nil
+}
+
+func (s *tracedClientStream) count() {
+	s.mu.Lock()
+	s.messages += 1
+	s.mu.Unlock()
+}
+
+func (s *tracedClientStream) finish(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+
+	s.mu.Lock()
+	s.span.SetAttribute("rpc.messages", s.messages)
+	s.mu.Unlock()
+	s.span.End(err) // only the first call does anything
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jtyrmn/reddit-votewatch/pb"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	this file makes sure that this program and subreddit-logger-database agree
+	on ListingsDatabase.proto. Protobuf quietly drops fields that the other side
+	doesn't know about, so a mismatch would otherwise show up as missing data
+	(eg: unfiltered or unpaginated listings) rather than as an error.
+*/
+
+// version of ListingsDatabase.proto this program was built against. Bump it with every change to the schema
+const schemaVersion = 7
+
+// oldest version of the service this program works with. Raise it when the program starts relying on a newer schema.
+// version 2 is required because an older service ignores CullListingsRequest.archive and deletes the listings
+const minServiceVersion = 2
+
+// first version of the service that filters listings by ListingsQuery.MinAge. An older one ignores it and returns every
+// listing, see SupportsMinAge
+const minAgeVersion = 6
+
+// exchanges schema versions with the database service. Returns an error describing which side is out of date on a mismatch
+func (c *connection) checkVersion(ctx context.Context) error {
+	check, err := util.GetEnvBool("DATABASE_VERSION_CHECK", true)
+	if err != nil {
+		return err
+	}
+	if !check {
+		c.serviceVersion = -1
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	response, err := c.getClient().Version(ctx, &pb.VersionRequest{ClientVersion: schemaVersion})
+	if status.Code(err) == codes.Unimplemented {
+		// the service predates versioning
+		response, err = &pb.VersionResponse{}, nil
+	}
+	if err != nil {
+		return fmt.Errorf("error checking schema version:\n%s", err)
+	}
+
+	c.serviceVersion = int(response.SchemaVersion)
+	if response.SchemaVersion < minServiceVersion {
+		return fmt.Errorf("the database service is out of date: it has schema version %d but this program needs at least version %d. Update subreddit-logger-database (or set DATABASE_VERSION_CHECK=false at your own risk)",
+			response.SchemaVersion, minServiceVersion)
+	}
+	if schemaVersion < response.MinClientVersion {
+		return fmt.Errorf("this program is out of date: it has schema version %d but the database service needs at least version %d. Update reddit-votewatch (or set DATABASE_VERSION_CHECK=false at your own risk)",
+			schemaVersion, response.MinClientVersion)
+	}
+
+	if response.SchemaVersion != schemaVersion {
+		fmt.Printf("note: database service has schema version %d, this program has version %d\n", response.SchemaVersion, schemaVersion)
+	}
+
+	return nil
+}
+
+// whether the service filters listings by ListingsQuery.MinAge. Unknown (and assumed) with DATABASE_VERSION_CHECK=false
+func (c *connection) SupportsMinAge() bool {
+	return c.serviceVersion < 0 || c.serviceVersion >= minAgeVersion
+}
@@ -0,0 +1,126 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	Re-submissions and crossposts of the same link end up as separate listings
+	that track the same content. This package finds listings by the same author
+	with the same title and url, and merges each group into its oldest listing:
+	the entries of every duplicate are added to the oldest listing's history
+	(sorted by date), then the duplicates are deleted.
+
+	listings stored before the author and url were recorded can't be told apart
+	from each other, so they're never considered duplicates
+*/
+
+// the part of database.Store that Run needs
+type listingsMerger interface {
+	RecieveListingsPage(context.Context, reddit.ContentGroup, database.ListingsQuery) (string, int, error)
+	GetHistory(context.Context, reddit.Fullname) ([]database.Snapshot, error)
+	ReplaceListings(context.Context, []database.ListingHistory) error
+	DeleteListings(context.Context, []reddit.Fullname) (int, error)
+}
+
+// a set of duplicate listings. Kept is the listing the others get merged into
+type Group struct {
+	Kept       reddit.RedditContent
+	Duplicates []reddit.RedditContent
+}
+
+// finds duplicates among the listings matching query, a page of query.Limit listings at a time
+func Find(ctx context.Context, store listingsMerger, query database.ListingsQuery) ([]Group, error) {
+	byKey := make(map[string][]reddit.RedditContent)
+	for {
+		page := make(reddit.ContentGroup)
+		next, _, err := store.RecieveListingsPage(ctx, page, query)
+		if err != nil {
+			return nil, fmt.Errorf("error recieving listings from database:\n%s", err)
+		}
+
+		for _, listing := range page {
+			if listing.Author == "" {
+				continue
+			}
+			key := duplicateKey(listing)
+			byKey[key] = append(byKey[key], listing)
+		}
+
+		if next == "" {
+			break
+		}
+		query.Cursor = next
+	}
+
+	groups := make([]Group, 0)
+	for _, listings := range byKey {
+		if len(listings) < 2 {
+			continue
+		}
+
+		sort.Slice(listings, func(i, j int) bool {
+			if listings[i].Date != listings[j].Date {
+				return listings[i].Date < listings[j].Date
+			}
+			return listings[i].FullId() < listings[j].FullId()
+		})
+		groups = append(groups, Group{Kept: listings[0], Duplicates: listings[1:]})
+	}
+
+	// oldest first, so that output is stable between runs
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Kept.Date < groups[j].Kept.Date })
+
+	return groups, nil
+}
+
+// listings with the same key are duplicates
+func duplicateKey(listing reddit.RedditContent) string {
+	return strings.Join([]string{
+		strings.ToLower(listing.Author),
+		strings.ToLower(strings.TrimSpace(listing.Title)),
+		listing.Url,
+	}, "\x00")
+}
+
+// merges every group's duplicates into the listing it keeps. Returns # of listings deleted
+func Merge(ctx context.Context, store listingsMerger, groups []Group) (int, error) {
+	deleted := 0
+	for _, group := range groups {
+		entries, err := store.GetHistory(ctx, group.Kept.FullId())
+		if err != nil {
+			return deleted, fmt.Errorf("error getting history of %s:\n%s", group.Kept.FullId(), err)
+		}
+
+		IDs := make([]reddit.Fullname, 0, len(group.Duplicates))
+		for _, duplicate := range group.Duplicates {
+			history, err := store.GetHistory(ctx, duplicate.FullId())
+			if err != nil {
+				return deleted, fmt.Errorf("error getting history of %s:\n%s", duplicate.FullId(), err)
+			}
+			entries = append(entries, history...)
+			IDs = append(IDs, duplicate.FullId())
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date < entries[j].Date })
+
+		// the kept listing is written first, so that nothing is lost if deleting fails
+		err = store.ReplaceListings(ctx, []database.ListingHistory{{Listing: group.Kept, Entries: entries}})
+		if err != nil {
+			return deleted, fmt.Errorf("error merging into %s:\n%s", group.Kept.FullId(), err)
+		}
+
+		count, err := store.DeleteListings(ctx, IDs)
+		if err != nil {
+			return deleted, fmt.Errorf("error deleting duplicates of %s:\n%s", group.Kept.FullId(), err)
+		}
+		deleted += count
+	}
+
+	return deleted, nil
+}
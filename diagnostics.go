@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/plugins"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	before tracking anything, the program checks that everything it depends on
+	works, so that a bad deploy fails right away with a clear message instead of
+	as errors from the first jobs. Once the configuration checks out (see
+	config.go): reddit accepts the access token, each subreddit can be fetched
+	from, the database answers (and how fast) and the access token cache can be
+	written to. Each check prints a line. If any of the hard ones fail the
+	program exits with an error, a problem with the token cache is only a
+	warning. STARTUP_DIAGNOSTICS=false skips them
+
+	`votewatch check` runs them without tracking anything, eg: as a step of a
+	deploy pipeline, and prints a table of the results instead. It exits with 1
+	if any check failed
+*/
+
+// how long a single check may take
+const diagnosticTimeout = 30 * time.Second
+
+// the reddit api handler, as far as the checks are concerned
+type redditProber interface {
+	Whoami(ctx context.Context) (string, error)
+	ProbeSubreddit(ctx context.Context, name string) (bool, error)
+	SubredditNames() []string
+}
+
+type diagnosticResult struct {
+	status string // ok, warn or FAIL
+	check  string
+	detail string
+}
+
+type diagnostics struct {
+	quiet    bool // only record the results, for the table of `votewatch check`
+	results  []diagnosticResult
+	failures int
+}
+
+func (d *diagnostics) record(status string, check string, format string, args ...interface{}) {
+	result := diagnosticResult{status: status, check: check, detail: fmt.Sprintf(format, args...)}
+	d.results = append(d.results, result)
+	if !d.quiet {
+		log.Printf("diagnostics: %-4s %s: %s\n", status, check, result.detail)
+	}
+}
+
+func (d *diagnostics) pass(check string, format string, args ...interface{}) {
+	d.record("ok", check, format, args...)
+}
+
+func (d *diagnostics) warn(check string, format string, args ...interface{}) {
+	d.record("warn", check, format, args...)
+}
+
+func (d *diagnostics) fail(check string, format string, args ...interface{}) {
+	d.failures += 1
+	d.record("FAIL", check, format, args...)
+}
+
+// one row per check, then the whole of every detail that didn't fit on its row (eg: errors from the database)
+func (d *diagnostics) printTable(out io.Writer) {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHECK\tRESULT\tDETAIL")
+
+	var details []diagnosticResult
+	warnings := 0
+	for _, result := range d.results {
+		detail, rest, multiline := strings.Cut(result.detail, "\n")
+		if multiline {
+			detail = strings.TrimSuffix(detail, ":") + ", see below"
+			details = append(details, diagnosticResult{check: result.check, detail: rest})
+		}
+		if result.status == "warn" {
+			warnings += 1
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", result.check, result.status, detail)
+	}
+	table.Flush()
+
+	for _, result := range details {
+		fmt.Fprintf(out, "\n%s:\n%s\n", result.check, result.detail)
+	}
+	fmt.Fprintf(out, "\n%d passed, %d warnings, %d failed\n", len(d.results)-warnings-d.failures, warnings, d.failures)
+}
+
+func diagnosticsEnabled() bool {
+	enabled, _ := util.GetEnvBool("STARTUP_DIAGNOSTICS", true)
+	return enabled
+}
+
+// runs every check past the configuration (see config.go). Returns false if any hard check failed
+func diagnose(ctx context.Context, r redditProber, store database.Store) bool {
+	d := diagnostics{}
+	d.run(ctx, r, store)
+
+	if d.failures > 0 {
+		log.Printf("diagnostics: %d failed\n", d.failures)
+		return false
+	}
+	return true
+}
+
+func (d *diagnostics) run(ctx context.Context, r redditProber, store database.Store) {
+	d.checkReddit(ctx, r)
+	d.checkDatabase(ctx, store)
+	d.checkTokenCache()
+}
+
+func (d *diagnostics) checkReddit(ctx context.Context, r redditProber) {
+	whoamiCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+	name, err := r.Whoami(whoamiCtx)
+	cancel()
+	if err != nil {
+		//every other request would fail the same way
+		d.fail("reddit login", "reddit didn't accept the access token, check the REDDIT_* env variables:\n%s", err)
+		return
+	}
+	d.pass("reddit login", "authenticated as u/%s", name)
+
+	names := r.SubredditNames()
+	if len(names) == 0 {
+		d.fail("subreddits", "no subreddits to track in %s", os.Getenv("SUBREDDITS_PATH"))
+		return
+	}
+
+	for _, sub := range names {
+		probeCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+		hasPosts, err := r.ProbeSubreddit(probeCtx, sub)
+		cancel()
+
+		switch {
+		case err != nil:
+			d.fail("r/"+sub, "can't be fetched from, check the name in %s:\n%s", os.Getenv("SUBREDDITS_PATH"), err)
+		case !hasPosts:
+			d.warn("r/"+sub, "no posts, it might not exist")
+		default:
+			d.pass("r/"+sub, "fetched the newest post")
+		}
+	}
+}
+
+func (d *diagnostics) checkDatabase(ctx context.Context, store database.Store) {
+	if !store.Healthy() {
+		d.fail("database", "unhealthy")
+		return
+	}
+
+	//the smallest read there is, to time a round trip
+	query := database.ListingsQuery{MaxAge: time.Now().Unix(), Limit: 1}
+	readCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := store.RecieveListingsPage(readCtx, make(reddit.ContentGroup), query)
+	if err != nil {
+		d.fail("database", "error reading from the database:\n%s", err)
+		return
+	}
+	d.pass("database", "answered in %s", time.Since(start).Round(time.Millisecond))
+}
+
+// the access token is cached to ACCESS_TOKEN_PATH so that restarts don't need a new one. Without it the program still works
+func (d *diagnostics) checkTokenCache() {
+	if cache, _ := util.GetEnvBool("CACHE_ACCESS_TOKEN", true); !cache {
+		return
+	}
+	path := os.Getenv("ACCESS_TOKEN_PATH")
+
+	_, err := os.Stat(path)
+	existed := err == nil
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		d.warn("token cache", "%s isn't writable, a new token will be needed on every start:\n%s", path, err)
+		return
+	}
+	file.Close()
+
+	//don't leave an empty cache behind, it wouldn't parse
+	if !existed {
+		os.Remove(path)
+	}
+	d.pass("token cache", "%s is writable", path)
+}
+
+// checks everything without tracking anything, exiting with an error if anything's wrong
+func runCheck(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	configOnly := flags.Bool("config", false, "only check the env variables, printing their problems instead of a table")
+	flags.Parse(args)
+
+	d := diagnostics{quiet: true}
+	ctx := context.Background()
+
+	//plugins register sinks, whose settings are part of the configuration
+	loaded, err := plugins.LoadFromEnv()
+
+	//how the env variables a SIGHUP would load are checked, see checkEnv
+	if *configOnly {
+		if err == nil {
+			err = validateConfig()
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err != nil {
+		d.fail("plugins", "%s", err)
+		d.printTable(os.Stdout)
+		os.Exit(1)
+	}
+	if len(loaded) > 0 {
+		d.pass("plugins", "loaded %s", strings.Join(loaded, ", "))
+	}
+
+	//nothing else can be checked without a valid configuration
+	err = validateConfig()
+	if err != nil {
+		d.fail("configuration", "%s", err)
+		d.printTable(os.Stdout)
+		os.Exit(1)
+	}
+	d.pass("configuration", "every setting is valid")
+
+	r, err := reddit.Connect()
+	if err != nil {
+		d.fail("reddit login", "error connecting to reddit:\n%s", err)
+	} else {
+		d.checkReddit(ctx, r)
+	}
+
+	store, err := database.Connect()
+	if err != nil {
+		d.fail("database", "error connecting to database:\n%s", err)
+	} else {
+		d.checkDatabase(ctx, store)
+		store.Close()
+	}
+
+	d.checkTokenCache()
+
+	d.printTable(os.Stdout)
+	if d.failures > 0 {
+		os.Exit(1)
+	}
+}
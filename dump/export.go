@@ -0,0 +1,227 @@
+package dump
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	This package dumps collected listings and their vote history to flat files
+	so the data can be loaded into pandas or a spreadsheet without talking grpc,
+	and reads them back (see import.go). Two formats are supported:
+
+	csv     - one row per entry: id,subreddit,title,created,date,upvotes,comments
+	          a listing without any entries gets a single row of its own upvotes/comments
+	json    - one object per line (json lines), each listing with its entries nested
+	parquet - the same rows as csv, in a columnar file for duckdb, spark... (see parquet.go). Export only
+	ndjson  - the same rows as csv, as a json object per line for jq, pandas, duckdb... Snapshots only (see
+	          snapshots.go), for votewatch dump and the api's /dump
+*/
+
+const (
+	CSV     = "csv"
+	JSON    = "json"
+	PARQUET = "parquet"
+	NDJSON  = "ndjson"
+)
+
+var csvHeader = []string{"id", "subreddit", "title", "created", "date", "upvotes", "comments"}
+
+// one line of a json dump
+type record struct {
+	Id        reddit.Fullname     `json:"id"`
+	Subreddit string              `json:"subreddit"`
+	Title     string              `json:"title"`
+	Author    string              `json:"author,omitempty"`
+	Url       string              `json:"url,omitempty"`
+	Created   uint64              `json:"created"`
+	Queried   uint64              `json:"queried"`
+	Upvotes   int                 `json:"upvotes"`
+	Comments  int                 `json:"comments"`
+	Entries   []database.Snapshot `json:"entries"`
+}
+
+// the part of database.Store that Export needs
+type historySource interface {
+	RecieveHistoryPage(context.Context, database.ListingsQuery) ([]database.ListingHistory, string, error)
+}
+
+// streams every listing matching query (and its entries) from store to out in the given format, a page of query.Limit listings at a time.
+// Returns # of listings written
+func Export(ctx context.Context, store historySource, query database.ListingsQuery, format string, out io.Writer) (int, error) {
+	var write func(database.ListingHistory) error
+	var flush func() error
+
+	switch format {
+	case CSV:
+		writer := csv.NewWriter(out)
+		err := writer.Write(csvHeader)
+		if err != nil {
+			return 0, err
+		}
+		write = func(history database.ListingHistory) error {
+			return writer.WriteAll(csvRows(history))
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case JSON:
+		encoder := json.NewEncoder(out)
+		write = func(history database.ListingHistory) error {
+			return encoder.Encode(toRecord(history))
+		}
+		flush = func() error { return nil }
+	case PARQUET:
+		writer, err := newParquetWriter(out)
+		if err != nil {
+			return 0, err
+		}
+		write = func(history database.ListingHistory) error {
+			for _, r := range rowsOf(history) {
+				if err := writer.write(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = writer.Close
+	default:
+		return 0, fmt.Errorf("unknown format \"%s\", expected %s, %s or %s", format, CSV, JSON, PARQUET)
+	}
+
+	written := 0
+	for {
+		page, next, err := store.RecieveHistoryPage(ctx, query)
+		if err != nil {
+			return written, fmt.Errorf("error recieving listings from database:\n%s", err)
+		}
+
+		for _, history := range page {
+			err = write(history)
+			if err != nil {
+				return written, fmt.Errorf("error writing listing %s:\n%s", history.Listing.FullId(), err)
+			}
+			written += 1
+		}
+
+		if next == "" {
+			break
+		}
+		query.Cursor = next
+	}
+
+	return written, flush()
+}
+
+// one row per entry, or a single row of the listing's own upvotes/comments if it has none
+func rowsOf(history database.ListingHistory) []row {
+	listing := history.Listing
+	entries := history.Entries
+	if len(entries) == 0 {
+		entries = []database.Snapshot{listing.Snapshot()}
+	}
+
+	rows := make([]row, len(entries))
+	for idx, entry := range entries {
+		rows[idx] = rowOf(listing, entry)
+	}
+	return rows
+}
+
+func rowOf(listing reddit.RedditContent, entry database.Snapshot) row {
+	return row{
+		id:        string(listing.FullId()),
+		subreddit: listing.Subreddit,
+		title:     listing.Title,
+		created:   listing.Date,
+		date:      entry.Date,
+		upvotes:   entry.Upvotes,
+		comments:  entry.Comments,
+
+		upvotesPerHour:  entry.UpvotesPerHour,
+		commentsPerHour: entry.CommentsPerHour,
+	}
+}
+
+func csvRows(history database.ListingHistory) [][]string {
+	rows := rowsOf(history)
+	records := make([][]string, len(rows))
+	for idx, r := range rows {
+		records[idx] = r.csv()
+	}
+	return records
+}
+
+// in csvHeader's order
+func (r row) csv() []string {
+	return []string{
+		r.id,
+		r.subreddit,
+		r.title,
+		strconv.FormatUint(r.created, 10),
+		strconv.FormatUint(r.date, 10),
+		strconv.Itoa(r.upvotes),
+		strconv.Itoa(r.comments),
+	}
+}
+
+func toRecord(history database.ListingHistory) record {
+	listing := history.Listing
+	entries := history.Entries
+	if entries == nil {
+		entries = make([]database.Snapshot, 0)
+	}
+
+	return record{
+		Id:        listing.FullId(),
+		Subreddit: listing.Subreddit,
+		Title:     listing.Title,
+		Author:    listing.Author,
+		Url:       listing.Url,
+		Created:   listing.Date,
+		Queried:   listing.QueryDate,
+		Upvotes:   listing.Upvotes,
+		Comments:  listing.Comments,
+		Entries:   entries,
+	}
+}
+
+// parses an age such as "7d", "12h" or "90m". Days aren't supported by time.ParseDuration so they're handled here
+func ParseAge(age string) (time.Duration, error) {
+	if strings.HasSuffix(age, "d") {
+		d, err := strconv.ParseUint(strings.TrimSuffix(age, "d"), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("malformed age \"%s\"", age)
+		}
+		return time.Hour * 24 * time.Duration(d), nil
+	}
+
+	d, err := time.ParseDuration(age)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("malformed age \"%s\"", age)
+	}
+	return d, nil
+}
+
+// parses a point in time, either an age (see ParseAge) before now or a date: 2026-10-01 or 2026-10-01T12:00:00Z
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if age, err := ParseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("malformed time \"%s\", expected an age (eg: 7d, 12h) or a date (eg: 2026-10-01)", value)
+}
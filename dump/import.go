@@ -0,0 +1,212 @@
+package dump
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	importing streams a dump back into the database, for restoring from an
+	export or migrating between backends. Listings are saved with SaveListings,
+	then their entries are replayed oldest first with RecordNewData, a batch of
+	listings at a time.
+
+	note that importing the same dump twice records every entry twice, and that a
+	csv row is always imported as an entry (even the single row exported for a
+	listing without entries)
+*/
+
+// the part of database.Store that Import needs
+type historySink interface {
+	SaveListings(context.Context, reddit.ContentGroup) error
+	RecordNewData(context.Context, reddit.ContentGroup) error
+}
+
+// reads a dump in the given format from in and saves it to store, batchSize listings at a time.
+// Returns # of listings imported
+func Import(ctx context.Context, store historySink, format string, in io.Reader, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	next, err := recordReader(format, in)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	batch := make([]record, 0, batchSize)
+	for {
+		r, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("error reading listing %d:\n%s", imported+len(batch)+1, err)
+		}
+
+		batch = append(batch, r)
+		if len(batch) == batchSize {
+			err = importBatch(ctx, store, batch)
+			if err != nil {
+				return imported, err
+			}
+			imported += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	err = importBatch(ctx, store, batch)
+	if err != nil {
+		return imported, err
+	}
+
+	return imported + len(batch), nil
+}
+
+// returns a function that reads the next listing from a dump in the given format, io.EOF after the last one
+func recordReader(format string, in io.Reader) (func() (record, error), error) {
+	switch format {
+	case CSV:
+		return csvRecords(in), nil
+	case JSON:
+		decoder := json.NewDecoder(in)
+		return func() (record, error) {
+			var r record
+			err := decoder.Decode(&r)
+			return r, err
+		}, nil
+	case PARQUET:
+		return nil, errParquetImport
+	default:
+		return nil, fmt.Errorf("unknown format \"%s\", expected %s or %s", format, CSV, JSON)
+	}
+}
+
+func importBatch(ctx context.Context, store historySink, batch []record) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	listings := make(reddit.ContentGroup, len(batch))
+	mostEntries := 0
+	for _, r := range batch {
+		listings[r.Id] = r.listing()
+		if len(r.Entries) > mostEntries {
+			mostEntries = len(r.Entries)
+		}
+	}
+
+	err := store.SaveListings(ctx, listings)
+	if err != nil {
+		return fmt.Errorf("error saving listings:\n%s", err)
+	}
+
+	//the i-th entry of every listing is recorded in the same call
+	for i := 0; i < mostEntries; i += 1 {
+		entries := make(reddit.ContentGroup)
+		for _, r := range batch {
+			if i >= len(r.Entries) {
+				continue
+			}
+
+			listing := r.listing()
+			listing.Upvotes = r.Entries[i].Upvotes
+			listing.Comments = r.Entries[i].Comments
+			listing.QueryDate = r.Entries[i].Date
+			entries[r.Id] = listing
+		}
+
+		err = store.RecordNewData(ctx, entries)
+		if err != nil {
+			return fmt.Errorf("error recording entries:\n%s", err)
+		}
+	}
+
+	return nil
+}
+
+func (r record) listing() reddit.RedditContent {
+	listing := reddit.RedditContent{
+		Title:     r.Title,
+		Upvotes:   r.Upvotes,
+		Comments:  r.Comments,
+		Date:      r.Created,
+		QueryDate: r.Queried,
+		Subreddit: r.Subreddit,
+		Author:    r.Author,
+		Url:       r.Url,
+	}
+	listing.ContentType, listing.Id, _ = strings.Cut(string(r.Id), "_")
+
+	return listing
+}
+
+// returns a function that reads the next listing from a csv dump. Consecutive rows with the same id are one listing
+func csvRecords(in io.Reader) func() (record, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	var pending []string // first row of the next listing, already read
+	headerRead := false
+
+	return func() (record, error) {
+		if !headerRead {
+			header, err := reader.Read()
+			if err != nil {
+				return record{}, err
+			}
+			if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+				return record{}, errors.New("unexpected csv header, expected " + strings.Join(csvHeader, ","))
+			}
+			headerRead = true
+		}
+
+		var r *record
+		for {
+			row := pending
+			pending = nil
+			if row == nil {
+				var err error
+				row, err = reader.Read()
+				if err == io.EOF && r != nil {
+					return *r, nil
+				}
+				if err != nil {
+					return record{}, err
+				}
+			}
+
+			if r != nil && reddit.Fullname(row[0]) != r.Id {
+				pending = row
+				return *r, nil
+			}
+
+			numbers := make([]int64, 4) // created, date, upvotes, comments
+			for idx := range numbers {
+				n, err := strconv.ParseInt(row[3+idx], 10, 64)
+				if err != nil {
+					return record{}, fmt.Errorf("malformed %s for %s:\n%s", csvHeader[3+idx], row[0], err)
+				}
+				numbers[idx] = n
+			}
+			created, date := uint64(numbers[0]), uint64(numbers[1])
+			upvotes, comments := int(numbers[2]), int(numbers[3])
+
+			if r == nil {
+				r = &record{Id: reddit.Fullname(row[0]), Subreddit: row[1], Title: row[2], Created: created}
+			}
+			r.Entries = append(r.Entries, database.Snapshot{Upvotes: upvotes, Comments: comments, Date: date})
+			r.Queried, r.Upvotes, r.Comments = date, upvotes, comments
+		}
+	}
+}
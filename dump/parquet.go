@@ -0,0 +1,81 @@
+package dump
+
+import (
+	"errors"
+	"io"
+
+	"github.com/jtyrmn/reddit-votewatch/version"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+/*
+	parquet (https://parquet.apache.org/docs/file-format/) dumps are written
+	with xitongsys/parquet-go: the same columns as csv dumps, every column
+	required and gzipped. created and date are timestamps (unix milliseconds)
+	instead of seconds, so that duckdb, spark, pandas... read them as such.
+*/
+
+// bytes of rows kept in memory before they're written out as a row group
+const parquetRowGroupSize = 16 * 1024 * 1024
+
+// one row of a parquet dump, in csvHeader's order
+type parquetRow struct {
+	Id        string `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Subreddit string `parquet:"name=subreddit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Title     string `parquet:"name=title, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Created   int64  `parquet:"name=created, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Date      int64  `parquet:"name=date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Upvotes   int32  `parquet:"name=upvotes, type=INT32"`
+	Comments  int32  `parquet:"name=comments, type=INT32"`
+}
+
+// one row of a csv or parquet dump, an entry of a listing
+type row struct {
+	id        string
+	subreddit string
+	title     string
+	created   uint64
+	date      uint64
+	upvotes   int
+	comments  int
+
+	upvotesPerHour  float64 // only in ndjson, csv and parquet keep the columns they've always had
+	commentsPerHour float64
+}
+
+type parquetWriter struct {
+	writer *writer.ParquetWriter
+}
+
+func newParquetWriter(out io.Writer) (*parquetWriter, error) {
+	w, err := writer.NewParquetWriterFromWriter(out, new(parquetRow), 1)
+	if err != nil {
+		return nil, err
+	}
+	w.CompressionType = parquet.CompressionCodec_GZIP
+	w.RowGroupSize = parquetRowGroupSize
+	createdBy := "votewatch " + version.Version
+	w.Footer.CreatedBy = &createdBy
+
+	return &parquetWriter{writer: w}, nil
+}
+
+func (w *parquetWriter) write(r row) error {
+	return w.writer.Write(parquetRow{
+		Id:        r.id,
+		Subreddit: r.subreddit,
+		Title:     r.title,
+		Created:   int64(r.created) * 1000,
+		Date:      int64(r.date) * 1000,
+		Upvotes:   int32(r.upvotes),
+		Comments:  int32(r.comments),
+	})
+}
+
+// writes the last row group and the footer. The writer can't be used after
+func (w *parquetWriter) Close() error {
+	return w.writer.WriteStop()
+}
+
+var errParquetImport = errors.New("parquet dumps can't be imported, export as json or csv to import later")
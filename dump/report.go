@@ -0,0 +1,354 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+)
+
+/*
+	a report of the stored listings created within some time range, as a
+	single self-contained html file (for votewatch report) that can be
+	emailed or archived, eg: a weekly snapshot from cron. Nothing is loaded
+	from elsewhere, the charts are drawn by a bit of inline javascript from
+	the data embedded next to it.
+
+	the report has the stats of every listing (see Stats) and their posting
+	volume, then a section per subreddit, busiest first, with its own stats,
+	posting volume, and its top listings by peak upvotes along with a chart
+	of how their upvotes grew after they were posted
+*/
+
+// posts per subreddit in a report when not given
+const DefaultReportTop = 10
+
+// the colors of the charts' series, the same order the top listings are in
+var reportColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+type Report struct {
+	Title     string
+	From      time.Time // zero if the report goes back to the first listing
+	Until     time.Time
+	Generated time.Time
+
+	Overall    Stats
+	Subreddits []SubredditReport // most listings first
+}
+
+type SubredditReport struct {
+	Name  string
+	Stats Stats
+	Top   []ReportListing // highest peak first
+}
+
+type ReportListing struct {
+	Id       string
+	Title    string
+	Author   string
+	Link     string // https://redd.it/<id>
+	Created  time.Time
+	Peak     int
+	Upvotes  int // latest
+	Comments int // latest
+	Color    string
+
+	entries []database.Snapshot
+	created uint64
+}
+
+// gathers a Report one listing at a time
+type ReportCollector struct {
+	top        int
+	overall    *StatsCollector
+	subreddits map[string]*subredditCollector // by lowercased name
+}
+
+type subredditCollector struct {
+	name  string
+	stats *StatsCollector
+	top   []ReportListing
+}
+
+// top is # of listings to keep per subreddit
+func NewReportCollector(top int) *ReportCollector {
+	if top <= 0 {
+		top = DefaultReportTop
+	}
+	return &ReportCollector{top: top, overall: NewStatsCollector(), subreddits: make(map[string]*subredditCollector)}
+}
+
+func (c *ReportCollector) Add(history database.ListingHistory) {
+	listing := history.Listing
+	c.overall.Add(history)
+
+	key := strings.ToLower(listing.Subreddit)
+	sub, exists := c.subreddits[key]
+	if !exists {
+		sub = &subredditCollector{name: listing.Subreddit, stats: NewStatsCollector()}
+		c.subreddits[key] = sub
+	}
+	sub.stats.Add(history)
+
+	peak, _ := peakOf(history)
+	if len(sub.top) == c.top && peak <= sub.top[len(sub.top)-1].Peak {
+		return
+	}
+	sub.top = append(sub.top, ReportListing{
+		Id:       string(listing.FullId()),
+		Title:    listing.Title,
+		Author:   listing.Author,
+		Link:     "https://redd.it/" + listing.Id,
+		Created:  time.Unix(int64(listing.Date), 0).UTC(),
+		Peak:     peak,
+		Upvotes:  listing.Upvotes,
+		Comments: listing.Comments,
+		entries:  trajectory(history),
+		created:  listing.Date,
+	})
+	sort.SliceStable(sub.top, func(i, j int) bool { return sub.top[i].Peak > sub.top[j].Peak })
+	if len(sub.top) > c.top {
+		sub.top = sub.top[:c.top]
+	}
+}
+
+// the entries recorded after the listing was created, then its latest scores if they're newer
+func trajectory(history database.ListingHistory) []database.Snapshot {
+	listing := history.Listing
+	entries := make([]database.Snapshot, 0, len(history.Entries)+1)
+	for _, entry := range history.Entries {
+		if entry.Date >= listing.Date {
+			entries = append(entries, entry)
+		}
+	}
+	if listing.QueryDate >= listing.Date && (len(entries) == 0 || listing.QueryDate > entries[len(entries)-1].Date) {
+		entries = append(entries, listing.Snapshot())
+	}
+	return entries
+}
+
+func (c *ReportCollector) Report(title string, from time.Time, until time.Time) Report {
+	report := Report{Title: title, From: from, Until: until, Generated: time.Now().UTC(), Overall: c.overall.Stats()}
+	for _, sub := range c.subreddits {
+		for idx := range sub.top {
+			sub.top[idx].Color = reportColors[idx%len(reportColors)]
+		}
+		report.Subreddits = append(report.Subreddits, SubredditReport{Name: sub.name, Stats: sub.stats.Stats(), Top: sub.top})
+	}
+	sort.Slice(report.Subreddits, func(i, j int) bool {
+		a, b := report.Subreddits[i], report.Subreddits[j]
+		if a.Stats.Listings != b.Stats.Listings {
+			return a.Stats.Listings > b.Stats.Listings
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return report
+}
+
+// the report of every listing matching query in store, a page of query.Limit listings at a time
+func ReportFromStore(ctx context.Context, store historySource, query database.ListingsQuery, collector *ReportCollector) error {
+	return eachFromStore(ctx, store, query, collector.Add)
+}
+
+// the report of the listings in a dump (in the given format) that query's MaxAge, MinAge and Subreddit match
+func ReportFromDump(format string, in io.Reader, query database.ListingsQuery, collector *ReportCollector) error {
+	return eachFromDump(format, in, query, collector.Add)
+}
+
+// what the inline javascript draws. Bars have a label per point, lines are upvotes by hours since posting
+type reportChart struct {
+	Id     string        `json:"id"`
+	Kind   string        `json:"kind"` // bars or lines
+	Labels []string      `json:"labels,omitempty"`
+	Series []chartSeries `json:"series"`
+}
+
+type chartSeries struct {
+	Color  string       `json:"color"`
+	Points [][2]float64 `json:"points"`
+}
+
+func volumeChart(id string, stats Stats) reportChart {
+	chart := reportChart{Id: id, Kind: "bars", Series: []chartSeries{{Color: reportColors[0]}}}
+	for idx, day := range stats.PerDay {
+		chart.Labels = append(chart.Labels, day.Day)
+		chart.Series[0].Points = append(chart.Series[0].Points, [2]float64{float64(idx), float64(day.Listings)})
+	}
+	return chart
+}
+
+func trajectoryChart(id string, top []ReportListing) reportChart {
+	chart := reportChart{Id: id, Kind: "lines"}
+	for _, listing := range top {
+		series := chartSeries{Color: listing.Color, Points: make([][2]float64, 0, len(listing.entries))}
+		for _, entry := range listing.entries {
+			series.Points = append(series.Points, [2]float64{float64(entry.Date-listing.created) / 3600, float64(entry.Upvotes)})
+		}
+		chart.Series = append(chart.Series, series)
+	}
+	return chart
+}
+
+// writes the report as a standalone html page
+func (r Report) WriteHTML(out io.Writer) error {
+	charts := []reportChart{volumeChart("volume", r.Overall)}
+	for idx, sub := range r.Subreddits {
+		charts = append(charts,
+			volumeChart(fmt.Sprintf("volume-%d", idx), sub.Stats),
+			trajectoryChart(fmt.Sprintf("top-%d", idx), sub.Top),
+		)
+	}
+
+	return reportTemplate.Execute(out, struct {
+		Report
+		Charts []reportChart
+	}{r, charts})
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"day":  func(t time.Time) string { return t.Format("Jan 2 2006") },
+	"time": func(t time.Time) string { return t.Format("Jan 2 2006 15:04 MST") },
+	"duration": func(d time.Duration) string {
+		return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	},
+}).Parse(reportHTML))
+
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+	body { font-family: sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222 }
+	h1 { margin-bottom: 0 }
+	.range { color: #666; margin-top: .3em }
+	section { border-top: 1px solid #ddd; margin-top: 2em }
+	dl { display: grid; grid-template-columns: max-content auto; gap: .3em 1.5em }
+	dt { color: #666 }
+	dd { margin: 0 }
+	table { border-collapse: collapse; width: 100% }
+	th, td { text-align: left; padding: .3em .5em; border-bottom: 1px solid #eee }
+	td.number { text-align: right }
+	.swatch { display: inline-block; width: .8em; height: .8em; border-radius: 2px }
+	canvas { width: 100%; height: 220px }
+	a { color: #1f5fa0 }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="range">{{if .From.IsZero}}everything{{else}}{{day .From}}{{end}} to {{day .Until}}, generated {{time .Generated}}</p>
+{{with .Overall}}{{if .Listings}}
+<dl>
+	<dt>posts</dt><dd>{{.Listings}} in {{len $.Subreddits}} subreddit(s), {{.Snapshots}} snapshots</dd>
+	<dt>peak upvotes</dt><dd>median {{.MedianPeak}}, mean {{printf "%.1f" .MeanPeak}}</dd>
+	{{with .Top}}<dt>highest</dt><dd>{{.Peak}}, r/{{.Subreddit}}: {{.Title}}</dd>{{end}}
+	{{if .AverageTimeToPeak}}<dt>time to peak</dt><dd>median {{duration .MedianTimeToPeak}}, average {{duration .AverageTimeToPeak}}</dd>{{end}}
+	<dt>posts per day</dt><dd>mean {{printf "%.1f" .MeanPerDay}}, busiest on {{.BusiestWeekday}}s</dd>
+</dl>
+<h3>posts per day</h3>
+<canvas id="volume" width="920" height="220"></canvas>
+{{else}}
+<p>no posts in this range</p>
+{{end}}{{end}}
+{{range $idx, $sub := .Subreddits}}
+<section>
+<h2>r/{{.Name}}</h2>
+{{with .Stats}}
+<dl>
+	<dt>posts</dt><dd>{{.Listings}}, {{.Snapshots}} snapshots</dd>
+	<dt>peak upvotes</dt><dd>median {{.MedianPeak}}, mean {{printf "%.1f" .MeanPeak}}</dd>
+	{{if .AverageTimeToPeak}}<dt>time to peak</dt><dd>median {{duration .MedianTimeToPeak}}, average {{duration .AverageTimeToPeak}}</dd>{{end}}
+	<dt>posts per day</dt><dd>mean {{printf "%.1f" .MeanPerDay}}, busiest on {{.BusiestWeekday}}s</dd>
+</dl>
+{{end}}
+<h3>posts per day</h3>
+<canvas id="volume-{{$idx}}" width="920" height="220"></canvas>
+<h3>top posts, upvotes by hours since posting</h3>
+<canvas id="top-{{$idx}}" width="920" height="220"></canvas>
+<table>
+	<tr><th></th><th>post</th><th>posted</th><th>peak</th><th>upvotes</th><th>comments</th></tr>
+	{{range .Top}}<tr>
+		<td><span class="swatch" style="background: {{.Color}}"></span></td>
+		<td><a href="{{.Link}}">{{.Title}}</a>{{if .Author}} by u/{{.Author}}{{end}}</td>
+		<td>{{time .Created}}</td>
+		<td class="number">{{.Peak}}</td>
+		<td class="number">{{.Upvotes}}</td>
+		<td class="number">{{.Comments}}</td>
+	</tr>{{end}}
+</table>
+</section>
+{{end}}
+<script>
+var charts = {{.Charts}};
+
+function drawChart(chart) {
+	var canvas = document.getElementById(chart.id);
+	if (!canvas || !canvas.getContext) return;
+	var ctx = canvas.getContext("2d"), width = canvas.width, height = canvas.height;
+	var left = 50, right = 10, top = 10, bottom = 25;
+
+	var maxX = 0, maxY = 0;
+	chart.series.forEach(function (series) {
+		series.points.forEach(function (point) {
+			maxX = Math.max(maxX, point[0]);
+			maxY = Math.max(maxY, point[1]);
+		});
+	});
+	if (chart.kind == "bars") maxX = chart.labels.length;
+	maxX = maxX || 1;
+	maxY = maxY || 1;
+	function x(value) { return left + (width - left - right) * value / maxX; }
+	function y(value) { return height - bottom - (height - top - bottom) * value / maxY; }
+
+	ctx.strokeStyle = "#bbb";
+	ctx.beginPath();
+	ctx.moveTo(left, top);
+	ctx.lineTo(left, height - bottom);
+	ctx.lineTo(width - right, height - bottom);
+	ctx.stroke();
+	ctx.fillStyle = "#666";
+	ctx.font = "11px sans-serif";
+	ctx.textAlign = "right";
+	ctx.fillText(maxY, left - 5, top + 8);
+	ctx.fillText("0", left - 5, height - bottom);
+
+	if (chart.kind == "bars") {
+		var barWidth = (width - left - right) / maxX;
+		ctx.fillStyle = chart.series[0].color;
+		chart.series[0].points.forEach(function (point) {
+			ctx.fillRect(x(point[0]) + 1, y(point[1]), Math.max(barWidth - 2, 1), height - bottom - y(point[1]));
+		});
+		ctx.fillStyle = "#666";
+		ctx.textAlign = "left";
+		ctx.fillText(chart.labels[0], left, height - 8);
+		ctx.textAlign = "right";
+		ctx.fillText(chart.labels[chart.labels.length - 1], width - right, height - 8);
+		return;
+	}
+
+	chart.series.forEach(function (series) {
+		ctx.strokeStyle = series.color;
+		ctx.lineWidth = 1.5;
+		ctx.beginPath();
+		series.points.forEach(function (point, idx) {
+			if (idx == 0) ctx.moveTo(x(point[0]), y(point[1]));
+			else ctx.lineTo(x(point[0]), y(point[1]));
+		});
+		ctx.stroke();
+	});
+	ctx.textAlign = "left";
+	ctx.fillText("0h", left, height - 8);
+	ctx.textAlign = "right";
+	ctx.fillText(Math.round(maxX) + "h", width - right, height - 8);
+}
+
+charts.forEach(drawChart);
+</script>
+</body>
+</html>
+`
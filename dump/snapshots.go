@@ -0,0 +1,140 @@
+package dump
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+// which entries ExportSnapshots writes
+type SnapshotFilter struct {
+	From       uint64                   // unix time, inclusive
+	Until      uint64                   // unix time, exclusive
+	Posts      map[reddit.Fullname]bool // only entries of these posts, if set
+	MinUpvotes int                      // only entries with at least this many upvotes
+}
+
+func (f SnapshotFilter) matches(listing reddit.RedditContent, entry database.Snapshot) bool {
+	if entry.Date < f.From || entry.Date >= f.Until || entry.Upvotes < f.MinUpvotes {
+		return false
+	}
+	return len(f.Posts) == 0 || f.Posts[listing.FullId()]
+}
+
+// the listings that can have entries recorded from from to until: they stop being updated MAX_TRACKING_AGE seconds after
+// being posted, so older ones don't, and the ones posted after until don't either
+func SnapshotsQuery(now time.Time, from time.Time, until time.Time, subreddit string) database.ListingsQuery {
+	maxTrackingAge := time.Duration(util.GetEnvIntDefault("MAX_TRACKING_AGE", 86400)) * time.Second
+	query := database.ListingsQuery{
+		MaxAge:    now.Unix(),
+		Subreddit: subreddit,
+		Sort:      database.OldestFirst,
+		Limit:     util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}
+	if oldest := from.Add(-maxTrackingAge); oldest.After(time.Unix(0, 0)) {
+		query.MaxAge = int64(now.Sub(oldest).Seconds())
+	}
+	if until.Before(now) {
+		query.MinAge = int64(now.Sub(until).Seconds())
+	}
+	return query
+}
+
+// a writer of rows, csv, parquet or ndjson
+type rowWriter struct {
+	write func(row) error
+	close func() error
+}
+
+func newRowWriter(format string, out io.Writer) (*rowWriter, error) {
+	switch format {
+	case CSV:
+		writer := csv.NewWriter(out)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &rowWriter{
+			write: func(r row) error { return writer.Write(r.csv()) },
+			close: func() error {
+				writer.Flush()
+				return writer.Error()
+			},
+		}, nil
+	case PARQUET:
+		writer, err := newParquetWriter(out)
+		if err != nil {
+			return nil, err
+		}
+		return &rowWriter{write: writer.write, close: writer.Close}, nil
+	case NDJSON:
+		encoder := json.NewEncoder(out)
+		return &rowWriter{
+			write: func(r row) error { return encoder.Encode(r.json()) },
+			close: func() error { return nil },
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown format \"%s\", expected %s, %s or %s", format, CSV, PARQUET, NDJSON)
+}
+
+// a row as a line of ndjson, with csvHeader's names and the snapshot's velocity
+type jsonRow struct {
+	Id        string `json:"id"`
+	Subreddit string `json:"subreddit"`
+	Title     string `json:"title"`
+	Created   uint64 `json:"created"`
+	Date      uint64 `json:"date"`
+	Upvotes   int    `json:"upvotes"`
+	Comments  int    `json:"comments"`
+
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"`
+	CommentsPerHour float64 `json:"comments_per_hour"`
+}
+
+func (r row) json() jsonRow {
+	return jsonRow{Id: r.id, Subreddit: r.subreddit, Title: r.title, Created: r.created, Date: r.date, Upvotes: r.upvotes, Comments: r.comments,
+		UpvotesPerHour: r.upvotesPerHour, CommentsPerHour: r.commentsPerHour}
+}
+
+// writes a row for every entry under the listings matching query that filter matches, in csv, parquet or ndjson. Unlike
+// Export, listings without matching entries aren't written at all. Returns # of rows written
+func ExportSnapshots(ctx context.Context, store historySource, query database.ListingsQuery, filter SnapshotFilter, format string, out io.Writer) (int, error) {
+	writer, err := newRowWriter(format, out)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for {
+		page, next, err := store.RecieveHistoryPage(ctx, query)
+		if err != nil {
+			return written, fmt.Errorf("error recieving listings from database:\n%s", err)
+		}
+
+		for _, history := range page {
+			for _, entry := range history.Entries {
+				if !filter.matches(history.Listing, entry) {
+					continue
+				}
+				err = writer.write(rowOf(history.Listing, entry))
+				if err != nil {
+					return written, fmt.Errorf("error writing listing %s:\n%s", history.Listing.FullId(), err)
+				}
+				written += 1
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		query.Cursor = next
+	}
+
+	return written, writer.close()
+}
@@ -0,0 +1,234 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	aggregate stats over stored listings, or over a dump of them (so that
+	they can be worked out without the database service), for votewatch
+	stats. Peaks are the most upvotes a listing was ever recorded with, and
+	time to peak is how long after being created that was. Posting volume is
+	counted per UTC day the listings were created on
+*/
+
+type Stats struct {
+	Listings  int       `json:"listings"`
+	Snapshots int       `json:"snapshots"`
+	First     time.Time `json:"first"` // the oldest listing's creation
+	Last      time.Time `json:"last"`  // the newest listing's creation
+
+	MedianPeak int         `json:"median_peak"`
+	MeanPeak   float64     `json:"mean_peak"`
+	Top        *TopListing `json:"top"` // the listing with the highest peak, nil without listings
+
+	// only over listings with a known creation and recording date. In seconds in json
+	MedianTimeToPeak  time.Duration `json:"-"`
+	AverageTimeToPeak time.Duration `json:"-"`
+
+	PerDay         []DayVolume  `json:"per_day"` // oldest first, days without listings included
+	MeanPerDay     float64      `json:"mean_per_day"`
+	BusiestWeekday time.Weekday `json:"-"`
+}
+
+type TopListing struct {
+	Id        reddit.Fullname `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Title     string          `json:"title"`
+	Peak      int             `json:"peak"`
+}
+
+type DayVolume struct {
+	Day      string `json:"day"` // YYYY-MM-DD
+	Listings int    `json:"listings"`
+}
+
+func (s Stats) MarshalJSON() ([]byte, error) {
+	type plain Stats //without the MarshalJSON method, to not recurse
+	return json.Marshal(struct {
+		plain
+		MedianTimeToPeak  int64  `json:"median_time_to_peak"`
+		AverageTimeToPeak int64  `json:"average_time_to_peak"`
+		BusiestWeekday    string `json:"busiest_weekday"`
+	}{plain(s), int64(s.MedianTimeToPeak.Seconds()), int64(s.AverageTimeToPeak.Seconds()), s.BusiestWeekday.String()})
+}
+
+// gathers Stats one listing at a time
+type StatsCollector struct {
+	snapshots   int
+	peaks       []int
+	timesToPeak []time.Duration
+	perDay      map[string]int
+	weekdays    [7]int
+	first, last uint64
+	top         *TopListing
+}
+
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{perDay: make(map[string]int)}
+}
+
+func (c *StatsCollector) Add(history database.ListingHistory) {
+	listing := history.Listing
+	c.snapshots += len(history.Entries)
+
+	peak, peakDate := peakOf(history)
+	c.peaks = append(c.peaks, peak)
+	if peakDate > listing.Date && listing.Date > 0 {
+		c.timesToPeak = append(c.timesToPeak, time.Duration(peakDate-listing.Date)*time.Second)
+	}
+	if c.top == nil || peak > c.top.Peak {
+		c.top = &TopListing{Id: listing.FullId(), Subreddit: listing.Subreddit, Title: listing.Title, Peak: peak}
+	}
+
+	created := time.Unix(int64(listing.Date), 0).UTC()
+	c.perDay[created.Format("2006-01-02")] += 1
+	c.weekdays[created.Weekday()] += 1
+	if c.first == 0 || listing.Date < c.first {
+		c.first = listing.Date
+	}
+	if listing.Date > c.last {
+		c.last = listing.Date
+	}
+}
+
+func (c *StatsCollector) Stats() Stats {
+	stats := Stats{Listings: len(c.peaks), Snapshots: c.snapshots, Top: c.top}
+	if stats.Listings == 0 {
+		return stats
+	}
+	stats.First = time.Unix(int64(c.first), 0).UTC()
+	stats.Last = time.Unix(int64(c.last), 0).UTC()
+
+	peaks := append([]int{}, c.peaks...)
+	sort.Ints(peaks)
+	total := 0
+	for _, peak := range peaks {
+		total += peak
+	}
+	stats.MedianPeak = peaks[len(peaks)/2]
+	if len(peaks)%2 == 0 {
+		stats.MedianPeak = (peaks[len(peaks)/2-1] + peaks[len(peaks)/2]) / 2
+	}
+	stats.MeanPeak = float64(total) / float64(len(peaks))
+
+	if len(c.timesToPeak) > 0 {
+		times := append([]time.Duration{}, c.timesToPeak...)
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		var sum time.Duration
+		for _, t := range times {
+			sum += t
+		}
+		stats.MedianTimeToPeak = times[len(times)/2]
+		if len(times)%2 == 0 {
+			stats.MedianTimeToPeak = (times[len(times)/2-1] + times[len(times)/2]) / 2
+		}
+		stats.AverageTimeToPeak = sum / time.Duration(len(times))
+	}
+
+	//every day in between, so that quiet days count towards the mean
+	for day := stats.First.Truncate(24 * time.Hour); !day.After(stats.Last); day = day.Add(24 * time.Hour) {
+		key := day.Format("2006-01-02")
+		stats.PerDay = append(stats.PerDay, DayVolume{Day: key, Listings: c.perDay[key]})
+	}
+	stats.MeanPerDay = float64(stats.Listings) / float64(len(stats.PerDay))
+
+	for weekday := range c.weekdays {
+		if c.weekdays[weekday] > c.weekdays[stats.BusiestWeekday] {
+			stats.BusiestWeekday = time.Weekday(weekday)
+		}
+	}
+
+	return stats
+}
+
+// the most upvotes a listing was recorded with, and when
+func peakOf(history database.ListingHistory) (int, uint64) {
+	peak, peakDate := history.Listing.Upvotes, history.Listing.QueryDate
+	for _, entry := range history.Entries {
+		if entry.Upvotes > peak {
+			peak, peakDate = entry.Upvotes, entry.Date
+		}
+	}
+	return peak, peakDate
+}
+
+// the stats of every listing matching query in store, a page of query.Limit listings at a time
+func StatsFromStore(ctx context.Context, store historySource, query database.ListingsQuery) (Stats, error) {
+	collector := NewStatsCollector()
+	if err := eachFromStore(ctx, store, query, collector.Add); err != nil {
+		return Stats{}, err
+	}
+	return collector.Stats(), nil
+}
+
+// the stats of the listings in a dump (in the given format) created at most maxAge seconds ago and, if subreddit is set,
+// from that subreddit
+func StatsFromDump(format string, in io.Reader, maxAge int64, subreddit string) (Stats, error) {
+	collector := NewStatsCollector()
+	if err := eachFromDump(format, in, database.ListingsQuery{MaxAge: maxAge, Subreddit: subreddit}, collector.Add); err != nil {
+		return Stats{}, err
+	}
+	return collector.Stats(), nil
+}
+
+// calls add with every listing matching query in store, a page of query.Limit listings at a time
+func eachFromStore(ctx context.Context, store historySource, query database.ListingsQuery, add func(database.ListingHistory)) error {
+	for {
+		page, next, err := store.RecieveHistoryPage(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, history := range page {
+			add(history)
+		}
+
+		if next == "" {
+			return nil
+		}
+		query.Cursor = next
+	}
+}
+
+// calls add with every listing in a dump (in the given format) that query's MaxAge, MinAge and Subreddit match
+func eachFromDump(format string, in io.Reader, query database.ListingsQuery, add func(database.ListingHistory)) error {
+	next, err := recordReader(format, in)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	oldest, newest := uint64(0), uint64(now)
+	if query.MaxAge < now {
+		oldest = uint64(now - query.MaxAge)
+	}
+	if query.MinAge > 0 {
+		newest = uint64(now - query.MinAge)
+	}
+
+	read := 0
+	for {
+		r, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading listing %d:\n%s", read+1, err)
+		}
+		read += 1
+
+		if r.Created < oldest || r.Created > newest || (query.Subreddit != "" && !strings.EqualFold(r.Subreddit, query.Subreddit)) {
+			continue
+		}
+		add(database.ListingHistory{Listing: r.listing(), Entries: r.Entries})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/*
+	every setting can also be given on the command line, taking precedence
+	over the environment and the env file, for quick experiments without
+	editing anything:
+
+		votewatch --new-posts-period 2m --subreddits golang,programming --dry-run
+
+	a setting's flag is its name in lowercase with dashes
+	(NEW_POSTS_REFRESH_PERIOD -> --new-posts-refresh-period), and the
+	*_REFRESH_PERIOD ones can be shortened to *-period. True/false settings
+	given without a value are set to true (--debug-http). The settings are
+	the ones in .env.template, the <NAME>_FILE of secrets and ENV_PATH. Like
+	--profile they work anywhere in the arguments, with every command but
+	the ones that run before the env file is loaded (see setupCommands)
+*/
+
+var settingLine = regexp.MustCompile(`(?m)^([A-Z][A-Z0-9_]*)=`)
+
+// every setting that has a flag, by flag name
+func settingFlagNames() map[string]string {
+	names := make(map[string]string)
+	add := func(setting string) {
+		name := strings.ToLower(strings.ReplaceAll(setting, "_", "-"))
+		names[name] = setting
+		if strings.HasSuffix(name, "-refresh-period") {
+			names[strings.TrimSuffix(name, "-refresh-period")+"-period"] = setting
+		}
+	}
+
+	for _, match := range settingLine.FindAllStringSubmatch(envTemplate, -1) {
+		add(match[1])
+	}
+	for _, secret := range secretSettings {
+		add(secret + "_FILE")
+	}
+	add("ENV_PATH")
+
+	return names
+}
+
+// takes the flags of settings (see the top of this file) out of args, wherever they are, returning the settings they set
+func takeSettingFlags(args []string) (map[string]string, []string, error) {
+	names := settingFlagNames()
+	settings := make(map[string]string)
+
+	rest := make([]string, 0, len(args))
+	for idx := 0; idx < len(args); idx += 1 {
+		arg := args[idx]
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		setting, known := names[flagName]
+		if !strings.HasPrefix(arg, "-") || !known {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if !hasValue {
+			switch {
+			case contains(flagSettings, setting):
+				value = "true"
+			case idx+1 < len(args):
+				idx += 1
+				value = args[idx]
+			default:
+				return nil, nil, fmt.Errorf("--%s needs a value", flagName)
+			}
+		}
+		settings[setting] = value
+	}
+
+	return settings, rest, nil
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+/*
+	an agency watching several clients' communities can run all of them from
+	one votewatch. List the watch groups in WATCH_GROUPS and give each one its
+	own subreddits, filters, database and notifications as a profile (see
+	profiles.go):
+
+		WATCH_GROUPS=acme,globex
+		acme.SUBREDDITS=acmegames,acmesupport
+		acme.MONGODB_COLLECTION_NAME=acme
+		acme.DISCORD_WEBHOOK_URL=https://discord.com/api/webhooks/...
+		acme.DISCORD_MIN_UPVOTES=500
+		globex.SUBREDDITS_PATH=./globex-subreddits.json
+		globex.MONGODB_COLLECTION_NAME=globex
+		globex.SLACK_WEBHOOK_URL=https://hooks.slack.com/services/...
+
+	unprefixed variables (the reddit account, the mongodb server...) are shared
+	by every group. `votewatch run` then runs each group's tracker as if it was
+	started with --profile <group>. Settings are env variables, which a process
+	only has one set of, so the trackers are child processes: they're started
+	and stopped with this one, get its SIGHUPs (the list of groups itself isn't
+	reloaded), and are restarted when they fail. Their log lines are prefixed
+	with their group, or get a "group" field when they're json.
+
+	every group's settings are checked before any tracker is started, including
+	that no two groups share a database, an address to listen on or anything
+	else only one tracker can use (see exclusiveSettings)
+*/
+
+const (
+	groupRestartDelay    = 5 * time.Second
+	groupRestartMaxDelay = 5 * time.Minute
+	groupHealthyAfter    = 10 * time.Minute //a tracker that ran this long before failing is restarted after groupRestartDelay again
+)
+
+// the groups in WATCH_GROUPS, in order. nil if there aren't any
+func watchGroups() ([]string, error) {
+	value, _ := os.LookupEnv("WATCH_GROUPS")
+
+	var groups []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.ContainsAny(name, ". \t") {
+			return nil, fmt.Errorf("WATCH_GROUPS: malformed group name \"%s\"", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("WATCH_GROUPS: \"%s\" is listed twice", name)
+		}
+		seen[name] = true
+		groups = append(groups, name)
+	}
+
+	return groups, nil
+}
+
+// something only one tracker can use at a time, eg: an address to listen on. claim returns what a tracker with the env
+// variables env would use, "" if nothing
+type exclusiveSetting struct {
+	name  string
+	claim func(env map[string]string) string
+}
+
+var exclusiveSettings = []exclusiveSetting{
+	{"database", databaseClaim},
+	{"METRICS_ADDRESS", addressClaim("METRICS_ADDRESS")},
+	{"PPROF_ADDRESS", addressClaim("PPROF_ADDRESS")},
+	{"CONTROL_ADDRESS", addressClaim("CONTROL_ADDRESS")},
+	{"API_ADDRESS", addressClaim("API_ADDRESS")},
+	{"GRPC_ADDRESS", addressClaim("GRPC_ADDRESS")},
+	{"LEADER_ELECTION_KEY", func(env map[string]string) string {
+		if strings.TrimSpace(env["LEADER_ELECTION"]) == "" {
+			return ""
+		}
+		return env["CONSUL_HTTP_ADDR"] + " " + strings.Trim(envOr(env, "LEADER_ELECTION_KEY", "votewatch/leader"), "/")
+	}},
+	{"REDIS_KEY_PREFIX", func(env map[string]string) string {
+		if strings.TrimSpace(env["REDIS_URL"]) == "" {
+			return ""
+		}
+		return env["REDIS_URL"] + " " + envOr(env, "REDIS_KEY_PREFIX", "votewatch:")
+	}},
+	{"BACKUP_PREFIX", func(env map[string]string) string {
+		if strings.TrimSpace(env["BACKUP_BUCKET"]) == "" {
+			return ""
+		}
+		return env["BACKUP_BUCKET"] + " " + strings.Trim(envOr(env, "BACKUP_PREFIX", "votewatch"), "/")
+	}},
+}
+
+// name's value in env, or def if it's empty
+func envOr(env map[string]string, name string, def string) string {
+	if value := strings.TrimSpace(env[name]); value != "" {
+		return value
+	}
+	return def
+}
+
+func addressClaim(name string) func(env map[string]string) string {
+	return func(env map[string]string) string {
+		return strings.TrimSpace(env[name])
+	}
+}
+
+// where STORAGE_BACKEND points to, see database.Connect
+func databaseClaim(env map[string]string) string {
+	switch backend := strings.ToLower(strings.TrimSpace(envOr(env, "STORAGE_BACKEND", "grpc"))); backend {
+	case "mongo", "mongodb":
+		return fmt.Sprintf("mongodb %s %s.%s", env["MONGODB_CONNECTION_STRING"], env["MONGODB_DATABASE_NAME"],
+			envOr(env, "MONGODB_COLLECTION_NAME", "listings"))
+	case "bolt", "embedded":
+		path, _ := filepath.Abs(envOr(env, "EMBEDDED_DATABASE_PATH", "./votewatch.db"))
+		return "embedded " + path
+	case "memory":
+		return ""
+	default:
+		return backend + " " + env["SUBREDDIT_LOGGER_DATABASE_LOCATION"]
+	}
+}
+
+// checks every group's settings (see checkEnv) and that they don't share anything in exclusiveSettings. Returns the
+// groups that only run once (RUN_ONCE)
+func checkGroups(groups []string, dryRun bool) (map[string]bool, error) {
+	values, err := godotenv.Read(envPath())
+	if err != nil {
+		return nil, errors.New("error loading .env file:\n" + err.Error())
+	}
+
+	var problems []string
+	once := make(map[string]bool)
+	claimed := make(map[string]string) //setting name and what it claims, to the group that claimed it first
+	for _, group := range groups {
+		//the env variables the group's tracker will have, see candidateEnv
+		env, err := candidateEnv(values, group)
+		if err == nil {
+			once[group], _ = strconv.ParseBool(strings.TrimSpace(env["RUN_ONCE"]))
+
+			for _, setting := range exclusiveSettings {
+				if dryRun && setting.name == "database" {
+					continue
+				}
+				claim := setting.claim(env)
+				if claim == "" {
+					continue
+				}
+				if other, taken := claimed[setting.name+"\n"+claim]; taken {
+					problems = append(problems, fmt.Sprintf("watch groups %s and %s use the same %s", other, group, setting.name))
+					continue
+				}
+				claimed[setting.name+"\n"+claim] = group
+			}
+
+			err = checkEnv(env, group)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("watch group %s: %s", group, strings.ReplaceAll(err.Error(), "\n", "\n  ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid watch groups:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return once, nil
+}
+
+// runs a tracker for each group until stopped, see the top of this file. --dry-run and --once are passed on to them
+func runGroups(groups []string, dryRun bool, runOnce bool) {
+	once, err := checkGroups(groups, dryRun)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal("error finding this program to run the watch groups with:\n" + err.Error())
+	}
+
+	//the trackers load the env file themselves, with their group's variables. Only the ones set before it was loaded are passed on
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if processEnv[name] {
+			env = append(env, variable)
+		}
+	}
+
+	args := []string{"run"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	if runOnce {
+		args = append(args, "--once")
+	}
+
+	s := &groupSupervisor{
+		executable: executable,
+		args:       args,
+		env:        env,
+		stop:       make(chan struct{}),
+		processes:  make(map[string]*os.Process),
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	var wg sync.WaitGroup
+	var failedMu sync.Mutex
+	var failed []string
+	log.Printf("running watch groups %s\n", strings.Join(groups, ", "))
+	for _, group := range groups {
+		group := group
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.supervise(group, runOnce || once[group])
+			if err != nil {
+				log.Printf("error: watch group %s's tracker failed:\n%s\n", group, err)
+				failedMu.Lock()
+				failed = append(failed, group)
+				failedMu.Unlock()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	stopping := false
+	for {
+		select {
+		case sig := <-signals:
+			switch {
+			case sig == syscall.SIGHUP:
+				s.signal(sig)
+			case stopping:
+				//a second signal kills the trackers right away, like it does a single tracker
+				s.signal(os.Kill)
+			default:
+				stopping = true
+				log.Println("stopping watch groups")
+				s.shutdown()
+			}
+		case <-done:
+			if len(failed) > 0 {
+				sort.Strings(failed)
+				log.Fatalf("watch groups %s failed", strings.Join(failed, ", "))
+			}
+			log.Println("shut down")
+			return
+		}
+	}
+}
+
+// starts, restarts and stops the groups' trackers, see runGroups
+type groupSupervisor struct {
+	executable string
+	args       []string //after --profile <group>
+	env        []string
+	stop       chan struct{} //closed by shutdown
+
+	mu        sync.Mutex //guards processes and closing stop, so that no tracker is started after shutdown
+	processes map[string]*os.Process
+	outputMu  sync.Mutex //shared by every tracker's output, so that their lines don't interleave
+}
+
+// runs group's tracker until it exits cleanly or the supervisor shuts down, restarting it (with a growing delay) when it
+// fails. Trackers that only run once aren't restarted. Returns the error the tracker last exited with
+func (s *groupSupervisor) supervise(group string, once bool) error {
+	delay := groupRestartDelay
+	for {
+		started := time.Now()
+		err := s.run(group)
+		if err == nil || once {
+			return err
+		}
+		select {
+		case <-s.stop:
+			return err
+		default:
+		}
+
+		if time.Since(started) >= groupHealthyAfter {
+			delay = groupRestartDelay
+		}
+		log.Printf("warning: watch group %s's tracker exited (%s), restarting it in %s\n", group, err, delay)
+		select {
+		case <-s.stop:
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > groupRestartMaxDelay {
+			delay = groupRestartMaxDelay
+		}
+	}
+}
+
+// runs group's tracker once, until it exits. Doesn't start it if the supervisor is shutting down
+func (s *groupSupervisor) run(group string) error {
+	cmd := exec.Command(s.executable, append([]string{"--profile", group}, s.args...)...)
+	cmd.Env = s.env
+	stdout := &groupLogWriter{group: group, out: os.Stdout, mu: &s.outputMu}
+	stderr := &groupLogWriter{group: group, out: os.Stderr, mu: &s.outputMu}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	//ctrl-c in a terminal goes to the whole process group. The trackers are stopped by shutdown instead, see groups_unix.go
+	detach(cmd)
+
+	s.mu.Lock()
+	select {
+	case <-s.stop:
+		s.mu.Unlock()
+		return nil
+	default:
+	}
+	err := cmd.Start()
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.processes[group] = cmd.Process
+	s.mu.Unlock()
+
+	err = cmd.Wait()
+	stdout.flush()
+	stderr.flush()
+
+	s.mu.Lock()
+	delete(s.processes, group)
+	s.mu.Unlock()
+	return err
+}
+
+// asks every tracker to shut down, and keeps new ones from starting
+func (s *groupSupervisor) shutdown() {
+	s.mu.Lock()
+	close(s.stop)
+	s.mu.Unlock()
+	s.signal(syscall.SIGTERM)
+}
+
+func (s *groupSupervisor) signal(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, process := range s.processes {
+		process.Signal(sig)
+	}
+}
+
+// writes a tracker's output line by line, each prefixed with its group. json lines (see LOG_FORMAT) get a "group" field
+// instead
+type groupLogWriter struct {
+	group   string
+	out     io.Writer
+	mu      *sync.Mutex
+	partial []byte //the end of the output that isn't a whole line yet
+}
+
+func (w *groupLogWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.writeLine(w.partial[:idx])
+		w.partial = w.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+// writes what's left of the output, for when the tracker exits without ending it with a newline
+func (w *groupLogWriter) flush() {
+	if len(w.partial) > 0 {
+		w.writeLine(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *groupLogWriter) writeLine(line []byte) {
+	var prefixed string
+	if trimmed := bytes.TrimSpace(line); bytes.HasPrefix(trimmed, []byte("{\"")) && bytes.HasSuffix(trimmed, []byte("}")) {
+		group, _ := json.Marshal(w.group)
+		prefixed = fmt.Sprintf("{\"group\":%s,%s", group, trimmed[1:])
+	} else {
+		prefixed = fmt.Sprintf("[%s] %s", w.group, line)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintln(w.out, prefixed)
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os/exec"
+
+// windows has no process groups to leave, trackers get ctrl-c along with the supervisor
+func detach(cmd *exec.Cmd) {}
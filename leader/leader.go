@@ -0,0 +1,298 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	This package lets several instances of votewatch run side by side (for
+	redundancy) while only one of them polls reddit and writes to the database.
+	The instances compete for a lock in consul's key/value store, held through a
+	consul session that the leader keeps renewing. Every other instance waits on
+	standby and tries to take the lock every LEADER_ELECTION_TTL/3 seconds.
+
+	if the leader crashes or loses its connection to consul, its session expires
+	after LEADER_ELECTION_TTL seconds and the lock goes to one of the standbys.
+	A leader that can't renew its session for that long steps down on its own,
+	so two instances never think they're leading at once. Consul also holds the
+	lock back for a few seconds (its session lock-delay) after a session
+	expires, in case the old leader is still finishing a request.
+
+	the database service has no way of locking, so consul is the only supported
+	backend for now. See LEADER_ELECTION in .env.template
+*/
+
+// returned by TryLead when the lock couldn't be taken because another instance holds it
+var ErrNotLeader = errors.New("another instance is the leader")
+
+type Elector struct {
+	api   string // base url of the consul http api
+	token string
+	key   string
+	ttl   time.Duration
+
+	client  *http.Client
+	session string // the consul session holding (or trying to take) the lock. "" until one's created
+}
+
+// reads the LEADER_ELECTION* env variables. Returns nil if LEADER_ELECTION isn't set
+func FromEnv() (*Elector, error) {
+	backend, _ := os.LookupEnv("LEADER_ELECTION")
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "":
+		return nil, nil
+	case "consul":
+	default:
+		return nil, fmt.Errorf("unknown LEADER_ELECTION \"%s\", only consul is supported", backend)
+	}
+
+	address := util.GetEnvOptional("CONSUL_HTTP_ADDR", "http://127.0.0.1:8500")
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	// consul doesn't accept session ttls under 10 seconds
+	ttl := time.Second * time.Duration(util.GetEnvIntDefault("LEADER_ELECTION_TTL", 15))
+	if ttl < 10*time.Second {
+		return nil, fmt.Errorf("LEADER_ELECTION_TTL must be at least 10 seconds, got %s", ttl)
+	}
+
+	return &Elector{
+		api:    strings.TrimSuffix(address, "/"),
+		token:  util.GetEnvOptional("CONSUL_HTTP_TOKEN", ""),
+		key:    strings.Trim(util.GetEnvOptional("LEADER_ELECTION_KEY", "votewatch/leader"), "/"),
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// blocks until this instance is the leader, retrying every LEADER_ELECTION_TTL/3 seconds.
+// The returned context is cancelled once leadership is lost (or ctx is done). Call Release when done leading
+func (e *Elector) Lead(ctx context.Context) (context.Context, error) {
+	waiting := false
+	for {
+		leaderCtx, err := e.TryLead(ctx)
+		if err == nil {
+			return leaderCtx, nil
+		}
+
+		if errors.Is(err, ErrNotLeader) {
+			if !waiting {
+				fmt.Printf("%s, waiting on standby...\n", err)
+				waiting = true
+			}
+		} else {
+			fmt.Printf("warning: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(e.ttl / 3):
+		}
+	}
+}
+
+// tries to become the leader once. Returns ErrNotLeader if another instance is the leader, see Lead
+func (e *Elector) TryLead(ctx context.Context) (context.Context, error) {
+	if e.session == "" {
+		session, err := e.createSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		e.session = session
+	} else if err := e.renew(ctx, e.session); err != nil {
+		e.session = ""
+		return nil, err
+	}
+
+	acquired, err := e.acquire(ctx)
+	if err != nil {
+		// eg: the session expired between renewing it and now. Start over with a new one
+		e.session = ""
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrNotLeader
+	}
+
+	fmt.Printf("elected leader (consul key %s)\n", e.key)
+	leaderCtx, cancel := context.WithCancel(ctx)
+	go e.keepLeading(leaderCtx, cancel, e.session)
+
+	return leaderCtx, nil
+}
+
+// renews the session until ctx is done, cancelling it if the session is lost or can't be renewed before it'd expire
+func (e *Elector) keepLeading(ctx context.Context, cancel context.CancelFunc, session string) {
+	defer cancel()
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := e.renew(ctx, session)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		fmt.Printf("warning: error renewing leader session:\n%s\n", err)
+		if errors.Is(err, errSessionGone) || time.Since(renewed) >= e.ttl {
+			fmt.Println("warning: lost leadership")
+			return
+		}
+	}
+}
+
+// gives the lock up (without consul's lock-delay, so a standby can take over right away) and ends the session.
+// Call it once the context returned by Lead is done
+func (e *Elector) Release() {
+	if e.session == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.client.Timeout)
+	defer cancel()
+
+	var released bool
+	err := e.call(ctx, http.MethodPut, "/v1/kv/"+e.escapedKey()+"?release="+url.QueryEscape(e.session), nil, &released)
+	if err != nil {
+		fmt.Printf("warning: error releasing the leader lock:\n%s\n", err)
+	}
+	err = e.call(ctx, http.MethodPut, "/v1/session/destroy/"+url.PathEscape(e.session), nil, nil)
+	if err != nil {
+		fmt.Printf("warning: error ending leader session:\n%s\n", err)
+	}
+
+	e.session = ""
+}
+
+var errSessionGone = errors.New("leader session expired")
+
+func (e *Elector) createSession(ctx context.Context) (string, error) {
+	hostname, _ := os.Hostname()
+	body := map[string]string{
+		"Name":     "votewatch " + hostname,
+		"TTL":      e.ttl.String(),
+		"Behavior": "release",
+	}
+
+	var response struct {
+		ID string
+	}
+	err := e.call(ctx, http.MethodPut, "/v1/session/create", body, &response)
+	if err != nil {
+		return "", fmt.Errorf("error creating leader session:\n%s", err)
+	}
+
+	return response.ID, nil
+}
+
+func (e *Elector) renew(ctx context.Context, session string) error {
+	err := e.call(ctx, http.MethodPut, "/v1/session/renew/"+url.PathEscape(session), nil, nil)
+	var status httpStatusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return errSessionGone
+	}
+
+	return err
+}
+
+// whether the session got the lock. The value of the key is the hostname of the leader, for whoever's looking at consul
+func (e *Elector) acquire(ctx context.Context) (bool, error) {
+	hostname, _ := os.Hostname()
+
+	var acquired bool
+	err := e.call(ctx, http.MethodPut, "/v1/kv/"+e.escapedKey()+"?acquire="+url.QueryEscape(e.session), hostname, &acquired)
+	if err != nil {
+		return false, fmt.Errorf("error taking the leader lock:\n%s", err)
+	}
+
+	return acquired, nil
+}
+
+func (e *Elector) escapedKey() string {
+	segments := strings.Split(e.key, "/")
+	for idx, segment := range segments {
+		segments[idx] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+type httpStatusError struct {
+	code   int
+	status string
+	body   string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("%s recieved from consul: %s", e.status, e.body)
+}
+
+// calls the consul http api. body is sent as json, except strings which are sent as is. The response is decoded into result, if set
+func (e *Elector) call(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var payload io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = strings.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, e.api+path, payload)
+	if err != nil {
+		return err
+	}
+	if e.token != "" {
+		request.Header.Set("X-Consul-Token", e.token)
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return httpStatusError{code: response.StatusCode, status: response.Status, body: strings.TrimSpace(string(message))}
+	}
+
+	if result == nil {
+		return nil
+	}
+	err = json.NewDecoder(response.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("error parsing consul response:\n%s", err)
+	}
+
+	return nil
+}
@@ -1,37 +1,406 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/joho/godotenv"
+	"github.com/jtyrmn/reddit-votewatch/api"
+	"github.com/jtyrmn/reddit-votewatch/backup"
+	"github.com/jtyrmn/reddit-votewatch/cache"
 	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/leader"
+	"github.com/jtyrmn/reddit-votewatch/metrics"
+	"github.com/jtyrmn/reddit-votewatch/notify"
+	"github.com/jtyrmn/reddit-votewatch/plugins"
+	"github.com/jtyrmn/reddit-votewatch/publish"
 	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/rpc"
 	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/tracing"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/jtyrmn/reddit-votewatch/version"
+	"github.com/jtyrmn/reddit-votewatch/webhooks"
 )
 
 func main() {
-	//load env variables
-	envPath := ".env"
-	if e, exists := os.LookupEnv("ENV_PATH"); exists {
-		envPath = e
+	//VOTEWATCH_NAME for NAME (even ENV_PATH), see util/prefix.go
+	util.UnprefixEnv()
+
+	//see profiles.go
+	var err error
+	profile, os.Args, err = takeProfileFlag(os.Args)
+	if err != nil {
+		log.Fatal(err.Error())
 	}
 
-	err := godotenv.Load(envPath)
+	//votewatch <command> [flags], see commands.go. Without a command, the tracker is run (same as votewatch run)
+	if len(os.Args) > 1 {
+		if os.Args[1] == "help" {
+			printUsage(os.Stdout)
+			return
+		}
+		if command, exists := setupCommands[os.Args[1]]; exists {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	//settings given as flags, see flags.go. They're set before the env file is loaded so that it can't override them
+	settings, args, err := takeSettingFlags(os.Args[1:])
 	if err != nil {
-		log.Fatal("error loading .env file: " + err.Error())
+		log.Fatal(err.Error())
+	}
+	for name, value := range settings {
+		os.Setenv(name, value)
+	}
+	os.Args = append(os.Args[:1], args...)
+
+	if len(os.Args) > 1 {
+		if command, exists := commands[os.Args[1]]; exists {
+			loadEnv()
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	loadEnv()
+	runTracker(os.Args[1:])
+}
+
+// tracks posts until stopped, the program's main job
+func runTracker(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "track posts without a database. Nothing is saved")
+	once := flags.Bool("once", false, "fetch new posts, update and save them once, then exit. Also enabled by RUN_ONCE=true")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: votewatch [run] [--dry-run] [--once]")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output())
+		printUsage(flags.Output())
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unknown command \"%s\"\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	err := run(*dryRun, *once)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// runs the tracker (or a tracker for each of WATCH_GROUPS) until it's stopped. Everything that was started is shut down
+// before it returns
+func run(dryRun bool, once bool) error {
+	//LOG_FORMAT=json, see scheduler/logging.go
+	if scheduler.JSONLogs() {
+		log.SetFlags(0)
+		log.SetOutput(scheduler.JSONLogWriter{})
+	}
+	log.Println("starting " + version.String())
+
+	//a tracker for each of WATCH_GROUPS, see groups.go. The trackers it starts have a profile, and run normally
+	groups, err := watchGroups()
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 && profile == "" {
+		runGroups(groups, dryRun, once)
+		return nil
+	}
+
+	//OTEL_EXPORTER_OTLP_ENDPOINT, see the tracing package
+	if tracing.Setup() {
+		defer tracing.Shutdown()
+	}
+
+	//custom sinks and filters built as go plugins, before their settings are checked. See the plugins package
+	loaded, err := plugins.LoadFromEnv()
+	if err != nil {
+		return err
+	}
+	for _, path := range loaded {
+		log.Printf("loaded plugin %s\n", path)
+	}
+
+	//every problem with the env variables at once, rather than one per restart. See config.go
+	err = validateConfig()
+	if err != nil {
+		return err
 	}
 
 	// init APIs to reddit and database
 	r, err := reddit.Connect()
 	if err != nil {
-		log.Fatal("error connecting to reddit:\n" + err.Error())
+		return errors.New("error connecting to reddit:\n" + err.Error())
+	}
+
+	var store database.Store
+	if dryRun {
+		log.Println("dry run: using an in-memory store, nothing will be saved")
+		store = database.NewMemoryStore()
+	} else {
+		store, err = database.Connect()
+		if err != nil {
+			return errors.New("error connecting to database:\n" + err.Error())
+		}
+	}
+
+	defer store.Close()
+
+	//protects against the database service losing its data. See the backup package
+	//flags are checked by validateConfig, so their errors can be ignored from here on
+	restoreOnStart, _ := util.GetEnvBool("BACKUP_RESTORE_ON_START", false)
+	backups, err := backup.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	if backups != nil && !dryRun && restoreOnStart {
+		count, err := backups.RestoreIfEmpty(context.Background(), store)
+		if err != nil {
+			return errors.New("error restoring backup:\n" + err.Error())
+		}
+		if count > 0 {
+			log.Printf("database was empty, restored %d listings from %s\n", count, backups.LatestKey())
+		}
+	}
+
+	//see the metrics package
+	if address, exists := os.LookupEnv("METRICS_ADDRESS"); exists && address != "" {
+		go func() {
+			err := metrics.Serve(address)
+			log.Println("error serving metrics:\n" + err.Error())
+		}()
+	}
+
+	//the same metrics, pushed to a StatsD/Datadog agent. See metrics/statsd.go
+	if address, exists := os.LookupEnv("STATSD_ADDRESS"); exists && address != "" {
+		err := metrics.PushStatsd(address)
+		if err != nil {
+			log.Println("warning: " + err.Error())
+		} else {
+			defer metrics.StopStatsd()
+		}
+	}
+
+	//see metrics/pprof.go
+	if address, exists := os.LookupEnv("PPROF_ADDRESS"); exists && address != "" {
+		go func() {
+			err := metrics.ServeProfiling(address)
+			log.Println("error serving profiles:\n" + err.Error())
+		}()
+	}
+
+	//stop on ctrl-c or when a container runtime asks nicely. A second signal kills the program right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	//see diagnostics.go
+	if diagnosticsEnabled() && !diagnose(ctx, r, store) {
+		return errors.New("startup diagnostics failed, see above. Set STARTUP_DIAGNOSTICS=false to start anyway")
 	}
 
-	database, err := database.Connect()
+	//when several instances are deployed, only the leader does anything. See the leader package
+	elector, err := leader.FromEnv()
 	if err != nil {
-		log.Fatal("error connecting to database:\n" + err.Error())
+		return errors.New("error setting up leader election:\n" + err.Error())
+	}
+
+	if runOnce, _ := util.GetEnvBool("RUN_ONCE", false); once || runOnce {
+		runCtx := ctx
+		if elector != nil {
+			//another instance already running is as good as this one running
+			runCtx, err = elector.TryLead(ctx)
+			if errors.Is(err, leader.ErrNotLeader) {
+				log.Println(err.Error() + ", not running")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			defer elector.Release()
+		}
+
+		err := scheduler.RunOnce(runCtx, r, store)
+		if err != nil {
+			return errors.New("error running once:\n" + err.Error())
+		}
+		return nil
 	}
 
-	scheduler.Start(r, database)
+	runCtx := ctx
+	if elector != nil {
+		runCtx, err = elector.Lead(ctx)
+		if err != nil {
+			log.Println("shut down before being elected leader")
+			return nil
+		}
+		defer elector.Release()
+	}
+
+	//see the publish package
+	err = publish.Setup()
+	if err != nil {
+		return errors.New("error setting up publishing:\n" + err.Error())
+	}
+	defer publish.Shutdown()
+
+	//see the notify package
+	err = notify.Setup(store)
+	if err != nil {
+		return errors.New("error setting up notifications:\n" + err.Error())
+	}
+
+	//see the webhooks package
+	err = webhooks.Setup()
+	if err != nil {
+		return errors.New("error setting up webhook rules:\n" + err.Error())
+	}
+	defer webhooks.Shutdown()
+
+	//the tracked posts mirrored into redis, see the cache package. Its settings are checked by validateConfig
+	trackedCache, _ := cache.FromEnv()
+	if trackedCache != nil {
+		trackedCache.Setup()
+		defer trackedCache.Shutdown()
+	}
+
+	//Subscribe streams the scheduler's events, which needs its handlers registered up front. See the rpc package
+	grpcAddress := strings.TrimSpace(os.Getenv("GRPC_ADDRESS"))
+	if grpcAddress != "" {
+		rpc.Setup()
+	}
+
+	s, err := scheduler.New(r, store)
+	if err != nil {
+		return errors.New("error setting up the scheduler:\n" + err.Error())
+	}
+	if trackedCache != nil {
+		s.UseCache(trackedCache)
+	}
+
+	//telegram's /top, /status and /track, see notify/telegram.go
+	notify.ListenTelegram(runCtx, r, s)
+
+	//SIGHUP reloads the env file and the subreddits file without restarting, see scheduler/reload.go
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			err := reloadEnv()
+			if err != nil {
+				log.Println(err.Error() + "\nnot reloading")
+				continue
+			}
+			s.Reload()
+		}
+	}()
+
+	//see the api package
+	if address, exists := os.LookupEnv("API_ADDRESS"); exists && address != "" {
+		go func() {
+			err := api.New(r, store).Serve(address)
+			log.Println("error serving api:\n" + err.Error())
+		}()
+	}
+
+	if grpcAddress != "" {
+		go func() {
+			err := rpc.New(r, store, s).Serve(grpcAddress)
+			log.Println("error serving grpc:\n" + err.Error())
+		}()
+	}
+
+	//see scheduler/control.go
+	if address, exists := os.LookupEnv("CONTROL_ADDRESS"); exists && address != "" {
+		go func() {
+			err := s.ServeControl(address)
+			log.Println("error serving control endpoint:\n" + err.Error())
+		}()
+	}
+
+	s.Run(runCtx)
+
+	//exit with an error so that whatever restarts the program puts it back on standby
+	if ctx.Err() == nil {
+		return errors.New("lost leadership, shut down")
+	}
+	log.Println("shut down")
+	return nil
+}
+
+// the variables set before the env file was loaded. The file never overrides them, not even when it's reloaded or with a profile
+var processEnv = make(map[string]bool)
+
+func envPath() string {
+	if e, exists := os.LookupEnv("ENV_PATH"); exists {
+		return e
+	}
+	return ".env"
+}
+
+// load env variables
+func loadEnv() {
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		processEnv[name] = true
+	}
+
+	values, err := godotenv.Read(envPath())
+	if err != nil {
+		log.Fatal("error loading .env file: " + err.Error())
+	}
+	err = applyEnvFile(values)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if profile != "" {
+		log.Printf("using profile %s\n", profile)
+	}
+
+	//secrets mounted as files, eg: REDDIT_PASSWORD_FILE
+	err = util.ResolveFileEnv(secretSettings...)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// loads the env file again, for SIGHUP. The new variables are checked (see checkEnv) before any is set, and only the
+// ones that changed are, so that the jobs running meanwhile never see an invalid or missing setting. If they aren't
+// valid nothing changes. Variables removed from the file keep their old value
+func reloadEnv() error {
+	values, err := godotenv.Read(envPath())
+	if err != nil {
+		return errors.New("error loading .env file:\n" + err.Error())
+	}
+
+	env, err := candidateEnv(values, profile)
+	if err != nil {
+		return err
+	}
+	err = checkEnv(env, profile)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range env {
+		if current, exists := os.LookupEnv(name); !exists || current != value {
+			os.Setenv(name, value)
+		}
+	}
+	return nil
 }
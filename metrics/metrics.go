@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jtyrmn/reddit-votewatch/version"
+)
+
+/*
+	This package keeps counters and timings of what the program is doing and
+	serves them at /metrics in the prometheus text format
+	(https://prometheus.io/docs/instrumenting/exposition_formats/), and/or
+	pushes them to a StatsD agent (see statsd.go), so that slow or failing
+	cycles can be diagnosed from a dashboard. It's deliberately
+	small: counters, gauges and summaries (sum + count), each with at most one label.
+
+	metrics are registered once, at package level, by the code they measure:
+
+		var listingsSent = metrics.NewCounter("votewatch_db_listings_sent_total", "listings streamed to the database", "method")
+		listingsSent.With("SaveListings").Add(500)
+*/
+
+// a metric as registered, with one series per label value
+type family struct {
+	name  string
+	help  string
+	kind  string // prometheus type, "counter", "gauge" or "summary"
+	label string // "" if the metric has no label
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// a single counter or gauge, or the sum and count of a summary
+type series struct {
+	count int64  // observations of a summary. Unused by counters
+	sum   uint64 // float64 bits, see Add()
+
+	family     *family
+	labelValue string
+}
+
+var registry = struct {
+	sync.Mutex
+	families []*family
+}{}
+
+func register(name string, help string, kind string, label string) *family {
+	f := &family{name: name, help: help, kind: kind, label: label, series: make(map[string]*series)}
+
+	registry.Lock()
+	registry.families = append(registry.families, f)
+	registry.Unlock()
+
+	return f
+}
+
+func (f *family) with(labelValue string) *series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, exists := f.series[labelValue]
+	if !exists {
+		s = &series{family: f, labelValue: labelValue}
+		f.series[labelValue] = s
+	}
+	return s
+}
+
+func (s *series) add(value float64) {
+	for {
+		old := atomic.LoadUint64(&s.sum)
+		updated := math.Float64bits(math.Float64frombits(old) + value)
+		if atomic.CompareAndSwapUint64(&s.sum, old, updated) {
+			return
+		}
+	}
+}
+
+// a value that only goes up, eg: # of listings sent
+type Counter struct {
+	family *family
+}
+
+// a counter. label is the name of its label, or "" for none
+func NewCounter(name string, help string, label string) Counter {
+	return Counter{register(name, help, "counter", label)}
+}
+
+// the series of the counter with the given label value. Pass "" if the counter has no label
+func (c Counter) With(labelValue string) CounterSeries {
+	return CounterSeries{c.family.with(labelValue)}
+}
+
+type CounterSeries struct {
+	series *series
+}
+
+func (c CounterSeries) Add(value float64) {
+	c.series.add(value)
+	pushStatsd(c.series, value)
+}
+
+func (c CounterSeries) Inc() {
+	c.Add(1)
+}
+
+// a value that can go up and down, eg: when something last happened
+type Gauge struct {
+	family *family
+}
+
+// a gauge. label is the name of its label, or "" for none
+func NewGauge(name string, help string, label string) Gauge {
+	return Gauge{register(name, help, "gauge", label)}
+}
+
+// the series of the gauge with the given label value. Pass "" if the gauge has no label
+func (g Gauge) With(labelValue string) GaugeSeries {
+	return GaugeSeries{g.family.with(labelValue)}
+}
+
+type GaugeSeries struct {
+	series *series
+}
+
+func (g GaugeSeries) Set(value float64) {
+	atomic.StoreUint64(&g.series.sum, math.Float64bits(value))
+	pushStatsd(g.series, value)
+}
+
+// the total and # of observations of some value, eg: how long streams took
+type Summary struct {
+	family *family
+}
+
+// a summary. label is the name of its label, or "" for none
+func NewSummary(name string, help string, label string) Summary {
+	return Summary{register(name, help, "summary", label)}
+}
+
+// the series of the summary with the given label value. Pass "" if the summary has no label
+func (s Summary) With(labelValue string) SummarySeries {
+	return SummarySeries{s.family.with(labelValue)}
+}
+
+type SummarySeries struct {
+	series *series
+}
+
+func (s SummarySeries) Observe(value float64) {
+	s.series.add(value)
+	atomic.AddInt64(&s.series.count, 1)
+	pushStatsd(s.series, value)
+}
+
+// writes every metric in the prometheus text format
+func Write(out io.Writer) error {
+	registry.Lock()
+	families := append([]*family(nil), registry.families...)
+	registry.Unlock()
+
+	for _, f := range families {
+		_, err := fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		if err != nil {
+			return err
+		}
+
+		f.mu.Lock()
+		labelValues := make([]string, 0, len(f.series))
+		for labelValue := range f.series {
+			labelValues = append(labelValues, labelValue)
+		}
+		sort.Strings(labelValues)
+
+		lines := make([]string, 0, len(labelValues))
+		for _, labelValue := range labelValues {
+			s := f.series[labelValue]
+			labels := ""
+			if f.label != "" {
+				labels = fmt.Sprintf("{%s=%q}", f.label, labelValue)
+			}
+
+			sum := math.Float64frombits(atomic.LoadUint64(&s.sum))
+			if f.kind == "summary" {
+				lines = append(lines,
+					fmt.Sprintf("%s_sum%s %g", f.name, labels, sum),
+					fmt.Sprintf("%s_count%s %d", f.name, labels, atomic.LoadInt64(&s.count)))
+			} else {
+				lines = append(lines, fmt.Sprintf("%s%s %g", f.name, labels, sum))
+			}
+		}
+		f.mu.Unlock()
+
+		if len(lines) > 0 {
+			_, err = io.WriteString(out, strings.Join(lines, "\n")+"\n")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// serves the metrics at /metrics on address (eg: ":9100"), and /healthz for liveness probes, which also says which build
+// is running (see the version package). Only returns if the server fails
+func Serve(address string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+			version.BuildInfo
+		}{"ok", version.Get()})
+	})
+
+	return http.ListenAndServe(address, mux)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+/*
+	the go runtime's profiles (goroutines, heap, cpu...) can be served on a
+	separate address with PPROF_ADDRESS, to track down goroutines that got stuck
+	(eg: a fetch waiting on reddit forever) or memory growing with the tracked
+	posts while the program is running, eg:
+
+		go tool pprof http://localhost:6060/debug/pprof/heap
+		curl "localhost:6060/debug/pprof/goroutine?debug=2"
+
+	profiles show a lot about the program's internals, so keep it local
+*/
+
+// serves the profiles at /debug/pprof/ on address (eg: "localhost:6060"). Only returns if the server fails
+func ServeProfiling(address string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index) // also serves the named profiles, eg: /debug/pprof/heap
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return http.ListenAndServe(address, mux)
+}
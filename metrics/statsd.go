@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	for shops that run a StatsD or Datadog agent rather than scraping
+	prometheus, every change to a metric can also be pushed to STATSD_ADDRESS
+	over udp, as it happens:
+
+		counters  -> votewatch.job_failures_total:1|c|#job:updating_posts
+		gauges    -> votewatch.job_failure_streak:3|g|#job:updating_posts
+		summaries -> votewatch.job_duration_seconds:2.5|h|#job:updating_posts
+
+	the label goes in a DogStatsD tag. Plain StatsD servers don't understand
+	tags, so with STATSD_TAGS=false it's added to the name instead
+	(votewatch.job_failures_total.updating_posts). Names lose their votewatch_
+	prefix in favour of STATSD_PREFIX. Lines are batched into packets and sent
+	every STATSD_FLUSH_INTERVAL milliseconds, and dropped if the agent can't
+	keep up
+*/
+
+const (
+	maxStatsdQueued = 8192
+	maxPacketSize   = 1432 // fits in an ethernet frame along with the ip and udp headers
+)
+
+type statsdEmitter struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+
+	lines chan string
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+var statsd struct {
+	sync.RWMutex
+	emitter *statsdEmitter
+}
+
+// starts pushing metrics to the StatsD agent at address (eg: "localhost:8125"), configured by the other STATSD_* env variables
+func PushStatsd(address string) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return fmt.Errorf("error connecting to statsd at %s:\n%s", address, err)
+	}
+
+	prefix := "votewatch."
+	if value, exists := os.LookupEnv("STATSD_PREFIX"); exists {
+		prefix = value
+	}
+	tags, err := util.GetEnvBool("STATSD_TAGS", true)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	interval := 1000
+	if value, exists := os.LookupEnv("STATSD_FLUSH_INTERVAL"); exists && strings.TrimSpace(value) != "" {
+		interval, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || interval <= 0 {
+			conn.Close()
+			return fmt.Errorf("malformed STATSD_FLUSH_INTERVAL \"%s\"", value)
+		}
+	}
+
+	e := &statsdEmitter{
+		conn:   conn,
+		prefix: prefix,
+		tags:   tags,
+		lines:  make(chan string, maxStatsdQueued),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.run(time.Millisecond * time.Duration(interval))
+
+	statsd.Lock()
+	statsd.emitter = e
+	statsd.Unlock()
+
+	return nil
+}
+
+// sends whatever's left to push and stops pushing
+func StopStatsd() {
+	statsd.Lock()
+	e := statsd.emitter
+	statsd.emitter = nil
+	statsd.Unlock()
+
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	e.conn.Close()
+}
+
+// queues a change to s, if metrics are being pushed
+func pushStatsd(s *series, value float64) {
+	statsd.RLock()
+	e := statsd.emitter
+	statsd.RUnlock()
+
+	if e == nil {
+		return
+	}
+
+	select {
+	case e.lines <- e.format(s, value):
+	default: // the agent isn't keeping up
+	}
+}
+
+// the statsd line of a change to s
+func (e *statsdEmitter) format(s *series, value float64) string {
+	var kind string
+	switch s.family.kind {
+	case "counter":
+		kind = "c"
+	case "gauge":
+		kind = "g"
+	default:
+		kind = "h"
+	}
+
+	name := e.prefix + strings.TrimPrefix(s.family.name, "votewatch_")
+	labelValue := sanitizeStatsd(s.labelValue)
+	if s.family.label == "" || labelValue == "" {
+		return fmt.Sprintf("%s:%g|%s", name, value, kind)
+	}
+	if !e.tags {
+		return fmt.Sprintf("%s.%s:%g|%s", name, labelValue, value, kind)
+	}
+	return fmt.Sprintf("%s:%g|%s|#%s:%s", name, value, kind, s.family.label, labelValue)
+}
+
+// label values can be anything (eg: job names with spaces), which would break the line format
+func sanitizeStatsd(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.', r == '/':
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(value))
+}
+
+func (e *statsdEmitter) run(interval time.Duration) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var packet strings.Builder
+	send := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		e.conn.Write([]byte(packet.String())) // udp, nothing to do about errors
+		packet.Reset()
+	}
+	add := func(line string) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+			send()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	for {
+		select {
+		case line := <-e.lines:
+			add(line)
+		case <-ticker.C:
+			send()
+		case <-e.stop:
+			for len(e.lines) > 0 {
+				add(<-e.lines)
+			}
+			send()
+			return
+		}
+	}
+}
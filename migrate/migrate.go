@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+)
+
+/*
+	This package upgrades listings that are already stored when the way they're
+	stored changes, eg: a new field that older listings need to be backfilled
+	with. Every listing is streamed out of the database a page at a time, run
+	through each migration newer than the version the data is at, and the
+	listings that changed are written back with Store.ReplaceListings.
+
+	migrations are numbered in the order they were added. To add one, append it
+	to the migrations slice below with the next version number. They must be
+	safe to run more than once, as the stored data doesn't record its version
+*/
+
+type migration struct {
+	version     int
+	description string
+
+	// transforms a listing in place. Returns whether anything was changed
+	apply func(*database.ListingHistory) bool
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "sort entries oldest first and drop duplicate entries (eg: from importing the same dump twice)",
+		apply:       dedupeEntries,
+	},
+}
+
+// the version the stored data is at after every migration has run
+func Latest() int {
+	return migrations[len(migrations)-1].version
+}
+
+// the part of database.Store that Run needs
+type listingsRewriter interface {
+	RecieveHistoryPage(context.Context, database.ListingsQuery) ([]database.ListingHistory, string, error)
+	ReplaceListings(context.Context, []database.ListingHistory) error
+}
+
+// the outcome of Run
+type Result struct {
+	Scanned  int // # of listings read
+	Migrated int // # of listings that were changed (and written back, unless it was a dry run)
+}
+
+// runs every migration after version from (up to and including version to) over the listings matching query,
+// a page of query.Limit listings at a time. Nothing is written back if dryRun is set
+func Run(ctx context.Context, store listingsRewriter, query database.ListingsQuery, from int, to int, dryRun bool) (Result, error) {
+	pending := make([]migration, 0)
+	for _, m := range migrations {
+		if m.version > from && m.version <= to {
+			pending = append(pending, m)
+		}
+	}
+
+	var result Result
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	for {
+		page, next, err := store.RecieveHistoryPage(ctx, query)
+		if err != nil {
+			return result, fmt.Errorf("error recieving listings from database:\n%s", err)
+		}
+
+		changed := make([]database.ListingHistory, 0)
+		for idx := range page {
+			modified := false
+			for _, m := range pending {
+				if m.apply(&page[idx]) {
+					modified = true
+				}
+			}
+			if modified {
+				changed = append(changed, page[idx])
+			}
+		}
+		result.Scanned += len(page)
+
+		if len(changed) > 0 && !dryRun {
+			err = store.ReplaceListings(ctx, changed)
+			if err != nil {
+				return result, fmt.Errorf("error writing migrated listings to database:\n%s", err)
+			}
+		}
+		result.Migrated += len(changed)
+
+		if next == "" {
+			break
+		}
+		query.Cursor = next
+	}
+
+	return result, nil
+}
+
+// describes the migrations after version from, one per line
+func Describe(from int) []string {
+	descriptions := make([]string, 0)
+	for _, m := range migrations {
+		if m.version > from {
+			descriptions = append(descriptions, fmt.Sprintf("%d: %s", m.version, m.description))
+		}
+	}
+
+	return descriptions
+}
+
+// version 1
+func dedupeEntries(history *database.ListingHistory) bool {
+	entries := history.Entries
+	sorted := sort.SliceIsSorted(entries, func(i, j int) bool { return entries[i].Date < entries[j].Date })
+	if !sorted {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date < entries[j].Date })
+	}
+
+	deduped := make([]database.Snapshot, 0, len(entries))
+	for idx, entry := range entries {
+		if idx > 0 && entry == entries[idx-1] {
+			continue
+		}
+		deduped = append(deduped, entry)
+	}
+
+	history.Entries = deduped
+	return !sorted || len(deduped) != len(entries)
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	notifications for no-code automation services, so that anyone can build
+	on them (eg: text me when a tracked post passes 10k upvotes) without
+	writing a program:
+
+		ZAPIER_WEBHOOK_URL    a zapier "catch hook" (or make, n8n... any webhook that takes json). Each notification
+		                      is POSTed as an AutomationEvent, a flat object that the zap's steps can pick fields from
+		IFTTT_WEBHOOK_KEY     the key of IFTTT's webhooks service (https://ifttt.com/maker_webhooks/settings). Each
+		                      notification triggers the IFTTT_EVENT event (defaults to votewatch), with the text as
+		                      Value1, the post's link as Value2 and its upvotes as Value3
+
+	the rules and template work like every other sink's (ZAPIER_MIN_UPVOTES...).
+	For services that would rather poll, the api serves the same events at
+	/api/triggers, see the api package
+*/
+
+// a notification as a flat json object, the way automation services expect triggers to look
+type AutomationEvent struct {
+	Id   string `json:"id"` // the same for every notification about a post from the same rule, services deduplicate on it
+	Kind Kind   `json:"kind"`
+	Text string `json:"text"`
+	Time string `json:"time"` // RFC 3339
+
+	//empty for alerts
+	PostId    string  `json:"post_id,omitempty"`
+	Subreddit string  `json:"subreddit,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Author    string  `json:"author,omitempty"`
+	Url       string  `json:"url,omitempty"`
+	Link      string  `json:"link,omitempty"`
+	Created   string  `json:"created,omitempty"` // RFC 3339
+	Upvotes   int     `json:"upvotes"`
+	Comments  int     `json:"comments"`
+	Velocity  float64 `json:"upvotes_per_hour"`
+	Threshold float64 `json:"threshold,omitempty"` // the rule's upvotes or upvotes an hour
+	RemovedBy string  `json:"removed_by,omitempty"`
+}
+
+// the notification as an AutomationEvent. Its text is the sink's template, filled in, or the Summary if there isn't one
+func AutomationEventOf(n Notification) AutomationEvent {
+	event := AutomationEvent{
+		Kind: n.Kind,
+		Text: n.Text,
+		Time: n.Time.UTC().Format(time.RFC3339),
+	}
+	if event.Text == "" {
+		event.Text = n.Summary()
+	}
+
+	if n.Kind == WatcherError {
+		event.Id = fmt.Sprintf("alert-%d", n.Time.UnixNano())
+		return event
+	}
+
+	event.Id = fmt.Sprintf("%s-%s", n.Post.FullId(), n.Kind)
+	if n.Kind == UpvotesReached || n.Kind == VelocityReached {
+		event.Id += "-" + strconv.FormatFloat(n.Threshold, 'f', -1, 64)
+	}
+	event.PostId = string(n.Post.FullId())
+	event.Subreddit = n.Post.Subreddit
+	event.Title = n.Post.Title
+	event.Author = n.Post.Author
+	event.Url = n.Post.Url
+	event.Link = n.Link()
+	event.Created = time.Unix(int64(n.Post.Date), 0).UTC().Format(time.RFC3339)
+	event.Upvotes = n.Post.Upvotes
+	event.Comments = n.Post.Comments
+	event.Velocity = n.Velocity
+	event.Threshold = n.Threshold
+	event.RemovedBy = n.Post.RemovedBy
+	return event
+}
+
+type zapierSink struct {
+	webhook string
+	client  *http.Client
+}
+
+// reads ZAPIER_WEBHOOK_URL. nil if it isn't set
+func newZapierSink() (Sink, error) {
+	webhook := strings.TrimSpace(os.Getenv("ZAPIER_WEBHOOK_URL"))
+	if webhook == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(webhook, "https://") && !strings.HasPrefix(webhook, "http://") {
+		return nil, errors.New("ZAPIER_WEBHOOK_URL should be a http(s) url")
+	}
+
+	return &zapierSink{webhook: webhook, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+func (s *zapierSink) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(AutomationEventOf(n))
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.webhook, body)
+}
+
+type iftttSink struct {
+	url    string // with the event and key
+	client *http.Client
+}
+
+// reads IFTTT_WEBHOOK_KEY and IFTTT_EVENT. nil if there's no key
+func newIftttSink() (Sink, error) {
+	key := strings.TrimSpace(os.Getenv("IFTTT_WEBHOOK_KEY"))
+	if key == "" {
+		return nil, nil
+	}
+
+	event := strings.TrimSpace(os.Getenv("IFTTT_EVENT"))
+	if event == "" {
+		event = "votewatch"
+	}
+
+	return &iftttSink{
+		url:    "https://maker.ifttt.com/trigger/" + url.PathEscape(event) + "/with/key/" + url.PathEscape(key),
+		client: &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+func (s *iftttSink) Send(ctx context.Context, n Notification) error {
+	event := AutomationEventOf(n)
+	values := map[string]string{"value1": event.Text, "value2": event.Link}
+	if n.Kind != WatcherError {
+		values["value3"] = strconv.Itoa(event.Upvotes)
+	}
+
+	body, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, body)
+}
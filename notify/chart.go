@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+// a post's score over time, drawn in text so it fits in any chat message
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// the most points a sparkline is drawn with, longer histories are sampled down to it
+const sparklineWidth = 24
+
+// the upvotes of a history (oldest first) as a line of block characters, eg: ▁▁▂▃▅▆█
+func Sparkline(entries []reddit.Snapshot) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	points := make([]int, 0, sparklineWidth)
+	if len(entries) <= sparklineWidth {
+		for _, entry := range entries {
+			points = append(points, entry.Upvotes)
+		}
+	} else {
+		for idx := 0; idx < sparklineWidth; idx += 1 {
+			points = append(points, entries[idx*(len(entries)-1)/(sparklineWidth-1)].Upvotes)
+		}
+	}
+
+	low, high := points[0], points[0]
+	for _, point := range points {
+		if point < low {
+			low = point
+		}
+		if point > high {
+			high = point
+		}
+	}
+
+	line := make([]rune, len(points))
+	for idx, point := range points {
+		level := 0
+		if high > low {
+			level = (point - low) * (len(sparks) - 1) / (high - low)
+		}
+		line[idx] = sparks[level]
+	}
+	return string(line)
+}
+
+// a sparkline and how the upvotes changed, eg: ▁▁▂▃▅▆█ 12 → 340 upvotes over 3h10m. Empty without at least two entries
+func ChartSummary(entries []reddit.Snapshot) string {
+	if len(entries) < 2 {
+		return ""
+	}
+
+	first, last := entries[0], entries[len(entries)-1]
+	over := time.Duration(0)
+	if last.Date > first.Date {
+		over = (time.Duration(last.Date-first.Date) * time.Second).Round(time.Minute)
+	}
+
+	return fmt.Sprintf("%s %d → %d upvotes over %s", Sparkline(entries), first.Upvotes, last.Upvotes, shortDuration(over))
+}
+
+// eg: 3h10m instead of 3h10m0s
+func shortDuration(d time.Duration) string {
+	text := d.String()
+	switch {
+	case strings.HasSuffix(text, "h0m0s"):
+		return strings.TrimSuffix(text, "0m0s")
+	case strings.HasSuffix(text, "m0s"):
+		return strings.TrimSuffix(text, "0s")
+	}
+	return text
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+// digests of what happened to the tracked posts since the last one, sent by email (see email.go) and to matrix (see matrix.go)
+
+const (
+	defaultDigestSize = 10
+
+	// the newly tracked and removed posts listed in a digest, the rest are only counted
+	maxDigestListed = 50
+)
+
+type Digest struct {
+	Since time.Time
+	Until time.Time
+
+	Risers []Riser // most upvotes gained first
+
+	Tracked      []reddit.RedditContent // newest first, at most 50
+	TrackedCount int
+	Removed      []reddit.RedditContent // Post.RemovedBy says why
+	RemovedCount int
+
+	Alerts []scheduler.Alert
+}
+
+type Riser struct {
+	Post  reddit.RedditContent // as of its last update
+	Gain  int                  // upvotes gained since the last digest (or since it was tracked)
+	Chart string               // see chart.go
+}
+
+func (r Riser) Link() string {
+	return "https://redd.it/" + r.Post.Id
+}
+
+// whether nothing happened since the last digest
+func (d Digest) Empty() bool {
+	return len(d.Risers) == 0 && d.TrackedCount == 0 && d.RemovedCount == 0 && len(d.Alerts) == 0
+}
+
+// eg: votewatch digest: 10 risers, 52 new posts, 1 removed
+func (d Digest) Subject() string {
+	subject := fmt.Sprintf("votewatch digest: %d risers, %d new posts, %d removed", len(d.Risers), d.TrackedCount, d.RemovedCount)
+	if len(d.Alerts) > 0 {
+		subject += fmt.Sprintf(", %d alerts", len(d.Alerts))
+	}
+	return subject
+}
+
+// reads the number of risers a digest lists from env, eg: EMAIL_DIGEST_SIZE
+func digestSizeFromEnv(env string) (int, error) {
+	value := strings.TrimSpace(os.Getenv(env))
+	if value == "" {
+		return defaultDigestSize, nil
+	}
+	size, err := util.GetEnvInt(env)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("%s=%s should be a positive number of posts", env, value)
+	}
+	return size, nil
+}
+
+// gathers what goes in the next digest from the scheduler's events
+type digestCollector struct {
+	size    int // # of risers listed
+	history historySource
+
+	mu      sync.Mutex
+	since   time.Time
+	risers  map[reddit.Fullname]*riserTally
+	tracked []reddit.RedditContent
+	removed []reddit.RedditContent
+	alerts  []scheduler.Alert
+}
+
+type riserTally struct {
+	post  reddit.RedditContent
+	first int // upvotes when it first showed up in this digest
+}
+
+func newDigestCollector(size int, history historySource) *digestCollector {
+	c := &digestCollector{size: size, history: history}
+	c.reset()
+	return c
+}
+
+// must be called with c.mu held, or before the collector is used
+func (c *digestCollector) reset() {
+	c.since = time.Now()
+	c.risers = make(map[reddit.Fullname]*riserTally)
+	c.tracked = nil
+	c.removed = nil
+	c.alerts = nil
+}
+
+func (c *digestCollector) onEvent(event scheduler.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch event.Kind {
+	case scheduler.PostTracked:
+		c.tracked = append(c.tracked, event.Post)
+	case scheduler.PostUpdated:
+		ID := event.Post.FullId()
+		if _, exists := c.risers[ID]; !exists {
+			c.risers[ID] = &riserTally{first: event.PreviousUpvotes}
+		}
+		c.risers[ID].post = event.Post
+	case scheduler.PostRemoved:
+		c.removed = append(c.removed, event.Post)
+	case scheduler.AlertRaised:
+		if event.Alert != nil {
+			c.alerts = append(c.alerts, *event.Alert)
+		}
+	}
+}
+
+// takes what happened since the last digest, with a chart of each riser's score
+func (c *digestCollector) collect() Digest {
+	digest := c.take()
+
+	if c.history != nil {
+		for idx, riser := range digest.Risers {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			entries, err := c.history.GetHistory(ctx, riser.Post.FullId())
+			cancel()
+			if err == nil {
+				digest.Risers[idx].Chart = ChartSummary(append(entries, riser.Post.Snapshot()))
+			}
+		}
+	}
+	return digest
+}
+
+func (c *digestCollector) take() Digest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	digest := Digest{
+		Since:        c.since,
+		Until:        time.Now(),
+		Tracked:      c.tracked,
+		TrackedCount: len(c.tracked),
+		Removed:      c.removed,
+		RemovedCount: len(c.removed),
+		Alerts:       c.alerts,
+	}
+
+	for _, tally := range c.risers {
+		if gain := tally.post.Upvotes - tally.first; gain > 0 {
+			digest.Risers = append(digest.Risers, Riser{Post: tally.post, Gain: gain})
+		}
+	}
+	sort.Slice(digest.Risers, func(i, j int) bool {
+		if digest.Risers[i].Gain != digest.Risers[j].Gain {
+			return digest.Risers[i].Gain > digest.Risers[j].Gain
+		}
+		return digest.Risers[i].Post.Id < digest.Risers[j].Post.Id
+	})
+	if len(digest.Risers) > c.size {
+		digest.Risers = digest.Risers[:c.size]
+	}
+
+	sort.Slice(digest.Tracked, func(i, j int) bool { return digest.Tracked[i].Date > digest.Tracked[j].Date })
+	if len(digest.Tracked) > maxDigestListed {
+		digest.Tracked = digest.Tracked[:maxDigestListed]
+	}
+	if len(digest.Removed) > maxDigestListed {
+		digest.Removed = digest.Removed[:maxDigestListed]
+	}
+
+	c.reset()
+	return digest
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	notifications posted to a discord channel through a webhook
+	(https://support.discord.com/hc/en-us/articles/228383668), set with
+	DISCORD_WEBHOOK_URL. Each is an embed with the post's title and link, the
+	text from DISCORD_TEMPLATE, the post's subreddit, score, comments and age,
+	and a chart of its score so far (an image too, with API_PUBLIC_URL set).
+	DISCORD_USERNAME is who they're posted as, and DISCORD_<RULE>_MENTION (eg:
+	<@&role id>) is pinged with them
+*/
+
+// longest an embed title can be, see https://discord.com/developers/docs/resources/channel#embed-object-embed-limits
+const discordTitleLimit = 256
+
+// embed colours by kind
+var discordColours = map[Kind]int{
+	UpvotesReached:  0xff4500, // reddit orange
+	VelocityReached: 0xffb000,
+	PostRemoved:     0x7f8c8d,
+	WatcherError:    0xe74c3c,
+}
+
+type discordSink struct {
+	webhook  string
+	username string
+	client   *http.Client
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordImage struct {
+	Url string `json:"url"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Url         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Colour      int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Image       *discordImage  `json:"image,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+// reads DISCORD_WEBHOOK_URL and DISCORD_USERNAME. nil if there's no webhook
+func newDiscordSink() (Sink, error) {
+	webhook := strings.TrimSpace(os.Getenv("DISCORD_WEBHOOK_URL"))
+	if webhook == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(webhook, "https://") && !strings.HasPrefix(webhook, "http://") {
+		return nil, errors.New("DISCORD_WEBHOOK_URL should be a http(s) url")
+	}
+
+	username := strings.TrimSpace(os.Getenv("DISCORD_USERNAME"))
+	if username == "" {
+		username = "votewatch"
+	}
+
+	return &discordSink{webhook: webhook, username: username, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+func (s *discordSink) Send(ctx context.Context, n Notification) error {
+	//mentions only ping outside of embeds. A webhook always posts in its own channel, so _CHANNEL doesn't apply
+	body, err := json.Marshal(map[string]interface{}{
+		"username": s.username,
+		"content":  n.Mention,
+		"embeds":   []discordEmbed{discordEmbedOf(n)},
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJSON(ctx, s.client, s.webhook, body)
+}
+
+func discordEmbedOf(n Notification) discordEmbed {
+	embed := discordEmbed{
+		Description: n.Text,
+		Colour:      discordColours[n.Kind],
+		Timestamp:   n.Time.UTC().Format(time.RFC3339),
+	}
+
+	if n.Kind == WatcherError {
+		embed.Title = "votewatch alert"
+		if n.Alert.Resolved {
+			embed.Title = "votewatch alert resolved"
+			embed.Colour = 0x2ecc71
+		}
+		if n.Alert.Job != "" {
+			embed.Fields = append(embed.Fields, discordField{Name: "Job", Value: n.Alert.Job})
+		}
+		return embed
+	}
+
+	embed.Title = n.Post.Title
+	if len([]rune(embed.Title)) > discordTitleLimit {
+		embed.Title = string([]rune(embed.Title)[:discordTitleLimit-1]) + "…"
+	}
+	embed.Url = n.Link()
+
+	age := time.Duration(0)
+	if n.Post.QueryDate > n.Post.Date {
+		age = (time.Duration(n.Post.QueryDate-n.Post.Date) * time.Second).Round(time.Minute)
+	}
+	embed.Fields = []discordField{
+		{Name: "Subreddit", Value: "r/" + n.Post.Subreddit, Inline: true},
+		{Name: "Upvotes", Value: strconv.Itoa(n.Post.Upvotes), Inline: true},
+		{Name: "Comments", Value: strconv.Itoa(n.Post.Comments), Inline: true},
+		{Name: "Age", Value: shortDuration(age), Inline: true},
+		{Name: "Upvotes an hour", Value: fmt.Sprintf("%.1f", n.Velocity), Inline: true},
+	}
+	if n.Chart != "" {
+		embed.Fields = append(embed.Fields, discordField{Name: "Score", Value: n.Chart})
+	}
+	if n.ChartURL != "" {
+		embed.Image = &discordImage{Url: n.ChartURL}
+	}
+	return embed
+}
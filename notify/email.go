@@ -0,0 +1,368 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	notifications by email, through an SMTP server (EMAIL_SMTP_ADDRESS, eg:
+	smtp.example.com:587) to EMAIL_TO. Instead of a message per post, a digest
+	is sent every EMAIL_DIGEST_PERIOD with what happened since the last one:
+
+		- the posts that gained the most upvotes (the top EMAIL_DIGEST_SIZE), with a chart of their score
+		- the posts that started being tracked
+		- the tracked posts that were removed
+		- the alerts that were raised and resolved
+
+	a digest where nothing happened isn't sent. With EMAIL_ERRORS=true, alerts
+	(a job keeps failing, nothing is being written, see scheduler/alerts.go)
+	are also sent right away, on their own.
+
+	each message has an html and a plain text version. Their templates can be
+	replaced by putting digest.html, digest.txt, alert.html and/or alert.txt in
+	EMAIL_TEMPLATES_DIR: go templates (https://pkg.go.dev/text/template) over a
+	Digest or an Alert. Port 465 is spoken to over TLS, other ports upgrade to
+	it with STARTTLS when the server offers it
+*/
+
+const defaultDigestPeriod = 24 * time.Hour
+
+type emailTemplates struct {
+	digestText *template.Template
+	digestHTML *htmltemplate.Template
+	alertText  *template.Template
+	alertHTML  *htmltemplate.Template
+}
+
+type emailSender struct {
+	address  string
+	username string
+	password string
+	from     string
+	to       []string
+
+	period    time.Duration // 0 disables digests
+	errors    bool
+	templates emailTemplates
+	digest    *digestCollector
+}
+
+// reads the EMAIL_* env variables. nil if EMAIL_SMTP_ADDRESS isn't set
+func newEmailSender(history historySource) (*emailSender, error) {
+	address := strings.TrimSpace(os.Getenv("EMAIL_SMTP_ADDRESS"))
+	if address == "" {
+		return nil, nil
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("EMAIL_SMTP_ADDRESS=%s should be a host:port:\n%s", address, err)
+	}
+
+	e := &emailSender{
+		address:  address,
+		username: os.Getenv("EMAIL_SMTP_USERNAME"),
+		password: os.Getenv("EMAIL_SMTP_PASSWORD"),
+		from:     strings.TrimSpace(os.Getenv("EMAIL_FROM")),
+		period:   defaultDigestPeriod,
+	}
+
+	var err error
+	e.to, err = util.GetEnvStringSlice("EMAIL_TO")
+	if err != nil || len(e.to) == 0 {
+		return nil, errors.New("EMAIL_TO should list who to send email to")
+	}
+	if e.from == "" {
+		return nil, errors.New("EMAIL_FROM should be set, eg: votewatch@example.com")
+	}
+
+	if value := strings.TrimSpace(os.Getenv("EMAIL_DIGEST_PERIOD")); value != "" {
+		e.period, err = util.GetEnvDuration("EMAIL_DIGEST_PERIOD", time.Hour)
+		if err != nil || e.period < 0 {
+			return nil, fmt.Errorf("EMAIL_DIGEST_PERIOD=%s should be a number of hours or a duration like 12h", value)
+		}
+	}
+	size, err := digestSizeFromEnv("EMAIL_DIGEST_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	e.errors, err = util.GetEnvBool("EMAIL_ERRORS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	e.templates, err = loadEmailTemplates(os.Getenv("EMAIL_TEMPLATES_DIR"))
+	if err != nil {
+		return nil, err
+	}
+
+	e.digest = newDigestCollector(size, history)
+	return e, nil
+}
+
+// the default templates, each replaced by its file in dir if it's there
+func loadEmailTemplates(dir string) (emailTemplates, error) {
+	source := func(name string, def string) (string, error) {
+		if strings.TrimSpace(dir) == "" {
+			return def, nil
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return def, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading email template %s:\n%s", name, err)
+		}
+		return string(data), nil
+	}
+
+	var templates emailTemplates
+	for _, t := range []struct {
+		name string
+		def  string
+		html bool
+		text **template.Template
+		page **htmltemplate.Template
+	}{
+		{"digest.txt", defaultDigestText, false, &templates.digestText, nil},
+		{"digest.html", defaultDigestHTML, true, nil, &templates.digestHTML},
+		{"alert.txt", defaultAlertText, false, &templates.alertText, nil},
+		{"alert.html", defaultAlertHTML, true, nil, &templates.alertHTML},
+	} {
+		text, err := source(t.name, t.def)
+		if err != nil {
+			return templates, err
+		}
+
+		if t.html {
+			*t.page, err = htmltemplate.New(t.name).Funcs(htmltemplate.FuncMap(emailFuncs)).Parse(text)
+		} else {
+			*t.text, err = template.New(t.name).Funcs(emailFuncs).Parse(text)
+		}
+		if err != nil {
+			return templates, fmt.Errorf("error parsing email template %s:\n%s", t.name, err)
+		}
+	}
+
+	return templates, nil
+}
+
+var emailFuncs = template.FuncMap{
+	"link": func(post reddit.RedditContent) string { return "https://redd.it/" + post.Id },
+	"time": func(t time.Time) string { return t.Format("Jan 2 15:04 MST") },
+}
+
+// registers for the events the digests and alerts are made from, and starts sending digests
+func (e *emailSender) start() {
+	scheduler.OnEvent(scheduler.PostTracked, e.onEvent)
+	scheduler.OnEvent(scheduler.PostUpdated, e.onEvent)
+	scheduler.OnEvent(scheduler.PostRemoved, e.onEvent)
+	scheduler.OnEvent(scheduler.AlertRaised, e.onEvent)
+
+	if e.period > 0 {
+		go func() {
+			for range time.Tick(e.period) {
+				e.sendDigest()
+			}
+		}()
+	}
+}
+
+func (e *emailSender) onEvent(event scheduler.Event) {
+	if event.Kind == scheduler.AlertRaised && e.errors && event.Alert != nil && !event.Alert.Resolved {
+		alert := *event.Alert
+		go func() {
+			err := e.sendAlert(alert)
+			if err != nil {
+				log.Println("error emailing alert:\n" + err.Error())
+			}
+		}()
+	}
+	if e.period > 0 {
+		e.digest.onEvent(event)
+	}
+}
+
+func (e *emailSender) sendDigest() {
+	digest := e.digest.collect()
+	if digest.Empty() {
+		return
+	}
+
+	err := e.send(digest.Subject(), e.templates.digestText, e.templates.digestHTML, digest)
+	if err != nil {
+		log.Println("error emailing digest:\n" + err.Error())
+	}
+}
+
+func (e *emailSender) sendAlert(alert scheduler.Alert) error {
+	return e.send("votewatch alert: "+firstLine(alert.Message), e.templates.alertText, e.templates.alertHTML, alert)
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSuffix(line, ":")
+}
+
+// fills in both templates and sends them as one multipart/alternative message
+func (e *emailSender) send(subject string, text *template.Template, page *htmltemplate.Template, data interface{}) error {
+	var plain, html bytes.Buffer
+	if err := text.Execute(&plain, data); err != nil {
+		return fmt.Errorf("error filling in %s:\n%s", text.Name(), err)
+	}
+	if err := page.Execute(&html, data); err != nil {
+		return fmt.Errorf("error filling in %s:\n%s", page.Name(), err)
+	}
+
+	var message bytes.Buffer
+	body := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "From: %s\r\n", e.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", plain.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		writer, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		encoder.Write(part.content)
+		encoder.Close()
+	}
+	body.Close()
+
+	return e.deliver(message.Bytes())
+}
+
+func (e *emailSender) deliver(message []byte) error {
+	host, port, _ := net.SplitHostPort(e.address)
+
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, host)
+	}
+	if port != "465" {
+		return smtp.SendMail(e.address, auth, e.from, e.to, message)
+	}
+
+	//implicit tls, which smtp.SendMail doesn't do
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: sendTimeout}, "tcp", e.address, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, bytes.NewReader(message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+const defaultDigestText = `votewatch digest, {{time .Since}} to {{time .Until}}
+
+top risers:
+{{range .Risers}}  +{{.Gain}}  r/{{.Post.Subreddit}}: {{.Post.Title}} ({{.Post.Upvotes}} upvotes)
+        {{.Link}}{{if .Chart}}
+        {{.Chart}}{{end}}
+{{else}}  none
+{{end}}
+newly tracked ({{.TrackedCount}}):
+{{range .Tracked}}  r/{{.Subreddit}}: {{.Title}} {{link .}}
+{{else}}  none
+{{end}}
+removed ({{.RemovedCount}}):
+{{range .Removed}}  r/{{.Subreddit}}: {{.Title}} ({{.RemovedBy}}, {{.Upvotes}} upvotes) {{link .}}
+{{else}}  none
+{{end}}{{if .Alerts}}
+alerts:
+{{range .Alerts}}  {{if .Resolved}}resolved: {{end}}{{.Message}}
+{{end}}{{end}}`
+
+const defaultDigestHTML = `<html><body style="font-family: sans-serif">
+<h2>votewatch digest</h2>
+<p>{{time .Since}} to {{time .Until}}</p>
+<h3>top risers</h3>
+{{if .Risers}}<table cellpadding="4">
+{{range .Risers}}<tr><td><b>+{{.Gain}}</b></td><td>r/{{.Post.Subreddit}}</td><td><a href="{{.Link}}">{{.Post.Title}}</a> ({{.Post.Upvotes}} upvotes){{if .Chart}}<br><code>{{.Chart}}</code>{{end}}</td></tr>
+{{end}}</table>{{else}}<p>none</p>{{end}}
+<h3>newly tracked ({{.TrackedCount}})</h3>
+{{if .Tracked}}<ul>
+{{range .Tracked}}<li>r/{{.Subreddit}}: <a href="{{link .}}">{{.Title}}</a></li>
+{{end}}</ul>{{else}}<p>none</p>{{end}}
+<h3>removed ({{.RemovedCount}})</h3>
+{{if .Removed}}<ul>
+{{range .Removed}}<li>r/{{.Subreddit}}: <a href="{{link .}}">{{.Title}}</a> ({{.RemovedBy}}, {{.Upvotes}} upvotes)</li>
+{{end}}</ul>{{else}}<p>none</p>{{end}}
+{{if .Alerts}}<h3>alerts</h3>
+<ul>
+{{range .Alerts}}<li>{{if .Resolved}}resolved: {{end}}<pre>{{.Message}}</pre></li>
+{{end}}</ul>{{end}}
+</body></html>
+`
+
+const defaultAlertText = `votewatch alert{{if .Job}} for {{.Job}}{{end}}:
+
+{{.Message}}
+`
+
+const defaultAlertHTML = `<html><body style="font-family: sans-serif">
+<h2>votewatch alert{{if .Job}} for {{.Job}}{{end}}</h2>
+<pre>{{.Message}}</pre>
+</body></html>
+`
@@ -0,0 +1,293 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	notifications sent to a matrix room (https://spec.matrix.org/latest/client-server-api/#sending-events-to-a-room)
+	by an account logged in on MATRIX_HOMESERVER_URL (eg: https://matrix.org),
+	with its MATRIX_ACCESS_TOKEN. MATRIX_ROOM_ID is the room's id (eg:
+	!abcdef:matrix.org, under the room's advanced settings), not an alias, and
+	the account has to have joined it. Notifications are sent as notices
+	(like a bot's) with the post's title and link, the text from
+	MATRIX_TEMPLATE, the post's subreddit, score and comments, and a chart of
+	its score so far. MATRIX_<RULE>_CHANNEL sends a rule's notifications to
+	another room id, and MATRIX_<RULE>_MENTION is written before them
+
+	with MATRIX_DIGEST_PERIOD set, the room also gets a digest every period,
+	the same as the email digests (see digest.go)
+*/
+
+type matrixSink struct {
+	homeserver string
+	token      string
+	room       string
+	client     *http.Client
+}
+
+// the transaction ids of the messages sent, so that a retried request isn't sent twice. Unique within the access token
+var matrixTransactions uint64
+
+// reads MATRIX_HOMESERVER_URL, MATRIX_ACCESS_TOKEN and MATRIX_ROOM_ID. nil if there's no homeserver
+func newMatrixSink() (Sink, error) {
+	sink, err := matrixFromEnv()
+	if sink == nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func matrixFromEnv() (*matrixSink, error) {
+	homeserver := strings.TrimSpace(os.Getenv("MATRIX_HOMESERVER_URL"))
+	if homeserver == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(homeserver, "https://") && !strings.HasPrefix(homeserver, "http://") {
+		return nil, errors.New("MATRIX_HOMESERVER_URL should be a http(s) url, eg: https://matrix.org")
+	}
+
+	token := strings.TrimSpace(os.Getenv("MATRIX_ACCESS_TOKEN"))
+	if token == "" {
+		return nil, errors.New("MATRIX_ACCESS_TOKEN should be the access token of the account that sends notifications")
+	}
+	room := strings.TrimSpace(os.Getenv("MATRIX_ROOM_ID"))
+	if !strings.HasPrefix(room, "!") {
+		return nil, fmt.Errorf("MATRIX_ROOM_ID=%s should be a room id, eg: !abcdef:matrix.org", room)
+	}
+
+	return &matrixSink{
+		homeserver: strings.TrimSuffix(homeserver, "/"),
+		token:      token,
+		room:       room,
+		client:     &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+func (s *matrixSink) Send(ctx context.Context, n Notification) error {
+	room := s.room
+	if n.Channel != "" {
+		room = n.Channel
+	}
+	plain, formatted := matrixMessageOf(n)
+	return s.sendMessage(ctx, room, plain, formatted)
+}
+
+// sends a notice with a plain text body and an html one. If it's rate limited, it's retried once after as long as the
+// homeserver asks for
+func (s *matrixSink) sendMessage(ctx context.Context, room string, plain string, formatted string) error {
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/votewatch-%d-%d", s.homeserver,
+		url.PathEscape(room), time.Now().UnixNano(), atomic.AddUint64(&matrixTransactions, 1))
+
+	retryAfter, err := s.put(ctx, endpoint, body)
+	if err != nil || retryAfter == 0 {
+		return err
+	}
+	select {
+	case <-time.After(retryAfter):
+	case <-ctx.Done():
+		return errors.New("rate limited, gave up waiting")
+	}
+	_, err = s.put(ctx, endpoint, body)
+	return err
+}
+
+// returns how long to wait if it was rate limited
+func (s *matrixSink) put(ctx context.Context, endpoint string, body []byte) (time.Duration, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+s.token)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return 0, nil
+	}
+
+	var failure struct {
+		Code         string `json:"errcode"`
+		Error        string `json:"error"`
+		RetryAfterMs int64  `json:"retry_after_ms"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	json.Unmarshal(data, &failure)
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		if failure.RetryAfterMs <= 0 {
+			failure.RetryAfterMs = 1000
+		}
+		return time.Duration(failure.RetryAfterMs) * time.Millisecond, nil
+	}
+	if failure.Code != "" {
+		return 0, fmt.Errorf("%s: %s (%s)", response.Status, failure.Error, failure.Code)
+	}
+	return 0, fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(data)))
+}
+
+// the notification as plain text and as matrix's html (https://spec.matrix.org/latest/client-server-api/#mroommessage-msgtypes)
+func matrixMessageOf(n Notification) (string, string) {
+	var plain, formatted strings.Builder
+	if n.Mention != "" {
+		plain.WriteString(n.Mention + " ")
+		formatted.WriteString(html.EscapeString(n.Mention) + " ")
+	}
+
+	if n.Kind == WatcherError {
+		title := "votewatch alert"
+		if n.Alert.Resolved {
+			title = "votewatch alert resolved"
+		}
+		fmt.Fprintf(&plain, "%s\n%s", title, n.Text)
+		fmt.Fprintf(&formatted, "<b>%s</b><br><pre><code>%s</code></pre>", title, html.EscapeString(n.Text))
+		return plain.String(), formatted.String()
+	}
+
+	stats := fmt.Sprintf("r/%s · %d upvotes · %d comments · %.1f upvotes an hour", n.Post.Subreddit, n.Post.Upvotes, n.Post.Comments, n.Velocity)
+
+	fmt.Fprintf(&plain, "%s\n%s\n", n.Post.Title, n.Link())
+	fmt.Fprintf(&formatted, "<b><a href=\"%s\">%s</a></b><br>", n.Link(), html.EscapeString(n.Post.Title))
+	if n.Text != "" {
+		plain.WriteString(n.Text + "\n")
+		formatted.WriteString(html.EscapeString(n.Text) + "<br>")
+	}
+	plain.WriteString(stats)
+	formatted.WriteString(html.EscapeString(stats))
+	if n.Chart != "" {
+		plain.WriteString("\n" + n.Chart)
+		formatted.WriteString("<br><code>" + html.EscapeString(n.Chart) + "</code>")
+	}
+	return plain.String(), formatted.String()
+}
+
+// digests sent to MATRIX_ROOM_ID every MATRIX_DIGEST_PERIOD
+type matrixDigests struct {
+	sink   *matrixSink
+	period time.Duration
+	digest *digestCollector
+
+	text *template.Template
+	page *htmltemplate.Template
+}
+
+// reads MATRIX_DIGEST_PERIOD and MATRIX_DIGEST_SIZE. nil if matrix or its digests aren't set up
+func newMatrixDigests(history historySource) (*matrixDigests, error) {
+	sink, err := matrixFromEnv()
+	if sink == nil {
+		return nil, err
+	}
+
+	value := strings.TrimSpace(os.Getenv("MATRIX_DIGEST_PERIOD"))
+	if value == "" {
+		return nil, nil
+	}
+	period, err := util.GetEnvDuration("MATRIX_DIGEST_PERIOD", time.Hour)
+	if err != nil || period < 0 {
+		return nil, fmt.Errorf("MATRIX_DIGEST_PERIOD=%s should be a number of hours or a duration like 12h", value)
+	}
+	if period == 0 {
+		return nil, nil
+	}
+
+	size, err := digestSizeFromEnv("MATRIX_DIGEST_SIZE")
+	if err != nil {
+		return nil, err
+	}
+
+	return &matrixDigests{
+		sink:   sink,
+		period: period,
+		digest: newDigestCollector(size, history),
+		text:   template.Must(template.New("digest.txt").Funcs(emailFuncs).Parse(defaultDigestText)),
+		page:   htmltemplate.Must(htmltemplate.New("digest.html").Funcs(htmltemplate.FuncMap(emailFuncs)).Parse(matrixDigestHTML)),
+	}, nil
+}
+
+func (m *matrixDigests) start() {
+	scheduler.OnEvent(scheduler.PostTracked, m.digest.onEvent)
+	scheduler.OnEvent(scheduler.PostUpdated, m.digest.onEvent)
+	scheduler.OnEvent(scheduler.PostRemoved, m.digest.onEvent)
+	scheduler.OnEvent(scheduler.AlertRaised, m.digest.onEvent)
+
+	go func() {
+		for range time.Tick(m.period) {
+			err := m.send()
+			if err != nil {
+				log.Println("error sending digest to matrix:\n" + err.Error())
+			}
+		}
+	}()
+}
+
+func (m *matrixDigests) send() error {
+	digest := m.digest.collect()
+	if digest.Empty() {
+		return nil
+	}
+
+	var plain, formatted bytes.Buffer
+	if err := m.text.Execute(&plain, digest); err != nil {
+		return fmt.Errorf("error filling in %s:\n%s", m.text.Name(), err)
+	}
+	if err := m.page.Execute(&formatted, digest); err != nil {
+		return fmt.Errorf("error filling in %s:\n%s", m.page.Name(), err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	return m.sink.sendMessage(ctx, m.sink.room, plain.String(), formatted.String())
+}
+
+// the email digest's html, without what matrix clients don't render
+const matrixDigestHTML = `<h4>{{.Subject}}</h4>
+<p>{{time .Since}} to {{time .Until}}</p>
+<p><b>top risers</b></p>
+{{if .Risers}}<ul>
+{{range .Risers}}<li><b>+{{.Gain}}</b> r/{{.Post.Subreddit}}: <a href="{{.Link}}">{{.Post.Title}}</a> ({{.Post.Upvotes}} upvotes){{if .Chart}}<br><code>{{.Chart}}</code>{{end}}</li>
+{{end}}</ul>{{else}}<p>none</p>{{end}}
+<p><b>newly tracked ({{.TrackedCount}})</b></p>
+{{if .Tracked}}<ul>
+{{range .Tracked}}<li>r/{{.Subreddit}}: <a href="{{link .}}">{{.Title}}</a></li>
+{{end}}</ul>{{else}}<p>none</p>{{end}}
+<p><b>removed ({{.RemovedCount}})</b></p>
+{{if .Removed}}<ul>
+{{range .Removed}}<li>r/{{.Subreddit}}: <a href="{{link .}}">{{.Title}}</a> ({{.RemovedBy}}, {{.Upvotes}} upvotes)</li>
+{{end}}</ul>{{else}}<p>none</p>{{end}}
+{{if .Alerts}}<p><b>alerts</b></p>
+<ul>
+{{range .Alerts}}<li>{{if .Resolved}}resolved: {{end}}<pre><code>{{.Message}}</code></pre></li>
+{{end}}</ul>{{end}}`
@@ -0,0 +1,440 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	notifications sent to chat services (sinks, eg: discord.go) when something
+	worth a look happens to the tracked posts (email is sent in digests
+	instead, see email.go). Each sink has its own rules,
+	read from env variables starting with its name (eg: DISCORD_MIN_UPVOTES):
+
+		<SINK>_MIN_UPVOTES=<n>     a post's upvotes reach n
+		<SINK>_MIN_VELOCITY=<n>    a post is gaining over n upvotes an hour, on average since it was posted
+		<SINK>_REMOVED=true        a tracked post is taken down (by moderators, reddit or its author)
+		<SINK>_ERRORS=true         an alert is raised or resolved (a job keeps failing, nothing is being written...),
+		                           see scheduler/alerts.go
+		<SINK>_TEMPLATE=<text>     the text of the notification, as a go template (https://pkg.go.dev/text/template)
+		                           over a Notification. Defaults to {{.Summary}}
+
+	and each rule can say where its notifications go, for the sinks that
+	support it (eg: SLACK_REMOVED_CHANNEL=#mods):
+
+		<SINK>_<RULE>_CHANNEL      the channel to post in, instead of the sink's own
+		<SINK>_<RULE>_MENTION      who to ping, in the service's own syntax (eg: <@U024BE7LH> or <!here> on slack)
+
+	rules that aren't set don't notify. A post only notifies once per rule and
+	sink. Notifications are sent one at a time in the background, in order,
+	and dropped if a sink falls too far behind
+*/
+
+const (
+	sendTimeout           = 10 * time.Second
+	maxQueuedNotification = 64
+
+	// posts younger than this are treated as this old for MIN_VELOCITY, so that a handful of early upvotes don't count as fast
+	minVelocityAge = 15 * time.Minute
+)
+
+type Kind string
+
+const (
+	UpvotesReached  Kind = "upvotes"
+	VelocityReached Kind = "velocity"
+	PostRemoved     Kind = "removed"
+	WatcherError    Kind = "error"
+)
+
+type Notification struct {
+	Kind Kind
+	Time time.Time
+
+	Post      reddit.RedditContent // every kind but WatcherError
+	Threshold float64              // the rule's upvotes or upvotes an hour, for UpvotesReached and VelocityReached
+	Velocity  float64              // upvotes an hour since the post was posted
+	Chart     string               // a one line summary of the post's score over time (see chart.go). Empty if it has no history yet
+	ChartURL  string               // a png chart of the post's score on the api, if API_PUBLIC_URL is set (see api/chart.go)
+
+	Alert *scheduler.Alert // for WatcherError
+
+	Text string // the sink's <SINK>_TEMPLATE, filled in
+
+	Target // from the rule that matched
+}
+
+// where a rule's notifications go. Both are optional
+type Target struct {
+	Channel string
+	Mention string
+}
+
+// the env name of each kind's rule
+var ruleNames = map[Kind]string{
+	UpvotesReached:  "MIN_UPVOTES",
+	VelocityReached: "MIN_VELOCITY",
+	PostRemoved:     "REMOVED",
+	WatcherError:    "ERRORS",
+}
+
+// where notifications go
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// the part of database.Store needed for Notification.Chart
+type historySource interface {
+	GetHistory(ctx context.Context, ID reddit.Fullname) ([]reddit.Snapshot, error)
+}
+
+// a sink, and the name of its env variables. newSink returns nil if the sink isn't configured
+type sinkSetup struct {
+	prefix  string
+	newSink func() (Sink, error)
+}
+
+var sinks = []sinkSetup{
+	{"DISCORD", newDiscordSink},
+	{"SLACK", newSlackSink},
+	{"TELEGRAM", newTelegramSink},
+	{"ZAPIER", newZapierSink},
+	{"IFTTT", newIftttSink},
+	{"MATRIX", newMatrixSink},
+	{"NTFY", newNtfySink},
+	{"PUSHOVER", newPushoverSink},
+}
+
+type Rules struct {
+	MinUpvotes  int
+	MinVelocity float64
+	Removed     bool
+	Errors      bool
+
+	Targets map[Kind]Target
+}
+
+// reads <prefix>_MIN_UPVOTES, <prefix>_MIN_VELOCITY, <prefix>_REMOVED and <prefix>_ERRORS, and their _CHANNEL and _MENTION
+func RulesFromEnv(prefix string) (Rules, error) {
+	rules := Rules{Targets: make(map[Kind]Target)}
+	var err error
+
+	for kind, rule := range ruleNames {
+		rules.Targets[kind] = Target{
+			Channel: strings.TrimSpace(os.Getenv(prefix + "_" + rule + "_CHANNEL")),
+			Mention: strings.TrimSpace(os.Getenv(prefix + "_" + rule + "_MENTION")),
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv(prefix + "_MIN_UPVOTES")); value != "" {
+		rules.MinUpvotes, err = strconv.Atoi(value)
+		if err != nil || rules.MinUpvotes < 0 {
+			return rules, fmt.Errorf("%s_MIN_UPVOTES=%s should be a whole number of upvotes", prefix, value)
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv(prefix + "_MIN_VELOCITY")); value != "" {
+		rules.MinVelocity, err = strconv.ParseFloat(value, 64)
+		if err != nil || rules.MinVelocity < 0 || math.IsInf(rules.MinVelocity, 0) {
+			return rules, fmt.Errorf("%s_MIN_VELOCITY=%s should be a number of upvotes an hour", prefix, value)
+		}
+	}
+
+	rules.Removed, err = util.GetEnvBool(prefix+"_REMOVED", false)
+	if err != nil {
+		return rules, err
+	}
+	rules.Errors, err = util.GetEnvBool(prefix+"_ERRORS", false)
+	if err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func templateFromEnv(prefix string) (*template.Template, error) {
+	text := os.Getenv(prefix + "_TEMPLATE")
+	if strings.TrimSpace(text) == "" {
+		text = "{{.Summary}}"
+	}
+	tmpl, err := template.New(prefix + "_TEMPLATE").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s_TEMPLATE:\n%s", prefix, err)
+	}
+	return tmpl, nil
+}
+
+// the problems with every configured sink's settings, for the config check
+func CheckConfig() []error {
+	problems := make([]error, 0)
+	for _, setup := range sinks {
+		sink, err := setup.newSink()
+		if err != nil {
+			problems = append(problems, err)
+		}
+		if sink == nil {
+			continue
+		}
+
+		if _, err := RulesFromEnv(setup.prefix); err != nil {
+			problems = append(problems, err)
+		}
+		if _, err := templateFromEnv(setup.prefix); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if _, err := newEmailSender(nil); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := chartBaseFromEnv(); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := newMatrixDigests(nil); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// starts sending notifications to every configured sink. Must be called before the scheduler starts (see scheduler.OnEvent)
+func Setup(history historySource) error {
+	charts, err := chartBaseFromEnv()
+	if err != nil {
+		return err
+	}
+
+	for _, setup := range sinks {
+		sink, err := setup.newSink()
+		if err != nil {
+			return err
+		}
+		if sink == nil {
+			continue
+		}
+
+		rules, err := RulesFromEnv(setup.prefix)
+		if err != nil {
+			return err
+		}
+		tmpl, err := templateFromEnv(setup.prefix)
+		if err != nil {
+			return err
+		}
+
+		d := &dispatcher{
+			name:     strings.ToLower(setup.prefix),
+			sink:     sink,
+			rules:    rules,
+			template: tmpl,
+			history:  history,
+			charts:   charts,
+			queue:    make(chan Notification, maxQueuedNotification),
+			notified: make(map[notifiedKey]uint64),
+		}
+		go d.run()
+
+		scheduler.OnEvent(scheduler.PostUpdated, d.onEvent)
+		scheduler.OnEvent(scheduler.PostRemoved, d.onEvent)
+		scheduler.OnEvent(scheduler.AlertRaised, d.onEvent)
+	}
+
+	//see email.go
+	email, err := newEmailSender(history)
+	if err != nil {
+		return err
+	}
+	if email != nil {
+		email.start()
+	}
+
+	//see matrix.go
+	matrix, err := newMatrixDigests(history)
+	if err != nil {
+		return err
+	}
+	if matrix != nil {
+		matrix.start()
+	}
+
+	return nil
+}
+
+type notifiedKey struct {
+	kind Kind
+	ID   reddit.Fullname
+}
+
+// applies one sink's rules to the scheduler's events
+type dispatcher struct {
+	name     string
+	sink     Sink
+	rules    Rules
+	template *template.Template
+	history  historySource
+	charts   string // API_PUBLIC_URL
+	queue    chan Notification
+
+	mu       sync.Mutex
+	notified map[notifiedKey]uint64 // the rules each post has notified, and when the post was posted
+}
+
+func (d *dispatcher) onEvent(event scheduler.Event) {
+	switch event.Kind {
+	case scheduler.PostUpdated:
+		post := event.Post
+		upvotes := d.rules.MinUpvotes
+		if upvotes > 0 && event.PreviousUpvotes < upvotes && post.Upvotes >= upvotes && d.once(UpvotesReached, post) {
+			d.push(Notification{Kind: UpvotesReached, Post: post, Threshold: float64(upvotes)})
+		}
+
+		if d.rules.MinVelocity > 0 && Velocity(post) >= d.rules.MinVelocity && d.once(VelocityReached, post) {
+			d.push(Notification{Kind: VelocityReached, Post: post, Threshold: d.rules.MinVelocity})
+		}
+	case scheduler.PostRemoved:
+		if d.rules.Removed && d.once(PostRemoved, event.Post) {
+			d.push(Notification{Kind: PostRemoved, Post: event.Post})
+		}
+	case scheduler.AlertRaised:
+		if d.rules.Errors && event.Alert != nil {
+			d.push(Notification{Kind: WatcherError, Alert: event.Alert})
+		}
+	}
+}
+
+// whether a rule hasn't notified about a post yet, marking it as notified. Posts past MAX_TRACKING_AGE are forgotten, they
+// aren't updated anymore
+func (d *dispatcher) once(kind Kind, post reddit.RedditContent) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := notifiedKey{kind, post.FullId()}
+	if _, notified := d.notified[key]; notified {
+		return false
+	}
+
+	maxAge := uint64(util.GetEnvIntDefault("MAX_TRACKING_AGE", 86400))
+	now := uint64(time.Now().Unix())
+	for key, posted := range d.notified {
+		if posted+maxAge < now {
+			delete(d.notified, key)
+		}
+	}
+
+	d.notified[key] = post.Date
+	return true
+}
+
+func (d *dispatcher) push(n Notification) {
+	n.Time = time.Now()
+	n.Target = d.rules.Targets[n.Kind]
+	if n.Kind != WatcherError {
+		n.Velocity = Velocity(n.Post)
+	}
+
+	select {
+	case d.queue <- n:
+	default:
+		log.Printf("too many notifications queued for %s, dropped one", d.name)
+	}
+}
+
+func (d *dispatcher) run() {
+	for n := range d.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := d.send(ctx, n)
+		cancel()
+		if err != nil {
+			log.Printf("error sending %s notification to %s:\n%s", n.Kind, d.name, err)
+		}
+	}
+}
+
+func (d *dispatcher) send(ctx context.Context, n Notification) error {
+	if n.Kind != WatcherError && d.history != nil {
+		entries, err := d.history.GetHistory(ctx, n.Post.FullId())
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("no chart for a %s notification, error getting %s's history:\n%s", d.name, n.Post.FullId(), err)
+		}
+		n.Chart = ChartSummary(append(entries, n.Post.Snapshot()))
+	}
+	if n.Kind != WatcherError && d.charts != "" {
+		n.ChartURL = d.charts + "/api/posts/" + string(n.Post.FullId()) + "/chart.png"
+	}
+
+	var text strings.Builder
+	err := d.template.Execute(&text, n)
+	if err != nil {
+		return errors.New("error filling in template:\n" + err.Error())
+	}
+	n.Text = text.String()
+
+	return d.sink.Send(ctx, n)
+}
+
+// reads API_PUBLIC_URL, where chat services can fetch charts from. Empty if it isn't set
+func chartBaseFromEnv() (string, error) {
+	base := strings.TrimSuffix(strings.TrimSpace(os.Getenv("API_PUBLIC_URL")), "/")
+	if base != "" && !strings.HasPrefix(base, "https://") && !strings.HasPrefix(base, "http://") {
+		return "", fmt.Errorf("API_PUBLIC_URL=%s should be the http(s) url API_ADDRESS is reachable at", base)
+	}
+	return base, nil
+}
+
+// upvotes an hour, on average since the post was posted (as of when it was fetched)
+func Velocity(post reddit.RedditContent) float64 {
+	age := time.Duration(0)
+	if post.QueryDate > post.Date {
+		age = time.Duration(post.QueryDate-post.Date) * time.Second
+	}
+	if age < minVelocityAge {
+		age = minVelocityAge
+	}
+
+	return float64(post.Upvotes) / age.Hours()
+}
+
+// https://redd.it/<id>
+func (n Notification) Link() string {
+	if n.Kind == WatcherError {
+		return ""
+	}
+	return "https://redd.it/" + n.Post.Id
+}
+
+// one line about what happened, eg: r/golang: "Go 1.18 is released" reached 1000 upvotes
+func (n Notification) Summary() string {
+	post := fmt.Sprintf("r/%s: \"%s\"", n.Post.Subreddit, n.Post.Title)
+	switch n.Kind {
+	case UpvotesReached:
+		return fmt.Sprintf("%s reached %d upvotes", post, int(n.Threshold))
+	case VelocityReached:
+		return fmt.Sprintf("%s is gaining %.0f upvotes an hour", post, n.Velocity)
+	case PostRemoved:
+		by := n.Post.RemovedBy
+		if by == "" {
+			by = "unknown"
+		}
+		return fmt.Sprintf("%s was removed (%s) at %d upvotes", post, strings.ReplaceAll(by, "_", " "), n.Post.Upvotes)
+	case WatcherError:
+		if n.Alert.Resolved {
+			return "resolved: " + n.Alert.Message
+		}
+		return "alert: " + n.Alert.Message
+	}
+
+	return post
+}
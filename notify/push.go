@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+/*
+	push notifications to phones, without a chat service in between:
+
+		NTFY_TOPIC            an ntfy (https://ntfy.sh) topic to publish to, on NTFY_URL (defaults to https://ntfy.sh,
+		                      or a self-hosted server). NTFY_TOKEN is an access token, for protected topics.
+		                      NTFY_<RULE>_CHANNEL publishes a rule's notifications to another topic
+		PUSHOVER_APP_TOKEN    a pushover (https://pushover.net) application's token, sending to PUSHOVER_USER_KEY (a user
+		                      or group key). PUSHOVER_<RULE>_CHANNEL sends a rule's notifications to one of the user's
+		                      devices only
+
+	each is titled with the post's title (or "votewatch alert") and opens the
+	post when tapped. Alerts are sent at a high priority
+*/
+
+// where pushover's api is, a variable so that it can be pointed somewhere else
+var pushoverAPI = "https://api.pushover.net/1/messages.json"
+
+// ntfy's tags (shown as emoji) by kind, see https://docs.ntfy.sh/emojis/
+var ntfyTags = map[Kind]string{
+	UpvotesReached:  "arrow_up",
+	VelocityReached: "chart_with_upwards_trend",
+	PostRemoved:     "wastebasket",
+	WatcherError:    "warning",
+}
+
+type ntfySink struct {
+	server string
+	topic  string
+	token  string
+	client *http.Client
+}
+
+// reads NTFY_URL, NTFY_TOPIC and NTFY_TOKEN. nil if there's no topic
+func newNtfySink() (Sink, error) {
+	topic := strings.TrimSpace(os.Getenv("NTFY_TOPIC"))
+	if topic == "" {
+		return nil, nil
+	}
+
+	server := strings.TrimSpace(os.Getenv("NTFY_URL"))
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	if !strings.HasPrefix(server, "https://") && !strings.HasPrefix(server, "http://") {
+		return nil, errors.New("NTFY_URL should be a http(s) url, eg: https://ntfy.sh")
+	}
+
+	return &ntfySink{
+		server: strings.TrimSuffix(server, "/"),
+		topic:  topic,
+		token:  strings.TrimSpace(os.Getenv("NTFY_TOKEN")),
+		client: &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// published as json, see https://docs.ntfy.sh/publish/#publish-as-json
+func (s *ntfySink) Send(ctx context.Context, n Notification) error {
+	title, message := pushTextOf(n)
+	topic := s.topic
+	if n.Channel != "" {
+		topic = n.Channel
+	}
+
+	priority := 3
+	if n.Kind == WatcherError && !n.Alert.Resolved {
+		priority = 4
+	}
+	fields := map[string]interface{}{
+		"topic":    topic,
+		"title":    title,
+		"message":  message,
+		"tags":     []string{ntfyTags[n.Kind]},
+		"priority": priority,
+	}
+	if n.Kind != WatcherError {
+		fields["click"] = n.Link()
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.server, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		request.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+type pushoverSink struct {
+	token  string
+	user   string
+	client *http.Client
+}
+
+// reads PUSHOVER_APP_TOKEN and PUSHOVER_USER_KEY. nil if there's no token
+func newPushoverSink() (Sink, error) {
+	token := strings.TrimSpace(os.Getenv("PUSHOVER_APP_TOKEN"))
+	if token == "" {
+		return nil, nil
+	}
+	user := strings.TrimSpace(os.Getenv("PUSHOVER_USER_KEY"))
+	if user == "" {
+		return nil, errors.New("PUSHOVER_USER_KEY should be the user or group key to send notifications to")
+	}
+
+	return &pushoverSink{token: token, user: user, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// see https://pushover.net/api
+func (s *pushoverSink) Send(ctx context.Context, n Notification) error {
+	title, message := pushTextOf(n)
+	fields := map[string]interface{}{
+		"token":    s.token,
+		"user":     s.user,
+		"title":    title,
+		"message":  message,
+		"priority": 0,
+	}
+	if n.Kind == WatcherError && !n.Alert.Resolved {
+		fields["priority"] = 1
+	}
+	if n.Kind != WatcherError {
+		fields["url"] = n.Link()
+		fields["url_title"] = "open on reddit"
+	}
+	if n.Channel != "" {
+		fields["device"] = n.Channel
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, pushoverAPI, body)
+}
+
+// the title and body of a push notification. Both services want a message, so there's always one
+func pushTextOf(n Notification) (string, string) {
+	if n.Kind == WatcherError {
+		title := "votewatch alert"
+		if n.Alert.Resolved {
+			title = "votewatch alert resolved"
+		}
+		return title, n.Text
+	}
+
+	var message strings.Builder
+	if n.Mention != "" {
+		message.WriteString(n.Mention + " ")
+	}
+	if n.Text != "" {
+		message.WriteString(n.Text + "\n")
+	}
+	fmt.Fprintf(&message, "r/%s · %d upvotes · %d comments · %.1f upvotes an hour", n.Post.Subreddit, n.Post.Upvotes, n.Post.Comments, n.Velocity)
+	return n.Post.Title, message.String()
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+	notifications posted to slack through an incoming webhook
+	(https://api.slack.com/messaging/webhooks), set with SLACK_WEBHOOK_URL.
+	Each is a Block Kit message with the post's title and link, the text from
+	SLACK_TEMPLATE, the post's subreddit, score, comments and age, and a chart
+	of its score so far (an image too, with API_PUBLIC_URL set). SLACK_<RULE>_CHANNEL posts a rule's notifications in
+	another channel (only for webhooks that allow it, like the legacy ones) and
+	SLACK_<RULE>_MENTION pings someone with them (eg: <@U024BE7LH>, <!here>)
+*/
+
+type slackSink struct {
+	webhook string
+	client  *http.Client
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+	ImageUrl string      `json:"image_url,omitempty"` // image blocks
+	AltText  string      `json:"alt_text,omitempty"`
+}
+
+// reads SLACK_WEBHOOK_URL. nil if it isn't set
+func newSlackSink() (Sink, error) {
+	webhook := strings.TrimSpace(os.Getenv("SLACK_WEBHOOK_URL"))
+	if webhook == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(webhook, "https://") && !strings.HasPrefix(webhook, "http://") {
+		return nil, errors.New("SLACK_WEBHOOK_URL should be a http(s) url")
+	}
+
+	return &slackSink{webhook: webhook, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+func (s *slackSink) Send(ctx context.Context, n Notification) error {
+	message := map[string]interface{}{
+		"text":   strings.TrimSpace(n.Mention + " " + slackEscape(n.Summary())), // shown in the notification itself, and where blocks aren't
+		"blocks": slackBlocksOf(n),
+	}
+	if n.Channel != "" {
+		message["channel"] = n.Channel
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.webhook, body)
+}
+
+func slackBlocksOf(n Notification) []slackBlock {
+	mrkdwn := func(text string) slackText {
+		return slackText{Type: "mrkdwn", Text: text}
+	}
+	heading := func(title string) slackBlock {
+		text := strings.TrimSpace(n.Mention + " *" + title + "*")
+		if n.Text != "" {
+			text += "\n" + slackEscape(n.Text)
+		}
+		return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}
+	}
+
+	if n.Kind == WatcherError {
+		title := "votewatch alert"
+		if n.Alert.Resolved {
+			title = "votewatch alert resolved"
+		}
+		blocks := []slackBlock{heading(title)}
+		if n.Alert.Job != "" {
+			blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{mrkdwn("job: " + slackEscape(n.Alert.Job))}})
+		}
+		return blocks
+	}
+
+	age := time.Duration(0)
+	if n.Post.QueryDate > n.Post.Date {
+		age = (time.Duration(n.Post.QueryDate-n.Post.Date) * time.Second).Round(time.Minute)
+	}
+
+	blocks := []slackBlock{
+		heading(fmt.Sprintf("<%s|%s>", n.Link(), slackEscape(n.Post.Title))),
+		{Type: "section", Fields: []slackText{
+			mrkdwn("*Subreddit*\nr/" + n.Post.Subreddit),
+			mrkdwn(fmt.Sprintf("*Upvotes*\n%d", n.Post.Upvotes)),
+			mrkdwn(fmt.Sprintf("*Comments*\n%d", n.Post.Comments)),
+			mrkdwn(fmt.Sprintf("*Age*\n%s (%.1f upvotes an hour)", shortDuration(age), n.Velocity)),
+		}},
+	}
+	if n.Chart != "" {
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{mrkdwn(n.Chart)}})
+	}
+	if n.ChartURL != "" {
+		blocks = append(blocks, slackBlock{Type: "image", ImageUrl: n.ChartURL, AltText: "score over time"})
+	}
+	return blocks
+}
+
+// slack's mrkdwn only needs &, < and > escaped, see https://api.slack.com/reference/surfaces/formatting#escaping
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,344 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/jtyrmn/reddit-votewatch/version"
+)
+
+/*
+	notifications sent to a telegram chat by a bot (https://core.telegram.org/bots),
+	with TELEGRAM_BOT_TOKEN from @BotFather and TELEGRAM_CHAT_ID, the chat to
+	send them to. TELEGRAM_<RULE>_CHANNEL sends a rule's notifications to
+	another chat id. The bot also answers a few commands in TELEGRAM_CHAT_ID
+	(and nowhere else):
+
+		/top [n]               the n (5 by default) most upvoted tracked posts
+		/status                how many posts are tracked, when data was last written and which jobs are failing
+		/track <subreddit>     starts tracking another subreddit, until the program restarts (see Scheduler.Track)
+
+	set TELEGRAM_COMMANDS=false to only send notifications
+*/
+
+// where the bot api is, a variable so that it can be pointed somewhere else
+var telegramAPI = "https://api.telegram.org"
+
+const (
+	// how long getUpdates waits for a message before returning empty
+	telegramPollTimeout = 50 * time.Second
+	// how long to wait before polling again after an error
+	telegramRetryDelay = 10 * time.Second
+
+	defaultTopPosts = 5
+	maxTopPosts     = 20
+)
+
+type telegramSink struct {
+	token  string
+	chat   string
+	client *http.Client
+}
+
+// reads TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID. nil if there's no token
+func newTelegramSink() (Sink, error) {
+	sink, err := telegramFromEnv()
+	if sink == nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func telegramFromEnv() (*telegramSink, error) {
+	token := strings.TrimSpace(os.Getenv("TELEGRAM_BOT_TOKEN"))
+	if token == "" {
+		return nil, nil
+	}
+	chat := strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID"))
+	if chat == "" {
+		return nil, errors.New("TELEGRAM_CHAT_ID should be the chat the bot sends to, see notify/telegram.go")
+	}
+
+	return &telegramSink{token: token, chat: chat, client: &http.Client{Timeout: telegramPollTimeout + sendTimeout}}, nil
+}
+
+func (s *telegramSink) Send(ctx context.Context, n Notification) error {
+	chat := s.chat
+	if n.Channel != "" {
+		chat = n.Channel
+	}
+	return s.sendMessage(ctx, chat, telegramMessageOf(n))
+}
+
+// text in telegram's html (https://core.telegram.org/bots/api#html-style)
+func (s *telegramSink) sendMessage(ctx context.Context, chat string, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  chat,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.redact(postJSON(ctx, s.client, s.method("sendMessage"), body))
+}
+
+func (s *telegramSink) method(name string) string {
+	return telegramAPI + "/bot" + s.token + "/" + name
+}
+
+// the token is part of every url, and http errors include the url
+func (s *telegramSink) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), s.token, "<TELEGRAM_BOT_TOKEN>"))
+}
+
+func telegramMessageOf(n Notification) string {
+	var message strings.Builder
+	if n.Mention != "" {
+		message.WriteString(html.EscapeString(n.Mention) + " ")
+	}
+
+	if n.Kind == WatcherError {
+		title := "votewatch alert"
+		if n.Alert.Resolved {
+			title = "votewatch alert resolved"
+		}
+		fmt.Fprintf(&message, "<b>%s</b>\n%s", title, html.EscapeString(n.Text))
+		return message.String()
+	}
+
+	fmt.Fprintf(&message, "<b><a href=\"%s\">%s</a></b>\n", n.Link(), html.EscapeString(n.Post.Title))
+	if n.Text != "" {
+		message.WriteString(html.EscapeString(n.Text) + "\n")
+	}
+	fmt.Fprintf(&message, "r/%s · %d upvotes · %d comments · %.1f upvotes an hour", html.EscapeString(n.Post.Subreddit), n.Post.Upvotes, n.Post.Comments, n.Velocity)
+	if n.Chart != "" {
+		fmt.Fprintf(&message, "\n<code>%s</code>", html.EscapeString(n.Chart))
+	}
+	return message.String()
+}
+
+// the tracked posts, for /top and /status
+type trackedSource interface {
+	GetTrackedPosts() reddit.ContentGroup
+	SubredditRefreshPeriods() map[string]uint64
+}
+
+// the scheduler, for /status and /track
+type trackingControl interface {
+	Track(subreddit string) (bool, error)
+	Status() scheduler.Status
+}
+
+type telegramBot struct {
+	sink    *telegramSink
+	tracked trackedSource
+	control trackingControl
+}
+
+// answers the commands described at the top of this file until ctx is done, if TELEGRAM_BOT_TOKEN is set. Doesn't block
+func ListenTelegram(ctx context.Context, tracked trackedSource, control trackingControl) {
+	sink, err := telegramFromEnv()
+	if sink == nil || err != nil {
+		return
+	}
+	if enabled, err := util.GetEnvBool("TELEGRAM_COMMANDS", true); err != nil || !enabled {
+		return
+	}
+
+	bot := &telegramBot{sink: sink, tracked: tracked, control: control}
+	go bot.poll(ctx)
+}
+
+type telegramUpdate struct {
+	UpdateId int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			Id int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+func (b *telegramBot) poll(ctx context.Context) {
+	offset := int64(0)
+	for ctx.Err() == nil {
+		updates, err := b.getUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("error getting telegram commands:\n" + err.Error())
+			}
+			select {
+			case <-time.After(telegramRetryDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateId + 1
+			if update.Message == nil || strconv.FormatInt(update.Message.Chat.Id, 10) != b.sink.chat {
+				continue
+			}
+
+			reply := b.answer(update.Message.Text)
+			if reply == "" {
+				continue
+			}
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			err := b.sink.sendMessage(sendCtx, b.sink.chat, reply)
+			cancel()
+			if err != nil {
+				log.Println("error answering telegram command:\n" + err.Error())
+			}
+		}
+	}
+}
+
+// long polls for messages sent to the bot, see https://core.telegram.org/bots/api#getupdates
+func (b *telegramBot) getUpdates(ctx context.Context, offset int64) ([]telegramUpdate, error) {
+	query := url.Values{}
+	query.Set("timeout", strconv.Itoa(int(telegramPollTimeout.Seconds())))
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("allowed_updates", `["message"]`)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, b.sink.method("getUpdates")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := b.sink.client.Do(request)
+	if err != nil {
+		return nil, b.sink.redact(err)
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		Ok          bool             `json:"ok"`
+		Description string           `json:"description"`
+		Result      []telegramUpdate `json:"result"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("%s, error parsing response:\n%s", response.Status, err)
+	}
+	if !result.Ok {
+		return nil, fmt.Errorf("%s: %s", response.Status, result.Description)
+	}
+	return result.Result, nil
+}
+
+// the reply to a message, in telegram's html. Empty for messages that aren't commands
+func (b *telegramBot) answer(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+
+	//in groups, commands can be addressed to a bot: /top@votewatch_bot
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := fields[1:]
+
+	switch command {
+	case "/top":
+		count := defaultTopPosts
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return "usage: /top [number of posts]"
+			}
+			count = n
+		}
+		if count > maxTopPosts {
+			count = maxTopPosts
+		}
+		return b.top(count)
+	case "/status":
+		return b.status()
+	case "/track":
+		if len(args) != 1 {
+			return "usage: /track &lt;subreddit&gt;"
+		}
+		name := "r/" + html.EscapeString(strings.TrimPrefix(args[0], "r/"))
+		added, err := b.control.Track(args[0])
+		switch {
+		case err != nil:
+			return html.EscapeString(err.Error())
+		case !added:
+			return name + " is already tracked"
+		}
+		return "now tracking " + name + " until votewatch restarts, add it to the subreddits file to keep it"
+	}
+
+	return "commands:\n/top [n] - the most upvoted tracked posts\n/status - how the watcher is doing\n/track &lt;subreddit&gt; - start tracking a subreddit"
+}
+
+func (b *telegramBot) top(count int) string {
+	posts := make([]reddit.RedditContent, 0)
+	for _, post := range b.tracked.GetTrackedPosts() {
+		posts = append(posts, post)
+	}
+	if len(posts) == 0 {
+		return "no posts are tracked yet"
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].Upvotes != posts[j].Upvotes {
+			return posts[i].Upvotes > posts[j].Upvotes
+		}
+		return posts[i].Id < posts[j].Id
+	})
+	if len(posts) > count {
+		posts = posts[:count]
+	}
+
+	var message strings.Builder
+	for idx, post := range posts {
+		fmt.Fprintf(&message, "%d. <b>%d</b> r/%s: <a href=\"https://redd.it/%s\">%s</a>\n",
+			idx+1, post.Upvotes, html.EscapeString(post.Subreddit), url.PathEscape(post.Id), html.EscapeString(post.Title))
+	}
+	return message.String()
+}
+
+func (b *telegramBot) status() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "<b>%s</b>\n", html.EscapeString(version.String()))
+	fmt.Fprintf(&message, "%d posts tracked in %d subreddits\n", len(b.tracked.GetTrackedPosts()), len(b.tracked.SubredditRefreshPeriods()))
+
+	status := b.control.Status()
+	if status.LastWrite.IsZero() {
+		message.WriteString("nothing written to the database yet\n")
+	} else {
+		fmt.Fprintf(&message, "last wrote to the database %s ago\n", time.Since(status.LastWrite).Round(time.Second))
+	}
+
+	failing := 0
+	for _, job := range status.Jobs {
+		if job.Failures == 0 {
+			continue
+		}
+		failing += 1
+		fmt.Fprintf(&message, "%s failed %d times in a row\n", html.EscapeString(job.Name), job.Failures)
+	}
+	if failing == 0 {
+		fmt.Fprintf(&message, "all %d jobs are fine\n", len(status.Jobs))
+	}
+	return message.String()
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// posting json to the sinks' webhooks
+
+// posts body to a webhook. If it's rate limited, it's retried once after as long as the service asks for (in a json
+// retry_after or a Retry-After header, in seconds)
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) error {
+	retryAfter, err := postOnce(ctx, client, url, body)
+	if err != nil || retryAfter == 0 {
+		return err
+	}
+
+	select {
+	case <-time.After(retryAfter):
+	case <-ctx.Done():
+		return errors.New("rate limited, gave up waiting")
+	}
+	_, err = postOnce(ctx, client, url, body)
+	return err
+}
+
+// posts to a webhook, returning how long to wait if it was rate limited
+func postOnce(ctx context.Context, client *http.Client, url string, body []byte) (time.Duration, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("content-type", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.NewDecoder(response.Body).Decode(&limited)
+		if limited.RetryAfter <= 0 {
+			limited.RetryAfter, _ = strconv.ParseFloat(response.Header.Get("Retry-After"), 64)
+		}
+		if limited.RetryAfter <= 0 {
+			limited.RetryAfter = 1
+		}
+		return time.Duration(limited.RetryAfter * float64(time.Second)), nil
+	}
+
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return 0, fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return 0, nil
+}
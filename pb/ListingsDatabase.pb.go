@@ -25,6 +25,55 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ListingsSort int32
+
+const (
+	ListingsSort_UNSORTED     ListingsSort = 0
+	ListingsSort_NEWEST_FIRST ListingsSort = 1 // by date created
+	ListingsSort_OLDEST_FIRST ListingsSort = 2
+)
+
+// Enum value maps for ListingsSort.
+var (
+	ListingsSort_name = map[int32]string{
+		0: "UNSORTED",
+		1: "NEWEST_FIRST",
+		2: "OLDEST_FIRST",
+	}
+	ListingsSort_value = map[string]int32{
+		"UNSORTED":     0,
+		"NEWEST_FIRST": 1,
+		"OLDEST_FIRST": 2,
+	}
+)
+
+func (x ListingsSort) Enum() *ListingsSort {
+	p := new(ListingsSort)
+	*p = x
+	return p
+}
+
+func (x ListingsSort) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ListingsSort) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_proto_ListingsDatabase_proto_enumTypes[0].Descriptor()
+}
+
+func (ListingsSort) Type() protoreflect.EnumType {
+	return &file_pb_proto_ListingsDatabase_proto_enumTypes[0]
+}
+
+func (x ListingsSort) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ListingsSort.Descriptor instead.
+func (ListingsSort) EnumDescriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{0}
+}
+
 // A listing object that's stored in + returned from the database.
 type RedditContent struct {
 	state         protoimpl.MessageState
@@ -89,6 +138,108 @@ func (x *RedditContent) GetEntries() []*RedditContent_ListingEntry {
 	return nil
 }
 
+type VersionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClientVersion uint32 `protobuf:"varint,1,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"` // schema version the client was built against
+}
+
+func (x *VersionRequest) Reset() {
+	*x = VersionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionRequest) ProtoMessage() {}
+
+func (x *VersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionRequest.ProtoReflect.Descriptor instead.
+func (*VersionRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VersionRequest) GetClientVersion() uint32 {
+	if x != nil {
+		return x.ClientVersion
+	}
+	return 0
+}
+
+type VersionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SchemaVersion    uint32 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`            // schema version the service was built against
+	MinClientVersion uint32 `protobuf:"varint,2,opt,name=min_client_version,json=minClientVersion,proto3" json:"min_client_version,omitempty"` // oldest client schema version the service still supports
+}
+
+func (x *VersionResponse) Reset() {
+	*x = VersionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionResponse) ProtoMessage() {}
+
+func (x *VersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionResponse.ProtoReflect.Descriptor instead.
+func (*VersionResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VersionResponse) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *VersionResponse) GetMinClientVersion() uint32 {
+	if x != nil {
+		return x.MinClientVersion
+	}
+	return 0
+}
+
 type SaveListingsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -98,7 +249,7 @@ type SaveListingsResponse struct {
 func (x *SaveListingsResponse) Reset() {
 	*x = SaveListingsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[1]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -111,7 +262,7 @@ func (x *SaveListingsResponse) String() string {
 func (*SaveListingsResponse) ProtoMessage() {}
 
 func (x *SaveListingsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[1]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -124,7 +275,7 @@ func (x *SaveListingsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SaveListingsResponse.ProtoReflect.Descriptor instead.
 func (*SaveListingsResponse) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{1}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{3}
 }
 
 type UpdateListingsResponse struct {
@@ -136,7 +287,7 @@ type UpdateListingsResponse struct {
 func (x *UpdateListingsResponse) Reset() {
 	*x = UpdateListingsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[2]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -149,7 +300,7 @@ func (x *UpdateListingsResponse) String() string {
 func (*UpdateListingsResponse) ProtoMessage() {}
 
 func (x *UpdateListingsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[2]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -162,7 +313,45 @@ func (x *UpdateListingsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateListingsResponse.ProtoReflect.Descriptor instead.
 func (*UpdateListingsResponse) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{2}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{4}
+}
+
+type ReplaceListingsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReplaceListingsResponse) Reset() {
+	*x = ReplaceListingsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplaceListingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplaceListingsResponse) ProtoMessage() {}
+
+func (x *ReplaceListingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplaceListingsResponse.ProtoReflect.Descriptor instead.
+func (*ReplaceListingsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{5}
 }
 
 type CullListingsRequest struct {
@@ -170,13 +359,14 @@ type CullListingsRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	MaxAge uint64 `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"` // max age is in seconds
+	MaxAge  uint64 `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"` // max age is in seconds
+	Archive bool   `protobuf:"varint,2,opt,name=archive,proto3" json:"archive,omitempty"`             // mark the listings as archived instead of deleting them
 }
 
 func (x *CullListingsRequest) Reset() {
 	*x = CullListingsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[3]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -189,7 +379,7 @@ func (x *CullListingsRequest) String() string {
 func (*CullListingsRequest) ProtoMessage() {}
 
 func (x *CullListingsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[3]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -202,7 +392,7 @@ func (x *CullListingsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CullListingsRequest.ProtoReflect.Descriptor instead.
 func (*CullListingsRequest) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{3}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *CullListingsRequest) GetMaxAge() uint64 {
@@ -212,18 +402,25 @@ func (x *CullListingsRequest) GetMaxAge() uint64 {
 	return 0
 }
 
+func (x *CullListingsRequest) GetArchive() bool {
+	if x != nil {
+		return x.Archive
+	}
+	return false
+}
+
 type CullListingsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NumDeleted uint32 `protobuf:"varint,1,opt,name=num_deleted,json=numDeleted,proto3" json:"num_deleted,omitempty"`
+	NumDeleted uint32 `protobuf:"varint,1,opt,name=num_deleted,json=numDeleted,proto3" json:"num_deleted,omitempty"` // or # archived
 }
 
 func (x *CullListingsResponse) Reset() {
 	*x = CullListingsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[4]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -236,7 +433,7 @@ func (x *CullListingsResponse) String() string {
 func (*CullListingsResponse) ProtoMessage() {}
 
 func (x *CullListingsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[4]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -249,7 +446,7 @@ func (x *CullListingsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CullListingsResponse.ProtoReflect.Descriptor instead.
 func (*CullListingsResponse) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{4}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *CullListingsResponse) GetNumDeleted() uint32 {
@@ -259,6 +456,194 @@ func (x *CullListingsResponse) GetNumDeleted() uint32 {
 	return 0
 }
 
+type PurgeListingsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MaxAge uint64 `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"` // only archived listings older than this (in seconds) are deleted. 0 deletes all of them
+}
+
+func (x *PurgeListingsRequest) Reset() {
+	*x = PurgeListingsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PurgeListingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeListingsRequest) ProtoMessage() {}
+
+func (x *PurgeListingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeListingsRequest.ProtoReflect.Descriptor instead.
+func (*PurgeListingsRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PurgeListingsRequest) GetMaxAge() uint64 {
+	if x != nil {
+		return x.MaxAge
+	}
+	return 0
+}
+
+type PurgeListingsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NumDeleted uint32 `protobuf:"varint,1,opt,name=num_deleted,json=numDeleted,proto3" json:"num_deleted,omitempty"`
+}
+
+func (x *PurgeListingsResponse) Reset() {
+	*x = PurgeListingsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PurgeListingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeListingsResponse) ProtoMessage() {}
+
+func (x *PurgeListingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeListingsResponse.ProtoReflect.Descriptor instead.
+func (*PurgeListingsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PurgeListingsResponse) GetNumDeleted() uint32 {
+	if x != nil {
+		return x.NumDeleted
+	}
+	return 0
+}
+
+type DeleteListingsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"` // fullnames, eg: t3_abcdef
+}
+
+func (x *DeleteListingsRequest) Reset() {
+	*x = DeleteListingsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteListingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteListingsRequest) ProtoMessage() {}
+
+func (x *DeleteListingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteListingsRequest.ProtoReflect.Descriptor instead.
+func (*DeleteListingsRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteListingsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type DeleteListingsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NumDeleted uint32 `protobuf:"varint,1,opt,name=num_deleted,json=numDeleted,proto3" json:"num_deleted,omitempty"`
+}
+
+func (x *DeleteListingsResponse) Reset() {
+	*x = DeleteListingsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteListingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteListingsResponse) ProtoMessage() {}
+
+func (x *DeleteListingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteListingsResponse.ProtoReflect.Descriptor instead.
+func (*DeleteListingsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteListingsResponse) GetNumDeleted() uint32 {
+	if x != nil {
+		return x.NumDeleted
+	}
+	return 0
+}
+
 type ManyListingsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -271,7 +656,7 @@ type ManyListingsRequest struct {
 func (x *ManyListingsRequest) Reset() {
 	*x = ManyListingsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[5]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -284,7 +669,7 @@ func (x *ManyListingsRequest) String() string {
 func (*ManyListingsRequest) ProtoMessage() {}
 
 func (x *ManyListingsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[5]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -297,48 +682,237 @@ func (x *ManyListingsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ManyListingsRequest.ProtoReflect.Descriptor instead.
 func (*ManyListingsRequest) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{5}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ManyListingsRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ManyListingsRequest) GetSkip() uint32 {
+	if x != nil {
+		return x.Skip
+	}
+	return 0
+}
+
+type ManyListingsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Listings []*RedditContent `protobuf:"bytes,1,rep,name=listings,proto3" json:"listings,omitempty"`
+}
+
+func (x *ManyListingsResponse) Reset() {
+	*x = ManyListingsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ManyListingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManyListingsResponse) ProtoMessage() {}
+
+func (x *ManyListingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManyListingsResponse.ProtoReflect.Descriptor instead.
+func (*ManyListingsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ManyListingsResponse) GetListings() []*RedditContent {
+	if x != nil {
+		return x.Listings
+	}
+	return nil
+}
+
+type FetchListingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *FetchListingRequest) Reset() {
+	*x = FetchListingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FetchListingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchListingRequest) ProtoMessage() {}
+
+func (x *FetchListingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchListingRequest.ProtoReflect.Descriptor instead.
+func (*FetchListingRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FetchListingRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetHistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetHistoryRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetHistoryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*RedditContent_ListingEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *ManyListingsRequest) GetLimit() uint32 {
-	if x != nil {
-		return x.Limit
-	}
-	return 0
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *ManyListingsRequest) GetSkip() uint32 {
+func (x *GetHistoryResponse) GetEntries() []*RedditContent_ListingEntry {
 	if x != nil {
-		return x.Skip
+		return x.Entries
 	}
-	return 0
+	return nil
 }
 
-type ManyListingsResponse struct {
+type CompactHistoryRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Listings []*RedditContent `protobuf:"bytes,1,rep,name=listings,proto3" json:"listings,omitempty"`
+	MaxAge     uint64 `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"` // in seconds
+	Resolution uint64 `protobuf:"varint,2,opt,name=resolution,proto3" json:"resolution,omitempty"`       // in seconds
 }
 
-func (x *ManyListingsResponse) Reset() {
-	*x = ManyListingsResponse{}
+func (x *CompactHistoryRequest) Reset() {
+	*x = CompactHistoryRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[6]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ManyListingsResponse) String() string {
+func (x *CompactHistoryRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ManyListingsResponse) ProtoMessage() {}
+func (*CompactHistoryRequest) ProtoMessage() {}
 
-func (x *ManyListingsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[6]
+func (x *CompactHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -349,43 +923,50 @@ func (x *ManyListingsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ManyListingsResponse.ProtoReflect.Descriptor instead.
-func (*ManyListingsResponse) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use CompactHistoryRequest.ProtoReflect.Descriptor instead.
+func (*CompactHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *ManyListingsResponse) GetListings() []*RedditContent {
+func (x *CompactHistoryRequest) GetMaxAge() uint64 {
 	if x != nil {
-		return x.Listings
+		return x.MaxAge
 	}
-	return nil
+	return 0
 }
 
-type FetchListingRequest struct {
+func (x *CompactHistoryRequest) GetResolution() uint64 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+type CompactHistoryResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NumRemoved uint32 `protobuf:"varint,1,opt,name=num_removed,json=numRemoved,proto3" json:"num_removed,omitempty"` // # of entries removed across all listings
 }
 
-func (x *FetchListingRequest) Reset() {
-	*x = FetchListingRequest{}
+func (x *CompactHistoryResponse) Reset() {
+	*x = CompactHistoryResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[7]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *FetchListingRequest) String() string {
+func (x *CompactHistoryResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FetchListingRequest) ProtoMessage() {}
+func (*CompactHistoryResponse) ProtoMessage() {}
 
-func (x *FetchListingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[7]
+func (x *CompactHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -396,16 +977,16 @@ func (x *FetchListingRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FetchListingRequest.ProtoReflect.Descriptor instead.
-func (*FetchListingRequest) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use CompactHistoryResponse.ProtoReflect.Descriptor instead.
+func (*CompactHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *FetchListingRequest) GetId() string {
+func (x *CompactHistoryResponse) GetNumRemoved() uint32 {
 	if x != nil {
-		return x.Id
+		return x.NumRemoved
 	}
-	return ""
+	return 0
 }
 
 type RetrieveListingsRequest struct {
@@ -413,13 +994,19 @@ type RetrieveListingsRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	MaxAge uint64 `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	MaxAge         uint64       `protobuf:"varint,1,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	Subreddit      string       `protobuf:"bytes,2,opt,name=subreddit,proto3" json:"subreddit,omitempty"` // only listings from this subreddit, if set
+	Limit          uint32       `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`        // max # of listings in this page. 0 means no limit
+	Cursor         string       `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"`       // the next-cursor trailer of the previous page. Empty for the first page
+	Sort           ListingsSort `protobuf:"varint,5,opt,name=sort,proto3,enum=ListingsSort" json:"sort,omitempty"`
+	IncludeEntries bool         `protobuf:"varint,6,opt,name=include_entries,json=includeEntries,proto3" json:"include_entries,omitempty"` // listings are sent without their entries unless this is set
+	MinAge         uint64       `protobuf:"varint,7,opt,name=min_age,json=minAge,proto3" json:"min_age,omitempty"`                         // only listings at least this old (in seconds), if set
 }
 
 func (x *RetrieveListingsRequest) Reset() {
 	*x = RetrieveListingsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[8]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -432,7 +1019,7 @@ func (x *RetrieveListingsRequest) String() string {
 func (*RetrieveListingsRequest) ProtoMessage() {}
 
 func (x *RetrieveListingsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[8]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -445,7 +1032,7 @@ func (x *RetrieveListingsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RetrieveListingsRequest.ProtoReflect.Descriptor instead.
 func (*RetrieveListingsRequest) Descriptor() ([]byte, []int) {
-	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{8}
+	return file_pb_proto_ListingsDatabase_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *RetrieveListingsRequest) GetMaxAge() uint64 {
@@ -455,6 +1042,48 @@ func (x *RetrieveListingsRequest) GetMaxAge() uint64 {
 	return 0
 }
 
+func (x *RetrieveListingsRequest) GetSubreddit() string {
+	if x != nil {
+		return x.Subreddit
+	}
+	return ""
+}
+
+func (x *RetrieveListingsRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *RetrieveListingsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *RetrieveListingsRequest) GetSort() ListingsSort {
+	if x != nil {
+		return x.Sort
+	}
+	return ListingsSort_UNSORTED
+}
+
+func (x *RetrieveListingsRequest) GetIncludeEntries() bool {
+	if x != nil {
+		return x.IncludeEntries
+	}
+	return false
+}
+
+func (x *RetrieveListingsRequest) GetMinAge() uint64 {
+	if x != nil {
+		return x.MinAge
+	}
+	return 0
+}
+
 type RedditContent_MetaData struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -467,12 +1096,18 @@ type RedditContent_MetaData struct {
 	Comments    uint32 `protobuf:"varint,5,opt,name=comments,proto3" json:"comments,omitempty"`
 	DateCreated uint64 `protobuf:"varint,6,opt,name=date_created,json=date,proto3" json:"date_created,omitempty"`
 	DateQueried uint64 `protobuf:"varint,7,opt,name=date_queried,json=querydate,proto3" json:"date_queried,omitempty"`
+	Subreddit   string `protobuf:"bytes,8,opt,name=subreddit,proto3" json:"subreddit,omitempty"` // without the r/
+	Author      string `protobuf:"bytes,9,opt,name=author,proto3" json:"author,omitempty"`       // without the u/
+	Url         string `protobuf:"bytes,10,opt,name=url,proto3" json:"url,omitempty"`
+	// gained an hour since the update before, recorded with the entry each update adds
+	UpvotesPerHour  float64 `protobuf:"fixed64,11,opt,name=upvotes_per_hour,json=upvotesPerHour,proto3" json:"upvotes_per_hour,omitempty"`
+	CommentsPerHour float64 `protobuf:"fixed64,12,opt,name=comments_per_hour,json=commentsPerHour,proto3" json:"comments_per_hour,omitempty"`
 }
 
 func (x *RedditContent_MetaData) Reset() {
 	*x = RedditContent_MetaData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[9]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -485,7 +1120,7 @@ func (x *RedditContent_MetaData) String() string {
 func (*RedditContent_MetaData) ProtoMessage() {}
 
 func (x *RedditContent_MetaData) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[9]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -550,20 +1185,57 @@ func (x *RedditContent_MetaData) GetDateQueried() uint64 {
 	return 0
 }
 
+func (x *RedditContent_MetaData) GetSubreddit() string {
+	if x != nil {
+		return x.Subreddit
+	}
+	return ""
+}
+
+func (x *RedditContent_MetaData) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *RedditContent_MetaData) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *RedditContent_MetaData) GetUpvotesPerHour() float64 {
+	if x != nil {
+		return x.UpvotesPerHour
+	}
+	return 0
+}
+
+func (x *RedditContent_MetaData) GetCommentsPerHour() float64 {
+	if x != nil {
+		return x.CommentsPerHour
+	}
+	return 0
+}
+
 type RedditContent_ListingEntry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upvotes     uint32 `protobuf:"varint,1,opt,name=upvotes,proto3" json:"upvotes,omitempty"`
-	Comments    uint32 `protobuf:"varint,2,opt,name=comments,proto3" json:"comments,omitempty"`
-	DateQueried uint64 `protobuf:"varint,3,opt,name=date_queried,json=date,proto3" json:"date_queried,omitempty"`
+	Upvotes         uint32  `protobuf:"varint,1,opt,name=upvotes,proto3" json:"upvotes,omitempty"`
+	Comments        uint32  `protobuf:"varint,2,opt,name=comments,proto3" json:"comments,omitempty"`
+	DateQueried     uint64  `protobuf:"varint,3,opt,name=date_queried,json=date,proto3" json:"date_queried,omitempty"`
+	UpvotesPerHour  float64 `protobuf:"fixed64,4,opt,name=upvotes_per_hour,json=upvotesPerHour,proto3" json:"upvotes_per_hour,omitempty"` // since the entry before, 0 for entries recorded before schema version 7
+	CommentsPerHour float64 `protobuf:"fixed64,5,opt,name=comments_per_hour,json=commentsPerHour,proto3" json:"comments_per_hour,omitempty"`
 }
 
 func (x *RedditContent_ListingEntry) Reset() {
 	*x = RedditContent_ListingEntry{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[10]
+		mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -576,7 +1248,7 @@ func (x *RedditContent_ListingEntry) String() string {
 func (*RedditContent_ListingEntry) ProtoMessage() {}
 
 func (x *RedditContent_ListingEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[10]
+	mi := &file_pb_proto_ListingsDatabase_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -613,12 +1285,26 @@ func (x *RedditContent_ListingEntry) GetDateQueried() uint64 {
 	return 0
 }
 
+func (x *RedditContent_ListingEntry) GetUpvotesPerHour() float64 {
+	if x != nil {
+		return x.UpvotesPerHour
+	}
+	return 0
+}
+
+func (x *RedditContent_ListingEntry) GetCommentsPerHour() float64 {
+	if x != nil {
+		return x.CommentsPerHour
+	}
+	return 0
+}
+
 var File_pb_proto_ListingsDatabase_proto protoreflect.FileDescriptor
 
 var file_pb_proto_ListingsDatabase_proto_rawDesc = []byte{
 	0x0a, 0x1f, 0x70, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x4c, 0x69, 0x73, 0x74, 0x69,
 	0x6e, 0x67, 0x73, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x22, 0xb7, 0x03, 0x0a, 0x0d, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74,
+	0x6f, 0x22, 0xac, 0x05, 0x0a, 0x0d, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74,
 	0x65, 0x6e, 0x74, 0x12, 0x0f, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x03, 0x5f, 0x69, 0x64, 0x12, 0x33, 0x0a, 0x09, 0x6d, 0x65, 0x74, 0x61, 0x5f, 0x64, 0x61, 0x74,
 	0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74,
@@ -627,7 +1313,7 @@ var file_pb_proto_ListingsDatabase_proto_rawDesc = []byte{
 	0x72, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x52, 0x65, 0x64,
 	0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x69,
 	0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73,
-	0x1a, 0xc6, 0x01, 0x0a, 0x08, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x21, 0x0a,
+	0x1a, 0xe4, 0x02, 0x0a, 0x08, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x21, 0x0a,
 	0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x74, 0x79, 0x70, 0x65,
 	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
@@ -639,62 +1325,159 @@ var file_pb_proto_ListingsDatabase_proto_rawDesc = []byte{
 	0x64, 0x61, 0x74, 0x65, 0x5f, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01,
 	0x28, 0x04, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x1f, 0x0a, 0x0c, 0x64, 0x61, 0x74, 0x65,
 	0x5f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09,
-	0x71, 0x75, 0x65, 0x72, 0x79, 0x64, 0x61, 0x74, 0x65, 0x1a, 0x60, 0x0a, 0x0c, 0x4c, 0x69, 0x73,
-	0x74, 0x69, 0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x75, 0x70, 0x76,
-	0x6f, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x75, 0x70, 0x76, 0x6f,
-	0x74, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12,
-	0x1a, 0x0a, 0x0c, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x64, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x22, 0x16, 0x0a, 0x14, 0x53,
-	0x61, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x22, 0x18, 0x0a, 0x16, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x73,
-	0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x2e, 0x0a,
-	0x13, 0x43, 0x75, 0x6c, 0x6c, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x22, 0x37, 0x0a,
-	0x14, 0x43, 0x75, 0x6c, 0x6c, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f, 0x64, 0x65, 0x6c,
-	0x65, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x44,
-	0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0x3f, 0x0a, 0x13, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69,
-	0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
-	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x69,
-	0x6d, 0x69, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x6b, 0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x04, 0x73, 0x6b, 0x69, 0x70, 0x22, 0x42, 0x0a, 0x14, 0x4d, 0x61, 0x6e, 0x79, 0x4c,
-	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
-	0x2a, 0x0a, 0x08, 0x6c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
-	0x74, 0x52, 0x08, 0x6c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x22, 0x25, 0x0a, 0x13, 0x46,
-	0x65, 0x74, 0x63, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
-	0x69, 0x64, 0x22, 0x32, 0x0a, 0x17, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x76, 0x65, 0x4c, 0x69,
-	0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a,
-	0x07, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06,
-	0x6d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x32, 0x84, 0x03, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x69,
-	0x6e, 0x67, 0x73, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x39, 0x0a, 0x0c, 0x53,
-	0x61, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x0e, 0x2e, 0x52, 0x65,
-	0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x15, 0x2e, 0x53, 0x61,
-	0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3d, 0x0a, 0x0e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
-	0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69,
-	0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x17, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x71, 0x75, 0x65, 0x72, 0x79, 0x64, 0x61, 0x74, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x75, 0x62,
+	0x72, 0x65, 0x64, 0x64, 0x69, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x75,
+	0x62, 0x72, 0x65, 0x64, 0x64, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x75, 0x74, 0x68, 0x6f,
+	0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x12,
+	0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72,
+	0x6c, 0x12, 0x28, 0x0a, 0x10, 0x75, 0x70, 0x76, 0x6f, 0x74, 0x65, 0x73, 0x5f, 0x70, 0x65, 0x72,
+	0x5f, 0x68, 0x6f, 0x75, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0e, 0x75, 0x70, 0x76,
+	0x6f, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x48, 0x6f, 0x75, 0x72, 0x12, 0x2a, 0x0a, 0x11, 0x63,
+	0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x68, 0x6f, 0x75, 0x72,
+	0x18, 0x0c, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x50, 0x65, 0x72, 0x48, 0x6f, 0x75, 0x72, 0x1a, 0xb6, 0x01, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74,
+	0x69, 0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x75, 0x70, 0x76, 0x6f,
+	0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x75, 0x70, 0x76, 0x6f, 0x74,
+	0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a,
+	0x0a, 0x0c, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x75, 0x70,
+	0x76, 0x6f, 0x74, 0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x68, 0x6f, 0x75, 0x72, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x0e, 0x75, 0x70, 0x76, 0x6f, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72,
+	0x48, 0x6f, 0x75, 0x72, 0x12, 0x2a, 0x0a, 0x11, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x5f, 0x70, 0x65, 0x72, 0x5f, 0x68, 0x6f, 0x75, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x50, 0x65, 0x72, 0x48, 0x6f, 0x75, 0x72,
+	0x22, 0x37, 0x0a, 0x0e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x63, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x66, 0x0a, 0x0f, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x0e,
+	0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x12, 0x6d, 0x69, 0x6e, 0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x10, 0x6d, 0x69, 0x6e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x61, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x18, 0x0a, 0x16, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x19, 0x0a, 0x17, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x4c, 0x69,
+	0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x48,
+	0x0a, 0x13, 0x43, 0x75, 0x6c, 0x6c, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x07, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x22, 0x37, 0x0a, 0x14, 0x43, 0x75, 0x6c, 0x6c,
+	0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x64, 0x22, 0x2f, 0x0a, 0x14, 0x50, 0x75, 0x72, 0x67, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78,
+	0x5f, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41,
+	0x67, 0x65, 0x22, 0x38, 0x0a, 0x15, 0x50, 0x75, 0x72, 0x67, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6e,
+	0x75, 0x6d, 0x5f, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0x29, 0x0a, 0x15,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x39, 0x0a, 0x16, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x64, 0x22, 0x3f, 0x0a, 0x13, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x6b, 0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73,
+	0x6b, 0x69, 0x70, 0x22, 0x42, 0x0a, 0x14, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x08, 0x6c,
+	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
+	0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x52, 0x08, 0x6c,
+	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x22, 0x25, 0x0a, 0x13, 0x46, 0x65, 0x74, 0x63, 0x68,
+	0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x23,
+	0x0a, 0x11, 0x47, 0x65, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x64, 0x22, 0x4b, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x07, 0x65, 0x6e, 0x74,
+	0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x52, 0x65, 0x64,
+	0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x22, 0x50, 0x0a, 0x15, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78,
+	0x5f, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41,
+	0x67, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69,
+	0x6f, 0x6e, 0x22, 0x39, 0x0a, 0x16, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x48, 0x69, 0x73,
+	0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x6e, 0x75, 0x6d, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x22, 0xe3, 0x01,
+	0x0a, 0x17, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78,
+	0x5f, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41,
+	0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x75, 0x62, 0x72, 0x65, 0x64, 0x64, 0x69, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x75, 0x62, 0x72, 0x65, 0x64, 0x64, 0x69, 0x74,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x21,
+	0x0a, 0x04, 0x73, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x53, 0x6f, 0x72, 0x74, 0x52, 0x04, 0x73, 0x6f, 0x72,
+	0x74, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x5f, 0x65, 0x6e, 0x74,
+	0x72, 0x69, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x69, 0x6e, 0x63, 0x6c,
+	0x75, 0x64, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x69,
+	0x6e, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x69, 0x6e,
+	0x41, 0x67, 0x65, 0x2a, 0x40, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x53,
+	0x6f, 0x72, 0x74, 0x12, 0x0c, 0x0a, 0x08, 0x55, 0x4e, 0x53, 0x4f, 0x52, 0x54, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x10, 0x0a, 0x0c, 0x4e, 0x45, 0x57, 0x45, 0x53, 0x54, 0x5f, 0x46, 0x49, 0x52, 0x53,
+	0x54, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x4f, 0x4c, 0x44, 0x45, 0x53, 0x54, 0x5f, 0x46, 0x49,
+	0x52, 0x53, 0x54, 0x10, 0x02, 0x32, 0xfa, 0x05, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x2e, 0x0a, 0x07, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0f, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x0c, 0x53, 0x61,
+	0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x0e, 0x2e, 0x52, 0x65, 0x64,
+	0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x15, 0x2e, 0x53, 0x61, 0x76,
+	0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3d, 0x0a, 0x0e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4c,
+	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74,
+	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x17, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x28, 0x01, 0x12, 0x3f, 0x0a, 0x0f, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x4c,
+	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74,
+	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x18, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63,
 	0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
 	0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3d, 0x0a, 0x0c, 0x43, 0x75, 0x6c, 0x6c, 0x4c, 0x69, 0x73,
 	0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x14, 0x2e, 0x43, 0x75, 0x6c, 0x6c, 0x4c, 0x69, 0x73, 0x74,
 	0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x43, 0x75,
 	0x6c, 0x6c, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x0c, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74,
-	0x69, 0x6e, 0x67, 0x73, 0x12, 0x14, 0x2e, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69,
-	0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x4d, 0x61, 0x6e,
-	0x79, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x10, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x76, 0x65, 0x4c,
-	0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x18, 0x2e, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65,
-	0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
-	0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x36, 0x0a, 0x0c, 0x46, 0x65, 0x74, 0x63, 0x68, 0x4c, 0x69,
-	0x73, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x2e, 0x46, 0x65, 0x74, 0x63, 0x68, 0x4c, 0x69, 0x73,
-	0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x52, 0x65,
-	0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x42, 0x07, 0x5a,
-	0x05, 0x2e, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x0d, 0x50, 0x75, 0x72, 0x67, 0x65, 0x4c, 0x69, 0x73,
+	0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x15, 0x2e, 0x50, 0x75, 0x72, 0x67, 0x65, 0x4c, 0x69, 0x73,
+	0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x50,
+	0x75, 0x72, 0x67, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x43, 0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x16, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x17, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x0c, 0x4d,
+	0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x14, 0x2e, 0x4d, 0x61,
+	0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x15, 0x2e, 0x4d, 0x61, 0x6e, 0x79, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x10, 0x52, 0x65,
+	0x74, 0x72, 0x69, 0x65, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x18,
+	0x2e, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x76, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69,
+	0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x36, 0x0a, 0x0c,
+	0x46, 0x65, 0x74, 0x63, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x2e, 0x46,
+	0x65, 0x74, 0x63, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x52, 0x65, 0x64, 0x64, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x65,
+	0x6e, 0x74, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x12, 0x12, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x69, 0x73, 0x74,
+	0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x43, 0x0a,
+	0x0e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12,
+	0x16, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63,
+	0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -709,41 +1492,68 @@ func file_pb_proto_ListingsDatabase_proto_rawDescGZIP() []byte {
 	return file_pb_proto_ListingsDatabase_proto_rawDescData
 }
 
-var file_pb_proto_ListingsDatabase_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_pb_proto_ListingsDatabase_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pb_proto_ListingsDatabase_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_pb_proto_ListingsDatabase_proto_goTypes = []interface{}{
-	(*RedditContent)(nil),              // 0: RedditContent
-	(*SaveListingsResponse)(nil),       // 1: SaveListingsResponse
-	(*UpdateListingsResponse)(nil),     // 2: UpdateListingsResponse
-	(*CullListingsRequest)(nil),        // 3: CullListingsRequest
-	(*CullListingsResponse)(nil),       // 4: CullListingsResponse
-	(*ManyListingsRequest)(nil),        // 5: ManyListingsRequest
-	(*ManyListingsResponse)(nil),       // 6: ManyListingsResponse
-	(*FetchListingRequest)(nil),        // 7: FetchListingRequest
-	(*RetrieveListingsRequest)(nil),    // 8: RetrieveListingsRequest
-	(*RedditContent_MetaData)(nil),     // 9: RedditContent.MetaData
-	(*RedditContent_ListingEntry)(nil), // 10: RedditContent.ListingEntry
+	(ListingsSort)(0),                  // 0: ListingsSort
+	(*RedditContent)(nil),              // 1: RedditContent
+	(*VersionRequest)(nil),             // 2: VersionRequest
+	(*VersionResponse)(nil),            // 3: VersionResponse
+	(*SaveListingsResponse)(nil),       // 4: SaveListingsResponse
+	(*UpdateListingsResponse)(nil),     // 5: UpdateListingsResponse
+	(*ReplaceListingsResponse)(nil),    // 6: ReplaceListingsResponse
+	(*CullListingsRequest)(nil),        // 7: CullListingsRequest
+	(*CullListingsResponse)(nil),       // 8: CullListingsResponse
+	(*PurgeListingsRequest)(nil),       // 9: PurgeListingsRequest
+	(*PurgeListingsResponse)(nil),      // 10: PurgeListingsResponse
+	(*DeleteListingsRequest)(nil),      // 11: DeleteListingsRequest
+	(*DeleteListingsResponse)(nil),     // 12: DeleteListingsResponse
+	(*ManyListingsRequest)(nil),        // 13: ManyListingsRequest
+	(*ManyListingsResponse)(nil),       // 14: ManyListingsResponse
+	(*FetchListingRequest)(nil),        // 15: FetchListingRequest
+	(*GetHistoryRequest)(nil),          // 16: GetHistoryRequest
+	(*GetHistoryResponse)(nil),         // 17: GetHistoryResponse
+	(*CompactHistoryRequest)(nil),      // 18: CompactHistoryRequest
+	(*CompactHistoryResponse)(nil),     // 19: CompactHistoryResponse
+	(*RetrieveListingsRequest)(nil),    // 20: RetrieveListingsRequest
+	(*RedditContent_MetaData)(nil),     // 21: RedditContent.MetaData
+	(*RedditContent_ListingEntry)(nil), // 22: RedditContent.ListingEntry
 }
 var file_pb_proto_ListingsDatabase_proto_depIdxs = []int32{
-	9,  // 0: RedditContent.meta_data:type_name -> RedditContent.MetaData
-	10, // 1: RedditContent.entries:type_name -> RedditContent.ListingEntry
-	0,  // 2: ManyListingsResponse.listings:type_name -> RedditContent
-	0,  // 3: ListingsDatabase.SaveListings:input_type -> RedditContent
-	0,  // 4: ListingsDatabase.UpdateListings:input_type -> RedditContent
-	3,  // 5: ListingsDatabase.CullListings:input_type -> CullListingsRequest
-	5,  // 6: ListingsDatabase.ManyListings:input_type -> ManyListingsRequest
-	8,  // 7: ListingsDatabase.RetrieveListings:input_type -> RetrieveListingsRequest
-	7,  // 8: ListingsDatabase.FetchListing:input_type -> FetchListingRequest
-	1,  // 9: ListingsDatabase.SaveListings:output_type -> SaveListingsResponse
-	2,  // 10: ListingsDatabase.UpdateListings:output_type -> UpdateListingsResponse
-	4,  // 11: ListingsDatabase.CullListings:output_type -> CullListingsResponse
-	6,  // 12: ListingsDatabase.ManyListings:output_type -> ManyListingsResponse
-	0,  // 13: ListingsDatabase.RetrieveListings:output_type -> RedditContent
-	0,  // 14: ListingsDatabase.FetchListing:output_type -> RedditContent
-	9,  // [9:15] is the sub-list for method output_type
-	3,  // [3:9] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	21, // 0: RedditContent.meta_data:type_name -> RedditContent.MetaData
+	22, // 1: RedditContent.entries:type_name -> RedditContent.ListingEntry
+	1,  // 2: ManyListingsResponse.listings:type_name -> RedditContent
+	22, // 3: GetHistoryResponse.entries:type_name -> RedditContent.ListingEntry
+	0,  // 4: RetrieveListingsRequest.sort:type_name -> ListingsSort
+	2,  // 5: ListingsDatabase.Version:input_type -> VersionRequest
+	1,  // 6: ListingsDatabase.SaveListings:input_type -> RedditContent
+	1,  // 7: ListingsDatabase.UpdateListings:input_type -> RedditContent
+	1,  // 8: ListingsDatabase.ReplaceListings:input_type -> RedditContent
+	7,  // 9: ListingsDatabase.CullListings:input_type -> CullListingsRequest
+	9,  // 10: ListingsDatabase.PurgeListings:input_type -> PurgeListingsRequest
+	11, // 11: ListingsDatabase.DeleteListings:input_type -> DeleteListingsRequest
+	13, // 12: ListingsDatabase.ManyListings:input_type -> ManyListingsRequest
+	20, // 13: ListingsDatabase.RetrieveListings:input_type -> RetrieveListingsRequest
+	15, // 14: ListingsDatabase.FetchListing:input_type -> FetchListingRequest
+	16, // 15: ListingsDatabase.GetHistory:input_type -> GetHistoryRequest
+	18, // 16: ListingsDatabase.CompactHistory:input_type -> CompactHistoryRequest
+	3,  // 17: ListingsDatabase.Version:output_type -> VersionResponse
+	4,  // 18: ListingsDatabase.SaveListings:output_type -> SaveListingsResponse
+	5,  // 19: ListingsDatabase.UpdateListings:output_type -> UpdateListingsResponse
+	6,  // 20: ListingsDatabase.ReplaceListings:output_type -> ReplaceListingsResponse
+	8,  // 21: ListingsDatabase.CullListings:output_type -> CullListingsResponse
+	10, // 22: ListingsDatabase.PurgeListings:output_type -> PurgeListingsResponse
+	12, // 23: ListingsDatabase.DeleteListings:output_type -> DeleteListingsResponse
+	14, // 24: ListingsDatabase.ManyListings:output_type -> ManyListingsResponse
+	1,  // 25: ListingsDatabase.RetrieveListings:output_type -> RedditContent
+	1,  // 26: ListingsDatabase.FetchListing:output_type -> RedditContent
+	17, // 27: ListingsDatabase.GetHistory:output_type -> GetHistoryResponse
+	19, // 28: ListingsDatabase.CompactHistory:output_type -> CompactHistoryResponse
+	17, // [17:29] is the sub-list for method output_type
+	5,  // [5:17] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_pb_proto_ListingsDatabase_proto_init() }
@@ -765,7 +1575,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SaveListingsResponse); i {
+			switch v := v.(*VersionRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -777,7 +1587,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UpdateListingsResponse); i {
+			switch v := v.(*VersionResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -789,7 +1599,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CullListingsRequest); i {
+			switch v := v.(*SaveListingsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -801,7 +1611,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CullListingsResponse); i {
+			switch v := v.(*UpdateListingsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -813,7 +1623,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ManyListingsRequest); i {
+			switch v := v.(*ReplaceListingsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -825,7 +1635,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ManyListingsResponse); i {
+			switch v := v.(*CullListingsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -837,7 +1647,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FetchListingRequest); i {
+			switch v := v.(*CullListingsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -849,7 +1659,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RetrieveListingsRequest); i {
+			switch v := v.(*PurgeListingsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -861,7 +1671,7 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RedditContent_MetaData); i {
+			switch v := v.(*PurgeListingsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -873,6 +1683,138 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 			}
 		}
 		file_pb_proto_ListingsDatabase_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteListingsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteListingsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ManyListingsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ManyListingsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FetchListingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetHistoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetHistoryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompactHistoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompactHistoryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RetrieveListingsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RedditContent_MetaData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_proto_ListingsDatabase_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*RedditContent_ListingEntry); i {
 			case 0:
 				return &v.state
@@ -890,13 +1832,14 @@ func file_pb_proto_ListingsDatabase_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pb_proto_ListingsDatabase_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      1,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_pb_proto_ListingsDatabase_proto_goTypes,
 		DependencyIndexes: file_pb_proto_ListingsDatabase_proto_depIdxs,
+		EnumInfos:         file_pb_proto_ListingsDatabase_proto_enumTypes,
 		MessageInfos:      file_pb_proto_ListingsDatabase_proto_msgTypes,
 	}.Build()
 	File_pb_proto_ListingsDatabase_proto = out.File
@@ -22,6 +22,12 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ListingsDatabaseClient interface {
+	//
+	//the "version" protocol is called by clients right after connecting so
+	//that mismatched schemas are caught up front instead of fields being
+	//silently dropped. The schema version is bumped on every change to this
+	//file. Services that predate this rpc are schema version 0
+	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error)
 	//
 	//the "save listings" protocol sends an unconstrained amount of newly
 	//discovered listings to the database to be saved.
@@ -36,21 +42,53 @@ type ListingsDatabaseClient interface {
 	//UpdateListings
 	UpdateListings(ctx context.Context, opts ...grpc.CallOption) (ListingsDatabase_UpdateListingsClient, error)
 	//
+	//the "replace listings" protocol overwrites listings, including all of
+	//their entries, with the ones sent. Listings that aren't in the database
+	//yet are inserted. Archived listings stay archived. Used to rewrite
+	//stored listings when migrating between schema versions
+	//
+	//ensure that the listings-count header is set before calling
+	//ReplaceListings
+	ReplaceListings(ctx context.Context, opts ...grpc.CallOption) (ListingsDatabase_ReplaceListingsClient, error)
+	//
 	//the "cull listings" protocol deletes all listings in the database at are
-	//over a certain age
+	//over a certain age. If archive is set, the listings are only marked as
+	//archived and can be deleted later with PurgeListings
 	CullListings(ctx context.Context, in *CullListingsRequest, opts ...grpc.CallOption) (*CullListingsResponse, error)
 	//
+	//the "purge listings" protocol permanently deletes archived listings
+	//that are over a certain age
+	PurgeListings(ctx context.Context, in *PurgeListingsRequest, opts ...grpc.CallOption) (*PurgeListingsResponse, error)
+	//
+	//the "delete listings" protocol deletes specific listings (and their
+	//entries) by ID, regardless of age. IDs that aren't in the database are
+	//ignored
+	DeleteListings(ctx context.Context, in *DeleteListingsRequest, opts ...grpc.CallOption) (*DeleteListingsResponse, error)
+	//
 	//pulls a limited amount of listings, usually for display on a web-page.
 	//Sorting method and other factors that choose the specific items are
 	//arbitrary as of writing this comment
 	ManyListings(ctx context.Context, in *ManyListingsRequest, opts ...grpc.CallOption) (*ManyListingsResponse, error)
 	//
 	//RetrieveListings differs from ManyListings in that it returns all
-	//listings past a certain age, doesn't sort, and streams output
+	//listings past a certain age and streams output. Results can optionally
+	//be filtered by subreddit, sorted, and paginated (see
+	//RetrieveListingsRequest). When paginating, the cursor for the next page
+	//is sent in the next-cursor trailer, which is empty on the last page
 	RetrieveListings(ctx context.Context, in *RetrieveListingsRequest, opts ...grpc.CallOption) (ListingsDatabase_RetrieveListingsClient, error)
 	//
 	//FetchListing retrieves a specific listing by ID from the database
 	FetchListing(ctx context.Context, in *FetchListingRequest, opts ...grpc.CallOption) (*RedditContent, error)
+	//
+	//GetHistory returns every entry recorded under a listing, oldest first.
+	//Responds with NOT_FOUND if the listing isn't in the database
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	//
+	//the "compact history" protocol downsamples the entries of every
+	//listing that were recorded more than max_age seconds ago, keeping only
+	//the latest entry of each resolution second window (eg: 3600 for hourly).
+	//Newer entries are left alone
+	CompactHistory(ctx context.Context, in *CompactHistoryRequest, opts ...grpc.CallOption) (*CompactHistoryResponse, error)
 }
 
 type listingsDatabaseClient struct {
@@ -61,6 +99,15 @@ func NewListingsDatabaseClient(cc grpc.ClientConnInterface) ListingsDatabaseClie
 	return &listingsDatabaseClient{cc}
 }
 
+func (c *listingsDatabaseClient) Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, "/ListingsDatabase/Version", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *listingsDatabaseClient) SaveListings(ctx context.Context, opts ...grpc.CallOption) (ListingsDatabase_SaveListingsClient, error) {
 	stream, err := c.cc.NewStream(ctx, &ListingsDatabase_ServiceDesc.Streams[0], "/ListingsDatabase/SaveListings", opts...)
 	if err != nil {
@@ -129,6 +176,40 @@ func (x *listingsDatabaseUpdateListingsClient) CloseAndRecv() (*UpdateListingsRe
 	return m, nil
 }
 
+func (c *listingsDatabaseClient) ReplaceListings(ctx context.Context, opts ...grpc.CallOption) (ListingsDatabase_ReplaceListingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ListingsDatabase_ServiceDesc.Streams[2], "/ListingsDatabase/ReplaceListings", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &listingsDatabaseReplaceListingsClient{stream}
+	return x, nil
+}
+
+type ListingsDatabase_ReplaceListingsClient interface {
+	Send(*RedditContent) error
+	CloseAndRecv() (*ReplaceListingsResponse, error)
+	grpc.ClientStream
+}
+
+type listingsDatabaseReplaceListingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *listingsDatabaseReplaceListingsClient) Send(m *RedditContent) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *listingsDatabaseReplaceListingsClient) CloseAndRecv() (*ReplaceListingsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ReplaceListingsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *listingsDatabaseClient) CullListings(ctx context.Context, in *CullListingsRequest, opts ...grpc.CallOption) (*CullListingsResponse, error) {
 	out := new(CullListingsResponse)
 	err := c.cc.Invoke(ctx, "/ListingsDatabase/CullListings", in, out, opts...)
@@ -138,6 +219,24 @@ func (c *listingsDatabaseClient) CullListings(ctx context.Context, in *CullListi
 	return out, nil
 }
 
+func (c *listingsDatabaseClient) PurgeListings(ctx context.Context, in *PurgeListingsRequest, opts ...grpc.CallOption) (*PurgeListingsResponse, error) {
+	out := new(PurgeListingsResponse)
+	err := c.cc.Invoke(ctx, "/ListingsDatabase/PurgeListings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *listingsDatabaseClient) DeleteListings(ctx context.Context, in *DeleteListingsRequest, opts ...grpc.CallOption) (*DeleteListingsResponse, error) {
+	out := new(DeleteListingsResponse)
+	err := c.cc.Invoke(ctx, "/ListingsDatabase/DeleteListings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *listingsDatabaseClient) ManyListings(ctx context.Context, in *ManyListingsRequest, opts ...grpc.CallOption) (*ManyListingsResponse, error) {
 	out := new(ManyListingsResponse)
 	err := c.cc.Invoke(ctx, "/ListingsDatabase/ManyListings", in, out, opts...)
@@ -148,7 +247,7 @@ func (c *listingsDatabaseClient) ManyListings(ctx context.Context, in *ManyListi
 }
 
 func (c *listingsDatabaseClient) RetrieveListings(ctx context.Context, in *RetrieveListingsRequest, opts ...grpc.CallOption) (ListingsDatabase_RetrieveListingsClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ListingsDatabase_ServiceDesc.Streams[2], "/ListingsDatabase/RetrieveListings", opts...)
+	stream, err := c.cc.NewStream(ctx, &ListingsDatabase_ServiceDesc.Streams[3], "/ListingsDatabase/RetrieveListings", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -188,10 +287,34 @@ func (c *listingsDatabaseClient) FetchListing(ctx context.Context, in *FetchList
 	return out, nil
 }
 
+func (c *listingsDatabaseClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, "/ListingsDatabase/GetHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *listingsDatabaseClient) CompactHistory(ctx context.Context, in *CompactHistoryRequest, opts ...grpc.CallOption) (*CompactHistoryResponse, error) {
+	out := new(CompactHistoryResponse)
+	err := c.cc.Invoke(ctx, "/ListingsDatabase/CompactHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ListingsDatabaseServer is the server API for ListingsDatabase service.
 // All implementations must embed UnimplementedListingsDatabaseServer
 // for forward compatibility
 type ListingsDatabaseServer interface {
+	//
+	//the "version" protocol is called by clients right after connecting so
+	//that mismatched schemas are caught up front instead of fields being
+	//silently dropped. The schema version is bumped on every change to this
+	//file. Services that predate this rpc are schema version 0
+	Version(context.Context, *VersionRequest) (*VersionResponse, error)
 	//
 	//the "save listings" protocol sends an unconstrained amount of newly
 	//discovered listings to the database to be saved.
@@ -206,21 +329,53 @@ type ListingsDatabaseServer interface {
 	//UpdateListings
 	UpdateListings(ListingsDatabase_UpdateListingsServer) error
 	//
+	//the "replace listings" protocol overwrites listings, including all of
+	//their entries, with the ones sent. Listings that aren't in the database
+	//yet are inserted. Archived listings stay archived. Used to rewrite
+	//stored listings when migrating between schema versions
+	//
+	//ensure that the listings-count header is set before calling
+	//ReplaceListings
+	ReplaceListings(ListingsDatabase_ReplaceListingsServer) error
+	//
 	//the "cull listings" protocol deletes all listings in the database at are
-	//over a certain age
+	//over a certain age. If archive is set, the listings are only marked as
+	//archived and can be deleted later with PurgeListings
 	CullListings(context.Context, *CullListingsRequest) (*CullListingsResponse, error)
 	//
+	//the "purge listings" protocol permanently deletes archived listings
+	//that are over a certain age
+	PurgeListings(context.Context, *PurgeListingsRequest) (*PurgeListingsResponse, error)
+	//
+	//the "delete listings" protocol deletes specific listings (and their
+	//entries) by ID, regardless of age. IDs that aren't in the database are
+	//ignored
+	DeleteListings(context.Context, *DeleteListingsRequest) (*DeleteListingsResponse, error)
+	//
 	//pulls a limited amount of listings, usually for display on a web-page.
 	//Sorting method and other factors that choose the specific items are
 	//arbitrary as of writing this comment
 	ManyListings(context.Context, *ManyListingsRequest) (*ManyListingsResponse, error)
 	//
 	//RetrieveListings differs from ManyListings in that it returns all
-	//listings past a certain age, doesn't sort, and streams output
+	//listings past a certain age and streams output. Results can optionally
+	//be filtered by subreddit, sorted, and paginated (see
+	//RetrieveListingsRequest). When paginating, the cursor for the next page
+	//is sent in the next-cursor trailer, which is empty on the last page
 	RetrieveListings(*RetrieveListingsRequest, ListingsDatabase_RetrieveListingsServer) error
 	//
 	//FetchListing retrieves a specific listing by ID from the database
 	FetchListing(context.Context, *FetchListingRequest) (*RedditContent, error)
+	//
+	//GetHistory returns every entry recorded under a listing, oldest first.
+	//Responds with NOT_FOUND if the listing isn't in the database
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	//
+	//the "compact history" protocol downsamples the entries of every
+	//listing that were recorded more than max_age seconds ago, keeping only
+	//the latest entry of each resolution second window (eg: 3600 for hourly).
+	//Newer entries are left alone
+	CompactHistory(context.Context, *CompactHistoryRequest) (*CompactHistoryResponse, error)
 	mustEmbedUnimplementedListingsDatabaseServer()
 }
 
@@ -228,15 +383,27 @@ type ListingsDatabaseServer interface {
 type UnimplementedListingsDatabaseServer struct {
 }
 
+func (UnimplementedListingsDatabaseServer) Version(context.Context, *VersionRequest) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
 func (UnimplementedListingsDatabaseServer) SaveListings(ListingsDatabase_SaveListingsServer) error {
 	return status.Errorf(codes.Unimplemented, "method SaveListings not implemented")
 }
 func (UnimplementedListingsDatabaseServer) UpdateListings(ListingsDatabase_UpdateListingsServer) error {
 	return status.Errorf(codes.Unimplemented, "method UpdateListings not implemented")
 }
+func (UnimplementedListingsDatabaseServer) ReplaceListings(ListingsDatabase_ReplaceListingsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReplaceListings not implemented")
+}
 func (UnimplementedListingsDatabaseServer) CullListings(context.Context, *CullListingsRequest) (*CullListingsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CullListings not implemented")
 }
+func (UnimplementedListingsDatabaseServer) PurgeListings(context.Context, *PurgeListingsRequest) (*PurgeListingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeListings not implemented")
+}
+func (UnimplementedListingsDatabaseServer) DeleteListings(context.Context, *DeleteListingsRequest) (*DeleteListingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteListings not implemented")
+}
 func (UnimplementedListingsDatabaseServer) ManyListings(context.Context, *ManyListingsRequest) (*ManyListingsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ManyListings not implemented")
 }
@@ -246,6 +413,12 @@ func (UnimplementedListingsDatabaseServer) RetrieveListings(*RetrieveListingsReq
 func (UnimplementedListingsDatabaseServer) FetchListing(context.Context, *FetchListingRequest) (*RedditContent, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FetchListing not implemented")
 }
+func (UnimplementedListingsDatabaseServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedListingsDatabaseServer) CompactHistory(context.Context, *CompactHistoryRequest) (*CompactHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompactHistory not implemented")
+}
 func (UnimplementedListingsDatabaseServer) mustEmbedUnimplementedListingsDatabaseServer() {}
 
 // UnsafeListingsDatabaseServer may be embedded to opt out of forward compatibility for this service.
@@ -259,6 +432,24 @@ func RegisterListingsDatabaseServer(s grpc.ServiceRegistrar, srv ListingsDatabas
 	s.RegisterService(&ListingsDatabase_ServiceDesc, srv)
 }
 
+func _ListingsDatabase_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ListingsDatabaseServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ListingsDatabase/Version",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ListingsDatabaseServer).Version(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ListingsDatabase_SaveListings_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(ListingsDatabaseServer).SaveListings(&listingsDatabaseSaveListingsServer{stream})
 }
@@ -311,6 +502,32 @@ func (x *listingsDatabaseUpdateListingsServer) Recv() (*RedditContent, error) {
 	return m, nil
 }
 
+func _ListingsDatabase_ReplaceListings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ListingsDatabaseServer).ReplaceListings(&listingsDatabaseReplaceListingsServer{stream})
+}
+
+type ListingsDatabase_ReplaceListingsServer interface {
+	SendAndClose(*ReplaceListingsResponse) error
+	Recv() (*RedditContent, error)
+	grpc.ServerStream
+}
+
+type listingsDatabaseReplaceListingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *listingsDatabaseReplaceListingsServer) SendAndClose(m *ReplaceListingsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *listingsDatabaseReplaceListingsServer) Recv() (*RedditContent, error) {
+	m := new(RedditContent)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _ListingsDatabase_CullListings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CullListingsRequest)
 	if err := dec(in); err != nil {
@@ -329,6 +546,42 @@ func _ListingsDatabase_CullListings_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ListingsDatabase_PurgeListings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeListingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ListingsDatabaseServer).PurgeListings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ListingsDatabase/PurgeListings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ListingsDatabaseServer).PurgeListings(ctx, req.(*PurgeListingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ListingsDatabase_DeleteListings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteListingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ListingsDatabaseServer).DeleteListings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ListingsDatabase/DeleteListings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ListingsDatabaseServer).DeleteListings(ctx, req.(*DeleteListingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ListingsDatabase_ManyListings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ManyListingsRequest)
 	if err := dec(in); err != nil {
@@ -386,6 +639,42 @@ func _ListingsDatabase_FetchListing_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ListingsDatabase_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ListingsDatabaseServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ListingsDatabase/GetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ListingsDatabaseServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ListingsDatabase_CompactHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ListingsDatabaseServer).CompactHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ListingsDatabase/CompactHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ListingsDatabaseServer).CompactHistory(ctx, req.(*CompactHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ListingsDatabase_ServiceDesc is the grpc.ServiceDesc for ListingsDatabase service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -393,10 +682,22 @@ var ListingsDatabase_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "ListingsDatabase",
 	HandlerType: (*ListingsDatabaseServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Version",
+			Handler:    _ListingsDatabase_Version_Handler,
+		},
 		{
 			MethodName: "CullListings",
 			Handler:    _ListingsDatabase_CullListings_Handler,
 		},
+		{
+			MethodName: "PurgeListings",
+			Handler:    _ListingsDatabase_PurgeListings_Handler,
+		},
+		{
+			MethodName: "DeleteListings",
+			Handler:    _ListingsDatabase_DeleteListings_Handler,
+		},
 		{
 			MethodName: "ManyListings",
 			Handler:    _ListingsDatabase_ManyListings_Handler,
@@ -405,6 +706,14 @@ var ListingsDatabase_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FetchListing",
 			Handler:    _ListingsDatabase_FetchListing_Handler,
 		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _ListingsDatabase_GetHistory_Handler,
+		},
+		{
+			MethodName: "CompactHistory",
+			Handler:    _ListingsDatabase_CompactHistory_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -417,6 +726,11 @@ var ListingsDatabase_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ListingsDatabase_UpdateListings_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ReplaceListings",
+			Handler:       _ListingsDatabase_ReplaceListings_Handler,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "RetrieveListings",
 			Handler:       _ListingsDatabase_RetrieveListings_Handler,
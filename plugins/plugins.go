@@ -0,0 +1,82 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"plugin"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/publish"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	custom outputs (eg: a proprietary api) and filters (eg: a bespoke scoring
+	model), for what doesn't belong in this repo, without forking it. Either
+	compile them in, registering them from a package imported by main:
+
+		func init() {
+			plugins.RegisterSink(func() (plugins.Sink, error) {
+				url := os.Getenv("ACME_URL")
+				if url == "" {
+					return nil, nil // not configured
+				}
+				return &acmeSink{url: url}, nil
+			})
+			plugins.RegisterFilter(spamFilter{})
+		}
+
+	or build them as go plugins (go build -buildmode=plugin) that register
+	themselves the same way from init, and list them in PLUGINS. A go plugin
+	has to be built with the same go version, and the same version of this
+	module and its dependencies, as the votewatch it's loaded into. Go plugins
+	only load on linux, macos and freebsd.
+
+	sinks are publishers like the built in brokers (see the publish package):
+	they get every message (a post started being tracked, a snapshot of its
+	votes, its removal) in batches, from a queue of their own, and are closed on
+	shutdown if they're an io.Closer. Filters decide which new posts are
+	tracked at all, see reddit.Filter
+*/
+
+// a custom output. Name is for logs, Publish is called with a batch of messages about every second
+type Sink = publish.Publisher
+
+// what sinks get, see publish.Message
+type Message = publish.Message
+
+// decides which new posts are tracked, see reddit.Filter
+type Filter = reddit.Filter
+
+// adds a sink. newSink is called on start (and by the config check) like the built in brokers' setup: it returns nil
+// if the sink isn't configured, and an error if it's misconfigured. Must be called before publish.Setup, eg: from init
+func RegisterSink(newSink func() (Sink, error)) {
+	publish.Register(newSink)
+}
+
+// makes every new post go through filter. A post is only tracked if every filter lets it through
+func RegisterFilter(filter Filter) {
+	reddit.RegisterFilter(filter)
+}
+
+// loads the go plugins in PLUGINS (a comma separated list of paths), which register their sinks and filters from init.
+// Returns the paths loaded
+func LoadFromEnv() ([]string, error) {
+	value, _ := os.LookupEnv("PLUGINS")
+
+	var loaded []string
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		_, err := plugin.Open(path)
+		if err != nil {
+			return loaded, fmt.Errorf("error loading plugin %s:\n%s", path, err)
+		}
+		loaded = append(loaded, path)
+	}
+
+	return loaded, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	several watchers (eg: dev, staging and prod) can share one checkout and one
+	env file. Any variable can be given a different value for a profile by
+	prefixing it with the profile's name and a dot:
+
+		SUBREDDITS_PATH=./subreddits.json
+		prod.SUBREDDITS_PATH=./prod-subreddits.json
+		prod.SUBREDDIT_LOGGER_DATABASE_LOCATION=db.internal:50051
+
+	`votewatch --profile prod` (the flag works with every command) then uses
+	the prod values, and the unprefixed ones for everything else. Without a
+	profile, prefixed variables are ignored
+*/
+
+// the profile picked with --profile, "" if none was
+var profile string
+
+// takes --profile <name> (or --profile=<name>) out of args, wherever it is, so that each command's own flags don't have to know about it
+func takeProfileFlag(args []string) (string, []string, error) {
+	rest := make([]string, 0, len(args))
+	name := ""
+	for idx := 0; idx < len(args); idx += 1 {
+		arg := args[idx]
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || flagName != "profile" {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if !hasValue {
+			if idx+1 >= len(args) {
+				return "", nil, fmt.Errorf("--profile needs a name")
+			}
+			idx += 1
+			value = args[idx]
+		}
+		if value == "" || strings.Contains(value, ".") {
+			return "", nil, fmt.Errorf("malformed profile name \"%s\"", value)
+		}
+		name = value
+	}
+
+	return name, rest, nil
+}
+
+// sets the variables read from the env file, except for the ones set before it was loaded (see processEnv). The profile's
+// variables replace the unprefixed ones, other profiles' are skipped. Within each, VOTEWATCH_NAME replaces NAME (see
+// util/prefix.go)
+func applyEnvFile(values map[string]string) error {
+	chosen, err := envFileVariables(values, profile)
+	for name, value := range chosen {
+		os.Setenv(name, value)
+	}
+	return err
+}
+
+// the variables that applyEnvFile sets from values for profile p, without setting them
+func envFileVariables(values map[string]string, p string) (map[string]string, error) {
+	type candidate struct {
+		value string
+		rank  int
+	}
+	chosen := make(map[string]candidate)
+
+	found := false
+	for key, value := range values {
+		name, rank := key, 0
+		if prefix, rest, prefixed := strings.Cut(key, "."); prefixed {
+			if prefix != p {
+				continue
+			}
+			name, rank = rest, 2
+			found = true
+		}
+		if unprefixed, prefixed := util.TrimEnvPrefix(name); prefixed {
+			name, rank = unprefixed, rank+1
+		}
+
+		if current, exists := chosen[name]; !exists || rank > current.rank {
+			chosen[name] = candidate{value, rank}
+		}
+	}
+
+	variables := make(map[string]string)
+	for name, c := range chosen {
+		if !processEnv[name] {
+			variables[name] = c.value
+		}
+	}
+
+	if p != "" && !found {
+		return variables, fmt.Errorf("no variables for profile \"%s\" in %s", p, envPath())
+	}
+	return variables, nil
+}
+
+// the env variables a tracker with profile p would have once values (an env file) is loaded: the current ones, replaced
+// by the file's, with secrets read from their files (see util.ResolveFileEnv). Nothing is set, see checkEnv
+func candidateEnv(values map[string]string, p string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		env[name] = value
+	}
+
+	variables, err := envFileVariables(values, p)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range variables {
+		env[name] = value
+	}
+
+	err = util.ResolveFileValues(env, secretSettings...)
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
@@ -0,0 +1,250 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	every post ever tracked indexed into Elasticsearch (or OpenSearch), one
+	document per post with its latest scores, so that everything votewatch has
+	seen can be searched by title, author or flair from kibana, opensearch
+	dashboards or the search api, eg:
+
+		GET votewatch-posts/_search?q=title:golang AND upvotes:>1000
+
+	documents are replaced each time the post is updated (its id is the
+	post's, eg: t3_62sjuh), so they stay after it stops being tracked. The
+	index is created with a mapping (title as full text, the rest as
+	keywords, numbers and dates) if it doesn't exist yet.
+
+		ELASTICSEARCH_URL         eg: http://localhost:9200
+		ELASTICSEARCH_INDEX       defaults to votewatch-posts
+		ELASTICSEARCH_API_KEY     an elasticsearch api key (the encoded one), or
+		ELASTICSEARCH_USERNAME    basic auth, eg: for opensearch
+		ELASTICSEARCH_PASSWORD
+*/
+
+// see https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-create-index.html#indices-create-api-path-params
+var indexNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]*$`)
+
+type elasticPublisher struct {
+	url      string // of the cluster, without a trailing /
+	index    string
+	apiKey   string
+	username string
+	password string
+	client   *http.Client
+
+	// whether the index is known to exist. Only used from the queue's goroutine
+	hasIndex bool
+}
+
+// a post as indexed
+type elasticDocument struct {
+	Id        reddit.Fullname `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Title     string          `json:"title"`
+	Author    string          `json:"author"`
+	Url       string          `json:"url"`
+	Flair     string          `json:"flair"`
+	Created   string          `json:"created"` // RFC 3339
+	Updated   string          `json:"updated"` // RFC 3339, when the scores are from
+	Upvotes   int             `json:"upvotes"`
+	Comments  int             `json:"comments"`
+	Ratio     float64         `json:"ratio"`
+	RemovedBy string          `json:"removed_by"`
+}
+
+const elasticMapping = `{
+	"mappings": {
+		"properties": {
+			"id":         {"type": "keyword"},
+			"subreddit":  {"type": "keyword"},
+			"title":      {"type": "text"},
+			"author":     {"type": "keyword"},
+			"url":        {"type": "keyword"},
+			"flair":      {"type": "keyword", "fields": {"text": {"type": "text"}}},
+			"created":    {"type": "date"},
+			"updated":    {"type": "date"},
+			"upvotes":    {"type": "integer"},
+			"comments":   {"type": "integer"},
+			"ratio":      {"type": "float"},
+			"removed_by": {"type": "keyword"}
+		}
+	}
+}`
+
+// reads the ELASTICSEARCH_* env variables. nil if ELASTICSEARCH_URL isn't set
+func newElasticPublisher() (Publisher, error) {
+	base := strings.TrimSpace(os.Getenv("ELASTICSEARCH_URL"))
+	if base == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(base); err != nil || !strings.Contains(base, "://") {
+		return nil, fmt.Errorf("ELASTICSEARCH_URL=%s should be the cluster's url, eg: http://localhost:9200", base)
+	}
+
+	index := strings.TrimSpace(os.Getenv("ELASTICSEARCH_INDEX"))
+	if index == "" {
+		index = "votewatch-posts"
+	}
+	if !indexNamePattern.MatchString(index) {
+		return nil, fmt.Errorf("ELASTICSEARCH_INDEX=%s isn't a valid index name, it should be lowercase letters, digits, -, _, . and +", index)
+	}
+
+	return &elasticPublisher{
+		url:      strings.TrimSuffix(base, "/"),
+		index:    index,
+		apiKey:   strings.TrimSpace(os.Getenv("ELASTICSEARCH_API_KEY")),
+		username: strings.TrimSpace(os.Getenv("ELASTICSEARCH_USERNAME")),
+		password: strings.TrimSpace(os.Getenv("ELASTICSEARCH_PASSWORD")),
+		client:   &http.Client{Timeout: publishTimeout},
+	}, nil
+}
+
+func (p *elasticPublisher) Name() string {
+	return "elasticsearch"
+}
+
+func (p *elasticPublisher) Publish(ctx context.Context, messages []Message) error {
+	if !p.hasIndex {
+		if err := p.createIndex(ctx); err != nil {
+			return errors.New("error creating the index:\n" + err.Error())
+		}
+		p.hasIndex = true
+	}
+
+	//only the latest state of each post is indexed
+	latest := make(map[reddit.Fullname]Message)
+	ids := make([]reddit.Fullname, 0)
+	for _, message := range messages {
+		previous, exists := latest[message.Id]
+		if !exists {
+			ids = append(ids, message.Id)
+		} else if previous.Time > message.Time {
+			continue
+		}
+		latest[message.Id] = message
+	}
+
+	//see https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, id := range ids {
+		message := latest[id]
+		encoder.Encode(map[string]map[string]string{"index": {"_index": p.index, "_id": string(id)}})
+		encoder.Encode(elasticDocument{
+			Id:        message.Id,
+			Subreddit: message.Subreddit,
+			Title:     message.Title,
+			Author:    message.Author,
+			Url:       message.Url,
+			Flair:     message.Flair,
+			Created:   time.Unix(message.Created, 0).UTC().Format(time.RFC3339),
+			Updated:   time.Unix(message.Time, 0).UTC().Format(time.RFC3339),
+			Upvotes:   message.Upvotes,
+			Comments:  message.Comments,
+			Ratio:     message.Ratio,
+			RemovedBy: message.RemovedBy,
+		})
+	}
+
+	response, err := p.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s recieved indexing %d posts: %s", response.Status, len(ids), strings.TrimSpace(string(message)))
+	}
+
+	//the bulk api succeeds as a whole even if some documents fail
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Id    string          `json:"_id"`
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return errors.New("error parsing the bulk response:\n" + err.Error())
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range result.Items {
+		for _, action := range item {
+			if len(action.Error) > 0 {
+				if failed == 0 {
+					first = fmt.Sprintf("%s: %s", action.Id, action.Error)
+				}
+				failed += 1
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d posts weren't indexed, eg: %s", failed, len(ids), first)
+}
+
+// creates the index with elasticMapping if it doesn't exist
+func (p *elasticPublisher) createIndex(ctx context.Context) error {
+	response, err := p.do(ctx, http.MethodHead, "/"+p.index, "", nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+	if response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("%s recieved checking whether %s exists", response.Status, p.index)
+	}
+
+	response, err = p.do(ctx, http.MethodPut, "/"+p.index, "application/json", strings.NewReader(elasticMapping))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	//another instance may have created it in the meantime
+	if response.StatusCode == http.StatusBadRequest && strings.Contains(string(message), "resource_already_exists_exception") {
+		return nil
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s recieved creating %s: %s", response.Status, p.index, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func (p *elasticPublisher) do(ctx context.Context, method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, p.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if p.apiKey != "" {
+		request.Header.Set("Authorization", "ApiKey "+p.apiKey)
+	} else if p.username != "" || p.password != "" {
+		request.SetBasicAuth(p.username, p.password)
+	}
+	return p.client.Do(request)
+}
@@ -0,0 +1,128 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+	each snapshot written to InfluxDB as a point, so that grafana (or
+	influx's own dashboards) can chart the votes straight from it. Each
+	subreddit is its own measurement, eg:
+
+		golang,id=t3_62sjuh,flair=Discussion ups=1204i,comments=87i,ratio=0.97 1665900000
+
+	posts are also written when they start being tracked. Points go to the
+	InfluxDB 2 write api (https://docs.influxdata.com/influxdb/v2/api/#operation/PostWrite),
+	which InfluxDB 1.8+ also serves:
+
+		INFLUX_URL       eg: http://localhost:8086
+		INFLUX_TOKEN     an api token with write access to the bucket. For InfluxDB 1.x, <username>:<password>
+		INFLUX_ORG       the organization the bucket belongs to. Not needed for 1.x
+		INFLUX_BUCKET    defaults to votewatch. For 1.x, <database>/<retention policy> or just <database>
+*/
+
+type influxPublisher struct {
+	url    string // of the write endpoint, with the org and bucket
+	token  string
+	client *http.Client
+}
+
+// reads the INFLUX_* env variables. nil if INFLUX_URL isn't set
+func newInfluxPublisher() (Publisher, error) {
+	base := strings.TrimSpace(os.Getenv("INFLUX_URL"))
+	if base == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(base); err != nil || !strings.Contains(base, "://") {
+		return nil, fmt.Errorf("INFLUX_URL=%s should be the server's url, eg: http://localhost:8086", base)
+	}
+
+	bucket := strings.TrimSpace(os.Getenv("INFLUX_BUCKET"))
+	if bucket == "" {
+		bucket = "votewatch"
+	}
+	query := url.Values{}
+	query.Set("bucket", bucket)
+	query.Set("precision", "s")
+	if org := strings.TrimSpace(os.Getenv("INFLUX_ORG")); org != "" {
+		query.Set("org", org)
+	}
+
+	return &influxPublisher{
+		url:    strings.TrimSuffix(base, "/") + "/api/v2/write?" + query.Encode(),
+		token:  strings.TrimSpace(os.Getenv("INFLUX_TOKEN")),
+		client: &http.Client{Timeout: publishTimeout},
+	}, nil
+}
+
+func (p *influxPublisher) Name() string {
+	return "influxdb"
+}
+
+func (p *influxPublisher) Publish(ctx context.Context, messages []Message) error {
+	var body bytes.Buffer
+	points := 0
+	for _, message := range messages {
+		if message.Type == Removed {
+			continue
+		}
+		writePoint(&body, message)
+		points += 1
+	}
+	if points == 0 {
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if p.token != "" {
+		request.Header.Set("Authorization", "Token "+p.token)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s recieved writing %d points: %s", response.Status, points, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// a message as a line of influx's line protocol (https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/)
+func writePoint(out *bytes.Buffer, message Message) {
+	subreddit := message.Subreddit
+	if subreddit == "" {
+		subreddit = "unknown"
+	}
+	out.WriteString(influxMeasurementEscaper.Replace(subreddit))
+
+	out.WriteString(",id=" + influxTagEscaper.Replace(string(message.Id)))
+	//tags can't be empty
+	if flair := strings.TrimSpace(message.Flair); flair != "" {
+		out.WriteString(",flair=" + influxTagEscaper.Replace(flair))
+	}
+
+	fmt.Fprintf(out, " ups=%di,comments=%di,ratio=%s %d\n",
+		message.Upvotes, message.Comments, strconv.FormatFloat(message.Ratio, 'f', -1, 64), message.Time)
+}
+
+// line breaks can't be escaped, they become spaces
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `)
+)
@@ -0,0 +1,168 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/*
+	messages produced to a kafka topic through a REST proxy (the confluent
+	REST proxy, or redpanda's http proxy), at KAFKA_REST_URL (eg:
+	http://localhost:8082) with the v2 api. Messages are keyed by the post's
+	fullname, so that each post's messages stay in order on one partition.
+
+		KAFKA_TOPIC            defaults to votewatch
+		KAFKA_FORMAT           json (default) or avro. Avro messages use the schema below, which the proxy
+		                       registers with its schema registry. Fields added to it get a default, to stay
+		                       compatible with the schema already registered
+		KAFKA_REST_USERNAME    and KAFKA_REST_PASSWORD, for a proxy behind basic auth
+*/
+
+// Message as an avro record
+const avroSchema = `{
+	"type": "record",
+	"name": "Message",
+	"namespace": "votewatch",
+	"fields": [
+		{"name": "type", "type": "string"},
+		{"name": "time", "type": "long"},
+		{"name": "id", "type": "string"},
+		{"name": "subreddit", "type": "string"},
+		{"name": "title", "type": "string"},
+		{"name": "author", "type": "string"},
+		{"name": "url", "type": "string"},
+		{"name": "created", "type": "long"},
+		{"name": "upvotes", "type": "int"},
+		{"name": "comments", "type": "int"},
+		{"name": "previous_upvotes", "type": "int"},
+		{"name": "removed_by", "type": "string"},
+		{"name": "flair", "type": "string", "default": ""},
+		{"name": "ratio", "type": "double", "default": 0},
+		{"name": "upvotes_per_hour", "type": "double", "default": 0},
+		{"name": "comments_per_hour", "type": "double", "default": 0}
+	]
+}`
+
+type kafkaPublisher struct {
+	url      string // of the topic
+	avro     bool
+	username string
+	password string
+	client   *http.Client
+}
+
+type kafkaRecord struct {
+	Key   string  `json:"key"`
+	Value Message `json:"value"`
+}
+
+// reads the KAFKA_* env variables. nil if KAFKA_REST_URL isn't set
+func newKafkaPublisher() (Publisher, error) {
+	base := strings.TrimSpace(os.Getenv("KAFKA_REST_URL"))
+	if base == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(base); err != nil || !strings.Contains(base, "://") {
+		return nil, fmt.Errorf("KAFKA_REST_URL=%s should be the proxy's url, eg: http://localhost:8082", base)
+	}
+
+	topic := strings.TrimSpace(os.Getenv("KAFKA_TOPIC"))
+	if topic == "" {
+		topic = "votewatch"
+	}
+
+	p := &kafkaPublisher{
+		url:      strings.TrimSuffix(base, "/") + "/topics/" + url.PathEscape(topic),
+		username: os.Getenv("KAFKA_REST_USERNAME"),
+		password: os.Getenv("KAFKA_REST_PASSWORD"),
+		client:   &http.Client{Timeout: publishTimeout},
+	}
+
+	switch format := strings.ToLower(strings.TrimSpace(os.Getenv("KAFKA_FORMAT"))); format {
+	case "", "json":
+	case "avro":
+		p.avro = true
+	default:
+		return nil, fmt.Errorf("KAFKA_FORMAT=%s should be json or avro", format)
+	}
+
+	return p, nil
+}
+
+func (p *kafkaPublisher) Name() string {
+	return "kafka"
+}
+
+// see https://docs.confluent.io/platform/current/kafka-rest/api.html#post--topics-(string-topic_name)
+func (p *kafkaPublisher) Publish(ctx context.Context, messages []Message) error {
+	records := make([]kafkaRecord, len(messages))
+	for idx, message := range messages {
+		records[idx] = kafkaRecord{Key: string(message.Id), Value: message}
+	}
+
+	request := map[string]interface{}{"records": records}
+	contentType := "application/vnd.kafka.json.v2+json"
+	if p.avro {
+		request["key_schema"] = `"string"`
+		request["value_schema"] = avroSchema
+		contentType = "application/vnd.kafka.avro.v2+json"
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", contentType)
+	httpRequest.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.username != "" {
+		httpRequest.SetBasicAuth(p.username, p.password)
+	}
+
+	response, err := p.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s recieved from %s: %s", response.Status, p.url, strings.TrimSpace(string(message)))
+	}
+
+	//the request can succeed while some of the records failed
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return errors.New("error parsing the proxy's response:\n" + err.Error())
+	}
+	failed, firstError := 0, ""
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			if failed == 0 {
+				firstError = offset.Error
+			}
+			failed += 1
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d records weren't produced, eg: %s", failed, len(messages), firstError)
+	}
+	return nil
+}
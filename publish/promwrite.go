@@ -0,0 +1,252 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/*
+	the vote history of the most upvoted posts as prometheus time series,
+	pushed with prometheus' remote write protocol
+	(https://prometheus.io/docs/specs/remote_write_spec/) to anything that
+	accepts it: prometheus itself (with --web.enable-remote-write-receiver),
+	mimir, cortex, thanos, victoriametrics, grafana cloud... so that grafana
+	alerting rules can fire on how a post's votes move, eg:
+	deriv(votewatch_post_upvotes[30m]) * 3600 > 1000. Each snapshot is a
+	sample of:
+
+		votewatch_post_upvotes{post_id="t3_62sjuh", subreddit="golang"}
+		votewatch_post_comments{...}
+		votewatch_post_upvote_ratio{...}
+
+	every post is its own series, which is more than a prometheus server
+	wants to hold with thousands of posts tracked. So only the
+	PROMETHEUS_REMOTE_WRITE_TOP_N most upvoted posts (defaults to 100), with at
+	least PROMETHEUS_REMOTE_WRITE_MIN_UPVOTES, are written. A post that falls
+	out of the top is no longer written and its series goes stale.
+
+		PROMETHEUS_REMOTE_WRITE_URL         eg: http://localhost:9090/api/v1/write, http://mimir:9009/api/v1/push
+		PROMETHEUS_REMOTE_WRITE_USERNAME    basic auth, eg: grafana cloud's instance id
+		PROMETHEUS_REMOTE_WRITE_PASSWORD    and its password or api token
+		PROMETHEUS_REMOTE_WRITE_TENANT      sent as X-Scope-OrgID, for mimir and cortex with multi-tenancy
+*/
+
+const (
+	defaultRemoteWriteTopN = 100
+
+	// how long a post that isn't updated stays in the ranking, eg: it stopped being tracked for being too old
+	rankingStaleAfter = 24 * time.Hour
+)
+
+type remoteWritePublisher struct {
+	url      string
+	username string
+	password string
+	tenant   string
+	client   *http.Client
+
+	topN       int
+	minUpvotes int
+
+	// the latest upvotes of each post seen, to rank them. Only used from the queue's goroutine
+	ranking map[reddit.Fullname]rankedPost
+}
+
+type rankedPost struct {
+	upvotes int
+	time    int64 // of the snapshot the upvotes are from
+	seen    time.Time
+}
+
+// reads the PROMETHEUS_REMOTE_WRITE_* env variables. nil if PROMETHEUS_REMOTE_WRITE_URL isn't set
+func newRemoteWritePublisher() (Publisher, error) {
+	endpoint := strings.TrimSpace(os.Getenv("PROMETHEUS_REMOTE_WRITE_URL"))
+	if endpoint == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil || !strings.Contains(endpoint, "://") {
+		return nil, fmt.Errorf("PROMETHEUS_REMOTE_WRITE_URL=%s should be the remote write endpoint, eg: http://localhost:9090/api/v1/write", endpoint)
+	}
+
+	topN := util.GetEnvIntDefault("PROMETHEUS_REMOTE_WRITE_TOP_N", defaultRemoteWriteTopN)
+	if topN < 1 {
+		return nil, fmt.Errorf("PROMETHEUS_REMOTE_WRITE_TOP_N should be a positive number of posts, got %d", topN)
+	}
+	minUpvotes := util.GetEnvIntDefault("PROMETHEUS_REMOTE_WRITE_MIN_UPVOTES", 0)
+
+	return &remoteWritePublisher{
+		url:        endpoint,
+		username:   strings.TrimSpace(os.Getenv("PROMETHEUS_REMOTE_WRITE_USERNAME")),
+		password:   strings.TrimSpace(os.Getenv("PROMETHEUS_REMOTE_WRITE_PASSWORD")),
+		tenant:     strings.TrimSpace(os.Getenv("PROMETHEUS_REMOTE_WRITE_TENANT")),
+		client:     &http.Client{Timeout: publishTimeout},
+		topN:       topN,
+		minUpvotes: minUpvotes,
+		ranking:    make(map[reddit.Fullname]rankedPost),
+	}, nil
+}
+
+func (p *remoteWritePublisher) Name() string {
+	return "prometheus remote write"
+}
+
+func (p *remoteWritePublisher) Publish(ctx context.Context, messages []Message) error {
+	written := p.rank(messages)
+
+	//samples of the same series go together, oldest first
+	series := make(map[reddit.Fullname][]Message)
+	ids := make([]reddit.Fullname, 0)
+	for _, message := range messages {
+		if message.Type == Removed || !written[message.Id] {
+			continue
+		}
+		if _, exists := series[message.Id]; !exists {
+			ids = append(ids, message.Id)
+		}
+		series[message.Id] = append(series[message.Id], message)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var request []byte
+	for _, id := range ids {
+		samples := series[id]
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time < samples[j].Time })
+
+		request = appendTimeSeries(request, "votewatch_post_upvotes", samples, func(m Message) float64 { return float64(m.Upvotes) })
+		request = appendTimeSeries(request, "votewatch_post_comments", samples, func(m Message) float64 { return float64(m.Comments) })
+		request = appendTimeSeries(request, "votewatch_post_upvote_ratio", samples, func(m Message) float64 { return m.Ratio })
+	}
+
+	return p.send(ctx, snappy.Encode(nil, request), len(ids))
+}
+
+// updates the ranking with the messages, and returns the posts that are in the top
+func (p *remoteWritePublisher) rank(messages []Message) map[reddit.Fullname]bool {
+	now := time.Now()
+	for _, message := range messages {
+		if message.Type == Removed {
+			delete(p.ranking, message.Id)
+			continue
+		}
+		if previous, exists := p.ranking[message.Id]; exists && previous.time > message.Time {
+			continue
+		}
+		p.ranking[message.Id] = rankedPost{upvotes: message.Upvotes, time: message.Time, seen: now}
+	}
+
+	type entry struct {
+		id      reddit.Fullname
+		upvotes int
+	}
+	entries := make([]entry, 0, len(p.ranking))
+	for id, post := range p.ranking {
+		if now.Sub(post.seen) > rankingStaleAfter {
+			delete(p.ranking, id)
+			continue
+		}
+		if post.upvotes >= p.minUpvotes {
+			entries = append(entries, entry{id: id, upvotes: post.upvotes})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].upvotes != entries[j].upvotes {
+			return entries[i].upvotes > entries[j].upvotes
+		}
+		return entries[i].id < entries[j].id
+	})
+	if len(entries) > p.topN {
+		entries = entries[:p.topN]
+	}
+
+	top := make(map[reddit.Fullname]bool, len(entries))
+	for _, e := range entries {
+		top[e.id] = true
+	}
+	return top
+}
+
+func (p *remoteWritePublisher) send(ctx context.Context, body []byte, posts int) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	request.Header.Set("User-Agent", "votewatch")
+	if p.username != "" || p.password != "" {
+		request.SetBasicAuth(p.username, p.password)
+	}
+	if p.tenant != "" {
+		request.Header.Set("X-Scope-OrgID", p.tenant)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s recieved writing %d posts: %s", response.Status, posts, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// appends a TimeSeries (field 1 of the WriteRequest) with the post's labels and a sample of value for each message. The
+// messages must all be of the same post. See prompb's types.proto:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; } // timestamp in milliseconds
+func appendTimeSeries(out []byte, name string, messages []Message, value func(Message) float64) []byte {
+	var series []byte
+
+	//labels are sorted by name
+	series = appendLabel(series, "__name__", name)
+	series = appendLabel(series, "post_id", string(messages[0].Id))
+	series = appendLabel(series, "subreddit", messages[0].Subreddit)
+
+	for _, message := range messages {
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(value(message)))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(message.Time*1000))
+
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+	}
+
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	return protowire.AppendBytes(out, series)
+}
+
+func appendLabel(out []byte, name string, value string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, name)
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, value)
+
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	return protowire.AppendBytes(out, label)
+}
@@ -0,0 +1,263 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+)
+
+/*
+	every snapshot recorded of a tracked post, and the points in a post's life
+	(it started being tracked, it was removed), published to message brokers
+	so that stream processing can follow the votes without going through the
+	database service. Each broker (kafka.go, nats.go, mqtt.go, influx.go,
+	timescale.go, promwrite.go, elastic.go, redis.go) is configured with its own env variables and turned on by
+	setting them, and gets every message. More can be registered from outside, see Register.
+
+	messages are batched and published every second from a queue in the
+	background. Messages that pile up faster than they can be published (eg:
+	the broker is down) are dropped rather than kept in memory. Call Shutdown
+	before exiting to publish the last ones
+*/
+
+const (
+	maxQueued      = 8192
+	maxBatch       = 512
+	publishPeriod  = time.Second
+	publishTimeout = 10 * time.Second
+)
+
+// what happened to a post
+type MessageType string
+
+const (
+	Tracked  MessageType = "tracked"  // it started being tracked
+	Snapshot MessageType = "snapshot" // its upvotes and comments were recorded
+	Removed  MessageType = "removed"  // it was taken down, see RemovedBy
+)
+
+// a message as published. Every field is always there (empty if it doesn't apply), so that it fits a fixed schema
+type Message struct {
+	Type MessageType `json:"type"`
+	Time int64       `json:"time"` // unix time it happened
+
+	Id        reddit.Fullname `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Title     string          `json:"title"`
+	Author    string          `json:"author"`
+	Url       string          `json:"url"`
+	Created   int64           `json:"created"`
+
+	Upvotes         int     `json:"upvotes"`
+	Comments        int     `json:"comments"`
+	PreviousUpvotes int     `json:"previous_upvotes"` // before the update, for snapshots and removals
+	RemovedBy       string  `json:"removed_by"`
+	Flair           string  `json:"flair"`
+	Ratio           float64 `json:"ratio"`             // share of the votes that are upvotes, 0 to 1
+	UpvotesPerHour  float64 `json:"upvotes_per_hour"`  // gained since the update before, for snapshots and removals
+	CommentsPerHour float64 `json:"comments_per_hour"` // see reddit.VelocityBetween
+}
+
+// where messages go
+type Publisher interface {
+	// name for logs, eg: kafka
+	Name() string
+	Publish(ctx context.Context, messages []Message) error
+}
+
+// each broker's setup from its env variables. They return nil if it isn't configured
+var brokers = []func() (Publisher, error){
+	newKafkaPublisher,
+	newNATSPublisher,
+	newMQTTPublisher,
+	newInfluxPublisher,
+	newTimescalePublisher,
+	newRemoteWritePublisher,
+	newElasticPublisher,
+	newRedisPublisher,
+}
+
+// adds a broker, eg: one compiled in from outside this repo (see the plugins package). newPublisher is called by Setup
+// and CheckConfig like the built in ones. Must be called before Setup
+func Register(newPublisher func() (Publisher, error)) {
+	brokers = append(brokers, newPublisher)
+}
+
+type queue struct {
+	publisher Publisher
+	messages  chan Message
+	stop      chan struct{}
+	done      chan struct{}
+
+	droppedMu sync.Mutex
+	dropped   int
+}
+
+var (
+	activeMu sync.Mutex
+	active   []*queue
+)
+
+// starts publishing to every configured broker. Must be called before the scheduler starts (see scheduler.OnEvent)
+func Setup() error {
+	for _, newPublisher := range brokers {
+		publisher, err := newPublisher()
+		if err != nil {
+			return err
+		}
+		if publisher == nil {
+			continue
+		}
+
+		q := &queue{
+			publisher: publisher,
+			messages:  make(chan Message, maxQueued),
+			stop:      make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		go q.run()
+
+		activeMu.Lock()
+		active = append(active, q)
+		activeMu.Unlock()
+
+		scheduler.OnEvent(scheduler.PostTracked, q.onEvent)
+		scheduler.OnEvent(scheduler.PostUpdated, q.onEvent)
+		scheduler.OnEvent(scheduler.PostRemoved, q.onEvent)
+	}
+
+	return nil
+}
+
+// the problems with every configured broker's settings, for the config check
+func CheckConfig() []error {
+	problems := make([]error, 0)
+	for _, newPublisher := range brokers {
+		if _, err := newPublisher(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+// stops publishing, publishing whatever's still queued first
+func Shutdown() {
+	activeMu.Lock()
+	queues := active
+	active = nil
+	activeMu.Unlock()
+
+	for _, q := range queues {
+		close(q.stop)
+		<-q.done
+
+		//brokers that keep a connection open
+		if closer, ok := q.publisher.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func (q *queue) onEvent(event scheduler.Event) {
+	message := messageOf(event)
+
+	select {
+	case q.messages <- message:
+	default:
+		q.droppedMu.Lock()
+		q.dropped += 1
+		q.droppedMu.Unlock()
+	}
+}
+
+func messageOf(event scheduler.Event) Message {
+	post := event.Post
+	message := Message{
+		Time:      event.Time.Unix(),
+		Id:        post.FullId(),
+		Subreddit: post.Subreddit,
+		Title:     post.Title,
+		Author:    post.Author,
+		Url:       post.Url,
+		Created:   int64(post.Date),
+		Upvotes:   post.Upvotes,
+		Comments:  post.Comments,
+		RemovedBy: post.RemovedBy,
+		Flair:     post.Flair,
+		Ratio:     post.Ratio,
+	}
+
+	switch event.Kind {
+	case scheduler.PostTracked:
+		message.Type = Tracked
+	case scheduler.PostUpdated:
+		message.Type = Snapshot
+		message.PreviousUpvotes = event.PreviousUpvotes
+		message.UpvotesPerHour, message.CommentsPerHour = post.UpvotesPerHour, post.CommentsPerHour
+	case scheduler.PostRemoved:
+		message.Type = Removed
+		message.PreviousUpvotes = event.PreviousUpvotes
+		message.UpvotesPerHour, message.CommentsPerHour = post.UpvotesPerHour, post.CommentsPerHour
+	}
+	return message
+}
+
+func (q *queue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(publishPeriod)
+	defer ticker.Stop()
+
+	batch := make([]Message, 0, maxBatch)
+	for {
+		select {
+		case message := <-q.messages:
+			batch = append(batch, message)
+			if len(batch) < maxBatch {
+				continue
+			}
+		case <-ticker.C:
+		case <-q.stop:
+			//whatever's left in the queue goes out with the last batches
+			for len(q.messages) > 0 {
+				batch = append(batch, <-q.messages)
+				if len(batch) == maxBatch {
+					q.publish(batch)
+					batch = batch[:0]
+				}
+			}
+			q.publish(batch)
+			return
+		}
+
+		q.publish(batch)
+		batch = batch[:0]
+	}
+}
+
+func (q *queue) publish(batch []Message) {
+	q.droppedMu.Lock()
+	dropped := q.dropped
+	q.dropped = 0
+	q.droppedMu.Unlock()
+	if dropped > 0 {
+		fmt.Printf("warning: dropped %d messages for %s, they were coming in faster than they could be published\n", dropped, q.publisher.Name())
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	err := q.publisher.Publish(ctx, batch)
+	if err != nil {
+		fmt.Printf("warning: error publishing %d messages to %s:\n%s\n", len(batch), q.publisher.Name(), err)
+	}
+}
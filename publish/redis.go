@@ -0,0 +1,107 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+	score changes published on redis pub/sub, one channel per subreddit:
+	<REDIS_PUBLISH_CHANNEL>:<subreddit>, eg: votewatch:golang, so that a bot
+	or a widget can follow live votes with nothing but a redis client:
+
+		SUBSCRIBE votewatch:golang
+		PSUBSCRIBE votewatch:*
+
+	each message is the json of a Message, with the upvotes gained since the
+	last snapshot as "delta". Snapshots are only published when the upvotes
+	changed, posts being tracked and removed always are. Redis doesn't keep
+	messages nobody's subscribed to, so a consumer only gets what happens
+	while it's listening.
+
+		REDIS_URL                the same redis as the cache (see the cache package), eg: redis://localhost:6379
+		REDIS_PUBLISH_CHANNEL    the channels' prefix, eg: votewatch. Leave empty to not publish
+*/
+
+type redisPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+// a message as published on redis
+type deltaMessage struct {
+	Message
+	Delta int `json:"delta"` // upvotes since the previous snapshot, 0 for tracked posts
+}
+
+// reads REDIS_URL and REDIS_PUBLISH_CHANNEL. nil if REDIS_PUBLISH_CHANNEL isn't set
+func newRedisPublisher() (Publisher, error) {
+	channel := strings.TrimSpace(os.Getenv("REDIS_PUBLISH_CHANNEL"))
+	if channel == "" {
+		return nil, nil
+	}
+	if strings.ContainsAny(channel, " \t*?[") {
+		return nil, fmt.Errorf("REDIS_PUBLISH_CHANNEL=%s should be a channel name without spaces or patterns, eg: votewatch", channel)
+	}
+
+	url := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if url == "" {
+		return nil, errors.New("REDIS_PUBLISH_CHANNEL needs REDIS_URL, the redis to publish on")
+	}
+	client, err := cache.NewClient(url)
+	if err != nil {
+		return nil, errors.New("REDIS_URL is wrong:\n" + err.Error())
+	}
+
+	return &redisPublisher{client: client, channel: channel}, nil
+}
+
+func (p *redisPublisher) Name() string {
+	return "redis"
+}
+
+func (p *redisPublisher) Publish(ctx context.Context, messages []Message) error {
+	pipeline := p.client.Pipeline()
+	for _, message := range messages {
+		delta := 0
+		if message.Type != Tracked {
+			delta = message.Upvotes - message.PreviousUpvotes
+		}
+		if message.Type == Snapshot && delta == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(deltaMessage{Message: message, Delta: delta})
+		if err != nil {
+			return err
+		}
+		pipeline.Publish(ctx, p.channelOf(message), data)
+	}
+	if pipeline.Len() == 0 {
+		return nil
+	}
+
+	//the first command that failed, if any
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// <REDIS_PUBLISH_CHANNEL>:<subreddit>
+func (p *redisPublisher) channelOf(message Message) string {
+	subreddit := strings.ToLower(message.Subreddit)
+	if subreddit == "" {
+		subreddit = "unknown"
+	}
+	return p.channel + ":" + subreddit
+}
+
+func (p *redisPublisher) Close() error {
+	return p.client.Close()
+}
@@ -12,7 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/jtyrmn/reddit-votewatch/util"
@@ -32,12 +32,16 @@ type accessTokenResponse struct {
 	InitializationTime int64 `json:"initialization_time"`
 }
 
-//**** IMPORTANT: never call cache() or pullFromCache() below if env var CACHE_ACCESS_TOKEN is not true, because ACCESS_TOKEN_PATH will probably not be set and the program will halt
+//**** IMPORTANT: never call cache() or pullFromCache() below if env var CACHE_ACCESS_TOKEN is not true, because ACCESS_TOKEN_PATH will probably not be set and they will fail
 
 //save the access token and its metadata to filesystem. Returns nil if successful
 func (a *accessTokenResponse) cache() error {
 	json, _ := json.Marshal(a) //encoding a static struct should never return an error I assume
-	err := os.WriteFile(util.GetEnv("ACCESS_TOKEN_PATH"), json, 0666)
+	path, err := util.GetEnv("ACCESS_TOKEN_PATH")
+	if err != nil {
+		return errors.New("error caching access token: " + err.Error())
+	}
+	err = os.WriteFile(path, json, 0666)
 	if err != nil {
 		return errors.New("error caching access token: " + err.Error())
 	}
@@ -46,7 +50,10 @@ func (a *accessTokenResponse) cache() error {
 
 //attempt to recieve access token from cache. if cache wasn't found and there wasn't any other error, this function will return (nil, nil)
 func (a accessTokenResponse) pullFromCache() (*accessTokenResponse, error) {
-	path := util.GetEnv("ACCESS_TOKEN_PATH")
+	path, err := util.GetEnv("ACCESS_TOKEN_PATH")
+	if err != nil {
+		return nil, err
+	}
 
 	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		//cache file does not exist
@@ -72,8 +79,12 @@ func (a accessTokenResponse) String() string {
 }
 
 //the api handler object
-//should be created using NewApi()
+//should be created using Connect()
 type redditApiHandler struct {
+	//the scheduler calls into the handler from several goroutines at once. mu guards accessToken, trackedListings,
+	//latestSnapshots and each subreddit's last post. It's never held during a request to reddit
+	mu sync.Mutex
+
 	accessToken      accessTokenResponse
 	cacheAccessToken bool //whether or not the access token should be cached/decached
 
@@ -85,19 +96,34 @@ type redditApiHandler struct {
 	redditUsername string
 	redditPassword string
 
+	//sent with every request, see header.go
+	userAgent string
+
 	//rate limiting
-	rateLimiter rate.Limiter
+	rateLimiter *rate.Limiter
+
+	//subreddits to track, only the ones in this instance's shard (see shard.go). Replaced by ReloadSubreddits, but a subreddit
+	//that's still in the list keeps its entry, so that fetches already in progress update the right one
+	subreddits []*subreddit
+	shard      Shard
 
-	//subreddits to track
-	subreddits []subreddit
+	//subreddits added while running, see AddSubreddit. ReloadSubreddits keeps them
+	added []string
 
 	//posts to track
 	trackedListings ContentGroup
+
+	//the tracked posts as of the last FetchPosts, trackedListings only has their scores as of when they were first seen
+	latestSnapshots map[Fullname]Snapshot
 }
 
 //dont want to print out private secrets + passwords while debugging
-func (r redditApiHandler) String() string {
-	return fmt.Sprintf("{%s %v %s <REDACTED> %s <REDACTED> %s}", r.accessToken, r.cacheAccessToken, r.clientId, r.redditUsername, r.subreddits)
+func (r *redditApiHandler) String() string {
+	names := make([]string, len(r.subreddits))
+	for idx, sub := range r.subreddits {
+		names[idx] = sub.name
+	}
+	return fmt.Sprintf("{%s %v %s <REDACTED> %s <REDACTED> %v}", r.accessToken, r.cacheAccessToken, r.clientId, r.redditUsername, names)
 }
 
 //Connect() creates a reddit api client and also initializes
@@ -105,19 +131,43 @@ func (r redditApiHandler) String() string {
 
 //make sure you have all the env variables assigned before calling this
 func Connect() (*redditApiHandler, error) {
+	cacheAccessToken, err := util.GetEnvBool("CACHE_ACCESS_TOKEN", true)
+	if err != nil {
+		return nil, err
+	}
+
+	clientId, err := util.GetEnv("REDDIT_CLIENT_ID")
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := util.GetEnv("REDDIT_CLIENT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+	redditUsername, err := util.GetEnv("REDDIT_USERNAME")
+	if err != nil {
+		return nil, err
+	}
+	redditPassword, err := util.GetEnv("REDDIT_PASSWORD")
+	if err != nil {
+		return nil, err
+	}
+
 	client := redditApiHandler{
-		clientId:         util.GetEnv("REDDIT_CLIENT_ID"),
-		clientSecret:     util.GetEnv("REDDIT_CLIENT_SECRET"),
-		redditUsername:   util.GetEnv("REDDIT_USERNAME"),
-		redditPassword:   util.GetEnv("REDDIT_PASSWORD"),
-		cacheAccessToken: strings.ToLower(util.GetEnvDefault("CACHE_ACCESS_TOKEN", "true")) == "true", //theres probably a better way to do this
+		clientId:         clientId,
+		clientSecret:     clientSecret,
+		redditUsername:   redditUsername,
+		redditPassword:   redditPassword,
+		cacheAccessToken: cacheAccessToken,
 
 		/*
 			The reddit API limits oauth2 clients to 60 requests per minute https://github.com/reddit-archive/reddit/wiki/API#rules
 			Observing the x-limit-remaining, x-limit-reset headers from oauth.reddit.com responses makes me thing the rate limit is actually around 600 requests per 10 minutes
 			which is the same frequecy but allows for greater bursts. I assume the 60 requests per minute means they don't want to deal with 600-request bursts
+			so: one request a second, with bursts of up to 60. The limiter is shared by every call (it's a pointer), so rate.Every(time.Minute) would allow
+			a single request a minute once the burst is used up
 		*/
-		rateLimiter: *rate.NewLimiter(rate.Every(time.Minute), 60),
+		rateLimiter: rate.NewLimiter(rate.Every(time.Minute/60), 60),
 		
 	}
 
@@ -128,7 +178,19 @@ func Connect() (*redditApiHandler, error) {
 	}
 	client.subreddits = subreddits
 
+	//the subreddits can be split between several instances
+	client.shard, err = ShardFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if client.shard.Count > 1 {
+		client.subreddits = client.shard.filter(subreddits)
+		fmt.Printf("shard %s: tracking %d of %d subreddits\n", client.shard, len(client.subreddits), len(subreddits))
+	}
+
+	client.userAgent = userAgent(client.redditUsername)
 	client.trackedListings = make(ContentGroup)
+	client.latestSnapshots = make(map[Fullname]Snapshot)
 
 	//recieve access token, either by cache or request to api
 	lookupAccessTokenCache := client.cacheAccessToken
@@ -156,7 +218,7 @@ func Connect() (*redditApiHandler, error) {
 	}
 	if !lookupAccessTokenCache { //query reddit api
 		fmt.Println("querying reddit for access token...")
-		token, err := fetchAccessToken(client)
+		token, err := fetchAccessToken(&client)
 
 		if err != nil {
 			//cannot obtain an access token at all. Stop the program
@@ -181,7 +243,7 @@ func Connect() (*redditApiHandler, error) {
 }
 
 //call reddit and request an access token
-func fetchAccessToken(client redditApiHandler) (*accessTokenResponse, error) {
+func fetchAccessToken(client *redditApiHandler) (*accessTokenResponse, error) {
 	requestBody := fmt.Sprintf("grant_type=password&username=%s&password=%s", client.redditUsername, client.redditPassword)
 	request, err := http.NewRequest("POST", "https://www.reddit.com/api/v1/access_token", bytes.NewBuffer([]byte(requestBody)))
 	if err != nil {
@@ -191,11 +253,11 @@ func fetchAccessToken(client redditApiHandler) (*accessTokenResponse, error) {
 	//headers
 	authorization := "basic " + base64.StdEncoding.EncodeToString([]byte(client.clientId+":"+client.clientSecret))
 	request.Header = http.Header{
-		"user-agent":    []string{util.GetEnv("REDDIT_USERAGENT_STRING")},
+		"user-agent":    []string{client.userAgent},
 		"authorization": []string{authorization},
 	}
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := send(request)
 	if err != nil {
 		return nil, errors.New("error querying for access token:\n" + err.Error())
 	}
@@ -263,21 +325,33 @@ func (a accessTokenResponse) TimeToNextTokenRefresh() time.Duration {
 }
 
 func (r *redditApiHandler) TimeToNextTokenRefresh() time.Duration {
-	return r.accessToken.TimeToNextTokenRefresh()
+	return r.token().TimeToNextTokenRefresh()
+}
+
+//the current access token
+func (r *redditApiHandler) token() accessTokenResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.accessToken
 }
 
 //refresh the access token
+//the scheduler's loop is the only thing that calls this (see scheduler.Run), so there's never more than one refresh at a time.
+//requests in progress keep the token they started with, the next ones pick up the new token through token()
 func (r *redditApiHandler) TokenRefresh() error {
 
-	token, err := fetchAccessToken(*r)
+	token, err := fetchAccessToken(r)
 	if err != nil {
 		return err
 	}
+	r.mu.Lock()
 	r.accessToken = *token
+	r.mu.Unlock()
 
 	//attempt to cache it
 	if r.cacheAccessToken {
-		err = r.accessToken.cache()
+		err = token.cache()
 		if err != nil {
 			fmt.Println("warning: unable to cache access token:\n" + err.Error())
 		}
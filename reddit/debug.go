@@ -0,0 +1,107 @@
+package reddit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+//this file logs every request to reddit and its response with DEBUG_HTTP=true, see .env.template
+
+// the response headers worth seeing, besides the status
+var debugHeaders = []string{"x-ratelimit-used", "x-ratelimit-remaining", "x-ratelimit-reset", "retry-after", "date"}
+
+// access tokens in the responses of the token endpoint
+var accessTokenPattern = regexp.MustCompile(`"access_token"\s*:\s*"[^"]*"`)
+
+func debugHTTP() bool {
+	debug, _ := util.GetEnvBool("DEBUG_HTTP", false)
+	return debug
+}
+
+// how much of each body to log, DEBUG_HTTP_BODY_LIMIT. 0 logs no bodies, a negative limit logs them whole
+func debugBodyLimit() int {
+	value, exists := os.LookupEnv("DEBUG_HTTP_BODY_LIMIT")
+	if !exists || strings.TrimSpace(value) == "" {
+		return 1024
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 1024
+	}
+	return limit
+}
+
+func debugRequest(request *http.Request) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "http: --> %s %s\n", request.Method, request.URL)
+	for name, values := range request.Header {
+		if strings.EqualFold(name, "authorization") {
+			values = []string{"<REDACTED>"}
+		}
+		fmt.Fprintf(&out, "  %s: %s\n", strings.ToLower(name), strings.Join(values, ", "))
+	}
+
+	if limit := debugBodyLimit(); limit != 0 && request.GetBody != nil {
+		body, err := request.GetBody()
+		if err == nil {
+			data, _ := io.ReadAll(body)
+			out.WriteString("  " + truncate(redactForm(string(data)), limit) + "\n")
+		}
+	}
+
+	fmt.Print(out.String())
+}
+
+// logs the response, reading the body (if it's logged) into memory so that the caller can still read it
+func debugResponse(request *http.Request, response *http.Response, elapsed time.Duration) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "http: <-- %s %s %s (%s)\n", request.Method, request.URL.Path, response.Status, elapsed.Round(time.Millisecond))
+	for _, name := range debugHeaders {
+		if value := response.Header.Get(name); value != "" {
+			fmt.Fprintf(&out, "  %s: %s\n", name, value)
+		}
+	}
+
+	if limit := debugBodyLimit(); limit != 0 {
+		data, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			fmt.Fprintf(&out, "  error reading body: %s\n", err)
+		}
+
+		body := accessTokenPattern.ReplaceAllString(string(data), `"access_token": "<REDACTED>"`)
+		out.WriteString("  " + truncate(body, limit) + "\n")
+	}
+
+	fmt.Print(out.String())
+}
+
+// the password in the token request
+func redactForm(body string) string {
+	form, err := url.ParseQuery(body)
+	if err != nil || !form.Has("password") {
+		return body
+	}
+
+	form.Set("password", "<REDACTED>")
+	return form.Encode()
+}
+
+func truncate(body string, limit int) string {
+	if limit < 0 || len(body) <= limit {
+		return body
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", body[:limit], len(body)-limit)
+}
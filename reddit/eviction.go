@@ -0,0 +1,98 @@
+package reddit
+
+import (
+	"fmt"
+	"sort"
+)
+
+//this file caps how many posts are tracked at once, see MAX_TRACKED_POSTS in .env.template
+
+// which posts get untracked first when there are too many
+type EvictionPolicy string
+
+const (
+	EvictOldest      EvictionPolicy = "oldest"       //the posts created the longest ago
+	EvictLowestScore EvictionPolicy = "lowest-score" //the posts with the least upvotes as of their last update
+)
+
+func ParseEvictionPolicy(policy string) (EvictionPolicy, error) {
+	switch p := EvictionPolicy(policy); p {
+	case EvictOldest, EvictLowestScore:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown eviction policy \"%s\", should be \"%s\" or \"%s\"", policy, EvictOldest, EvictLowestScore)
+}
+
+// stops tracking posts, in the order given by policy, until at most max are tracked
+// returns number of posts untracked
+func (r *redditApiHandler) EvictTrackedPosts(max int, policy EvictionPolicy) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	excess := len(r.trackedListings) - max
+	if max < 0 || excess <= 0 {
+		return 0
+	}
+
+	posts := make([]RedditContent, 0, len(r.trackedListings))
+	for _, post := range r.trackedListings {
+		posts = append(posts, post)
+	}
+
+	//ties go to the oldest, then by fullname so that eviction is deterministic
+	sort.Slice(posts, func(i, j int) bool {
+		if policy == EvictLowestScore {
+			a, b := r.score(posts[i]), r.score(posts[j])
+			if a != b {
+				return a < b
+			}
+		}
+		if posts[i].Date != posts[j].Date {
+			return posts[i].Date < posts[j].Date
+		}
+		return posts[i].FullId() < posts[j].FullId()
+	})
+
+	for _, post := range posts[:excess] {
+		r.untrack(post.FullId())
+	}
+
+	return excess
+}
+
+func (r *redditApiHandler) score(post RedditContent) int {
+	upvotes, _ := r.upvotesOf(post.FullId())
+	return upvotes
+}
+
+// a tracked post's upvotes as of the last time it was fetched. false if the post isn't tracked
+func (r *redditApiHandler) LatestUpvotes(ID Fullname) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.upvotesOf(ID)
+}
+
+// why a tracked post was taken down, as of the last time it was fetched. Empty if it's up or isn't tracked
+func (r *redditApiHandler) RemovedBy(ID Fullname) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.trackedListings[ID].RemovedBy
+}
+
+// LatestUpvotes without locking
+func (r *redditApiHandler) upvotesOf(ID Fullname) (int, bool) {
+	if snapshot, exists := r.latestSnapshots[ID]; exists {
+		return snapshot.Upvotes, true
+	}
+
+	post, tracked := r.trackedListings[ID]
+	return post.Upvotes, tracked
+}
+
+func (r *redditApiHandler) untrack(ID Fullname) {
+	delete(r.trackedListings, ID)
+	delete(r.latestSnapshots, ID)
+}
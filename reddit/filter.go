@@ -0,0 +1,53 @@
+package reddit
+
+import (
+	"fmt"
+	"sync"
+)
+
+//this file lets other packages (and plugins, see the plugins package) decide which new posts get tracked
+
+// decides whether a newly created post is worth tracking, eg: by scoring its title. Only new posts found in the
+// subreddits go through filters, not the ones tracked by hand (eg: telegram's /track)
+type Filter interface {
+	// name for logs, eg: spam-score
+	Name() string
+	// whether post should be tracked. Called concurrently, from whichever job found it
+	Track(post RedditContent) bool
+}
+
+var (
+	filtersMu sync.RWMutex
+	filters   []Filter
+)
+
+// makes every new post go through filter too. A post is only tracked if every filter lets it through
+func RegisterFilter(filter Filter) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+
+	filters = append(filters, filter)
+}
+
+// whether every filter lets post through. A filter that panics is logged and lets it through
+func passesFilters(post RedditContent) bool {
+	filtersMu.RLock()
+	defer filtersMu.RUnlock()
+
+	for _, filter := range filters {
+		if !callFilter(filter, post) {
+			return false
+		}
+	}
+	return true
+}
+
+func callFilter(filter Filter, post RedditContent) (track bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Printf("warning: filter %s panicked on %s: %v\n", filter.Name(), post.FullId(), recovered)
+			track = true
+		}
+	}()
+	return filter.Track(post)
+}
@@ -4,15 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/jtyrmn/reddit-votewatch/util"
+	"github.com/jtyrmn/reddit-votewatch/tracing"
+	"github.com/jtyrmn/reddit-votewatch/version"
 )
 
+//REDDIT_USERAGENT_STRING, or one naming this build and the account if it's empty. See the version package.
+//Read once by Connect, so that requests don't depend on the env variables staying set (see reloadEnv in main.go)
+func userAgent(username string) string {
+	if v, _ := os.LookupEnv("REDDIT_USERAGENT_STRING"); strings.TrimSpace(v) != "" {
+		return v
+	}
+	return version.UserAgent(username)
+}
+
 //function to set standard outgoing headers to reddit.com
 //only useful for queries after you get the access token, not before
-func populateStandardHeaders(header *http.Header, token accessTokenResponse) {
-	userAgent := util.GetEnv("REDDIT_USERAGENT_STRING")
+func populateStandardHeaders(header *http.Header, token accessTokenResponse, userAgent string) {
 	authorization := fmt.Sprintf("%s %s", token.TokenType, token.AccessToken)
 
 	header.Add("user-agent", userAgent)
@@ -38,3 +49,42 @@ func getTimeOfSending(response *http.Response) (uint64, error) {
 	return uint64(date.Unix()), nil
 
 }
+
+//sends a request to reddit. Every request goes through here, so that it shows up in traces (see the tracing package)
+//and can be logged with DEBUG_HTTP (see debug.go)
+func send(request *http.Request) (*http.Response, error) {
+	ctx, span := tracing.StartKind(request.Context(), "reddit "+request.Method+" "+request.URL.Path, tracing.Client)
+	span.SetAttribute("http.method", request.Method)
+	span.SetAttribute("http.url", request.URL.Scheme+"://"+request.URL.Host+request.URL.Path)
+
+	debug := debugHTTP()
+	if debug {
+		debugRequest(request)
+	}
+
+	start := time.Now()
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		if debug {
+			fmt.Printf("http: <-- %s %s failed after %s:\n%s\n", request.Method, request.URL.Path, time.Since(start).Round(time.Millisecond), err)
+		}
+		span.End(err)
+		return nil, err
+	}
+	if debug {
+		debugResponse(request, response, time.Since(start))
+	}
+
+	recordRateLimit(response)
+	span.SetAttribute("http.status_code", response.StatusCode)
+	if remaining := response.Header.Get("x-ratelimit-remaining"); remaining != "" {
+		span.SetAttribute("reddit.ratelimit_remaining", remaining)
+	}
+	if response.StatusCode != http.StatusOK {
+		span.End(errors.New(response.Status))
+	} else {
+		span.End(nil)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,23 @@
+package reddit
+
+// a single recording of a listing's upvotes and comments at some point in time. Stored listings get one every update cycle
+type Snapshot struct {
+	Upvotes  int    `bson:"upvotes" json:"upvotes"`
+	Comments int    `bson:"comments" json:"comments"`
+	Date     uint64 `bson:"date" json:"date"` //time of recording
+
+	//gained an hour since the snapshot before, see velocity.go. 0 for snapshots recorded before they were
+	UpvotesPerHour  float64 `bson:"upvotes_per_hour,omitempty" json:"upvotes_per_hour"`
+	CommentsPerHour float64 `bson:"comments_per_hour,omitempty" json:"comments_per_hour"`
+}
+
+// a listing along with the snapshots recorded under it, oldest first
+type ListingHistory struct {
+	Listing RedditContent
+	Entries []Snapshot
+}
+
+// the current upvotes/comments of the listing as a snapshot
+func (r RedditContent) Snapshot() Snapshot {
+	return Snapshot{Upvotes: r.Upvotes, Comments: r.Comments, Date: r.QueryDate, UpvotesPerHour: r.UpvotesPerHour, CommentsPerHour: r.CommentsPerHour}
+}
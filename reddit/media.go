@@ -27,6 +27,14 @@ type RedditContent struct {
 	Comments  int    `json:"num_comments" mapstructure:"num_comments"`
 	Date      uint64 `json:"created_utc" mapstructure:"created_utc"` //time of creation
 	QueryDate uint64 //time of recieval from the API
+	Subreddit string `json:"subreddit"` //does not include the r/
+	Author    string `json:"author"`    //username without the u/. Empty for listings stored before it was recorded
+	Url       string `json:"url"`       //what a link post links to. For self posts, the post's own permalink
+	RemovedBy string `json:"removed_by_category" mapstructure:"removed_by_category"` //why the post was taken down (eg: moderator, deleted, reddit). Empty while it's up. Not stored
+	Flair     string `json:"link_flair_text" mapstructure:"link_flair_text"`         //the post's flair as text, empty if it has none. Not stored
+	Ratio     float64 `json:"upvote_ratio" mapstructure:"upvote_ratio"`              //the share of votes that are upvotes, 0 to 1. Not stored
+	UpvotesPerHour  float64 `json:"upvotes_per_hour" mapstructure:"-"`  //upvotes gained an hour since the update before, 0 until the first update. See velocity.go
+	CommentsPerHour float64 `json:"comments_per_hour" mapstructure:"-"` //same for comments
 }
 
 func (r *RedditContent) UnmarshalJSON(data []byte) error {
@@ -77,7 +85,10 @@ type responseParserStruct struct {
 }
 
 //converts the tracked reddit posts ContentGroup to a slice of IDs
-func (r redditApiHandler) GetTrackedIDs() []Fullname {
+func (r *redditApiHandler) GetTrackedIDs() []Fullname {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	list := make([]Fullname, len(r.trackedListings))
 
 	idx := 0
@@ -89,30 +100,55 @@ func (r redditApiHandler) GetTrackedIDs() []Fullname {
 	return list
 }
 
-func (r redditApiHandler) GetTrackedPosts() ContentGroup {
-	return r.trackedListings
+//a copy of the tracked posts
+func (r *redditApiHandler) GetTrackedPosts() ContentGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	posts := make(ContentGroup, len(r.trackedListings))
+	for ID, post := range r.trackedListings {
+		posts[ID] = post
+	}
+	return posts
+}
+
+//starts tracking posts, eg: ones pulled from the database
+func (r *redditApiHandler) TrackPosts(posts ContentGroup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for ID, post := range posts {
+		//another instance tracks posts from the subreddits of other shards, see shard.go
+		if !r.shard.Owns(post.Subreddit) {
+			continue
+		}
+		r.trackedListings[ID] = post
+	}
 }
 
 //get the <num> latest posts at a specific subreddit
 //it's important to note that exactly <num> posts being returned is not garanteed. Their might be 100 <num> posts on the subreddit, and other cases
 //note: (non-concurrent) api calls are done in groups of 100 listings. So 101 requests will block for twice as long as 100 requests
 //while process recieved posts up to last (unless last is nil)
-func (r redditApiHandler) getNewestPosts(subreddit string, num int, last *Fullname) ([]RedditContent, error) {
+func (r *redditApiHandler) getNewestPosts(ctx context.Context, subreddit string, num int, last *Fullname) ([]RedditContent, error) {
 	if num <= 0 {
 		return nil, fmt.Errorf("num %d must be positive", num)
 	}
 
 	//our nested function to call api. Used in loop below
 	callApi := func(url string) (*responseParserStruct, uint64, error) {
-		request, err := http.NewRequest("GET", url, nil)
+		request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, 0, err
 		}
 
-		populateStandardHeaders(&request.Header, r.accessToken)
+		populateStandardHeaders(&request.Header, r.token(), r.userAgent)
 
-		r.rateLimiter.Wait(context.Background())
-		response, err := http.DefaultClient.Do(request)
+		err = r.rateLimiter.Wait(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		response, err := send(request)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -218,7 +254,8 @@ func (r redditApiHandler) getNewestPosts(subreddit string, num int, last *Fullna
 
 //given a list of fullname IDs (justFullID()), queries reddit for the posts corresponding to those IDS
 //returns a mapping of listings, indexed by their own fullname IDs
-func (r redditApiHandler) FetchPosts(IDs []Fullname) (*ContentGroup, error) {
+//requests still in progress (or waiting on the rate limit) are abandoned once ctx is done
+func (r *redditApiHandler) FetchPosts(ctx context.Context, IDs []Fullname) (*ContentGroup, error) {
 	const limit = 100
 	/*
 		the /api/info endpoint allows at most 100 listings to be fetched in a single call, or behaviour will be undefined
@@ -246,16 +283,20 @@ func (r redditApiHandler) FetchPosts(IDs []Fullname) (*ContentGroup, error) {
 		url := "https://oauth.reddit.com/api/info/?id=" + url_builder.String()
 		//fmt.Println(url)
 
-		request, err := http.NewRequest("GET", url, nil)
+		request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			errChan <- err
 			return
 		}
 
-		populateStandardHeaders(&request.Header, r.accessToken)
+		populateStandardHeaders(&request.Header, r.token(), r.userAgent)
 
-		r.rateLimiter.Wait(context.Background())
-		response, err := http.DefaultClient.Do(request)
+		err = r.rateLimiter.Wait(ctx)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		response, err := send(request)
 		if err != nil {
 			errChan <- err
 			return
@@ -312,13 +353,15 @@ func (r redditApiHandler) FetchPosts(IDs []Fullname) (*ContentGroup, error) {
 	out := make(chan fetchBatchReturn)
 	errChan := make(chan error)
 
-	r.rateLimiter.WaitN(context.Background(), totalCalls)
+	//each batch waits for the rate limiter itself, see fetchBatch
 	for currentCall := 0; currentCall < totalCalls; currentCall += 1 {
 		go fetchBatch(batchIDs[currentCall], out, errChan)
 	}
 
 	//recieve content from goroutines
 	contentMap := make(ContentGroup)
+	var lastErr error
+	failedCalls := 0
 	for i := 0; i < totalCalls; i += 1 {
 		select {
 		case result := <-out: //a response was successfully recieved and processed
@@ -329,21 +372,51 @@ func (r redditApiHandler) FetchPosts(IDs []Fullname) (*ContentGroup, error) {
 		case err := <-errChan: //not successful
 			//apparently im supposed to use an errgroup instead of an error channel for this? idk
 			fmt.Printf("warning: error during batch request %d:\n%s\n", i+1, err.Error())
+			lastErr = err
+			failedCalls += 1
 		}
 	}
 
+	//some batches failing is worth a warning, all of them failing means reddit is down or we're not authorized
+	if totalCalls > 0 && failedCalls == totalCalls {
+		return nil, fmt.Errorf("every batch request failed, the last with:\n%s", lastErr)
+	}
+
 	//check over all our IDs to make sure they were inserted
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for _, ID := range IDs {
-		if _, exists := contentMap[ID]; !exists {
+		content, exists := contentMap[ID]
+		if !exists {
 			fmt.Printf("warning: ID %s returned nothing from reddit\n", ID)
+			continue
+		}
+
+		//see EvictTrackedPosts
+		if post, tracked := r.trackedListings[ID]; tracked {
+			//compared to the update before, or to when the post was first seen. See velocity.go
+			previous, updated := r.latestSnapshots[ID]
+			if !updated {
+				previous = post.Snapshot()
+			}
+			content.setVelocity(previous)
+			contentMap[ID] = content
+			r.latestSnapshots[ID] = content.Snapshot()
+
+			//see RemovedBy
+			post.RemovedBy = content.RemovedBy
+			post.UpvotesPerHour, post.CommentsPerHour = content.UpvotesPerHour, content.CommentsPerHour
+			r.trackedListings[ID] = post
 		}
 	}
 
 	return &contentMap, nil
 }
 
-//this function is called on a routine to fetch all the newly created posts from the subreddit list and add them to the tracked posts
-func (r *redditApiHandler) TrackNewlyCreatedPosts() int {
+//this function is called on a routine to fetch all the newly created posts from the given subreddits and add them to the tracked posts
+//if no subreddits are given, every subreddit in the subreddit list is fetched
+//returns the posts that were added, and an error if every subreddit failed
+func (r *redditApiHandler) TrackNewlyCreatedPosts(ctx context.Context, names []string) ([]RedditContent, error) {
 	TEMP := 10
 
 	//just holds the output of task func
@@ -355,40 +428,56 @@ func (r *redditApiHandler) TrackNewlyCreatedPosts() int {
 
 	//do a new goroutine for each subreddit
 	task := func(sub *subreddit, out chan<- taskResult) {
+		r.mu.Lock()
 		var last *Fullname = nil
 		if sub.last != "" {
-			last = &sub.last
+			previous := sub.last
+			last = &previous
 		}
+		depth := TEMP
+		if sub.catchUp > depth {
+			depth = sub.catchUp //catching up after being offline, see ResumeFromTrackedPosts
+		}
+		r.mu.Unlock()
 
 		//whether or not we should actually save any posts this iteration for this subreddit. We only want to save posts if last is set, or else the posts we recieved were untracked for some time before recieving them
 		trackPosts := last != nil
 
-		result, err := r.getNewestPosts(sub.name, TEMP, last)
+		result, err := r.getNewestPosts(ctx, sub.name, depth, last)
 		if err != nil {
-			out <- taskResult{nil, false, fmt.Errorf("error getting posts from %s:\n", err.Error())}
+			out <- taskResult{nil, false, fmt.Errorf("error getting posts from r/%s:\n%s", sub.name, err.Error())}
 			return
 		}
 
 		//the newest post recieved is now the last post seen in this subreddit
+		r.mu.Lock()
 		if len(result) > 0 {
 			sub.last = result[0].FullId()
 		}
+		sub.catchUp = 0
+		r.mu.Unlock()
 
 		out <- taskResult{result, trackPosts, nil}
 	}
 
+	subreddits := r.findSubreddits(names)
+
 	out := make(chan taskResult)
-	for idx := range r.subreddits {
-		go task(&r.subreddits[idx], out)
+	for _, sub := range subreddits {
+		go task(sub, out)
 	}
 
-	postsTracked := 0 //keep count
+	postsTracked := make([]RedditContent, 0)
+	var lastErr error
+	failedSubreddits := 0
 
 	//recieve the channels and add the new posts to the tracker
-	for i := 0; i < len(r.subreddits); i += 1 {
+	for i := 0; i < len(subreddits); i += 1 {
 		results := <-out
 		if results.err != nil {
 			fmt.Println("warning: " + results.err.Error())
+			lastErr = results.err
+			failedSubreddits += 1
 		}
 
 		if !results.trackPosts {
@@ -396,22 +485,38 @@ func (r *redditApiHandler) TrackNewlyCreatedPosts() int {
 			continue
 		}
 
+		//see filter.go
+		accepted := make([]RedditContent, 0, len(results.result))
 		for _, post := range results.result {
+			if passesFilters(post) {
+				accepted = append(accepted, post)
+			}
+		}
+
+		r.mu.Lock()
+		for _, post := range accepted {
 			r.trackedListings[post.FullId()] = post
-			postsTracked += 1
+			postsTracked = append(postsTracked, post)
 		}
+		r.mu.Unlock()
 	}
 
-	return postsTracked
+	if len(subreddits) > 0 && failedSubreddits == len(subreddits) {
+		return postsTracked, fmt.Errorf("every subreddit failed, the last with:\n%s", lastErr)
+	}
+	return postsTracked, nil
 }
 
 //stop tracking all posts that are over maxAge seconds old
 //returns number of posts untracked
-func (r redditApiHandler) StopTrackingOldPosts(maxAge uint64) int {
+func (r *redditApiHandler) StopTrackingOldPosts(maxAge uint64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	untrackedPosts := 0
 	for ID, post := range r.trackedListings {
 		if post.Date < uint64(time.Now().Unix()) - maxAge {
-			delete(r.trackedListings, ID)
+			r.untrack(ID)
 			untrackedPosts += 1
 		}
 	}
@@ -0,0 +1,71 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+//this file has the checks run by the startup diagnostics, see diagnostics.go in the main package
+
+// the reddit account the access token belongs to. Fails if reddit doesn't accept the token
+func (r *redditApiHandler) Whoami(ctx context.Context) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", "https://oauth.reddit.com/api/v1/me", nil)
+	if err != nil {
+		return "", err
+	}
+	populateStandardHeaders(&request.Header, r.token(), r.userAgent)
+
+	err = r.rateLimiter.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	response, err := send(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", errors.New(response.Status + " recieved querying reddit")
+	}
+
+	var me struct {
+		Name string `json:"name"`
+	}
+	body, _ := io.ReadAll(response.Body)
+	err = json.Unmarshal(body, &me)
+	if err != nil {
+		return "", errors.New("error parsing JSON response:\n" + err.Error())
+	}
+
+	return me.Name, nil
+}
+
+// fetches the newest post of a subreddit in the subreddit list, to check that it can be fetched from. Returns whether it has any posts
+func (r *redditApiHandler) ProbeSubreddit(ctx context.Context, name string) (bool, error) {
+	posts, err := r.getNewestPosts(ctx, name, 1, nil)
+	if err != nil {
+		return false, fmt.Errorf("error fetching from r/%s:\n%s", name, err)
+	}
+
+	return len(posts) > 0, nil
+}
+
+// the names of the subreddits in the subreddit list (only the ones in this instance's shard), sorted
+func (r *redditApiHandler) SubredditNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, len(r.subreddits))
+	for idx, sub := range r.subreddits {
+		names[idx] = sub.name
+	}
+	sort.Strings(names)
+
+	return names
+}
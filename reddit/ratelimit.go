@@ -0,0 +1,62 @@
+package reddit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/metrics"
+)
+
+//this file keeps track of how much of the account's rate limit is left, as reported by reddit on every response
+
+var (
+	rateLimitRemaining = metrics.NewGauge("votewatch_reddit_ratelimit_remaining", "requests left in reddit's current rate limit window", "")
+	rateLimitUsed      = metrics.NewGauge("votewatch_reddit_ratelimit_used", "requests made in reddit's current rate limit window", "")
+	rateLimitReset     = metrics.NewGauge("votewatch_reddit_ratelimit_reset_seconds", "seconds until reddit's current rate limit window ends, as of the last response", "")
+)
+
+// the rate limit as of the last response from reddit
+type RateLimitStatus struct {
+	Remaining float64
+	Used      float64
+	Reset     time.Time // when the window ends and Remaining goes back up
+	Updated   time.Time // when reddit last reported it. Zero if it hasn't yet
+}
+
+var rateLimit struct {
+	sync.Mutex
+	status RateLimitStatus
+}
+
+// reads the x-ratelimit-* headers of a response. Responses without them (eg: the token endpoint's) are ignored
+func recordRateLimit(response *http.Response) {
+	remaining, err := strconv.ParseFloat(response.Header.Get("x-ratelimit-remaining"), 64)
+	if err != nil {
+		return
+	}
+	used, _ := strconv.ParseFloat(response.Header.Get("x-ratelimit-used"), 64)
+	reset, _ := strconv.ParseFloat(response.Header.Get("x-ratelimit-reset"), 64)
+
+	rateLimit.Lock()
+	rateLimit.status = RateLimitStatus{
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Now().Add(time.Duration(reset * float64(time.Second))),
+		Updated:   time.Now(),
+	}
+	rateLimit.Unlock()
+
+	rateLimitRemaining.With("").Set(remaining)
+	rateLimitUsed.With("").Set(used)
+	rateLimitReset.With("").Set(reset)
+}
+
+// how much of the rate limit was left as of the last response from reddit
+func (r *redditApiHandler) RateLimit() RateLimitStatus {
+	rateLimit.Lock()
+	defer rateLimit.Unlock()
+
+	return rateLimit.status
+}
@@ -0,0 +1,63 @@
+package reddit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+//this file splits the subreddits between several instances, see SHARD_COUNT in .env.template
+
+// a long subreddit list can be split between SHARD_COUNT instances, each with its own reddit account and so its own
+// rate limit. Every subreddit belongs to exactly one shard, picked by hashing its name, so every instance can be given
+// the same subreddits file. SHARD_INDEX (0 to SHARD_COUNT-1) is the shard of this instance
+type Shard struct {
+	Index int
+	Count int
+}
+
+// reads SHARD_INDEX and SHARD_COUNT. Without them every subreddit belongs to the one shard
+func ShardFromEnv() (Shard, error) {
+	shard := Shard{
+		Index: util.GetEnvIntDefault("SHARD_INDEX", 0),
+		Count: util.GetEnvIntDefault("SHARD_COUNT", 1),
+	}
+
+	if shard.Count < 1 {
+		return Shard{}, fmt.Errorf("SHARD_COUNT must be at least 1, got %d", shard.Count)
+	}
+	if shard.Index < 0 || shard.Index >= shard.Count {
+		return Shard{}, fmt.Errorf("SHARD_INDEX must be between 0 and %d, got %d", shard.Count-1, shard.Index)
+	}
+
+	return shard, nil
+}
+
+// whether the subreddit belongs to this shard. Names are case insensitive, like on reddit
+func (s Shard) Owns(subreddit string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(subreddit)))
+	return int(hash.Sum32()%uint32(s.Count)) == s.Index
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// the subreddits that belong to the shard
+func (s Shard) filter(subreddits []*subreddit) []*subreddit {
+	owned := make([]*subreddit, 0, len(subreddits))
+	for _, sub := range subreddits {
+		if s.Owns(sub.name) {
+			owned = append(owned, sub)
+		}
+	}
+
+	return owned
+}
@@ -0,0 +1,95 @@
+package reddit
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShardFromEnv(t *testing.T) {
+	tests := []struct {
+		index, count string
+		shard        Shard
+		fails        bool
+	}{
+		{index: "0", count: "1", shard: Shard{0, 1}},
+		{index: "2", count: "3", shard: Shard{2, 3}},
+		{index: "3", count: "3", fails: true},
+		{index: "-1", count: "3", fails: true},
+		{index: "0", count: "0", fails: true},
+		{index: "0", count: "-2", fails: true},
+	}
+
+	for _, test := range tests {
+		t.Setenv("SHARD_INDEX", test.index)
+		t.Setenv("SHARD_COUNT", test.count)
+		shard, err := ShardFromEnv()
+		if test.fails {
+			if err == nil {
+				t.Errorf("SHARD_INDEX=%s SHARD_COUNT=%s didn't fail", test.index, test.count)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SHARD_INDEX=%s SHARD_COUNT=%s: %s", test.index, test.count, err)
+		} else if shard != test.shard {
+			t.Errorf("SHARD_INDEX=%s SHARD_COUNT=%s: %s, expected %s", test.index, test.count, shard, test.shard)
+		}
+	}
+}
+
+func TestShardOwns(t *testing.T) {
+	// pinned, since changing the hash would move subreddits between running instances
+	tests := []struct {
+		subreddit string
+		count     int
+		owner     int
+	}{
+		{"golang", 1, 0},
+		{"golang", 2, 1},
+		{"golang", 3, 2},
+		{"GoLang", 3, 2},
+		{"askreddit", 3, 0},
+		{"AskReddit", 5, 1},
+		{"programming", 3, 1},
+		{"worldnews", 2, 0},
+		{"rust", 5, 2},
+	}
+
+	for _, test := range tests {
+		for index := 0; index < test.count; index++ {
+			shard := Shard{Index: index, Count: test.count}
+			if owns := shard.Owns(test.subreddit); owns != (index == test.owner) {
+				t.Errorf("shard %s owns %s: %t, expected shard %d/%d to own it", shard, test.subreddit, owns, test.owner, test.count)
+			}
+		}
+	}
+}
+
+func TestShardFilter(t *testing.T) {
+	subreddits := make([]*subreddit, 0)
+	for idx := 0; idx < 100; idx++ {
+		subreddits = append(subreddits, &subreddit{name: fmt.Sprintf("subreddit%d", idx)})
+	}
+
+	// every subreddit ends up in exactly one shard
+	const count = 4
+	owners := make(map[string]int)
+	for index := 0; index < count; index++ {
+		owned := Shard{Index: index, Count: count}.filter(subreddits)
+		if len(owned) == 0 {
+			t.Errorf("shard %d/%d owns no subreddits out of %d", index, count, len(subreddits))
+		}
+		for _, sub := range owned {
+			owners[sub.name] += 1
+		}
+	}
+	for _, sub := range subreddits {
+		if owners[sub.name] != 1 {
+			t.Errorf("%s is in %d shards", sub.name, owners[sub.name])
+		}
+	}
+
+	if owned := (Shard{Index: 0, Count: 1}).filter(subreddits); len(owned) != len(subreddits) {
+		t.Errorf("the only shard owns %d subreddits, expected all %d", len(owned), len(subreddits))
+	}
+}
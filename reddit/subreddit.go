@@ -5,6 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jtyrmn/reddit-votewatch/util"
 )
@@ -14,13 +19,56 @@ import (
 type subreddit struct {
 	name string   //does not include the r/.
 	last Fullname //last post queried on this subreddit, see GetNewestPosts
+
+	refreshPeriod uint64 //seconds between fetching new posts from this subreddit. 0 means NEW_POSTS_REFRESH_PERIOD
+	catchUp       int    //how many posts the next fetch looks through for last, if that's deeper than usual. See ResumeFromTrackedPosts
+}
+
+// an entry in the "subreddits" array is either just the name, or an object with the name and its own refresh period:
+// {"name": "wallstreetbets", "refresh_period": 10}
+type subredditEntry struct {
+	Name          string `json:"name"`
+	RefreshPeriod uint64 `json:"refresh_period"`
+}
+
+func (s *subredditEntry) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		s.Name = name
+		return nil
+	}
+
+	type plain subredditEntry //without the UnmarshalJSON method, to not recurse
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// the other way around, just the name if it has no refresh period of its own
+func (s subredditEntry) MarshalJSON() ([]byte, error) {
+	if s.RefreshPeriod == 0 {
+		return json.Marshal(s.Name)
+	}
+
+	type plain subredditEntry
+	return json.Marshal(plain(s))
 }
 
 //gets a list of subreddits defined in SUBREDDITS_PATH
 //see subreddits.json.template
-func  getSubredditsFromFile() ([]subreddit, error) {
+func  getSubredditsFromFile() ([]*subreddit, error) {
+	//a comma separated SUBREDDITS (eg: from --subreddits, see flags.go) replaces the file
+	if names, err := util.GetEnvStringSlice("SUBREDDITS"); err == nil && len(names) > 0 {
+		subreddits := make([]*subreddit, len(names))
+		for idx, name := range names {
+			subreddits[idx] = &subreddit{name: strings.TrimPrefix(name, "r/")}
+		}
+		return subreddits, nil
+	}
+
 	//get the location of it
-	path := util.GetEnv("SUBREDDITS_PATH")
+	path, err := util.GetEnv("SUBREDDITS_PATH")
+	if err != nil {
+		return nil, err
+	}
 	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		//cache file does not exist
 		return nil, fmt.Errorf("file not found at %s\n", path)
@@ -30,25 +78,258 @@ func  getSubredditsFromFile() ([]subreddit, error) {
 	if err != nil {
 		return nil, errors.New("error reading subreddits file:\n" + err.Error())
 	}
-	
-	//SUBREDDITS_PATH file is a json object with a "subreddits" field containing an array of strings
-	type jsonStruct struct {
-		Subreddits []string `json:"subreddits"`
-	}
 
-	var parsing jsonStruct
-	err = json.Unmarshal(data, &parsing)
+	return parseSubreddits(data)
+}
+
+//SUBREDDITS_PATH file is a json object with a "subreddits" field containing an array of subreddit names and/or subredditEntry objects
+type subredditsFile struct {
+	Subreddits []subredditEntry `json:"subreddits"`
+}
+
+func parseSubreddits(data []byte) ([]*subreddit, error) {
+	var parsing subredditsFile
+	err := json.Unmarshal(data, &parsing)
 	if err != nil {
 		return nil, errors.New("error parsing json:\n" + err.Error())
 	}
 
-	subreddits := make([]subreddit, len(parsing.Subreddits))
-	for idx, name := range parsing.Subreddits {
-		subreddits[idx] = subreddit{
-			name: name,
-			last: "",
+	subreddits := make([]*subreddit, len(parsing.Subreddits))
+	for idx, entry := range parsing.Subreddits {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("subreddit #%d has no name", idx+1)
+		}
+
+		subreddits[idx] = &subreddit{
+			name:          entry.Name,
+			last:          "",
+			refreshPeriod: entry.RefreshPeriod,
 		}
 	}
 
 	return subreddits, nil
 }
+
+// the subreddits in the subreddit list with the given names, or all of them if names is empty
+func (r *redditApiHandler) findSubreddits(names []string) []*subreddit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := make([]*subreddit, 0, len(r.subreddits))
+	for _, sub := range r.subreddits {
+		if len(names) == 0 {
+			found = append(found, sub)
+			continue
+		}
+
+		for _, name := range names {
+			if strings.EqualFold(sub.name, name) {
+				found = append(found, sub)
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// each subreddit's refresh period in seconds, as set in SUBREDDITS_PATH. 0 means it has none of its own (use NEW_POSTS_REFRESH_PERIOD)
+func (r *redditApiHandler) SubredditRefreshPeriods() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	periods := make(map[string]uint64, len(r.subreddits))
+	for _, sub := range r.subreddits {
+		periods[sub.name] = sub.refreshPeriod
+	}
+
+	return periods
+}
+
+// sets the last post seen in each subreddit to the newest tracked post in it, for subreddits that haven't been fetched yet.
+// Without this, the first fetch after starting never tracks anything (see TrackNewlyCreatedPosts), so posts created while
+// the program wasn't running would be skipped. That fetch looks through up to depth posts to find the last one, to catch up
+// on everything posted in the meantime.
+// Returns how long ago the newest tracked post was created, roughly how long the program was offline. 0 if nothing's tracked
+func (r *redditApiHandler) ResumeFromTrackedPosts(depth int) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var newestOverall uint64
+	for _, sub := range r.subreddits {
+		if sub.last != "" {
+			continue
+		}
+
+		var newest *RedditContent
+		for _, post := range r.trackedListings {
+			if !strings.EqualFold(post.Subreddit, sub.name) {
+				continue
+			}
+			if newest == nil || post.Date > newest.Date {
+				post := post
+				newest = &post
+			}
+		}
+
+		if newest != nil {
+			sub.last = newest.FullId()
+			sub.catchUp = depth
+			if newest.Date > newestOverall {
+				newestOverall = newest.Date
+			}
+		}
+	}
+
+	if newestOverall == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(int64(newestOverall), 0))
+}
+
+//re-reads SUBREDDITS_PATH, eg: after the file was edited. Subreddits that are still in it carry on from the last post seen in
+//them, new ones start being fetched from their newest post (like on startup) and the posts of removed ones stay tracked until
+//they're too old. The list is left alone if the file can't be read. Returns the names of the added and removed subreddits
+func (r *redditApiHandler) ReloadSubreddits() (added []string, removed []string, err error) {
+	subreddits, err := getSubredditsFromFile()
+	if err != nil {
+		return nil, nil, errors.New("error getting subreddits from file:\n" + err.Error())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	//subreddits added with AddSubreddit stay, unless the file has them too
+	for _, name := range r.added {
+		if !containsSubreddit(subreddits, name) {
+			subreddits = append(subreddits, &subreddit{name: name})
+		}
+	}
+
+	added, removed = r.replaceSubreddits(subreddits)
+	return added, removed, nil
+}
+
+//replaces the subreddit list with the one given as the contents of a SUBREDDITS_PATH file, eg: from an admin ui. It's written
+//to SUBREDDITS_PATH, so that it lasts past a restart, and then applied like ReloadSubreddits, except that subreddits added
+//with AddSubreddit are dropped unless they're in it. Nothing changes if a subreddit name isn't valid, or with SUBREDDITS
+//set (it overrides the file). Returns the names of the added and removed subreddits
+func (r *redditApiHandler) ReplaceSubreddits(data []byte) (added []string, removed []string, err error) {
+	if names, err := util.GetEnvStringSlice("SUBREDDITS"); err == nil && len(names) > 0 {
+		return nil, nil, errors.New("SUBREDDITS is set, which replaces the subreddits file. Unset it to manage the list this way")
+	}
+
+	subreddits, err := parseSubreddits(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]subredditEntry, len(subreddits))
+	for idx, sub := range subreddits {
+		sub.name = strings.TrimPrefix(strings.TrimSpace(sub.name), "r/")
+		if !subredditName.MatchString(sub.name) {
+			return nil, nil, fmt.Errorf("\"%s\" isn't a subreddit name", sub.name)
+		}
+		entries[idx] = subredditEntry{Name: sub.name, RefreshPeriod: sub.refreshPeriod}
+	}
+
+	//written next to the file then renamed over it, so that it's never half written
+	path, err := util.GetEnv("SUBREDDITS_PATH")
+	if err != nil {
+		return nil, nil, err
+	}
+	contents, err := json.MarshalIndent(subredditsFile{Subreddits: entries}, "", "    ")
+	if err != nil {
+		return nil, nil, err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".subreddits-*.json")
+	if err != nil {
+		return nil, nil, errors.New("error writing subreddits file:\n" + err.Error())
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(append(contents, '\n'))
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return nil, nil, errors.New("error writing subreddits file:\n" + err.Error())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.added = nil
+	added, removed = r.replaceSubreddits(subreddits)
+	return added, removed, nil
+}
+
+//must be called with r.mu held. Subreddits that are still in the list carry on from the last post seen in them
+func (r *redditApiHandler) replaceSubreddits(subreddits []*subreddit) (added []string, removed []string) {
+	if r.shard.Count > 1 {
+		subreddits = r.shard.filter(subreddits)
+	}
+
+	previous := make(map[string]*subreddit, len(r.subreddits))
+	for _, sub := range r.subreddits {
+		previous[strings.ToLower(sub.name)] = sub
+	}
+
+	for idx, sub := range subreddits {
+		key := strings.ToLower(sub.name)
+		kept, exists := previous[key]
+		if !exists {
+			added = append(added, sub.name)
+			continue
+		}
+
+		kept.refreshPeriod = sub.refreshPeriod
+		subreddits[idx] = kept
+		delete(previous, key)
+	}
+	for _, sub := range previous {
+		removed = append(removed, sub.name)
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	r.subreddits = subreddits
+	return added, removed
+}
+
+var subredditName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{1,20}$`)
+
+//starts fetching new posts from another subreddit, on top of the ones in SUBREDDITS_PATH, from its newest post. It isn't written to
+//the file, so it's only tracked until the program restarts. Returns false if it's already tracked
+func (r *redditApiHandler) AddSubreddit(name string) (bool, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(name), "/"), "r/")
+	if !subredditName.MatchString(name) {
+		return false, fmt.Errorf("\"%s\" isn't a subreddit name", name)
+	}
+	if !r.shard.Owns(name) {
+		return false, fmt.Errorf("r/%s belongs to another shard than %s, see SHARD_COUNT", name, r.shard)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if containsSubreddit(r.subreddits, name) {
+		return false, nil
+	}
+	r.subreddits = append(r.subreddits, &subreddit{name: name})
+	r.added = append(r.added, name)
+	return true, nil
+}
+
+func containsSubreddit(subreddits []*subreddit, name string) bool {
+	for _, sub := range subreddits {
+		if strings.EqualFold(sub.name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package reddit
+
+//this file works out how fast a post is rising, so that every consumer of the updates doesn't have to
+
+// upvotes and comments gained an hour between two snapshots of a post, eg: its last two updates. Negative when it lost
+// some. 0 when current isn't later than previous
+func VelocityBetween(previous, current Snapshot) (upvotesPerHour float64, commentsPerHour float64) {
+	if current.Date <= previous.Date {
+		return 0, 0
+	}
+
+	hours := float64(current.Date-previous.Date) / 3600
+	return float64(current.Upvotes-previous.Upvotes) / hours, float64(current.Comments-previous.Comments) / hours
+}
+
+// sets post's UpvotesPerHour and CommentsPerHour from previous, its snapshot before this one
+func (r *RedditContent) setVelocity(previous Snapshot) {
+	r.UpvotesPerHour, r.CommentsPerHour = VelocityBetween(previous, r.Snapshot())
+}
@@ -0,0 +1,340 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/pb"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	the Votewatch grpc service (see pb/proto/votewatch.proto), so that other
+	services can use votewatch through generated, typed clients instead of
+	the json api. With GRPC_ADDRESS set, it serves:
+
+		ListTracked        the tracked posts and their current scores
+		GetHistory         a post's vote history
+		Subscribe          a stream of what happens to the tracked posts (see scheduler/events.go)
+		ControlTracking    starts tracking another subreddit, or runs a job now (see scheduler/control.go)
+
+	eg, from another go service:
+
+		conn, err := grpc.Dial("localhost:9103", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		client := pb.NewVotewatchClient(conn)
+		stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{Kinds: []pb.EventKind{pb.EventKind_SCORE_THRESHOLD_CROSSED}})
+
+	if API_TOKEN is set, calls need "authorization: Bearer <API_TOKEN>"
+	metadata, like the json api. The connection isn't encrypted, so
+	GRPC_ADDRESS should only be reachable locally or through a proxy that
+	terminates TLS
+*/
+
+// how long looking up a post's history, or waiting for the scheduler to pick up a job, may take
+const callTimeout = 10 * time.Second
+
+// how many events a subscriber can fall behind by before newer ones are dropped
+const subscriberBuffer = 256
+
+// the reddit api handler, as far as the service is concerned
+type trackedSource interface {
+	GetTrackedPosts() reddit.ContentGroup
+}
+
+// the part of database.Store that the service needs
+type historySource interface {
+	GetHistory(ctx context.Context, ID reddit.Fullname) ([]database.Snapshot, error)
+}
+
+// the scheduler, as far as the service is concerned
+type trackingControl interface {
+	Track(subreddit string) (bool, error)
+	JobNames() []string
+	Trigger(ctx context.Context, name string) error
+}
+
+type Server struct {
+	pb.UnimplementedVotewatchServer
+
+	tracked trackedSource
+	history historySource
+	control trackingControl
+	token   string
+}
+
+// reads API_TOKEN. Subscribe only streams events if Setup was called before the scheduler was created
+func New(tracked trackedSource, history historySource, control trackingControl) *Server {
+	return &Server{tracked: tracked, history: history, control: control, token: os.Getenv("API_TOKEN")}
+}
+
+// serves the service on address (eg: "localhost:9103"). Only returns if the server fails
+func (s *Server) Serve(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authenticate(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authenticate(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	)
+	pb.RegisterVotewatchServer(server, s)
+	return server.Serve(listener)
+}
+
+func (s *Server) authenticate(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		given := strings.TrimPrefix(value, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong API_TOKEN")
+}
+
+func (s *Server) ListTracked(ctx context.Context, request *pb.ListTrackedRequest) (*pb.ListTrackedResponse, error) {
+	subreddit := strings.TrimPrefix(request.GetSubreddit(), "r/")
+
+	var key func(*pb.TrackedPost) int64
+	switch request.GetSort() {
+	case pb.PostOrder_MOST_UPVOTED:
+		key = func(p *pb.TrackedPost) int64 { return int64(p.Upvotes) }
+	case pb.PostOrder_MOST_COMMENTED:
+		key = func(p *pb.TrackedPost) int64 { return int64(p.Comments) }
+	case pb.PostOrder_NEWEST:
+		key = func(p *pb.TrackedPost) int64 { return int64(p.Created) }
+	case pb.PostOrder_OLDEST:
+		key = func(p *pb.TrackedPost) int64 { return -int64(p.Created) }
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort %d", request.GetSort())
+	}
+
+	posts := make([]*pb.TrackedPost, 0)
+	for _, listing := range s.tracked.GetTrackedPosts() {
+		if subreddit != "" && !strings.EqualFold(listing.Subreddit, subreddit) {
+			continue
+		}
+		posts = append(posts, toTrackedPost(listing))
+	}
+	//ties broken by id, like the json api
+	sort.Slice(posts, func(i, j int) bool {
+		if key(posts[i]) != key(posts[j]) {
+			return key(posts[i]) > key(posts[j])
+		}
+		return posts[i].Id < posts[j].Id
+	})
+	if limit := int(request.GetLimit()); limit > 0 && len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	return &pb.ListTrackedResponse{Posts: posts}, nil
+}
+
+func (s *Server) GetHistory(ctx context.Context, request *pb.PostHistoryRequest) (*pb.PostHistoryResponse, error) {
+	//posts can be given by their id alone, as it appears in their url
+	ID := reddit.Fullname(request.GetId())
+	if !ID.IsValid() {
+		ID = reddit.Fullname("t3_" + request.GetId())
+	}
+	if !ID.IsValid() {
+		return nil, status.Error(codes.InvalidArgument, "expected a fullname (eg: t3_62sjuh) or post id (eg: 62sjuh)")
+	}
+
+	response := &pb.PostHistoryResponse{}
+	if listing, tracked := s.tracked.GetTrackedPosts()[ID]; tracked {
+		response.Tracked = true
+		response.Post = toTrackedPost(listing)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	entries, err := s.history.GetHistory(ctx, ID)
+	switch {
+	case errors.Is(err, database.ErrListingNotFound) && !response.Tracked:
+		return nil, status.Error(codes.NotFound, string(ID)+" isn't tracked or stored")
+	case errors.Is(err, database.ErrListingNotFound):
+		// tracked, but not saved yet
+	case err != nil:
+		return nil, status.Error(codes.Unavailable, "error getting history:\n"+err.Error())
+	}
+
+	response.Entries = make([]*pb.PostSnapshot, len(entries))
+	for idx, entry := range entries {
+		response.Entries[idx] = &pb.PostSnapshot{Upvotes: int32(entry.Upvotes), Comments: int32(entry.Comments), Date: entry.Date,
+			UpvotesPerHour: entry.UpvotesPerHour, CommentsPerHour: entry.CommentsPerHour}
+	}
+	return response, nil
+}
+
+func (s *Server) ControlTracking(ctx context.Context, request *pb.ControlTrackingRequest) (*pb.ControlTrackingResponse, error) {
+	switch request.GetAction() {
+	case pb.ControlAction_TRACK_SUBREDDIT:
+		added, err := s.control.Track(request.GetTarget())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &pb.ControlTrackingResponse{Changed: added}, nil
+
+	case pb.ControlAction_RUN_JOB:
+		known := false
+		for _, name := range s.control.JobNames() {
+			known = known || strings.EqualFold(name, request.GetTarget())
+		}
+		if !known {
+			return nil, status.Errorf(codes.NotFound, "no job named \"%s\"", request.GetTarget())
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, callTimeout)
+		defer cancel()
+
+		err := s.control.Trigger(ctx, request.GetTarget())
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return &pb.ControlTrackingResponse{Changed: true}, nil
+	}
+	return nil, status.Errorf(codes.InvalidArgument, "unknown action %d", request.GetAction())
+}
+
+// the kinds of events Subscribe streams, the others aren't about a post
+var eventKinds = map[scheduler.EventKind]pb.EventKind{
+	scheduler.PostTracked:           pb.EventKind_POST_TRACKED,
+	scheduler.PostUpdated:           pb.EventKind_POST_UPDATED,
+	scheduler.ScoreThresholdCrossed: pb.EventKind_SCORE_THRESHOLD_CROSSED,
+	scheduler.PostRemoved:           pb.EventKind_POST_REMOVED,
+}
+
+type subscriber struct {
+	kinds      map[pb.EventKind]bool // every kind if empty
+	subreddits map[string]bool       // lowercased, every subreddit if empty
+	events     chan scheduler.Event
+	missed     uint32 // since the last event sent, accessed atomically
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   = make(map[*subscriber]bool)
+)
+
+// registers the event handlers that Subscribe streams from. Call it before scheduler.New
+func Setup() {
+	for kind := range eventKinds {
+		scheduler.OnEvent(kind, dispatch)
+	}
+}
+
+// hands the event to every subscriber that wants it, without waiting on any of them
+func dispatch(event scheduler.Event) {
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+
+	for sub := range subscribers {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint32(&sub.missed, 1)
+		}
+	}
+}
+
+func (sub *subscriber) wants(event scheduler.Event) bool {
+	if len(sub.kinds) > 0 && !sub.kinds[eventKinds[event.Kind]] {
+		return false
+	}
+	return len(sub.subreddits) == 0 || sub.subreddits[strings.ToLower(event.Post.Subreddit)]
+}
+
+func (s *Server) Subscribe(request *pb.SubscribeRequest, stream pb.Votewatch_SubscribeServer) error {
+	sub := &subscriber{
+		kinds:      make(map[pb.EventKind]bool),
+		subreddits: make(map[string]bool),
+		events:     make(chan scheduler.Event, subscriberBuffer),
+	}
+	for _, kind := range request.GetKinds() {
+		sub.kinds[kind] = true
+	}
+	for _, subreddit := range request.GetSubreddits() {
+		sub.subreddits[strings.ToLower(strings.TrimPrefix(subreddit, "r/"))] = true
+	}
+
+	subscribersMu.Lock()
+	subscribers[sub] = true
+	subscribersMu.Unlock()
+	defer func() {
+		subscribersMu.Lock()
+		delete(subscribers, sub)
+		subscribersMu.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-sub.events:
+			message := &pb.PostEvent{
+				Kind:            eventKinds[event.Kind],
+				Time:            uint64(event.Time.Unix()),
+				Post:            toTrackedPost(event.Post),
+				PreviousUpvotes: int32(event.PreviousUpvotes),
+				Threshold:       int32(event.Threshold),
+				RemovedBy:       event.Post.RemovedBy,
+				Missed:          atomic.SwapUint32(&sub.missed, 0),
+			}
+			if err := stream.Send(message); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func toTrackedPost(listing reddit.RedditContent) *pb.TrackedPost {
+	return &pb.TrackedPost{
+		Id:          string(listing.FullId()),
+		Subreddit:   listing.Subreddit,
+		Title:       listing.Title,
+		Author:      listing.Author,
+		Url:         listing.Url,
+		Flair:       listing.Flair,
+		Created:     listing.Date,
+		Queried:     listing.QueryDate,
+		Upvotes:     int32(listing.Upvotes),
+		Comments:    int32(listing.Comments),
+		UpvoteRatio: listing.Ratio,
+
+		UpvotesPerHour:  listing.UpvotesPerHour,
+		CommentsPerHour: listing.CommentsPerHour,
+	}
+}
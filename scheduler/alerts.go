@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	a job failing over and over, or nothing reaching the database, is easy to
+	miss among the rest of the log. When a job fails ALERT_FAILURES times in a
+	row, or nothing has been written to the database for ALERT_NO_WRITES
+	minutes, an alert is raised:
+
+		- logged at the alert level (prefixed with ALERT: in text logs)
+		- POSTed as json to ALERT_WEBHOOK_URL, if set. The message is in "text",
+		  so slack/mattermost incoming webhooks can take it as is
+		- sent out as an AlertRaised event, see events.go
+
+	an alert is only raised once, when it starts. Once the job succeeds again
+	(or something is written), a second alert with Resolved set says so
+*/
+
+const (
+	writeCheckPeriod = time.Minute
+	webhookTimeout   = 10 * time.Second
+	maxQueuedAlerts  = 32
+)
+
+type Alert struct {
+	Job      string // the job that keeps failing. Empty for alerts about nothing being written
+	Message  string
+	Resolved bool // the problem went away
+}
+
+// when anything was last written to the database, see markWritten
+var lastWrite struct {
+	sync.Mutex
+	time time.Time
+}
+
+// called after every successful write to the database, for ALERT_NO_WRITES
+func markWritten() {
+	lastWrite.Lock()
+	lastWrite.time = time.Now()
+	lastWrite.Unlock()
+}
+
+type alerter struct {
+	failureThreshold int           // consecutive failures of a job that raise an alert. 0 disables them
+	noWrites         time.Duration // time without writes that raises an alert. 0 disables it
+
+	webhook string
+	client  *http.Client
+	queue   chan Alert // sent to the webhook one at a time, so that they arrive in order
+
+	mu      sync.Mutex
+	started time.Time
+	raised  map[string]bool // by job, "" for nothing being written
+}
+
+// reads the ALERT_* env variables. Returns nil if alerts are disabled
+func newAlerter() *alerter {
+	a := &alerter{
+		failureThreshold: util.GetEnvIntDefault("ALERT_FAILURES", 3),
+		noWrites:         time.Minute * time.Duration(util.GetEnvIntDefault("ALERT_NO_WRITES", 60)),
+		client:           &http.Client{Timeout: webhookTimeout},
+		started:          time.Now(),
+		raised:           make(map[string]bool),
+	}
+	if a.failureThreshold <= 0 && a.noWrites <= 0 {
+		return nil
+	}
+
+	if webhook, exists := os.LookupEnv("ALERT_WEBHOOK_URL"); exists && strings.TrimSpace(webhook) != "" {
+		a.webhook = strings.TrimSpace(webhook)
+		a.queue = make(chan Alert, maxQueuedAlerts)
+		go func() {
+			for alert := range a.queue {
+				a.post(alert)
+			}
+		}()
+	}
+	return a
+}
+
+// called after every run of a job, with how many times in a row it's failed (see stats.go)
+func (a *alerter) jobRan(name string, failures int, err error) {
+	if a == nil || a.failureThreshold <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	raised := a.raised[name]
+	switch {
+	case err == nil && raised:
+		a.raised[name] = false
+	case err != nil && !raised && failures >= a.failureThreshold:
+		a.raised[name] = true
+	default:
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+
+	if err == nil {
+		a.raise(Alert{Job: name, Message: fmt.Sprintf("%s succeeded again", name), Resolved: true})
+		return
+	}
+	a.raise(Alert{Job: name, Message: fmt.Sprintf("%s failed %d times in a row:\n%s", name, failures, err)})
+}
+
+// raises an alert if nothing has been written for too long. Run every writeCheckPeriod as a job
+func (a *alerter) checkWrites() {
+	if a == nil || a.noWrites <= 0 {
+		return
+	}
+
+	lastWrite.Lock()
+	last := lastWrite.time
+	lastWrite.Unlock()
+
+	a.mu.Lock()
+	since := a.started
+	if last.After(since) {
+		since = last
+	}
+	stale := time.Since(since) >= a.noWrites
+	raised := a.raised[""]
+	if stale == raised {
+		a.mu.Unlock()
+		return
+	}
+	a.raised[""] = stale
+	a.mu.Unlock()
+
+	if !stale {
+		a.raise(Alert{Message: "data is being written to the database again", Resolved: true})
+		return
+	}
+
+	message := fmt.Sprintf("nothing has been written to the database for %s", time.Since(since).Round(time.Minute))
+	if last.IsZero() {
+		message = fmt.Sprintf("nothing has been written to the database since starting %s ago", time.Since(since).Round(time.Minute))
+	}
+	a.raise(Alert{Message: message})
+}
+
+func (a *alerter) raise(alert Alert) {
+	fields := map[string]interface{}{"alert": true, "resolved": alert.Resolved}
+	if alert.Job != "" {
+		fields["job"] = alert.Job
+	}
+
+	switch {
+	case alert.Resolved:
+		logWithFields(false, "resolved: "+alert.Message, fields)
+	case jsonLogs():
+		logJSON("alert", alert.Message, fields)
+	default:
+		logOutputError("ALERT: " + alert.Message)
+	}
+
+	emit(Event{Kind: AlertRaised, Job: alert.Job, Alert: &alert})
+
+	if a.queue != nil {
+		select {
+		case a.queue <- alert:
+		default:
+			logOutputError("too many alerts queued for ALERT_WEBHOOK_URL, dropped one")
+		}
+	}
+}
+
+// sends an alert to ALERT_WEBHOOK_URL
+func (a *alerter) post(alert Alert) {
+	text := "votewatch alert: " + alert.Message
+	if alert.Resolved {
+		text = "votewatch resolved: " + alert.Message
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"text":     text,
+		"job":      alert.Job,
+		"resolved": alert.Resolved,
+		"time":     time.Now().UTC().Format(time.RFC3339),
+	})
+
+	request, err := http.NewRequest(http.MethodPost, a.webhook, bytes.NewReader(body))
+	if err != nil {
+		logOutputError("error sending alert to ALERT_WEBHOOK_URL:\n" + err.Error())
+		return
+	}
+	request.Header.Set("content-type", "application/json")
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		logOutputError("error sending alert to ALERT_WEBHOOK_URL:\n" + err.Error())
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		logOutputError(fmt.Sprintf("error sending alert to ALERT_WEBHOOK_URL: %s", response.Status))
+	}
+}
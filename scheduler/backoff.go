@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	when reddit keeps failing (an outage, a revoked token...) there's no point
+	spending the rate limit on requests that are going to fail too. After
+	REDDIT_BACKOFF_THRESHOLD consecutive failed fetches or updates, the jobs
+	that call reddit are skipped for REDDIT_BACKOFF_BASE seconds, doubling
+	after every further failure up to REDDIT_BACKOFF_MAX. The first success
+	goes back to the normal schedule
+*/
+
+// an error that came from reddit rather than the database. Only these count towards backing off
+type redditError struct {
+	err error
+}
+
+func (e redditError) Error() string {
+	return e.err.Error()
+}
+
+func (e redditError) Unwrap() error {
+	return e.err
+}
+
+type redditBackoff struct {
+	mu       sync.Mutex
+	failures int       //consecutive
+	until    time.Time //reddit jobs are skipped until then
+
+	threshold int
+	base      time.Duration
+	max       time.Duration
+}
+
+func newRedditBackoff() *redditBackoff {
+	return &redditBackoff{
+		threshold: util.GetEnvIntDefault("REDDIT_BACKOFF_THRESHOLD", 3),
+		base:      time.Second * time.Duration(util.GetEnvIntDefault("REDDIT_BACKOFF_BASE", 60)),
+		max:       time.Second * time.Duration(util.GetEnvIntDefault("REDDIT_BACKOFF_MAX", 3600)),
+	}
+}
+
+// whether a job calling reddit should be skipped for now
+func (b *redditBackoff) waiting(job string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Now().Before(b.until) {
+		logOutput(fmt.Sprintf("skipping %s, reddit failed %d times in a row. Backing off until %s", job, b.failures, b.until.Format(time.ANSIC)))
+		return true
+	}
+	return false
+}
+
+// records the outcome of a job calling reddit
+func (b *redditBackoff) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.failures >= b.threshold {
+			logOutput("reddit recovered, back to the normal schedule")
+		}
+		b.failures = 0
+		b.until = time.Time{}
+		return
+	}
+
+	//a database error says nothing about reddit
+	var redditErr redditError
+	if !errors.As(err, &redditErr) {
+		return
+	}
+
+	b.failures += 1
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return
+	}
+
+	delay := b.base
+	for i := b.threshold; i < b.failures && delay < b.max; i += 1 {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+
+	b.until = time.Now().Add(delay)
+	logOutputError(fmt.Sprintf("reddit failed %d times in a row, backing off for %s", b.failures, delay))
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+// a copy of the tracked posts kept outside of the program (eg: in redis, see the cache package), to start from instead
+// of pulling them from the database
+type trackedPostsCache interface {
+	Load(context.Context) (reddit.ContentGroup, error)
+	Store(context.Context, reddit.ContentGroup) error
+}
+
+// makes Run take the tracked posts from cache, falling back to the database if it's empty or fails. The posts pulled
+// from the database are then stored in it. Must be called before Run
+func (s *Scheduler) UseCache(cache trackedPostsCache) {
+	s.cache = cache
+}
+
+// the tracked posts from before the program started, from the cache if there is one
+func (s *Scheduler) pullTrackedPosts(ctx context.Context) {
+	if s.cache == nil {
+		pullFromDB(ctx, s.reddit, s.database)
+		return
+	}
+
+	cached, err := s.cache.Load(ctx)
+	if err != nil {
+		logOutputError("warning: error loading tracked posts from cache, pulling them from the database instead:\n" + err.Error())
+	} else if len(cached) > 0 {
+		posts := s.reddit.GetTrackedPosts()
+		for ID, post := range cached {
+			posts[ID] = post
+		}
+		logOutput(fmt.Sprintf("%d posts recieved from cache\n", len(cached)))
+		s.reddit.TrackPosts(posts)
+		evictTrackedPosts(s.reddit)
+		return
+	}
+
+	pullFromDB(ctx, s.reddit, s.database)
+	err = s.cache.Store(ctx, s.reddit.GetTrackedPosts())
+	if err != nil {
+		logOutputError("warning: error caching the tracked posts:\n" + err.Error())
+	}
+}
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	jobs can be run on demand, eg: right after changing the subreddit list, or
+	while debugging. With CONTROL_ADDRESS set, the scheduler serves:
+
+		GET  /jobs                     the names of every job, one per line
+		POST /jobs/run?name=<job name> runs the job now
+		GET  /subreddits               the subreddits being tracked, as a SUBREDDITS_PATH file
+		POST /subreddits               replaces the subreddit list with the SUBREDDITS_PATH file in the body, see
+		                               SetSubreddits. Responds with the added and removed subreddits
+
+	eg: curl -X POST "localhost:9101/jobs/run?name=updating%20posts"
+
+	a triggered job runs even during quiet hours or while backing off, but
+	never alongside itself (see overlap.go).
+
+	with CONTROL_TOKEN set, every request needs an "Authorization: Bearer
+	<CONTROL_TOKEN>" header. Without it, there's no authentication, so
+	CONTROL_ADDRESS should only be reachable locally, and POST /subreddits is
+	refused since it writes to the host's files
+*/
+
+// the largest subreddit list POST /subreddits accepts
+const maxSubredditsBody = 1 << 20
+
+var errUnknownJob = errors.New("no job named")
+
+// the names of every registered job, as accepted by Trigger
+func (s *Scheduler) JobNames() []string {
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+
+	names := make([]string, len(s.jobs))
+	for idx, j := range s.jobs {
+		names[idx] = j.name
+	}
+	return names
+}
+
+// runs the job with the given name (case insensitive) now, on top of its schedule. Blocks until the scheduler
+// loop picks it up or ctx is done
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	j := s.findJob(name)
+	if j == nil {
+		return fmt.Errorf("%w \"%s\"", errUnknownJob, name)
+	}
+
+	select {
+	case s.ticks <- jobTick{job: j, time: time.Now(), manual: true}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler didn't pick up the job:\n%s", ctx.Err())
+	}
+}
+
+// the lock isn't held while the tick is sent, the scheduler loop may need it to reload (see reload.go)
+func (s *Scheduler) findJob(name string) *job {
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+
+	for _, j := range s.jobs {
+		if strings.EqualFold(j.name, name) {
+			return j
+		}
+	}
+	return nil
+}
+
+// starts fetching new posts from another subreddit until the program restarts (see AddSubreddit in the reddit package). The
+// jobs are registered again, like on Reload, in case the other subreddits have their own refresh periods. Returns false if
+// it's already tracked
+func (s *Scheduler) Track(subreddit string) (bool, error) {
+	added, err := s.reddit.AddSubreddit(subreddit)
+	if err != nil || !added {
+		return false, err
+	}
+
+	logOutput("now tracking r/" + strings.TrimPrefix(subreddit, "r/"))
+	s.Reload()
+	return true, nil
+}
+
+// replaces the subreddit list with data, the contents of a SUBREDDITS_PATH file, and writes it to SUBREDDITS_PATH (see
+// ReplaceSubreddits in the reddit package). The jobs are registered again, like on Reload. Returns the names of the added and
+// removed subreddits
+func (s *Scheduler) SetSubreddits(data []byte) ([]string, []string, error) {
+	added, removed, err := s.reddit.ReplaceSubreddits(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(added) > 0 {
+		logOutput("now tracking r/" + strings.Join(added, ", r/"))
+	}
+	if len(removed) > 0 {
+		logOutput("no longer tracking r/" + strings.Join(removed, ", r/") + ", their posts stay tracked until they're too old")
+	}
+	s.Reload()
+	return added, removed, nil
+}
+
+// serves the endpoints described above on address (eg: "localhost:9101"). Only returns if the server fails
+func (s *Scheduler) ServeControl(address string) error {
+	token := strings.TrimSpace(os.Getenv("CONTROL_TOKEN"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintln(w, strings.Join(s.JobNames(), "\n"))
+	})
+	mux.HandleFunc("/jobs/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		name := r.URL.Query().Get("name")
+		err := s.Trigger(ctx, name)
+		if errors.Is(err, errUnknownJob) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "triggered %s\n", name)
+	})
+	mux.HandleFunc("/subreddits", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.listSubreddits(w)
+		case http.MethodPost:
+			if token == "" {
+				http.Error(w, "set CONTROL_TOKEN to change the subreddits", http.StatusForbidden)
+				return
+			}
+			s.setSubreddits(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "use GET or POST", http.StatusMethodNotAllowed)
+		}
+	})
+
+	return http.ListenAndServe(address, requireToken(token, mux))
+}
+
+// rejects requests without the token as a bearer token. Lets everything through if there's no token
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or wrong CONTROL_TOKEN", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// a SUBREDDITS_PATH file, see getSubredditsFromFile in the reddit package
+type subredditsFile struct {
+	Subreddits []subredditEntry `json:"subreddits"`
+}
+
+type subredditEntry struct {
+	Name          string `json:"name"`
+	RefreshPeriod uint64 `json:"refresh_period,omitempty"`
+}
+
+func (s *Scheduler) listSubreddits(w http.ResponseWriter) {
+	periods := s.reddit.SubredditRefreshPeriods()
+	list := subredditsFile{Subreddits: make([]subredditEntry, 0, len(periods))}
+	for name, period := range periods {
+		list.Subreddits = append(list.Subreddits, subredditEntry{Name: name, RefreshPeriod: period})
+	}
+	sort.Slice(list.Subreddits, func(i, j int) bool { return list.Subreddits[i].Name < list.Subreddits[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (s *Scheduler) setSubreddits(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubredditsBody))
+	if err != nil {
+		http.Error(w, "error reading the subreddit list:\n"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, removed, err := s.SetSubreddits(data)
+	if err != nil {
+		http.Error(w, "subreddits left unchanged:\n"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"added": nonNil(added), "removed": nonNil(removed)})
+}
+
+// so that an empty list is encoded as [] rather than null
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	jobs run every <JOB>_REFRESH_PERIOD seconds (or a duration like 90s) by
+	default, or on a cron schedule if <JOB>_SCHEDULE is set, eg: update every
+	15 minutes during the day but hourly at night:
+
+		UPDATE_TRACKED_POSTS_SCHEDULE="0,15,30,45 8-23 * * *; 0 0-7 * * *"
+
+	schedules are standard 5 field cron expressions (minute hour day-of-month
+	month day-of-week) in local time, supporting *, ranges (a-b), steps (a-b/n,
+	or * followed by /n) and lists (a,b). Several expressions can be given
+	separated by semicolons, in which case the job runs whenever any of them
+	matches. Like in cron, if both day fields are restricted a day matching
+	either of them is enough. Names (eg: MON, JAN) aren't supported
+*/
+
+// fires like a time.Ticker, on either a fixed period or a cron schedule
+type jobTicker struct {
+	C <-chan time.Time
+
+	stop func()
+
+	//roughly how long until the tick after the next one. For cron schedules this changes over time
+	interval func() time.Duration
+}
+
+func (t *jobTicker) Stop() {
+	t.stop()
+}
+
+func (t *jobTicker) Interval() time.Duration {
+	return t.interval()
+}
+
+func newPeriodTicker(period time.Duration) *jobTicker {
+	ticker := time.NewTicker(period)
+	return &jobTicker{C: ticker.C, stop: ticker.Stop, interval: func() time.Duration { return period }}
+}
+
+// a ticker following the cron schedule in scheduleEnv if it's set, otherwise every periodEnv (seconds, or a duration
+// like 90s). The env variables are read right away but nothing ticks until the scheduler starts, see Scheduler.Run
+func jobTickerFromEnv(scheduleEnv string, periodEnv string) (func() *jobTicker, error) {
+	if expression, exists := lookupSchedule(scheduleEnv); exists {
+		schedule, err := parseCronSchedule(expression)
+		if err != nil {
+			logOutputError(fmt.Sprintf("malformed %s, using %s instead:\n%s", scheduleEnv, periodEnv, err))
+		} else {
+			return func() *jobTicker {
+				return newCronTicker(schedule)
+			}, nil
+		}
+	}
+
+	period, err := util.GetEnvDuration(periodEnv, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("%s must be positive", periodEnv)
+	}
+	return periodTicker(period), nil
+}
+
+// newPeriodTicker, for when the scheduler starts
+func periodTicker(period time.Duration) func() *jobTicker {
+	return func() *jobTicker {
+		return newPeriodTicker(period)
+	}
+}
+
+// schedules are optional, so a missing one isn't worth a warning
+func lookupSchedule(env string) (string, bool) {
+	expression, _ := os.LookupEnv(env)
+	expression = strings.TrimSpace(expression)
+	return expression, expression != ""
+}
+
+func newCronTicker(schedule cronSchedule) *jobTicker {
+	c := make(chan time.Time, 1)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(schedule.next(time.Now())))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case now := <-timer.C:
+				// like time.Ticker, drop the tick if the last one hasn't been handled yet
+				select {
+				case c <- now:
+				default:
+				}
+			}
+		}
+	}()
+
+	interval := func() time.Duration {
+		next := schedule.next(time.Now())
+		return schedule.next(next).Sub(next)
+	}
+
+	return &jobTicker{C: c, stop: func() { close(done) }, interval: interval}
+}
+
+// one or more cron expressions
+type cronSchedule []cronExpression
+
+// the allowed values of each field of an expression, as bitsets
+type cronExpression struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// whether the day fields are "*". See matchesDay()
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+func parseCronSchedule(schedule string) (cronSchedule, error) {
+	expressions := make(cronSchedule, 0)
+	for _, expression := range strings.Split(schedule, ";") {
+		parsed, err := parseCronExpression(expression)
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, parsed)
+	}
+
+	return expressions, nil
+}
+
+func parseCronExpression(expression string) (cronExpression, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return cronExpression{}, fmt.Errorf("\"%s\" should have 5 fields (minute hour day-of-month month day-of-week)", expression)
+	}
+
+	var e cronExpression
+	var err error
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	targets := [5]*uint64{&e.minute, &e.hour, &e.dayOfMonth, &e.month, &e.dayOfWeek}
+	for idx, field := range fields {
+		*targets[idx], err = parseCronField(field, bounds[idx][0], bounds[idx][1])
+		if err != nil {
+			return cronExpression{}, fmt.Errorf("malformed field \"%s\" in \"%s\": %s", field, expression, err)
+		}
+	}
+
+	// sunday is both 0 and 7
+	if e.dayOfWeek&(1<<7) != 0 {
+		e.dayOfWeek |= 1
+	}
+	e.anyDayOfMonth = fields[2] == "*"
+	e.anyDayOfWeek = fields[4] == "*"
+
+	return e, nil
+}
+
+// parses a comma separated list of *, a, a-b, */n or a-b/n into a bitset
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("bad step \"%s\"", stepPart)
+			}
+			step = s
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+
+			l, err := strconv.Atoi(lowPart)
+			if err != nil {
+				return 0, fmt.Errorf("bad value \"%s\"", lowPart)
+			}
+			low, high = l, l
+
+			if isRange {
+				h, err := strconv.Atoi(highPart)
+				if err != nil {
+					return 0, fmt.Errorf("bad value \"%s\"", highPart)
+				}
+				high = h
+			} else if hasStep {
+				high = max // a/n means every n starting at a
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%s is out of range %d-%d", rangePart, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+func (e cronExpression) matchesDay(t time.Time) bool {
+	dayOfMonth := e.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := e.dayOfWeek&(1<<t.Weekday()) != 0
+
+	// cron runs on either day if both are restricted
+	if !e.anyDayOfMonth && !e.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// the first time after t that the expression matches
+func (e cronExpression) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// a matching time is always within a few years (feb 29th on a specific weekday being the worst case)
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		if e.month&(1<<t.Month()) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	// can't match at all, eg: february 31st
+	return limit
+}
+
+// the first time after t that any of the expressions matches
+func (s cronSchedule) next(t time.Time) time.Time {
+	var earliest time.Time
+	for idx, e := range s {
+		next := e.next(t)
+		if idx == 0 || next.Before(earliest) {
+			earliest = next
+		}
+	}
+
+	return earliest
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// a bitset with the given values
+func bits(values ...int) uint64 {
+	var set uint64
+	for _, value := range values {
+		set |= 1 << value
+	}
+	return set
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		expected uint64
+	}{
+		{"*", 0, 7, bits(0, 1, 2, 3, 4, 5, 6, 7)},
+		{"5", 0, 59, bits(5)},
+		{"1,3,5", 0, 59, bits(1, 3, 5)},
+		{"8-11", 0, 23, bits(8, 9, 10, 11)},
+		{"*/15", 0, 59, bits(0, 15, 30, 45)},
+		{"*/5", 1, 12, bits(1, 6, 11)},
+		{"0-10/5", 0, 59, bits(0, 5, 10)},
+		{"50/3", 0, 59, bits(50, 53, 56, 59)}, // every 3 starting at 50
+		{"1-3,20-22/2", 1, 31, bits(1, 2, 3, 20, 22)},
+		{"0", 0, 7, bits(0)},
+		{"7", 0, 7, bits(7)},
+	}
+
+	for _, test := range tests {
+		parsed, err := parseCronField(test.field, test.min, test.max)
+		if err != nil {
+			t.Errorf("parseCronField(%q, %d, %d): %s", test.field, test.min, test.max, err)
+			continue
+		}
+		if parsed != test.expected {
+			t.Errorf("parseCronField(%q, %d, %d) = %b, expected %b", test.field, test.min, test.max, parsed, test.expected)
+		}
+	}
+}
+
+func TestParseCronFieldErrors(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+	}{
+		{"", 0, 59},
+		{"60", 0, 59},
+		{"0", 1, 31},
+		{"10-5", 0, 59},
+		{"5-", 0, 59},
+		{"*/0", 0, 59},
+		{"*/x", 0, 59},
+		{"1,,2", 0, 59},
+		{"MON", 0, 7},
+		{"-1", 0, 59},
+	}
+
+	for _, test := range tests {
+		if _, err := parseCronField(test.field, test.min, test.max); err == nil {
+			t.Errorf("parseCronField(%q, %d, %d) didn't fail", test.field, test.min, test.max)
+		}
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, schedule := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"0 24 * * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"0 * * * *; 0 0 * *",
+	} {
+		if _, err := parseCronSchedule(schedule); err == nil {
+			t.Errorf("parseCronSchedule(%q) didn't fail", schedule)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	// 2026-10-16 is a friday
+	tests := []struct {
+		schedule string
+		from     string
+		expected string
+	}{
+		{"* * * * *", "2026-10-16 12:00", "2026-10-16 12:01"},
+		{"*/15 * * * *", "2026-10-16 12:07", "2026-10-16 12:15"},
+		{"*/15 * * * *", "2026-10-16 12:45", "2026-10-16 13:00"},
+		{"0 0 * * *", "2026-10-16 12:00", "2026-10-17 00:00"},
+		{"30 9 1 * *", "2026-10-16 12:00", "2026-11-01 09:30"},
+		{"0 0 1 1 *", "2026-10-16 12:00", "2027-01-01 00:00"},
+		{"0 0 29 2 *", "2026-10-16 12:00", "2028-02-29 00:00"},
+		{"0 12 31 * *", "2026-11-01 00:00", "2026-12-31 12:00"}, // skips november, which has 30 days
+
+		// day of week, with sunday as both 0 and 7
+		{"0 9 * * 1", "2026-10-16 12:00", "2026-10-19 09:00"},
+		{"0 9 * * 0", "2026-10-16 12:00", "2026-10-18 09:00"},
+		{"0 9 * * 7", "2026-10-16 12:00", "2026-10-18 09:00"},
+		{"0 9 * * 6-7", "2026-10-16 12:00", "2026-10-17 09:00"},
+		{"0 9 * * 5", "2026-10-16 08:00", "2026-10-16 09:00"},
+
+		// with both day fields restricted, either one matching is enough
+		{"0 0 20 * 0", "2026-10-16 12:00", "2026-10-18 00:00"}, // sunday comes before the 20th
+		{"0 0 17 * 1", "2026-10-16 12:00", "2026-10-17 00:00"}, // the 17th comes before monday
+		// with only one of them restricted, the other one is ignored
+		{"0 0 20 * *", "2026-10-16 12:00", "2026-10-20 00:00"},
+		{"0 0 * * 0", "2026-10-16 12:00", "2026-10-18 00:00"},
+		{"0 0 20 10 0", "2026-10-16 12:00", "2026-10-18 00:00"},
+
+		// several expressions, whichever matches first
+		{"0,15,30,45 8-23 * * *; 0 0-7 * * *", "2026-10-16 23:50", "2026-10-17 00:00"},
+		{"0,15,30,45 8-23 * * *; 0 0-7 * * *", "2026-10-17 07:00", "2026-10-17 08:00"},
+		{"0,15,30,45 8-23 * * *; 0 0-7 * * *", "2026-10-17 08:05", "2026-10-17 08:15"},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseCronSchedule(test.schedule)
+		if err != nil {
+			t.Errorf("parseCronSchedule(%q): %s", test.schedule, err)
+			continue
+		}
+		next := schedule.next(at(test.from))
+		if !next.Equal(at(test.expected)) {
+			t.Errorf("%q after %s: %s, expected %s", test.schedule, test.from, next.Format("2006-01-02 15:04 Mon"), test.expected)
+		}
+	}
+}
+
+func TestCronNextNeverMatches(t *testing.T) {
+	schedule, err := parseCronSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if next := schedule.next(from); next.Before(from.AddDate(8, 0, 0)) {
+		t.Errorf("february 31st matched %s", next)
+	}
+}
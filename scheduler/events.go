@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	other packages can react to what the scheduler does (send notifications,
+	write posts somewhere else...) by registering a handler with OnEvent before
+	calling Start, eg:
+
+		scheduler.OnEvent(scheduler.ScoreThresholdCrossed, func(e scheduler.Event) {
+			notify(fmt.Sprintf("%s passed %d upvotes", e.Post.Title, e.Threshold))
+		})
+
+	handlers are called synchronously from the job the event happened in, so
+	anything slow should be done in its own goroutine. Jobs run concurrently
+	(see pool.go), so handlers can be called from several goroutines at once. A
+	handler that panics is logged and otherwise ignored
+*/
+
+type EventKind string
+
+const (
+	PostTracked           EventKind = "post-tracked"            //a new post started being tracked
+	PostUpdated           EventKind = "post-updated"            //a tracked post's new upvotes + comments were recorded
+	ScoreThresholdCrossed EventKind = "score-threshold-crossed" //an update took a post's upvotes past one of SCORE_THRESHOLDS
+	PostRemoved           EventKind = "post-removed"            //an update found a tracked post taken down (by its subreddit's moderators, reddit or its author)
+	CycleFailed           EventKind = "cycle-failed"            //a scheduled job (fetching new posts, updating the tracked posts...) failed
+	PeriodicReport        EventKind = "report"                  //a summary of the last REPORT_CYCLES updates, see report.go
+	AlertRaised           EventKind = "alert"                   //a job kept failing or nothing was written for too long (or that stopped), see alerts.go
+)
+
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	//the post, for every kind but CycleFailed, PeriodicReport and AlertRaised. For PostUpdated, ScoreThresholdCrossed and PostRemoved, as of the update.
+	//Post.RemovedBy says why a removed post was taken down
+	Post reddit.RedditContent
+
+	//the post's upvotes before the update, for PostUpdated, ScoreThresholdCrossed and PostRemoved
+	PreviousUpvotes int
+
+	//the threshold that was crossed, for ScoreThresholdCrossed
+	Threshold int
+
+	//the job that failed (as it was registered, see Scheduler.Register) and why, for CycleFailed. Also the job of an AlertRaised, if any
+	Job string
+	Err error
+
+	//for PeriodicReport
+	Report *Report
+
+	//for AlertRaised
+	Alert *Alert
+}
+
+type EventHandler func(Event)
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = make(map[EventKind][]EventHandler)
+)
+
+// calls handler every time an event of the given kind happens
+func OnEvent(kind EventKind, handler EventHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers[kind] = append(handlers[kind], handler)
+}
+
+func hasHandlers(kind EventKind) bool {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+
+	return len(handlers[kind]) > 0
+}
+
+func emit(event Event) {
+	handlersMu.RLock()
+	registered := handlers[event.Kind]
+	handlersMu.RUnlock()
+
+	event.Time = time.Now()
+	for _, handler := range registered {
+		callHandler(handler, event)
+	}
+}
+
+func callHandler(handler EventHandler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logOutputError(fmt.Sprintf("%s event handler panicked: %v", event.Kind, r))
+		}
+	}()
+
+	handler(event)
+}
+
+func emitCycleFailed(job string, err error) {
+	emit(Event{Kind: CycleFailed, Job: job, Err: err})
+}
+
+// a tracked post as it was before an update
+type previousState struct {
+	upvotes   int
+	removedBy string
+}
+
+func previousStates(handler redditApiHandlerScheduler, IDs []reddit.Fullname) map[reddit.Fullname]previousState {
+	states := make(map[reddit.Fullname]previousState, len(IDs))
+	for _, ID := range IDs {
+		upvotes, _ := handler.LatestUpvotes(ID)
+		states[ID] = previousState{upvotes: upvotes, removedBy: handler.RemovedBy(ID)}
+	}
+
+	return states
+}
+
+// the PostUpdated, ScoreThresholdCrossed and PostRemoved events of an update. previous are the posts before it
+func emitUpdated(posts reddit.ContentGroup, previous map[reddit.Fullname]previousState) {
+	if !hasHandlers(PostUpdated) && !hasHandlers(ScoreThresholdCrossed) && !hasHandlers(PostRemoved) {
+		return
+	}
+	thresholds := scoreThresholds()
+
+	for ID, post := range posts {
+		before, exists := previous[ID]
+		if !exists {
+			continue
+		}
+
+		emit(Event{Kind: PostUpdated, Post: post, PreviousUpvotes: before.upvotes})
+
+		for _, threshold := range thresholds {
+			if before.upvotes < threshold && post.Upvotes >= threshold {
+				emit(Event{Kind: ScoreThresholdCrossed, Post: post, PreviousUpvotes: before.upvotes, Threshold: threshold})
+			}
+		}
+
+		if before.removedBy == "" && post.RemovedBy != "" {
+			emit(Event{Kind: PostRemoved, Post: post, PreviousUpvotes: before.upvotes})
+		}
+	}
+}
+
+// SCORE_THRESHOLDS, a comma separated list of upvote counts. Optional, so it isn't warned about when missing
+func scoreThresholds() []int {
+	thresholds := make([]int, 0)
+	if !hasHandlers(ScoreThresholdCrossed) {
+		return thresholds
+	}
+
+	fields, _ := util.GetEnvStringSlice("SCORE_THRESHOLDS")
+	for _, field := range fields {
+		threshold, err := strconv.Atoi(field)
+		if err != nil {
+			logOutputError(fmt.Sprintf("ignoring malformed score threshold \"%s\"", field))
+			continue
+		}
+		thresholds = append(thresholds, threshold)
+	}
+
+	return thresholds
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	with LOG_FORMAT=json, the scheduler logs one json object per line instead
+	of colored text, so the logs can be shipped to Loki, ELK... and queried by
+	field:
+
+		{"duration_seconds":2.1,"error":"...","failure_streak":3,"job":"updating posts","level":"error","msg":"updating posts failed after 2.1s, 3 times in a row...","time":"2022-06-01T12:00:00.123Z"}
+
+	level is "info", "warning" or "error". Some events add fields of their own,
+	eg: the job and its duration once a job finishes (see stats.go)
+*/
+
+var (
+	jsonLogsOnce sync.Once
+	jsonLogsOn   bool
+
+	logMu sync.Mutex //so that lines from concurrent jobs don't interleave
+)
+
+// whether LOG_FORMAT=json. Read on first use, since the env file is loaded after the package is
+func jsonLogs() bool {
+	jsonLogsOnce.Do(func() {
+		format, _ := os.LookupEnv("LOG_FORMAT")
+		jsonLogsOn = strings.EqualFold(strings.TrimSpace(format), "json")
+	})
+
+	return jsonLogsOn
+}
+
+// whether logs are json (LOG_FORMAT=json), for the rest of the program to follow along. See JSONLogWriter
+func JSONLogs() bool {
+	return jsonLogs()
+}
+
+// "warning" or "error" for messages that start with either word, like the program's own warnings and errors do, otherwise "info"
+func levelOf(msg string) string {
+	lower := strings.ToLower(strings.TrimSpace(msg))
+	switch {
+	case strings.HasPrefix(lower, "warning"):
+		return "warning"
+	case strings.HasPrefix(lower, "error"):
+		return "error"
+	}
+	return "info"
+}
+
+// writes a json log line. fields are added next to time, level and msg, and can't replace them
+func logJSON(level string, msg string, fields map[string]interface{}) {
+	line := make(map[string]interface{}, len(fields)+3)
+	for key, value := range fields {
+		line[key] = value
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["msg"] = msg
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]string{"time": line["time"].(string), "level": level, "msg": msg})
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	fmt.Println(string(encoded))
+}
+
+// logs like logOutput/logOutputError, with fields added to the line when logs are json
+func logWithFields(isError bool, msg string, fields map[string]interface{}) {
+	if !jsonLogs() {
+		if isError {
+			logOutputError(msg)
+		} else {
+			logOutput(msg)
+		}
+		return
+	}
+
+	level := levelOf(msg)
+	if isError && level == "info" {
+		level = "error"
+	}
+	logJSON(level, msg, fields)
+}
+
+// an io.Writer for the standard log package (log.SetOutput) that turns each message into a json log line, see levelOf
+type JSONLogWriter struct{}
+
+func (JSONLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	logJSON(levelOf(msg), msg, nil)
+
+	return len(p), nil
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+	a job that takes longer than its refresh period isn't run again until it
+	finishes. Ticks that fire while it's running are skipped, as is a tick the
+	ticker held on to while it ran (which would otherwise start the job again
+	straight away). The job runs on the next tick instead
+*/
+
+// whether a job is running, and when it last finished
+type jobState struct {
+	mu       sync.Mutex
+	running  bool
+	finished time.Time
+}
+
+// whether the job should run for a tick that fired at tick. If it should, the caller has to call done() once it finishes
+func (j *jobState) tryStart(tick time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running || tick.Before(j.finished) {
+		return false
+	}
+
+	j.running = true
+	return true
+}
+
+func (j *jobState) done() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.running = false
+	j.finished = time.Now()
+}
+
+// the state of every job, by name
+type jobStates struct {
+	mu     sync.Mutex
+	states map[string]*jobState
+}
+
+func newJobStates() *jobStates {
+	return &jobStates{states: make(map[string]*jobState)}
+}
+
+// marks the job with the given name as running for a tick that fired at tick, unless the previous run of it was still in progress
+// at the time. If it's started, the caller has to call done() on the returned state once the job finishes
+func (j *jobStates) start(name string, tick time.Time) (*jobState, bool) {
+	j.mu.Lock()
+	state, exists := j.states[name]
+	if !exists {
+		state = &jobState{}
+		j.states[name] = state
+	}
+	j.mu.Unlock()
+
+	if !state.tryStart(tick) {
+		logOutput(fmt.Sprintf("skipping %s, the previous run was still in progress", name))
+		return nil, false
+	}
+
+	return state, true
+}
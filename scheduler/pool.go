@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	jobs run in the background on a bounded number of goroutines
+	(SCHEDULER_WORKERS), so that a slow job (usually updating every tracked
+	post) doesn't hold up the scheduler loop, and with it refreshing the access
+	token or fetching new posts. A job still never runs twice at the same time,
+	see overlap.go
+
+	a watchdog keeps a stuck job from wedging the scheduler: a job that's still
+	running after WATCHDOG_INTERVAL_MULTIPLE times its interval is logged
+	(along with what every goroutine is doing), its context is cancelled and
+	its worker is given to other jobs. It still counts as running until it
+	actually returns, so a job that doesn't notice the cancellation is never
+	run twice at once: its ticks are skipped until it's done. A job that
+	panics is logged and started fresh on its next tick instead of crashing
+	the program
+*/
+
+type workerPool struct {
+	ctx   context.Context
+	slots chan struct{} //one per worker
+	jobs  *jobStates
+	wg    sync.WaitGroup
+
+	watchdogMultiple int //0 disables the watchdog
+}
+
+func newWorkerPool(ctx context.Context, workers int) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &workerPool{
+		ctx:              ctx,
+		slots:            make(chan struct{}, workers),
+		jobs:             newJobStates(),
+		watchdogMultiple: util.GetEnvIntDefault("WATCHDOG_INTERVAL_MULTIPLE", 3),
+	}
+}
+
+// runs job once a worker is free, unless it's still running (or waiting for a worker) from an earlier tick
+// interval is how often the job is expected to run, for the watchdog. 0 means the job isn't watched
+func (p *workerPool) submit(name string, tick time.Time, interval time.Duration, job func(context.Context)) {
+	state, started := p.jobs.start(name, tick)
+	if !started {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	acquired := false
+
+	//gives the worker back, called once when the job returns, panics, or is abandoned by the watchdog, whichever
+	//happens first. The job only stops counting as running once it returns, see overlap.go
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			cancel()
+			if acquired {
+				<-p.slots
+			}
+			p.wg.Done()
+		})
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer state.done()
+		defer release()
+		defer func() {
+			if r := recover(); r != nil {
+				logOutputError(fmt.Sprintf("%s panicked, restarting it on its next tick: %v\n%s", name, r, debug.Stack()))
+				emitCycleFailed(name, fmt.Errorf("panicked: %v", r))
+			}
+		}()
+
+		select {
+		case p.slots <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+			return
+		}
+
+		if p.watchdogMultiple > 0 && interval > 0 {
+			limit := interval * time.Duration(p.watchdogMultiple)
+			watchdog := time.AfterFunc(limit, func() {
+				logOutputError(fmt.Sprintf("%s hasn't finished after %s, cancelling it. It's started again on the first tick after it returns. Goroutines:\n%s", name, limit, goroutineDump()))
+				emitCycleFailed(name, fmt.Errorf("stalled for %s", limit))
+				release()
+			})
+			defer watchdog.Stop()
+		}
+
+		job(ctx)
+	}()
+}
+
+// waits for every submitted job to finish (or be abandoned by the watchdog)
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+// the stack traces of every goroutine, cut short if there's a lot of them
+func goroutineDump() string {
+	buf := make([]byte, 64*1024)
+	n := runtime.Stack(buf, true)
+	return string(buf[:n])
+}
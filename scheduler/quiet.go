@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	during quiet hours (QUIET_HOURS, eg: "23:00-07:00" or several windows like
+	"00:00-06:00,13:00-14:00", in QUIET_HOURS_TIMEZONE) the jobs that poll
+	reddit only run on every QUIET_HOURS_FACTOR-th tick, or not at all if it's
+	0. For installations that only care about certain hours of the day, or want
+	to save their rate limit overnight
+*/
+
+// minutes since midnight. end is before start for windows that go past midnight
+type quietWindow struct {
+	start, end int
+}
+
+func (w quietWindow) contains(minute int) bool {
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+type quietHours struct {
+	windows  []quietWindow
+	location *time.Location
+	factor   int //jobs run on every factor-th tick during quiet hours. 0 suspends them
+
+	mu     sync.Mutex
+	ticks  map[string]int //ticks of each job since quiet hours started
+	active bool
+}
+
+// reads the QUIET_HOURS* env variables. Returns nil if QUIET_HOURS isn't set
+func quietHoursFromEnv() (*quietHours, error) {
+	windows, _ := os.LookupEnv("QUIET_HOURS")
+	if strings.TrimSpace(windows) == "" {
+		return nil, nil
+	}
+
+	q := &quietHours{
+		factor: util.GetEnvIntDefault("QUIET_HOURS_FACTOR", 0),
+		ticks:  make(map[string]int),
+	}
+
+	location, err := time.LoadLocation(util.GetEnvOptional("QUIET_HOURS_TIMEZONE", "Local"))
+	if err != nil {
+		return nil, fmt.Errorf("malformed QUIET_HOURS_TIMEZONE:\n%s", err)
+	}
+	q.location = location
+
+	for _, window := range strings.Split(windows, ",") {
+		start, end, found := strings.Cut(strings.TrimSpace(window), "-")
+		if !found {
+			return nil, fmt.Errorf("malformed quiet hours \"%s\", should be like 23:00-07:00", window)
+		}
+
+		var w quietWindow
+		w.start, err = parseClock(start)
+		if err == nil {
+			w.end, err = parseClock(end)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed quiet hours \"%s\": %s", window, err)
+		}
+		q.windows = append(q.windows, w)
+	}
+
+	return q, nil
+}
+
+// "hh:mm" to minutes since midnight
+func parseClock(clock string) (int, error) {
+	hours, minutes, found := strings.Cut(strings.TrimSpace(clock), ":")
+	if !found {
+		return 0, fmt.Errorf("\"%s\" should be hh:mm", clock)
+	}
+
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("bad hour \"%s\"", hours)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("bad minute \"%s\"", minutes)
+	}
+
+	return h*60 + m, nil
+}
+
+// whether the job should sit out a tick that fired at tick
+func (q *quietHours) skip(job string, tick time.Time) bool {
+	local := tick.In(q.location)
+	minute := local.Hour()*60 + local.Minute()
+
+	quiet := false
+	for _, w := range q.windows {
+		if w.contains(minute) {
+			quiet = true
+			break
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if quiet != q.active {
+		q.active = quiet
+		q.ticks = make(map[string]int)
+		if quiet {
+			logOutput("quiet hours started, polling reddit less")
+		} else {
+			logOutput("quiet hours ended")
+		}
+	}
+	if !quiet {
+		return false
+	}
+	if q.factor <= 0 {
+		return true
+	}
+
+	//run on the first tick of quiet hours, then every factor-th one
+	q.ticks[job] += 1
+	return (q.ticks[job]-1)%q.factor != 0
+}
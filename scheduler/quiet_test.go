@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock   string
+		minutes int
+		fails   bool
+	}{
+		{clock: "00:00", minutes: 0},
+		{clock: "07:30", minutes: 7*60 + 30},
+		{clock: "7:05", minutes: 7*60 + 5},
+		{clock: " 23:59 ", minutes: 23*60 + 59},
+		{clock: "24:00", minutes: 24 * 60},
+		{clock: "24:01", fails: true},
+		{clock: "25:00", fails: true},
+		{clock: "12:60", fails: true},
+		{clock: "-1:00", fails: true},
+		{clock: "1200", fails: true},
+		{clock: "noon", fails: true},
+		{clock: "", fails: true},
+	}
+
+	for _, test := range tests {
+		minutes, err := parseClock(test.clock)
+		if test.fails {
+			if err == nil {
+				t.Errorf("parseClock(%q) didn't fail", test.clock)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseClock(%q): %s", test.clock, err)
+		} else if minutes != test.minutes {
+			t.Errorf("parseClock(%q) = %d, expected %d", test.clock, minutes, test.minutes)
+		}
+	}
+}
+
+func TestQuietWindowContains(t *testing.T) {
+	tests := []struct {
+		window   quietWindow
+		minute   int
+		contains bool
+	}{
+		{quietWindow{60, 120}, 59, false},
+		{quietWindow{60, 120}, 60, true},
+		{quietWindow{60, 120}, 119, true},
+		{quietWindow{60, 120}, 120, false}, // the end is excluded
+
+		// past midnight
+		{quietWindow{23 * 60, 7 * 60}, 22*60 + 59, false},
+		{quietWindow{23 * 60, 7 * 60}, 23 * 60, true},
+		{quietWindow{23 * 60, 7 * 60}, 0, true},
+		{quietWindow{23 * 60, 7 * 60}, 6*60 + 59, true},
+		{quietWindow{23 * 60, 7 * 60}, 7 * 60, false},
+		{quietWindow{23 * 60, 7 * 60}, 12 * 60, false},
+
+		{quietWindow{0, 24 * 60}, 23*60 + 59, true},
+		{quietWindow{5, 5}, 5, false},
+	}
+
+	for _, test := range tests {
+		if contains := test.window.contains(test.minute); contains != test.contains {
+			t.Errorf("%+v contains %d: %t, expected %t", test.window, test.minute, contains, test.contains)
+		}
+	}
+}
+
+func TestQuietHoursFromEnv(t *testing.T) {
+	t.Setenv("QUIET_HOURS_FACTOR", "0")
+	t.Setenv("QUIET_HOURS_TIMEZONE", "UTC")
+
+	tests := []struct {
+		env     string
+		windows []quietWindow
+		fails   bool
+	}{
+		{env: "23:00-07:00", windows: []quietWindow{{23 * 60, 7 * 60}}},
+		{env: "00:00-06:00, 13:00-14:00", windows: []quietWindow{{0, 6 * 60}, {13 * 60, 14 * 60}}},
+		{env: "23:00", fails: true},
+		{env: "23:00-7", fails: true},
+		{env: "23:00-07:00,", fails: true},
+	}
+
+	for _, test := range tests {
+		t.Setenv("QUIET_HOURS", test.env)
+		q, err := quietHoursFromEnv()
+		if test.fails {
+			if err == nil {
+				t.Errorf("QUIET_HOURS=%q didn't fail", test.env)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("QUIET_HOURS=%q: %s", test.env, err)
+			continue
+		}
+		if len(q.windows) != len(test.windows) {
+			t.Errorf("QUIET_HOURS=%q: %+v, expected %+v", test.env, q.windows, test.windows)
+			continue
+		}
+		for idx := range q.windows {
+			if q.windows[idx] != test.windows[idx] {
+				t.Errorf("QUIET_HOURS=%q: %+v, expected %+v", test.env, q.windows, test.windows)
+				break
+			}
+		}
+	}
+
+	t.Setenv("QUIET_HOURS", " ")
+	if q, err := quietHoursFromEnv(); q != nil || err != nil {
+		t.Errorf("blank QUIET_HOURS: %+v, %v, expected no quiet hours", q, err)
+	}
+
+	t.Setenv("QUIET_HOURS", "23:00-07:00")
+	t.Setenv("QUIET_HOURS_TIMEZONE", "Mars/Olympus_Mons")
+	if _, err := quietHoursFromEnv(); err == nil {
+		t.Error("an unknown QUIET_HOURS_TIMEZONE didn't fail")
+	}
+}
+
+func TestQuietHoursSkip(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 16, hour, minute, 0, 0, time.UTC)
+	}
+	quiet := func(factor int) *quietHours {
+		return &quietHours{
+			windows:  []quietWindow{{23 * 60, 7 * 60}},
+			location: time.UTC,
+			factor:   factor,
+			ticks:    make(map[string]int),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		factor  int
+		ticks   []time.Time
+		skipped []bool
+	}{
+		{"outside quiet hours", 0, []time.Time{at(12, 0), at(22, 59), at(7, 0)}, []bool{false, false, false}},
+		{"suspended", 0, []time.Time{at(22, 59), at(23, 0), at(3, 0), at(6, 59), at(7, 0)}, []bool{false, true, true, true, false}},
+		{"every 3rd tick", 3, []time.Time{at(23, 0), at(23, 1), at(23, 2), at(23, 3), at(23, 4), at(23, 5), at(23, 6)},
+			[]bool{false, true, true, false, true, true, false}},
+		{"every tick", 1, []time.Time{at(23, 0), at(23, 1), at(23, 2)}, []bool{false, false, false}},
+		{"restarts after quiet hours", 2, []time.Time{at(23, 0), at(23, 1), at(8, 0), at(23, 0), at(23, 1)},
+			[]bool{false, true, false, false, true}},
+	}
+
+	for _, test := range tests {
+		q := quiet(test.factor)
+		for idx, tick := range test.ticks {
+			if skipped := q.skip("job", tick); skipped != test.skipped[idx] {
+				t.Errorf("%s: tick %d at %s skipped: %t, expected %t", test.name, idx, tick.Format("15:04"), skipped, test.skipped[idx])
+			}
+		}
+	}
+
+	// each job counts its own ticks
+	q := quiet(2)
+	if q.skip("a", at(23, 0)) || q.skip("b", at(23, 0)) {
+		t.Error("the first tick of quiet hours was skipped")
+	}
+	if !q.skip("a", at(23, 1)) || !q.skip("b", at(23, 1)) {
+		t.Error("the second tick of quiet hours wasn't skipped")
+	}
+}
+
+func TestQuietHoursTimezone(t *testing.T) {
+	location := time.FixedZone("UTC+2", 2*60*60)
+	q := &quietHours{windows: []quietWindow{{0, 6 * 60}}, location: location, ticks: make(map[string]int)}
+
+	// 23:00 UTC is 01:00 in UTC+2
+	if !q.skip("job", time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)) {
+		t.Error("quiet hours weren't in QUIET_HOURS_TIMEZONE")
+	}
+	if q.skip("job", time.Date(2026, 10, 16, 5, 0, 0, 0, time.UTC)) {
+		t.Error("quiet hours weren't in QUIET_HOURS_TIMEZONE")
+	}
+}
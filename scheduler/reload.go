@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	the configuration can be changed without restarting, and without losing
+	what's only kept in memory (the tracked posts, the last post seen in each
+	subreddit, updates waiting to be retried, job stats...). On SIGHUP, main.go
+	reloads the .env file and calls Reload, which then:
+
+		- re-reads SUBREDDITS_PATH, see ReloadSubreddits in the reddit package
+		- registers every built in job again from the env variables, so that
+		  new periods and schedules take effect right away, and subreddits
+		  that got (or lost) their own refresh period get (or lose) their own
+		  job. Jobs already running finish as they were
+		- re-reads QUIET_HOURS
+
+	settings read whenever they're used (eg: MAX_TRACKING_AGE, CULL_MODE)
+	follow along on their own. The rest (reddit credentials and user agent,
+	the database, SCHEDULER_WORKERS, LOG_FORMAT, alerts, reports...) need a
+	restart
+*/
+
+// asks the scheduler loop to reload the configuration between jobs. Doesn't wait for it
+func (s *Scheduler) Reload() {
+	select {
+	case s.reloads <- struct{}{}:
+	default: // one is already pending
+	}
+}
+
+// called from the scheduler loop. done is the loop's, see startTicking
+func (s *Scheduler) reload(done chan struct{}) {
+	logOutput("reloading configuration...")
+
+	added, removed, err := s.reddit.ReloadSubreddits()
+	if err != nil {
+		logOutputError(err.Error() + "\nkeeping the current subreddits")
+	} else {
+		if len(added) > 0 {
+			logOutput("now tracking r/" + strings.Join(added, ", r/"))
+		}
+		if len(removed) > 0 {
+			logOutput("no longer tracking r/" + strings.Join(removed, ", r/") + ", their posts stay tracked until they're too old")
+		}
+
+		//a subreddit that's added back continues from its newest tracked post, see catchUp
+		s.reddit.ResumeFromTrackedPosts(util.GetEnvIntDefault("CATCH_UP_DEPTH", 100))
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	//the new jobs are registered before the old ones are stopped, so that a bad period keeps the current jobs going
+	current := s.jobs
+	s.jobs = nil
+	err = s.registerBuiltins()
+	builtins := s.jobs
+	s.jobs = current
+	if err != nil {
+		logOutputError(err.Error() + "\nkeeping the current jobs")
+		return
+	}
+
+	//jobs registered with Register aren't touched
+	var registered []*job
+	for _, j := range current {
+		if !j.builtin {
+			registered = append(registered, j)
+			continue
+		}
+		j.ticker.Stop()
+		close(j.stop)
+	}
+
+	for _, j := range builtins {
+		s.startTicking(j, done)
+		logOutput(fmt.Sprintf("%s: every %s", j.name, j.ticker.Interval()))
+	}
+	s.jobs = append(builtins, registered...)
+
+	logOutput("reloaded configuration")
+}
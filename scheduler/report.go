@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	every REPORT_CYCLES updates of the tracked posts, a summary of what the
+	watcher has been doing is logged and sent out as a PeriodicReport event
+	(eg: to post it to a chat, see events.go): how many posts are tracked in
+	each subreddit, how many new posts were found, how much their scores moved
+	on average and which jobs failed since the last report. A heartbeat, so it's
+	noticed when a subreddit quietly stops producing anything
+*/
+
+// a subreddit's part of a Report
+type SubredditReport struct {
+	Subreddit string
+
+	Tracked int // posts tracked at the time of the report
+	New     int // posts that started being tracked since the last report
+
+	Updates            int     // updates of its posts recorded since the last report
+	AverageScoreChange float64 // average change in upvotes per update
+
+	Errors int // failed runs of the job fetching new posts from just this subreddit, if it has its own (see subreddits.go)
+}
+
+type Report struct {
+	Since  time.Time // the previous report, or when the scheduler started
+	Cycles int       // updates of the tracked posts since then
+
+	Subreddits []SubredditReport // by name
+
+	Errors map[string]int // failed runs since the last report, by job
+}
+
+type subredditTally struct {
+	name        string
+	new         int
+	updates     int
+	scoreChange int
+}
+
+// tallies the events between reports
+type reportCollector struct {
+	every int
+
+	mu         sync.Mutex
+	since      time.Time
+	cycles     int
+	subreddits map[string]*subredditTally // by lowercase name
+	errors     map[string]int
+}
+
+// reads REPORT_CYCLES and starts tallying events. Returns nil if reports are disabled
+func newReportCollector() *reportCollector {
+	every := util.GetEnvIntDefault("REPORT_CYCLES", 30)
+	if every <= 0 {
+		return nil
+	}
+
+	c := &reportCollector{every: every}
+	c.reset()
+
+	OnEvent(PostTracked, c.onEvent)
+	OnEvent(PostUpdated, c.onEvent)
+	OnEvent(CycleFailed, c.onEvent)
+	return c
+}
+
+func (c *reportCollector) reset() {
+	c.since = time.Now()
+	c.cycles = 0
+	c.subreddits = make(map[string]*subredditTally)
+	c.errors = make(map[string]int)
+}
+
+func (c *reportCollector) tally(subreddit string) *subredditTally {
+	key := strings.ToLower(subreddit)
+	tally, exists := c.subreddits[key]
+	if !exists {
+		tally = &subredditTally{name: subreddit}
+		c.subreddits[key] = tally
+	}
+	return tally
+}
+
+func (c *reportCollector) onEvent(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch event.Kind {
+	case PostTracked:
+		c.tally(event.Post.Subreddit).new += 1
+	case PostUpdated:
+		tally := c.tally(event.Post.Subreddit)
+		tally.updates += 1
+		tally.scoreChange += event.Post.Upvotes - event.PreviousUpvotes
+	case CycleFailed:
+		c.errors[event.Job] += 1
+	}
+}
+
+// counts an update of the tracked posts, reporting every REPORT_CYCLES of them
+func (c *reportCollector) cycle(handler redditApiHandlerScheduler) {
+	c.mu.Lock()
+	c.cycles += 1
+	if c.cycles < c.every {
+		c.mu.Unlock()
+		return
+	}
+
+	report := c.build(handler.SubredditRefreshPeriods(), handler.GetTrackedPosts())
+	c.reset()
+	c.mu.Unlock()
+
+	logReport(report)
+	emit(Event{Kind: PeriodicReport, Report: &report})
+}
+
+// must be called with c.mu held. Every configured subreddit is in the report, even if nothing happened in it
+func (c *reportCollector) build(configured map[string]uint64, tracked reddit.ContentGroup) Report {
+	for name := range configured {
+		c.tally(name)
+	}
+
+	trackedCounts := make(map[string]int)
+	for _, post := range tracked {
+		c.tally(post.Subreddit)
+		trackedCounts[strings.ToLower(post.Subreddit)] += 1
+	}
+
+	report := Report{Since: c.since, Cycles: c.cycles, Errors: make(map[string]int, len(c.errors))}
+	for job, count := range c.errors {
+		report.Errors[job] = count
+	}
+
+	for key, tally := range c.subreddits {
+		sub := SubredditReport{
+			Subreddit: tally.name,
+			Tracked:   trackedCounts[key],
+			New:       tally.new,
+			Updates:   tally.updates,
+		}
+		for job, count := range c.errors {
+			if strings.EqualFold(job, "fetching new posts from r/"+tally.name) {
+				sub.Errors += count
+			}
+		}
+		if tally.updates > 0 {
+			sub.AverageScoreChange = float64(tally.scoreChange) / float64(tally.updates)
+		}
+		report.Subreddits = append(report.Subreddits, sub)
+	}
+	sort.Slice(report.Subreddits, func(i, j int) bool {
+		return strings.ToLower(report.Subreddits[i].Subreddit) < strings.ToLower(report.Subreddits[j].Subreddit)
+	})
+
+	return report
+}
+
+func logReport(report Report) {
+	logOutput(fmt.Sprintf("report for the last %d updates (since %s):", report.Cycles, report.Since.Format(time.ANSIC)))
+
+	for _, sub := range report.Subreddits {
+		fields := map[string]interface{}{
+			"subreddit":            sub.Subreddit,
+			"tracked":              sub.Tracked,
+			"new":                  sub.New,
+			"updates":              sub.Updates,
+			"average_score_change": sub.AverageScoreChange,
+			"errors":               sub.Errors,
+		}
+		logWithFields(false, fmt.Sprintf("r/%s: %d tracked, %d new, %+.1f upvotes per update on average over %d updates, %d errors",
+			sub.Subreddit, sub.Tracked, sub.New, sub.AverageScoreChange, sub.Updates, sub.Errors), fields)
+	}
+
+	for _, job := range sortedJobs(report.Errors) {
+		logWithFields(true, fmt.Sprintf("%s failed %d times", job, report.Errors[job]), map[string]interface{}{"job": job, "errors": report.Errors[job]})
+	}
+}
+
+func sortedJobs(errors map[string]int) []string {
+	jobs := make([]string, 0, len(errors))
+	for job := range errors {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	return jobs
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	a snapshot of the tracked posts can't be fetched again later, so an update
+	that was fetched from reddit but couldn't be recorded (the database was
+	down, a call timed out...) is kept here instead of being dropped. Queued
+	updates are retried before the next update is recorded, and every
+	UPDATE_RETRY_PERIOD seconds in between.
+
+	the queue holds at most UPDATE_RETRY_QUEUE_SIZE updates, past that the
+	oldest are dropped. An update that failed partway through is retried whole,
+	which can record some entries twice. Running the first migration again
+	("votewatch migrate --from 0 --to 1") cleans those up
+*/
+
+type retryQueue struct {
+	mu      sync.Mutex
+	batches []reddit.ContentGroup //oldest first
+	max     int
+}
+
+func newRetryQueue() *retryQueue {
+	return &retryQueue{max: util.GetEnvIntDefault("UPDATE_RETRY_QUEUE_SIZE", 10)}
+}
+
+// queues an update that couldn't be recorded
+func (q *retryQueue) push(batch reddit.ContentGroup) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.max <= 0 {
+		return
+	}
+
+	q.batches = append(q.batches, batch)
+	if dropped := len(q.batches) - q.max; dropped > 0 {
+		q.batches = q.batches[dropped:]
+		logOutputError(fmt.Sprintf("more than %d failed updates queued, dropped the oldest %d", q.max, dropped))
+	}
+}
+
+func (q *retryQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.batches)
+}
+
+// records the queued updates, oldest first. Stops at the first one that fails, leaving it and the rest queued
+func (q *retryQueue) flush(ctx context.Context, database databaseConnectionScheduler) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) == 0 {
+		return nil
+	}
+	if !database.Healthy() {
+		return errors.New("database unhealthy, not retrying failed updates")
+	}
+
+	logOutput(fmt.Sprintf("retrying %d failed updates...", len(q.batches)))
+	for len(q.batches) > 0 {
+		err := database.RecordNewData(withProgressLog(ctx, "updated"), q.batches[0])
+		if err != nil {
+			return fmt.Errorf("error retrying failed update, %d still queued:\n%s", len(q.batches), err)
+		}
+		q.batches = q.batches[1:]
+		markWritten()
+	}
+
+	logOutput("recorded every failed update")
+	return nil
+}
@@ -1,11 +1,18 @@
 package scheduler
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/jtyrmn/reddit-votewatch/backup"
+	"github.com/jtyrmn/reddit-votewatch/database"
 	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/tracing"
 	"github.com/jtyrmn/reddit-votewatch/util"
 )
 
@@ -15,86 +22,478 @@ type redditApiHandlerScheduler interface {
 	TimeToNextTokenRefresh() time.Duration
 	TokenRefresh() error
 
-	TrackNewlyCreatedPosts() int
+	TrackNewlyCreatedPosts(context.Context, []string) ([]reddit.RedditContent, error)
+	SubredditRefreshPeriods() map[string]uint64
 	GetTrackedPosts() reddit.ContentGroup
+	TrackPosts(reddit.ContentGroup)
 
 	GetTrackedIDs() []reddit.Fullname
-	FetchPosts([]reddit.Fullname) (*reddit.ContentGroup, error)
+	FetchPosts(context.Context, []reddit.Fullname) (*reddit.ContentGroup, error)
 
 	StopTrackingOldPosts(uint64) int
+	EvictTrackedPosts(int, reddit.EvictionPolicy) int
+	LatestUpvotes(reddit.Fullname) (int, bool)
+	RemovedBy(reddit.Fullname) string
+
+	ResumeFromTrackedPosts(int) time.Duration
+	ReloadSubreddits() ([]string, []string, error)
+	ReplaceSubreddits([]byte) ([]string, []string, error)
+	AddSubreddit(string) (bool, error)
+
+	RateLimit() reddit.RateLimitStatus
 }
 
 type databaseConnectionScheduler interface {
-	RecordNewData(reddit.ContentGroup) error
+	RecordNewData(context.Context, reddit.ContentGroup) error
+
+	SaveListings(context.Context, reddit.ContentGroup) error
+
+	RecieveListingsPage(context.Context, reddit.ContentGroup, database.ListingsQuery) (string, int, error)
 
-	SaveListings(reddit.ContentGroup) error
+	RecieveHistoryPage(context.Context, database.ListingsQuery) ([]database.ListingHistory, string, error)
 
-	RecieveListings(reddit.ContentGroup, int64) (int, error)
+	CullListings(context.Context, uint64, bool) (int, error)
 
-	CullListings(uint64) (int, error)
+	CompactHistory(context.Context, uint64, uint64) (int, error)
+
+	DeleteListings(context.Context, []reddit.Fullname) (int, error)
+
+	Healthy() bool
 }
 
-//this function starts a forever loops that goes over all the events of both the reddit and database handler simultaneously
-func Start(reddit redditApiHandlerScheduler, database databaseConnectionScheduler) {
-	//before starting the loop, pull pre-existing listings from db
-	pullFromDB(reddit, database)
+// a periodic task, see Register
+type job struct {
+	name      string
+	newTicker func() *jobTicker //called by Run, so that nothing ticks before the scheduler starts
+	task      func(context.Context) error
 
-	//ticker for reddit token refresh
-	redditTicker := time.NewTicker(reddit.TimeToNextTokenRefresh())
+	usesReddit bool          //skipped while reddit keeps failing, see backoff.go
+	timeout    time.Duration //the task's context is cancelled after this long. 0 means no deadline
+	builtin    bool          //registered by New, and registered again on Reload. See reload.go
+
+	ticker *jobTicker    //set by Run
+	stop   chan struct{} //stops forwarding the ticker's ticks, closed when the job is replaced or the scheduler stops
+}
+
+// a job's tick, for fanning every job's ticker (and manual triggers, see Trigger) in to the scheduler loop
+type jobTick struct {
+	job    *job
+	time   time.Time
+	manual bool //manual ticks ignore quiet hours and backing off
+}
+
+type Scheduler struct {
+	reddit   redditApiHandlerScheduler
+	database databaseConnectionScheduler
+
+	jobs    []*job
+	jobsMu  sync.RWMutex //jobs are replaced on Reload while the control endpoint may be reading them
+	ticks   chan jobTick
+	reloads chan struct{}
+	retries *retryQueue       //updates that couldn't be recorded, see retry.go
+	stats   *jobStats         //durations, failure streaks..., see stats.go
+	alerts  *alerter          //nil if alerts are disabled, see alerts.go
+	report  *reportCollector  //nil if reports are disabled, see report.go
+	cache   trackedPostsCache //nil without one, see cache.go
+}
+
+//creates a scheduler with the built in jobs registered (fetching new posts, updating them, culling, backups...), as
+//configured in the env variables. Fails if the periods of the built in jobs are missing or malformed
+func New(reddit redditApiHandlerScheduler, database databaseConnectionScheduler) (*Scheduler, error) {
+	s := &Scheduler{
+		reddit:   reddit,
+		database: database,
+		ticks:    make(chan jobTick),
+		reloads:  make(chan struct{}, 1),
+		retries:  newRetryQueue(),
+		stats:    newJobStats(),
+		alerts:   newAlerter(),
+		report:   newReportCollector(),
+	}
+	err := s.registerBuiltins()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//registers the built in jobs, as configured in the env variables at the time
+func (s *Scheduler) registerBuiltins() error {
+	reddit, database, retries, report := s.reddit, s.database, s.retries, s.report
+	first := len(s.jobs)
+	defer func() {
+		for _, j := range s.jobs[first:] {
+			j.builtin = true
+		}
+	}()
+
+	//subreddits with their own refresh period get their own job, the rest are fetched together. See subreddits.go
+	//the new posts, update and cull jobs can also follow a cron schedule, see cron.go
+	defaults, ownPeriods := splitSubreddits(reddit.SubredditRefreshPeriods())
+	if len(ownPeriods) == 0 || len(defaults) > 0 {
+		newTicker, err := jobTickerFromEnv("NEW_POSTS_SCHEDULE", "NEW_POSTS_REFRESH_PERIOD")
+		if err != nil {
+			return err
+		}
+		s.register("fetching new posts", newTicker, true, "NEW_POSTS_TIMEOUT", func(ctx context.Context) error {
+			return fetchNewPosts(ctx, reddit, database, defaults)
+		})
+	}
+	for _, name := range sortedKeys(ownPeriods) {
+		subreddits := []string{name}
+		s.register("fetching new posts from r/"+name, periodTicker(time.Second*time.Duration(ownPeriods[name])), true, "NEW_POSTS_TIMEOUT", func(ctx context.Context) error {
+			return fetchNewPosts(ctx, reddit, database, subreddits)
+		})
+	}
+
+	//the update can be spread over most of its interval, see spread.go
+	//every so many updates, a summary is logged, see report.go
+	newTicker, err := jobTickerFromEnv("UPDATE_TRACKED_POSTS_SCHEDULE", "UPDATE_TRACKED_POSTS_REFRESH_PERIOD")
+	if err != nil {
+		return err
+	}
+	var updates *job
+	updates = s.register("updating posts", newTicker, true, "UPDATE_TRACKED_POSTS_TIMEOUT", func(ctx context.Context) error {
+		err := updateTrackedPosts(ctx, reddit, database, retries, spreadFromEnv(updates.ticker.Interval()))
+		if report != nil {
+			report.cycle(reddit)
+		}
+		return err
+	})
+
+	//retrying updates that couldn't be recorded in between updates. Otherwise they're only retried on the next update
+	if period := util.GetEnvIntDefault("UPDATE_RETRY_PERIOD", 30); period > 0 {
+		s.Register("retrying failed updates", time.Second*time.Duration(period), func(ctx context.Context) error {
+			return retries.flush(ctx, database)
+		})
+	}
+
+	//untracking posts that are past a certain age
+	untrackPeriod, err := util.GetEnvDuration("UNTRACK_POSTS_REFRESH_PERIOD", time.Second)
+	if err != nil {
+		return err
+	}
+	if untrackPeriod <= 0 {
+		return errors.New("UNTRACK_POSTS_REFRESH_PERIOD must be positive")
+	}
+	s.Register("untracking old posts", untrackPeriod, func(ctx context.Context) error {
+		return stopTrackingOldPosts(reddit)
+	})
+
+	//how close the subreddits are pushing the account to being throttled. Also exposed as metrics, see reddit/ratelimit.go
+	if period := util.GetEnvIntDefault("RATE_LIMIT_REPORT_PERIOD", 600); period > 0 {
+		s.Register("reporting rate limit", time.Second*time.Duration(period), func(ctx context.Context) error {
+			reportRateLimit(reddit)
+			return nil
+		})
+	}
+
+	//noticing when nothing reaches the database anymore, see alerts.go
+	if alerts := s.alerts; alerts != nil && alerts.noWrites > 0 {
+		s.Register("checking for writes", writeCheckPeriod, func(ctx context.Context) error {
+			alerts.checkWrites()
+			return nil
+		})
+	}
+
+	//the rest is database maintenance, only one instance has to do it. See subreddits.go
+	if !maintainsDatabase() {
+		return nil
+	}
+
+	//the backup bucket is also used for cold storage, see cullDatabase()
+	backups, err := backup.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	newTicker, err = jobTickerFromEnv("CULL_POSTS_SCHEDULE", "CULL_POSTS_REFRESH_PERIOD")
+	if err != nil {
+		return err
+	}
+	s.register("culling posts", newTicker, false, "CULL_POSTS_TIMEOUT", func(ctx context.Context) error {
+		return cullDatabase(ctx, backups, reddit, database)
+	})
+
+	//downsampling old vote history. Disabled unless a period is set
+	if period := util.GetEnvIntDefault("COMPACT_HISTORY_REFRESH_PERIOD", 0); period > 0 {
+		s.Register("compacting history", time.Second*time.Duration(period), func(ctx context.Context) error {
+			return compactHistory(ctx, database)
+		})
+	}
+
+	//backing the database up to object storage. Disabled unless a bucket and period are set
+	if period := util.GetEnvIntDefault("BACKUP_REFRESH_PERIOD", 0); backups != nil && period > 0 {
+		s.Register("backing up database", time.Second*time.Duration(period), func(ctx context.Context) error {
+			return backupDatabase(ctx, backups, database)
+		})
+	}
+
+	//snapshots exported for analytics, see backup/snapshots.go. Disabled unless a period is set
+	exports, err := backup.SnapshotExportFromEnv()
+	if err != nil {
+		return err
+	}
+	if exports != nil {
+		s.Register("exporting snapshots", exports.Period(), func(ctx context.Context) error {
+			return exportSnapshots(ctx, exports, database)
+		})
+	}
+
+	return nil
+}
+
+//adds a task that runs every interval once the scheduler is started. Must be called before Run
+//name identifies the task in the log and for overlap protection (see overlap.go), so it should be unique. An error
+//returned by the task is logged and fires a CycleFailed event. The task's context has a deadline of JOB_TIMEOUT seconds, if set
+func (s *Scheduler) Register(name string, interval time.Duration, task func(context.Context) error) {
+	s.register(name, periodTicker(interval), false, "", task)
+}
+
+//timeoutEnv is the env variable with the job's timeout in seconds, JOB_TIMEOUT is used if it's empty or not set
+func (s *Scheduler) register(name string, newTicker func() *jobTicker, usesReddit bool, timeoutEnv string, task func(context.Context) error) *job {
+	timeout := util.GetEnvIntDefault("JOB_TIMEOUT", 0)
+	if value, _ := os.LookupEnv(timeoutEnv); strings.TrimSpace(value) != "" {
+		timeout = util.GetEnvIntDefault(timeoutEnv, timeout)
+	}
+
+	j := &job{
+		name:       name,
+		newTicker:  newTicker,
+		task:       task,
+		usesReddit: usesReddit,
+		timeout:    time.Second * time.Duration(timeout),
+	}
+	s.jobs = append(s.jobs, j)
+	return j
+}
+
+//starts a loop that runs every registered job on its own schedule, and refreshes the reddit access token when needed
+//the loop stops once ctx is cancelled, which also abandons any database calls in progress. Once the running jobs return, the tracked posts are saved one last time
+func (s *Scheduler) Run(ctx context.Context) {
+	reddit, database := s.reddit, s.database
+
+	//before starting the loop, pull pre-existing listings from db (or the cache, see cache.go)
+	s.pullTrackedPosts(ctx)
+	catchUp(reddit)
 
-	//ticker for fetching new posts
-	newPostsTicker := time.NewTicker(time.Second * time.Duration(util.GetEnvInt("NEW_POSTS_REFRESH_PERIOD")))
+	//posts past CULLING_AGE are untracked when they're culled, so a higher MAX_TRACKING_AGE never takes effect
+	maxTrackingAge, trackingErr := util.GetEnvInt("MAX_TRACKING_AGE")
+	cullingAge, cullingErr := util.GetEnvInt("CULLING_AGE")
+	if trackingErr == nil && cullingErr == nil && maxTrackingAge > cullingAge {
+		logOutputError(fmt.Sprintf("warning: MAX_TRACKING_AGE (%d) is over CULLING_AGE (%d), posts will stop being tracked once they're culled", maxTrackingAge, cullingAge))
+	}
 
-	//ticker for downloading fetching new posts and downloading them to db
-	updatePostsTicker := time.NewTicker(time.Second * time.Duration(util.GetEnvInt("UPDATE_TRACKED_POSTS_REFRESH_PERIOD")))
+	//ticker for reddit token refresh. Its period changes with every token, so it isn't a job
+	redditTicker := time.NewTicker(reddit.TimeToNextTokenRefresh())
 
-	//ticker for untracking posts that are past a certain age
-	untrackPostsTicker := time.NewTicker(time.Second * time.Duration(util.GetEnvInt("UNTRACK_POSTS_REFRESH_PERIOD")))
+	//every job's ticks end up in s.ticks
+	done := make(chan struct{})
+	for _, j := range s.jobs {
+		s.startTicking(j, done)
+	}
 
-	//ticker for culling old posts
-	cullPostsTicker := time.NewTicker(time.Second * time.Duration(util.GetEnvInt("CULL_POSTS_REFRESH_PERIOD")))
+	//jobs run in the background, see pool.go
+	pool := newWorkerPool(ctx, util.GetEnvIntDefault("SCHEDULER_WORKERS", 4))
 
+	//fetching and updating are skipped for a while when reddit keeps failing, see backoff.go
+	backoff := newRedditBackoff()
+
+	//and slowed down or stopped during quiet hours, see quiet.go
+	quiet, err := quietHoursFromEnv()
+	if err != nil {
+		logOutputError(err.Error() + "\nquiet hours disabled")
+	}
 
 	logOutput("starting scheduler\n")
 	for {
 		select {
-		case <-redditTicker.C:
-			refreshToken(reddit, *redditTicker)
+		case <-ctx.Done():
+			logOutput("stopping scheduler")
+			redditTicker.Stop()
+			close(done)
+			for _, j := range s.jobs {
+				j.ticker.Stop()
+			}
+			pool.wait() //the jobs' contexts are cancelled along with ctx
+			savePostsOnShutdown(reddit, database, s.retries)
+			return
 
-		case <-newPostsTicker.C:
-			fetchNewPosts(reddit, database)
+		case <-redditTicker.C:
+			refreshToken(reddit, redditTicker)
 
-		case <-updatePostsTicker.C:
-			err := updateTrackedPosts(reddit, database)
+		//see reload.go
+		case <-s.reloads:
+			s.reload(done)
+			quiet, err = quietHoursFromEnv()
 			if err != nil {
-				logOutputError("error updating:\n" + err.Error())
+				logOutputError(err.Error() + "\nquiet hours disabled")
 			}
 
-		case <-untrackPostsTicker.C:
-			stopTrackingOldPosts(reddit)
+		case tick := <-s.ticks:
+			j := tick.job
+			if tick.manual {
+				logOutput(fmt.Sprintf("%s was triggered manually", j.name))
+			} else if j.usesReddit && quiet != nil && quiet.skip(j.name, tick.time) {
+				continue
+			}
 
-		case <-cullPostsTicker.C:
-			cullDatabase(database)
+			pool.submit(j.name, tick.time, j.ticker.Interval(), func(ctx context.Context) {
+				if j.usesReddit && !tick.manual && backoff.waiting(j.name) {
+					return
+				}
+
+				if j.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, j.timeout)
+					defer cancel()
+				}
+
+				//every run of a job is a trace, see the tracing package
+				ctx, span := tracing.Start(ctx, j.name)
+				span.SetAttribute("manual", tick.manual)
+
+				started := time.Now()
+				err := j.task(ctx)
+				failures := s.stats.record(j.name, time.Since(started), err)
+				s.alerts.jobRan(j.name, failures, err)
+				span.End(err)
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					logOutputError(fmt.Sprintf("%s timed out after %s, its requests were abandoned", j.name, j.timeout))
+				}
+				if j.usesReddit {
+					backoff.record(err)
+				}
+				if err != nil {
+					logOutputError(err.Error())
+					emitCycleFailed(j.name, err)
+				}
+			})
+		}
+		if !jsonLogs() {
+			fmt.Println() //create spacing between the different events
 		}
-		fmt.Println() //create spacing between the different events
+	}
+}
+
+//starts the job's ticker, and forwards its ticks to the scheduler loop until the job is replaced or done is closed
+func (s *Scheduler) startTicking(j *job, done chan struct{}) {
+	j.ticker = j.newTicker()
+	j.stop = make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-j.stop:
+				return
+			case now := <-j.ticker.C:
+				select {
+				case s.ticks <- jobTick{job: j, time: now}:
+				case <-done:
+					return
+				case <-j.stop:
+					return
+				}
+			}
+		}
+	}()
+}
+
+//starts the scheduler with just the built in jobs, see New and Run
+func Start(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler) error {
+	s, err := New(reddit, database)
+	if err != nil {
+		return err
+	}
+
+	s.Run(ctx)
+	return nil
+}
+
+//does a single pass of fetching new posts, updating the tracked posts and saving them, instead of looping forever
+//this is for running the program from cron, a kubernetes CronJob, CI, etc. Subreddit refresh periods and the other jobs are ignored
+func RunOnce(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler) error {
+	pullFromDB(ctx, reddit, database)
+
+	//nothing is remembered between runs besides the database, so continue from the newest posts in it
+	catchUp(reddit)
+
+	fetchCtx, span := tracing.Start(ctx, "fetching new posts")
+	err := fetchNewPosts(fetchCtx, reddit, database, nil)
+	span.End(err)
+	if err != nil {
+		emitCycleFailed("fetching new posts", err)
+		return err
+	}
+
+	updateCtx, span := tracing.Start(ctx, "updating posts")
+	err = updateTrackedPosts(updateCtx, reddit, database, newRetryQueue(), 0)
+	span.End(err)
+	if err != nil {
+		emitCycleFailed("updating posts", err)
+	}
+	return err
+}
+
+//continues fetching new posts from the newest tracked ones, so posts created while the program wasn't running are still tracked
+//the first fetch looks through up to CATCH_UP_DEPTH posts per subreddit (100 by default) instead of the usual amount
+func catchUp(reddit redditApiHandlerScheduler) {
+	depth := util.GetEnvIntDefault("CATCH_UP_DEPTH", 100)
+
+	offline := reddit.ResumeFromTrackedPosts(depth)
+	if offline > 0 {
+		logOutput(fmt.Sprintf("newest tracked post is %s old, catching up on up to %d posts per subreddit", offline.Round(time.Second), depth))
 	}
 }
 
 //following functions are just wrappers for self-explanatory behaviour
 
-func pullFromDB(reddit redditApiHandlerScheduler, database databaseConnectionScheduler) {
+func pullFromDB(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler) {
 	logOutput("pulling from db...")
 
-	maxAge := util.GetEnvInt("MAX_TRACKING_AGE")
-
-	insertions, err := database.RecieveListings(reddit.GetTrackedPosts(), int64(maxAge)) //reddit API handler's tracked posts <<< posts from db
+	//GetTrackedPosts() is a copy, the pulled posts are tracked once they're all recieved
+	posts := reddit.GetTrackedPosts()
+	query, err := pullFromDBQuery()
 	if err != nil {
-		logOutputError("warning: error recieving listings from database:\n" + err.Error())
+		logOutputError("warning: not pulling from database:\n" + err.Error())
+		return
+	}
+	insertions := 0
+	for {
+		next, count, err := database.RecieveListingsPage(ctx, posts, query) //posts <<< posts from db
+		insertions += count
+		if err != nil {
+			logOutputError("warning: error recieving listings from database:\n" + err.Error())
+			break
+		}
+
+		if next == "" {
+			break
+		}
+		query.Cursor = next
 	}
 	logOutput(fmt.Sprintf("%d posts recieved from database\n", insertions))
+	reddit.TrackPosts(posts)
+
+	evictTrackedPosts(reddit)
+}
+
+//listings are pulled a page at a time so that large databases don't have to be streamed all at once
+func pullFromDBQuery() (database.ListingsQuery, error) {
+	maxAge, err := util.GetEnvInt("MAX_TRACKING_AGE")
+	if err != nil {
+		return database.ListingsQuery{}, err
+	}
+
+	return database.ListingsQuery{
+		MaxAge: int64(maxAge),
+		Limit:  util.GetEnvIntDefault("DATABASE_RETRIEVE_PAGE_SIZE", 1000),
+	}, nil
 }
 
-func refreshToken(reddit redditApiHandlerScheduler, redditTicker time.Ticker) {
+func refreshToken(reddit redditApiHandlerScheduler, redditTicker *time.Ticker) {
 	logOutput("refreshing access token...")
 	err := reddit.TokenRefresh()
 	if err != nil {
@@ -103,65 +502,335 @@ func refreshToken(reddit redditApiHandlerScheduler, redditTicker time.Ticker) {
 	redditTicker.Reset(reddit.TimeToNextTokenRefresh())
 }
 
-func fetchNewPosts(reddit redditApiHandlerScheduler, database databaseConnectionScheduler) {
-	logOutput("fetching new posts...")
-	count := reddit.TrackNewlyCreatedPosts()
+//subreddits is the subreddits to fetch from, all of them if it's empty
+func fetchNewPosts(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler, subreddits []string) error {
+	if len(subreddits) == 1 {
+		logOutput(fmt.Sprintf("fetching new posts from r/%s...", subreddits[0]))
+	} else {
+		logOutput("fetching new posts...")
+	}
+	//the phases of a job are spans of their own, see the tracing package
+	redditCtx, span := tracing.Start(ctx, "fetching new posts from reddit")
+	newPosts, err := reddit.TrackNewlyCreatedPosts(redditCtx, subreddits)
+	span.SetAttribute("posts", len(newPosts))
+	span.End(err)
+	if err != nil {
+		return redditError{errors.New("error fetching new posts from reddit:\n" + err.Error())}
+	}
+	count := len(newPosts)
 	logOutput(fmt.Sprintf("%d new posts tracked", count))
+	evictTrackedPosts(reddit)
+
+	//posts that were evicted right away never really got tracked
+	for _, post := range newPosts {
+		if _, tracked := reddit.LatestUpvotes(post.FullId()); tracked {
+			emit(Event{Kind: PostTracked, Post: post})
+		}
+	}
 	logOutput(fmt.Sprintf("%d total posts tracked", len(reddit.GetTrackedPosts())))
 
 	if count == 0 { //no need to save new posts if there are no new posts
+		return nil
+	}
+
+	//the posts stay tracked in memory, they'll get saved on the next fetch that finds new posts
+	if !database.Healthy() {
+		return errors.New("database unhealthy, not saving posts")
+	}
+
+	logOutput("saving posts...")
+	saveCtx, span := tracing.Start(ctx, "saving posts")
+	err = database.SaveListings(withProgressLog(saveCtx, "saved"), reddit.GetTrackedPosts())
+	span.End(err)
+	if err != nil {
+		return errors.New("error saving posts:\n" + err.Error())
+	}
+	markWritten()
+
+	return nil
+}
+
+//new posts are only saved when the next fetch finds new posts, so some tracked posts may not be in the database yet
+//ctx is already cancelled at this point, so the save gets its own deadline
+//failed updates that are still queued get one last try as well
+func savePostsOnShutdown(reddit redditApiHandlerScheduler, database databaseConnectionScheduler, retries *retryQueue) {
+	if !database.Healthy() {
+		logOutputError("database unhealthy, tracked posts not saved")
 		return
 	}
-	
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(util.GetEnvIntDefault("SHUTDOWN_TIMEOUT", 30)))
+	defer cancel()
+
 	logOutput("saving posts...")
-	err := database.SaveListings(reddit.GetTrackedPosts())
+	err := database.SaveListings(withProgressLog(ctx, "saved"), reddit.GetTrackedPosts())
 	if err != nil {
 		logOutputError("error saving posts:\n" + err.Error())
 	}
+
+	err = retries.flush(ctx, database)
+	if err != nil {
+		logOutputError(err.Error())
+	}
 }
 
-func updateTrackedPosts(reddit redditApiHandlerScheduler, database databaseConnectionScheduler) error {
+//an update that can't be recorded is queued in retries, and queued updates are retried first
+//with a spread, the tracked posts are updated a batch at a time over that long instead of all at once, see spread.go
+func updateTrackedPosts(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler, retries *retryQueue, spread time.Duration) error {
 	logOutput("updating posts...")
 
+	//posts are fetched even if the database is unhealthy, so that the update can be recorded once it's back
+	err := retries.flush(ctx, database)
+	if err != nil {
+		logOutputError(err.Error())
+	}
+
+	//don't wait for the untrack job to stop refetching posts that are too old to be tracked
+	err = stopTrackingOldPosts(reddit)
+	if err != nil {
+		logOutputError(err.Error())
+	}
+
 	IDs := reddit.GetTrackedIDs()
+	if spread <= 0 {
+		return updateBatch(ctx, reddit, database, retries, IDs)
+	}
+	return updateSpread(ctx, reddit, database, retries, IDs, spread)
+}
+
+//fetches and records a single batch of the tracked posts
+func updateBatch(ctx context.Context, reddit redditApiHandlerScheduler, database databaseConnectionScheduler, retries *retryQueue, IDs []reddit.Fullname) error {
+	//for the PostUpdated, ScoreThresholdCrossed and PostRemoved events, see events.go
+	previous := previousStates(reddit, IDs)
 
-	posts, err := reddit.FetchPosts(IDs)
+	redditCtx, span := tracing.Start(ctx, "fetching posts from reddit")
+	span.SetAttribute("posts", len(IDs))
+	posts, err := reddit.FetchPosts(redditCtx, IDs)
+	span.End(err)
 	if err != nil {
-		return errors.New("error fetching posts from reddit:\n" + err.Error())
+		return redditError{errors.New("error fetching posts from reddit:\n" + err.Error())}
+	}
+
+	if !database.Healthy() {
+		retries.push(*posts)
+		return fmt.Errorf("database unhealthy, update queued (%d queued)", retries.pending())
 	}
 
-	err = database.RecordNewData(*posts)
+	recordCtx, span := tracing.Start(ctx, "recording update")
+	err = database.RecordNewData(withProgressLog(recordCtx, "updated"), *posts)
+	span.End(err)
 	if err != nil {
-		return errors.New("error recording data in database:\n" + err.Error())
+		retries.push(*posts)
+		return fmt.Errorf("error recording data in database, update queued (%d queued):\n%s", retries.pending(), err)
 	}
+	markWritten()
 
+	emitUpdated(*posts, previous)
 	return nil
 }
 
-func stopTrackingOldPosts(reddit redditApiHandlerScheduler) {
-	untrackedPosts := reddit.StopTrackingOldPosts(uint64(util.GetEnvInt("MAX_TRACKING_AGE")))
+func stopTrackingOldPosts(reddit redditApiHandlerScheduler) error {
+	maxAge, err := util.GetEnvInt("MAX_TRACKING_AGE")
+	if err != nil {
+		return err
+	}
+
+	untrackedPosts := reddit.StopTrackingOldPosts(uint64(maxAge))
 	if untrackedPosts > 0 {
 		logOutput(fmt.Sprintf("no longer tracking %d old posts", untrackedPosts))
 	}
+	return nil
+}
+
+func reportRateLimit(handler redditApiHandlerScheduler) {
+	status := handler.RateLimit()
+	if status.Updated.IsZero() {
+		logOutput("reddit hasn't reported the rate limit yet")
+		return
+	}
+
+	msg := fmt.Sprintf("reddit rate limit: %.0f requests used, %.0f remaining, resets in %s", status.Used, status.Remaining, time.Until(status.Reset).Round(time.Second))
+	if total := status.Used + status.Remaining; total > 0 && status.Remaining/total < 0.1 {
+		logOutputError("warning: " + msg)
+		return
+	}
+	logOutput(msg)
 }
 
-func cullDatabase(database databaseConnectionScheduler) {
+//keeps the number of tracked posts under MAX_TRACKED_POSTS, if it's set
+func evictTrackedPosts(handler redditApiHandlerScheduler) {
+	max := util.GetEnvIntDefault("MAX_TRACKED_POSTS", 0)
+	if max <= 0 {
+		return
+	}
+
+	policy, err := reddit.ParseEvictionPolicy(util.GetEnvOptional("TRACKED_POSTS_EVICTION", string(reddit.EvictOldest)))
+	if err != nil {
+		logOutputError(fmt.Sprintf("%s, evicting the oldest posts", err))
+		policy = reddit.EvictOldest
+	}
+
+	evictedPosts := handler.EvictTrackedPosts(max, policy)
+	if evictedPosts > 0 {
+		logOutput(fmt.Sprintf("over %d tracked posts, no longer tracking %d posts", max, evictedPosts))
+	}
+}
+
+func cullDatabase(ctx context.Context, backups *backup.Config, reddit redditApiHandlerScheduler, database databaseConnectionScheduler) error {
 	logOutput("culling posts...")
 
-	deletedPosts, err := database.CullListings(uint64(util.GetEnvInt("CULLING_AGE")))
+	if !database.Healthy() {
+		return errors.New("database unhealthy, skipping cull")
+	}
+
+	cullingAge, err := util.GetEnvInt("CULLING_AGE")
 	if err != nil {
-		logOutputError("error culling database:\n" + err.Error())
-		return
+		return err
+	}
+	maxAge := uint64(cullingAge)
+
+	//if CULLING_AGE is under MAX_TRACKING_AGE, culled posts would otherwise keep being updated (and recorded) until they're untracked
+	untrackedPosts := reddit.StopTrackingOldPosts(maxAge)
+	if untrackedPosts > 0 {
+		logOutput(fmt.Sprintf("no longer tracking %d posts about to be culled", untrackedPosts))
 	}
 
-	logOutput(fmt.Sprintf("culled %d posts", deletedPosts))
+	return cull(ctx, backups, database, maxAge)
 }
 
-//pretty formatted printing
+//culls the database once, the same way the culling job does, without tracking anything. For the cull subcommand
+func Cull(ctx context.Context, database databaseConnectionScheduler) error {
+	logOutput("culling posts...")
+
+	if !database.Healthy() {
+		return errors.New("database unhealthy, skipping cull")
+	}
+
+	cullingAge, err := util.GetEnvInt("CULLING_AGE")
+	if err != nil {
+		return err
+	}
+
+	backups, err := backup.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+
+	return cull(ctx, backups, database, uint64(cullingAge))
+}
+
+func cull(ctx context.Context, backups *backup.Config, database databaseConnectionScheduler, maxAge uint64) error {
+	//by default culled posts are only archived, so that their history can still be exported. See the purge subcommand
+	switch mode := strings.ToLower(util.GetEnvOptional("CULL_MODE", "archive")); mode {
+	case "coldstorage":
+		if backups == nil {
+			return errors.New("CULL_MODE=coldstorage needs BACKUP_BUCKET to be set, skipping cull")
+		}
+
+		movedPosts, err := backups.ColdStore(ctx, database, maxAge)
+		if err != nil {
+			return errors.New("error moving posts to cold storage:\n" + err.Error())
+		}
+		logOutput(fmt.Sprintf("moved %d posts to cold storage", movedPosts))
+
+	case "delete":
+		culledPosts, err := database.CullListings(ctx, maxAge, false)
+		if err != nil {
+			return errors.New("error culling database:\n" + err.Error())
+		}
+		logOutput(fmt.Sprintf("culled %d posts", culledPosts))
+
+	default:
+		if mode != "archive" {
+			logOutputError(fmt.Sprintf("unknown CULL_MODE \"%s\", archiving", mode))
+		}
+
+		archivedPosts, err := database.CullListings(ctx, maxAge, true)
+		if err != nil {
+			return errors.New("error culling database:\n" + err.Error())
+		}
+		logOutput(fmt.Sprintf("archived %d posts", archivedPosts))
+	}
+
+	return nil
+}
+
+func compactHistory(ctx context.Context, database databaseConnectionScheduler) error {
+	logOutput("compacting history...")
+
+	if !database.Healthy() {
+		return errors.New("database unhealthy, skipping compaction")
+	}
+
+	maxAge, err := util.GetEnvInt("COMPACT_HISTORY_AGE")
+	if err != nil {
+		return err
+	}
+	resolution := uint64(util.GetEnvIntDefault("COMPACT_HISTORY_RESOLUTION", 3600))
+
+	removed, err := database.CompactHistory(ctx, uint64(maxAge), resolution)
+	if err != nil {
+		return errors.New("error compacting history:\n" + err.Error())
+	}
+
+	logOutput(fmt.Sprintf("removed %d entries", removed))
+	return nil
+}
+
+func backupDatabase(ctx context.Context, backups *backup.Config, database databaseConnectionScheduler) error {
+	logOutput("backing up database...")
+
+	if !database.Healthy() {
+		return errors.New("database unhealthy, skipping backup")
+	}
+
+	count, err := backups.Backup(ctx, database)
+	if err != nil {
+		return errors.New("error backing up database:\n" + err.Error())
+	}
+
+	logOutput(fmt.Sprintf("backed up %d posts", count))
+	return nil
+}
+
+func exportSnapshots(ctx context.Context, exports *backup.SnapshotExport, database databaseConnectionScheduler) error {
+	if !database.Healthy() {
+		return errors.New("database unhealthy, skipping export")
+	}
+
+	count, written, err := exports.Export(ctx, database, time.Now())
+	if err != nil {
+		return errors.New("error exporting snapshots:\n" + err.Error())
+	}
+
+	if len(written) > 0 {
+		logOutput(fmt.Sprintf("exported %d snapshots to %s", count, strings.Join(written, ", ")))
+	}
+	return nil
+}
+
+//large batches are streamed to the database in chunks, log how far along they are
+func withProgressLog(ctx context.Context, verb string) context.Context {
+	return database.WithProgress(ctx, func(sent, total int) {
+		if sent < total {
+			logOutput(fmt.Sprintf("%s %d/%d posts...", verb, sent, total))
+		}
+	})
+}
+
+//pretty formatted printing, or json lines with LOG_FORMAT=json (see logging.go)
 func logOutput(str string) {
+	if jsonLogs() {
+		logWithFields(false, str, nil)
+		return
+	}
 	fmt.Printf("\033[0;36m%s\033[0m: %s\n", time.Now().Format(time.ANSIC), str)
 }
 
 func logOutputError(str string) {
+	if jsonLogs() {
+		logWithFields(true, str, nil)
+		return
+	}
 	fmt.Printf("\033[0;36m%s\033[0m: \033[0;31m%s\033[0m\n", time.Now().Format(time.ANSIC), str)
 }
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/database"
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+// a reddit api handler that doesn't call reddit. TrackNewlyCreatedPosts tracks the posts in newPosts, FetchPosts returns
+// the tracked posts with the upvotes in upvotes
+type fakeReddit struct {
+	newPosts []reddit.RedditContent
+	upvotes  map[reddit.Fullname]int
+	tracked  reddit.ContentGroup
+}
+
+func newFakeReddit() *fakeReddit {
+	return &fakeReddit{upvotes: make(map[reddit.Fullname]int), tracked: make(reddit.ContentGroup)}
+}
+
+func (f *fakeReddit) TimeToNextTokenRefresh() time.Duration { return time.Hour }
+func (f *fakeReddit) TokenRefresh() error                   { return nil }
+
+func (f *fakeReddit) TrackNewlyCreatedPosts(ctx context.Context, subreddits []string) ([]reddit.RedditContent, error) {
+	posts := f.newPosts
+	f.newPosts = nil
+	for _, post := range posts {
+		f.tracked[post.FullId()] = post
+	}
+	return posts, nil
+}
+
+func (f *fakeReddit) SubredditRefreshPeriods() map[string]uint64 { return nil }
+func (f *fakeReddit) GetTrackedPosts() reddit.ContentGroup       { return f.tracked }
+
+func (f *fakeReddit) TrackPosts(posts reddit.ContentGroup) {
+	for ID, post := range posts {
+		f.tracked[ID] = post
+	}
+}
+
+func (f *fakeReddit) GetTrackedIDs() []reddit.Fullname {
+	IDs := make([]reddit.Fullname, 0, len(f.tracked))
+	for ID := range f.tracked {
+		IDs = append(IDs, ID)
+	}
+	return IDs
+}
+
+func (f *fakeReddit) FetchPosts(ctx context.Context, IDs []reddit.Fullname) (*reddit.ContentGroup, error) {
+	posts := make(reddit.ContentGroup)
+	for _, ID := range IDs {
+		post, tracked := f.tracked[ID]
+		if !tracked {
+			continue
+		}
+		post.Upvotes = f.upvotes[ID]
+		post.QueryDate = uint64(time.Now().Unix())
+		posts[ID] = post
+	}
+	return &posts, nil
+}
+
+func (f *fakeReddit) StopTrackingOldPosts(maxAge uint64) int {
+	untracked := 0
+	for ID, post := range f.tracked {
+		if post.Date < uint64(time.Now().Unix())-maxAge {
+			delete(f.tracked, ID)
+			untracked += 1
+		}
+	}
+	return untracked
+}
+
+func (f *fakeReddit) EvictTrackedPosts(int, reddit.EvictionPolicy) int { return 0 }
+
+func (f *fakeReddit) LatestUpvotes(ID reddit.Fullname) (int, bool) {
+	post, tracked := f.tracked[ID]
+	return post.Upvotes, tracked
+}
+
+func (f *fakeReddit) RemovedBy(reddit.Fullname) string                     { return "" }
+func (f *fakeReddit) ResumeFromTrackedPosts(int) time.Duration             { return 0 }
+func (f *fakeReddit) ReloadSubreddits() ([]string, []string, error)        { return nil, nil, nil }
+func (f *fakeReddit) ReplaceSubreddits([]byte) ([]string, []string, error) { return nil, nil, nil }
+func (f *fakeReddit) AddSubreddit(string) (bool, error)                    { return false, nil }
+func (f *fakeReddit) RateLimit() reddit.RateLimitStatus                    { return reddit.RateLimitStatus{} }
+
+// tracks two posts, one older than MAX_TRACKING_AGE, then runs the update, untrack and cull jobs against the in-memory
+// store: only the new post is updated, the old one is untracked and then culled
+func TestJobsWithMemoryStore(t *testing.T) {
+	t.Setenv("MAX_TRACKING_AGE", "3600")
+	t.Setenv("CULLING_AGE", "4000")
+	t.Setenv("CULL_MODE", "delete")
+
+	ctx := context.Background()
+	now := uint64(time.Now().Unix())
+	fresh := reddit.RedditContent{ContentType: "t3", Id: "aaaaaa", Subreddit: "golang", Upvotes: 1, Date: now - 60, QueryDate: now}
+	old := reddit.RedditContent{ContentType: "t3", Id: "bbbbbb", Subreddit: "golang", Upvotes: 1, Date: now - 5000, QueryDate: now}
+
+	r := newFakeReddit()
+	r.newPosts = []reddit.RedditContent{fresh, old}
+	store := database.NewMemoryStore()
+
+	err := fetchNewPosts(ctx, r, store, nil)
+	if err != nil {
+		t.Fatalf("fetching new posts: %s", err)
+	}
+	for _, ID := range []reddit.Fullname{fresh.FullId(), old.FullId()} {
+		if _, err := store.GetHistory(ctx, ID); err != nil {
+			t.Fatalf("%s wasn't saved: %s", ID, err)
+		}
+	}
+
+	r.upvotes[fresh.FullId()] = 40
+	r.upvotes[old.FullId()] = 40
+	err = updateTrackedPosts(ctx, r, store, newRetryQueue(), 0)
+	if err != nil {
+		t.Fatalf("updating tracked posts: %s", err)
+	}
+	history, err := store.GetHistory(ctx, fresh.FullId())
+	if err != nil || len(history) != 1 || history[0].Upvotes != 40 {
+		t.Fatalf("expected one snapshot of %s with 40 upvotes, got %+v (%v)", fresh.FullId(), history, err)
+	}
+	history, err = store.GetHistory(ctx, old.FullId())
+	if err != nil || len(history) != 0 {
+		t.Fatalf("expected %s to be untracked before the update, got %+v (%v)", old.FullId(), history, err)
+	}
+
+	err = stopTrackingOldPosts(r)
+	if err != nil {
+		t.Fatalf("untracking old posts: %s", err)
+	}
+	if _, tracked := r.tracked[fresh.FullId()]; !tracked || len(r.tracked) != 1 {
+		t.Fatalf("expected only %s to be tracked, got %v", fresh.FullId(), r.GetTrackedIDs())
+	}
+
+	err = cullDatabase(ctx, nil, r, store)
+	if err != nil {
+		t.Fatalf("culling: %s", err)
+	}
+	if _, err := store.GetHistory(ctx, old.FullId()); !errors.Is(err, database.ErrListingNotFound) {
+		t.Fatalf("expected %s to be culled, got %v", old.FullId(), err)
+	}
+	if _, err := store.GetHistory(ctx, fresh.FullId()); err != nil {
+		t.Fatalf("%s was culled: %s", fresh.FullId(), err)
+	}
+}
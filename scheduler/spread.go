@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	by default every tracked post is fetched and recorded at once on each
+	update, which is a burst of requests to reddit and the database followed by
+	nothing until the next update. With UPDATE_SPREAD set (eg: 0.8), the
+	tracked posts are split into batches of UPDATE_BATCH_SIZE and the batches
+	are spaced out evenly over that fraction of the update interval instead.
+	Each post is still updated once per interval, just not all at the same time
+*/
+
+// how long an update over interval should be spread over. 0 means not spread
+func spreadFromEnv(interval time.Duration) time.Duration {
+	value, _ := os.LookupEnv("UPDATE_SPREAD")
+	if strings.TrimSpace(value) == "" {
+		return 0
+	}
+
+	fraction, err := strconv.ParseFloat(value, 64)
+	if err != nil || fraction < 0 || fraction >= 1 {
+		logOutputError(fmt.Sprintf("UPDATE_SPREAD=%s should be at least 0 and under 1, not spreading updates", value))
+		return 0
+	}
+
+	return time.Duration(float64(interval) * fraction)
+}
+
+// updates IDs a batch at a time, spaced out over spread. A database error doesn't stop the other batches (the batch is
+// queued, see retry.go), but an error from reddit does since the next batch would most likely fail too
+func updateSpread(ctx context.Context, handler redditApiHandlerScheduler, database databaseConnectionScheduler, retries *retryQueue, IDs []reddit.Fullname, spread time.Duration) error {
+	batchSize := util.GetEnvIntDefault("UPDATE_BATCH_SIZE", 100)
+	if batchSize < 1 {
+		batchSize = 100
+	}
+
+	batches := make([][]reddit.Fullname, 0, len(IDs)/batchSize+1)
+	for start := 0; start < len(IDs); start += batchSize {
+		end := start + batchSize
+		if end > len(IDs) {
+			end = len(IDs)
+		}
+		batches = append(batches, IDs[start:end])
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+
+	wait := spread / time.Duration(len(batches))
+	failed := 0
+	var lastErr error
+	for idx, batch := range batches {
+		if idx > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return fmt.Errorf("update abandoned after %d/%d batches:\n%s", idx, len(batches), ctx.Err())
+			}
+		}
+
+		err := updateBatch(ctx, handler, database, retries, batch)
+
+		var redditErr redditError
+		if errors.As(err, &redditErr) {
+			return fmt.Errorf("update stopped after %d/%d batches:\n%w", idx, len(batches), err)
+		}
+		if err != nil {
+			logOutputError(err.Error())
+			failed += 1
+			lastErr = err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d/%d batches couldn't be recorded, the last with:\n%s", failed, len(batches), lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/metrics"
+)
+
+/*
+	how long each job takes, when it last succeeded and how many times in a row
+	it's failed, so that a job slowly getting worse (reddit responding slower,
+	the tracked posts piling up...) shows up before it starts failing outright.
+	Logged after every run, and exposed through the metrics package
+*/
+
+var (
+	jobDuration      = metrics.NewSummary("votewatch_job_duration_seconds", "time the scheduled jobs took to run", "job")
+	jobFailures      = metrics.NewCounter("votewatch_job_failures_total", "runs of the scheduled jobs that failed", "job")
+	jobLastSuccess   = metrics.NewGauge("votewatch_job_last_success_timestamp_seconds", "unix time the scheduled jobs last succeeded", "job")
+	jobFailureStreak = metrics.NewGauge("votewatch_job_failure_streak", "runs of the scheduled jobs that failed since they last succeeded", "job")
+)
+
+type jobStat struct {
+	lastSuccess time.Time
+	failures    int //consecutive
+}
+
+// the stats of every job, by name
+type jobStats struct {
+	mu    sync.Mutex
+	stats map[string]*jobStat
+}
+
+// a job's stats, as returned by Status
+type JobStatus struct {
+	Name        string
+	LastSuccess time.Time // zero if it hasn't succeeded yet
+	Failures    int       // in a row
+}
+
+type Status struct {
+	Jobs      []JobStatus // every registered job, in the order they were registered
+	LastWrite time.Time   // when anything was last written to the database. Zero if nothing has been yet
+}
+
+// how every job is doing
+func (s *Scheduler) Status() Status {
+	status := Status{}
+	lastWrite.Lock()
+	status.LastWrite = lastWrite.time
+	lastWrite.Unlock()
+
+	names := s.JobNames()
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	for _, name := range names {
+		job := JobStatus{Name: name}
+		if stat, exists := s.stats.stats[name]; exists {
+			job.LastSuccess = stat.lastSuccess
+			job.Failures = stat.failures
+		}
+		status.Jobs = append(status.Jobs, job)
+	}
+	return status
+}
+
+func newJobStats() *jobStats {
+	return &jobStats{stats: make(map[string]*jobStat)}
+}
+
+// records a run of the job that took duration and returned err. Returns how many times in a row the job has failed
+func (j *jobStats) record(name string, duration time.Duration, err error) int {
+	j.mu.Lock()
+	stat, exists := j.stats[name]
+	if !exists {
+		stat = &jobStat{}
+		j.stats[name] = stat
+	}
+
+	if err == nil {
+		stat.lastSuccess = time.Now()
+		stat.failures = 0
+	} else {
+		stat.failures += 1
+	}
+	current := *stat
+	j.mu.Unlock()
+
+	jobDuration.With(name).Observe(duration.Seconds())
+	jobFailureStreak.With(name).Set(float64(current.failures))
+
+	//for querying by job with LOG_FORMAT=json, see logging.go
+	fields := map[string]interface{}{
+		"job":              name,
+		"duration_seconds": duration.Seconds(),
+		"failure_streak":   current.failures,
+	}
+	if !current.lastSuccess.IsZero() {
+		fields["last_success"] = current.lastSuccess.UTC().Format(time.RFC3339)
+	}
+
+	if err == nil {
+		jobLastSuccess.With(name).Set(float64(current.lastSuccess.Unix()))
+		logWithFields(false, fmt.Sprintf("%s took %s", name, duration.Round(time.Millisecond)), fields)
+		return 0
+	}
+	jobFailures.With(name).Inc()
+
+	lastSuccess := "hasn't succeeded since starting"
+	if !current.lastSuccess.IsZero() {
+		lastSuccess = fmt.Sprintf("last succeeded %s ago", time.Since(current.lastSuccess).Round(time.Second))
+	}
+	fields["error"] = err.Error()
+	logWithFields(true, fmt.Sprintf("%s failed after %s, %d times in a row. It %s", name, duration.Round(time.Millisecond), current.failures, lastSuccess), fields)
+	return current.failures
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+)
+
+/*
+	subreddits can have their own refresh period in subreddits.json (see
+	subreddits.json.template), eg: a busy subreddit every 10 seconds and a quiet
+	one every 10 minutes. Each of those gets its own job, while the rest are
+	fetched together by the new posts job (NEW_POSTS_REFRESH_PERIOD or
+	NEW_POSTS_SCHEDULE)
+*/
+
+// splits subreddits into the ones without a refresh period of their own, and the periods of the rest
+// defaults is nil when no subreddit has its own period, so that the new posts job fetches every subreddit
+func splitSubreddits(periods map[string]uint64) (defaults []string, ownPeriods map[string]uint64) {
+	ownPeriods = make(map[string]uint64)
+	for name, period := range periods {
+		if period == 0 {
+			defaults = append(defaults, name)
+		} else {
+			ownPeriods[name] = period
+		}
+	}
+
+	if len(ownPeriods) == 0 {
+		return nil, ownPeriods
+	}
+	sort.Strings(defaults)
+	return defaults, ownPeriods
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// whether this instance looks after the database (culling, compacting, backups). When the subreddits are split
+// between several instances (see reddit/shard.go), they all share a database, so only the first shard does
+func maintainsDatabase() bool {
+	shard, err := reddit.ShardFromEnv()
+	return err != nil || shard.Index == 0
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/*
+	`votewatch config init` writes a starting .env and subreddits file, so that
+	nobody has to hunt through the source for the names of the settings. The
+	.env is .env.template with every setting at its default and every comment
+	kept (as # comments, which is what the env file parser understands)
+*/
+
+//go:embed .env.template
+var envTemplate string
+
+//go:embed subreddits.json.template
+var subredditsTemplate string
+
+// commands that run before there's an env file to load, see main()
+var setupCommands = map[string]func(args []string){
+	"config":  runConfig,
+	"version": runVersion,
+}
+
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "init" {
+		fmt.Fprintln(os.Stderr, "usage: votewatch config init [flags]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("config init", flag.ExitOnError)
+	envOut := flags.String("env", envPath(), "where to write the env file")
+	subredditsOut := flags.String("subreddits", "./subreddits.json", "where to write the subreddits file")
+	force := flags.Bool("force", false, "overwrite the files if they already exist")
+	flags.Parse(args[1:])
+
+	env := envFromTemplate(envTemplate, *subredditsOut)
+
+	//the env file holds secrets
+	for _, file := range []struct {
+		path, content string
+		mode          os.FileMode
+	}{{*envOut, env, 0600}, {*subredditsOut, subredditsTemplate, 0644}} {
+		err := writeNewFile(file.path, file.content, file.mode, *force)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("wrote %s\n", file.path)
+	}
+
+	fmt.Println("fill in the REDDIT_* settings and the subreddits to watch, then run `votewatch check`")
+}
+
+var subredditsPathLine = regexp.MustCompile(`(?m)^SUBREDDITS_PATH=.*$`)
+
+// .env.template as an env file, pointing at the subreddits file at subredditsPath
+func envFromTemplate(template string, subredditsPath string) string {
+	lines := strings.Split(template, "\n")
+	for idx, line := range lines {
+		if strings.HasPrefix(line, "//") {
+			lines[idx] = "# " + strings.TrimPrefix(line, "//")
+		}
+	}
+	env := strings.Join(lines, "\n")
+
+	return subredditsPathLine.ReplaceAllString(env, fmt.Sprintf("SUBREDDITS_PATH=\"%s\"", subredditsPath))
+}
+
+func writeNewFile(path string, content string, mode os.FileMode, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, mode)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating %s:\n%s", path, err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	if err != nil {
+		return fmt.Errorf("error writing %s:\n%s", path, err)
+	}
+	return nil
+}
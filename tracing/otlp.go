@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+	finished spans are batched and posted to the collector over OTLP/HTTP
+	(https://opentelemetry.io/docs/specs/otlp/#otlphttp) every few seconds by
+	the OpenTelemetry SDK's exporter. The standard OpenTelemetry env variables
+	are used:
+
+	OTEL_EXPORTER_OTLP_ENDPOINT        - eg: http://localhost:4318, spans are posted to /v1/traces under it
+	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT - the full url to post spans to, instead of the above
+	OTEL_EXPORTER_OTLP_HEADERS         - extra headers, eg: for an api key: "x-api-key=abc,x-team=def"
+	OTEL_SERVICE_NAME                  - defaults to votewatch
+
+	spans that pile up faster than they can be exported (eg: the collector is
+	down) are dropped rather than kept in memory
+*/
+
+const (
+	maxQueued     = 4096
+	maxBatch      = 512
+	exportPeriod  = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+var (
+	providerMu sync.RWMutex
+	provider   *sdktrace.TracerProvider
+)
+
+func currentTracer() trace.Tracer {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+
+	if provider == nil {
+		return nil
+	}
+	return provider.Tracer("github.com/jtyrmn/reddit-votewatch")
+}
+
+// starts exporting spans if OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set. Call Shutdown
+// before exiting to export the last spans. Returns whether tracing is on
+func Setup() bool {
+	endpoint, _ := os.LookupEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		base, _ := os.LookupEnv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		base = strings.TrimSpace(base)
+		if base == "" {
+			return false
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		fmt.Printf("warning: not tracing, %s isn't a url:\n%s\n", endpoint, err)
+		return false
+	}
+
+	service, _ := os.LookupEnv("OTEL_SERVICE_NAME")
+	if strings.TrimSpace(service) == "" {
+		service = "votewatch"
+	}
+
+	headers := make(map[string]string)
+	list, _ := os.LookupEnv("OTEL_EXPORTER_OTLP_HEADERS")
+	for _, field := range strings.Split(list, ",") {
+		key, value, found := strings.Cut(field, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(parsed.Host),
+		otlptracehttp.WithURLPath(parsed.Path),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithTimeout(exportTimeout),
+	}
+	if parsed.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		fmt.Printf("warning: not tracing, error setting up the OTLP exporter:\n%s\n", err)
+		return false
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		fmt.Printf("warning: error exporting spans:\n%s\n", err)
+	}))
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxQueueSize(maxQueued),
+			sdktrace.WithMaxExportBatchSize(maxBatch),
+			sdktrace.WithBatchTimeout(exportPeriod),
+			sdktrace.WithExportTimeout(exportTimeout),
+		),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+
+	providerMu.Lock()
+	provider = tracerProvider
+	providerMu.Unlock()
+
+	return true
+}
+
+// stops tracing, exporting the spans that are still queued first
+func Shutdown() {
+	providerMu.Lock()
+	tracerProvider := provider
+	provider = nil
+	providerMu.Unlock()
+
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	err := tracerProvider.Shutdown(ctx)
+	if err != nil {
+		fmt.Printf("warning: error exporting the last spans:\n%s\n", err)
+	}
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+	This package traces what each scheduled job spends its time on: every run
+	of a job is a trace, with a span for each request to reddit and each call
+	to the database under it. Slow cycles can then be broken down into reddit
+	latency vs database latency in Jaeger, Tempo, Honeycomb... The spans are
+	recorded with the OpenTelemetry SDK and exported over OTLP/HTTP (see
+	otlp.go) to OTEL_EXPORTER_OTLP_ENDPOINT, and nothing is traced if it isn't
+	set.
+
+	the rest of votewatch only uses the few calls here, like the metrics
+	package, rather than the OpenTelemetry api:
+
+		ctx, span := tracing.Start(ctx, "updating posts")
+		span.SetAttribute("posts", len(IDs))
+		err := update(ctx)
+		span.End(err)
+
+	Start returns a nil span when tracing is off, and every method of Span is
+	safe to call on nil
+*/
+
+type Kind = trace.SpanKind
+
+// the span kinds that are used
+const (
+	Internal = trace.SpanKindInternal
+	Client   = trace.SpanKindClient // a request to another service (reddit, the database...)
+)
+
+type Span struct {
+	span trace.Span
+}
+
+// starts a span as a child of the one in ctx, or as the root of a new trace if there's none. The returned context
+// carries the new span for its children. Returns a nil span if tracing is off
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, Internal)
+}
+
+// same as Start, with a kind other than Internal
+func StartKind(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	tracer := currentTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &Span{span: span}
+}
+
+// the span in ctx, nil if there's none
+func FromContext(ctx context.Context) *Span {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return &Span{span: span}
+}
+
+// sets an attribute of the span. value should be a string, bool, integer or float, anything else is formatted as a string
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	var encoded attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		encoded = attribute.String(key, v)
+	case bool:
+		encoded = attribute.Bool(key, v)
+	case int:
+		encoded = attribute.Int(key, v)
+	case int64:
+		encoded = attribute.Int64(key, v)
+	case uint64:
+		encoded = attribute.Int64(key, int64(v))
+	case float64:
+		encoded = attribute.Float64(key, v)
+	default:
+		encoded = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(encoded)
+}
+
+// ends the span, marking it as failed if err isn't nil, and queues it to be exported. Only the first call does anything
+func (s *Span) End(err error) {
+	if s == nil || !s.span.IsRecording() {
+		return
+	}
+
+	if err != nil {
+		s.span.SetStatus(codes.Error, err.Error())
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+}
+
+// the W3C traceparent header (https://www.w3.org/TR/trace-context/) of the span in ctx, so that the service being called
+// can continue the trace. "" if there's no span
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
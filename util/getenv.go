@@ -1,20 +1,26 @@
 package util
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-//get environment variable
-func GetEnv(str string) string {
+//wrapped by the errors of the typed getters below when the variable isn't set, so that callers can tell it apart from
+//a malformed value with errors.Is
+var ErrEnvMissing = errors.New("missing environment variable")
+
+//get environment variable. The error wraps ErrEnvMissing
+func GetEnv(str string) (string, error) {
 	v, exists := os.LookupEnv(str)
 	if !exists {
-		log.Fatalf("cannot find environment variable \"%s\": halting execution...\n", str)
+		return "", fmt.Errorf("%w %s", ErrEnvMissing, str)
 	}
 
-	return v
+	return v, nil
 }
 
 //equivelant to getEnv except doesn't cause an error and substitutes a default value (def)
@@ -29,14 +35,98 @@ func GetEnvDefault(str string, def string) string {
 	return v
 }
 
+//equivelant to GetEnvDefault except it doesn't warn about the variable missing. For optional settings, that are
+//left out of most env files and can be read every cycle
+func GetEnvOptional(str string, def string) string {
+	v, exists := os.LookupEnv(str)
+	if !exists {
+		return def
+	}
+
+	return v
+}
+
 //get an integer
-func GetEnvInt(str string) int {
-	v := GetEnv(str)
+func GetEnvInt(str string) (int, error) {
+	v, exists := os.LookupEnv(str)
+	if !exists {
+		return 0, fmt.Errorf("%w %s", ErrEnvMissing, str)
+	}
 
-	i, err := strconv.ParseInt(v, 10, 32)
+	i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
 	if err != nil {
-		log.Fatalf("cannot parse environment variable %s=%s:%s halting executing...\n", str, v, err.Error())
+		return 0, fmt.Errorf("cannot parse environment variable %s=%s:\n%s", str, v, err)
+	}
+
+	return int(i), nil
+}
+
+//get a length of time, either a plain number of units (eg: seconds for unit=time.Second) or a duration like 90s, 5m
+//or 1h30m
+func GetEnvDuration(str string, unit time.Duration) (time.Duration, error) {
+	v, exists := os.LookupEnv(str)
+	if !exists {
+		return 0, fmt.Errorf("%w %s", ErrEnvMissing, str)
+	}
+
+	trimmed := strings.TrimSpace(v)
+	if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return unit * time.Duration(i), nil
+	}
+	d, err := time.ParseDuration(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse environment variable %s=%s, expected a number or a duration like 90s:\n%s", str, v, err)
+	}
+
+	return d, nil
+}
+
+//get a true/false flag (also 1/0, t/f, see strconv.ParseBool). Flags are optional, so def is returned if the variable
+//is missing or empty. If it's malformed def is returned along with an error
+func GetEnvBool(str string, def bool) (bool, error) {
+	v, _ := os.LookupEnv(str)
+	if strings.TrimSpace(v) == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return def, fmt.Errorf("cannot parse environment variable %s=%s, expected true or false", str, v)
+	}
+
+	return b, nil
+}
+
+//get a comma separated list. Items are trimmed and empty ones dropped, so an empty variable is an empty list
+func GetEnvStringSlice(str string) ([]string, error) {
+	v, exists := os.LookupEnv(str)
+	if !exists {
+		return nil, fmt.Errorf("%w %s", ErrEnvMissing, str)
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+//equivelant to GetEnvInt except substitutes a default value (def) if the variable is missing, empty or unreadable
+func GetEnvIntDefault(str string, def int) int {
+	v, exists := os.LookupEnv(str)
+	if !exists || strings.TrimSpace(v) == "" {
+		return def
+	}
+
+	i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+	if err != nil {
+		fmt.Printf("warning: env variable %s=%s unreadable, defaulting to %d...\n", str, v, def)
+		return def
 	}
 
 	return int(i)
-}
\ No newline at end of file
+}
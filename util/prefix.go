@@ -0,0 +1,35 @@
+package util
+
+import (
+	"os"
+	"strings"
+)
+
+/*
+	every variable can also be given with a VOTEWATCH_ prefix, eg:
+	VOTEWATCH_REDDIT_CLIENT_ID, so that it can't collide with another app's
+	variables in a shared environment (a docker compose file, a kubernetes
+	namespace's ConfigMap...). The old names keep working, and the prefixed
+	name wins if both are set. Everything reads the unprefixed names, so the
+	prefix is taken off as the environment is loaded
+*/
+
+const EnvPrefix = "VOTEWATCH_"
+
+// NAME for VOTEWATCH_NAME, and whether name had the prefix
+func TrimEnvPrefix(name string) (string, bool) {
+	if !strings.HasPrefix(name, EnvPrefix) || name == EnvPrefix {
+		return name, false
+	}
+	return strings.TrimPrefix(name, EnvPrefix), true
+}
+
+// sets NAME from every VOTEWATCH_NAME in the environment
+func UnprefixEnv() {
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if unprefixed, prefixed := TrimEnvPrefix(name); prefixed {
+			os.Setenv(unprefixed, value)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+	secrets can be mounted as files (docker/kubernetes secrets) instead of
+	being put in the environment, where they show up in `docker inspect`, crash
+	reports, /proc... For each of the given variables, NAME_FILE is the path of
+	a file holding its value, eg: REDDIT_PASSWORD_FILE=/run/secrets/reddit_password.
+	The file wins over NAME if both are set. A single trailing newline is
+	dropped, since most editors add one
+*/
+
+// sets each of names from NAME_FILE, if that's set. Returns every file that couldn't be read at once
+func ResolveFileEnv(names ...string) error {
+	values := make(map[string]string)
+	for _, name := range names {
+		if path, exists := os.LookupEnv(name + "_FILE"); exists {
+			values[name+"_FILE"] = path
+		}
+	}
+
+	err := ResolveFileValues(values, names...)
+	for _, name := range names {
+		if value, exists := values[name]; exists {
+			os.Setenv(name, value)
+		}
+	}
+	return err
+}
+
+// the same as ResolveFileEnv, for variables that aren't set yet, eg: ones that are checked before they're used
+func ResolveFileValues(values map[string]string, names ...string) error {
+	var problems []string
+	for _, name := range names {
+		path, exists := values[name+"_FILE"]
+		if !exists || strings.TrimSpace(path) == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s_FILE: %s", name, err))
+			continue
+		}
+
+		value := strings.TrimSuffix(string(data), "\n")
+		value = strings.TrimSuffix(value, "\r")
+		values[name] = value
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("error reading secrets:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
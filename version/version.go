@@ -0,0 +1,82 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+/*
+	which build is running, so that deployed binaries can be told apart. The
+	version, commit and build date are set at build time with ldflags:
+
+		go build -ldflags "\
+			-X github.com/jtyrmn/reddit-votewatch/version.Version=v1.4.0 \
+			-X github.com/jtyrmn/reddit-votewatch/version.Commit=$(git rev-parse --short HEAD) \
+			-X github.com/jtyrmn/reddit-votewatch/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+	without them, the commit and its date recorded by the go toolchain are
+	used instead (when built inside the git checkout). They're shown by
+	`votewatch version`, on start, on the health endpoint (see the metrics
+	package) and in the default user agent sent to reddit
+*/
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// the build's version info, filled in from the toolchain's where ldflags didn't set it
+func Get() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+
+	if build, ok := debug.ReadBuildInfo(); ok && Commit == "" {
+		modified := false
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+				if len(info.Commit) > 12 {
+					info.Commit = info.Commit[:12]
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+// eg: votewatch v1.4.0 (commit 3e5f0cd, built 2026-10-16T12:00:00Z, go1.18.10)
+func String() string {
+	info := Get()
+	return fmt.Sprintf("votewatch %s (commit %s, built %s, %s)", info.Version, info.Commit, info.Date, info.GoVersion)
+}
+
+// the user agent reddit asks for (https://github.com/reddit-archive/reddit/wiki/API#rules), for when
+// REDDIT_USERAGENT_STRING isn't set
+func UserAgent(username string) string {
+	return fmt.Sprintf("%s:reddit-votewatch:%s (by /u/%s)", runtime.GOOS, Version, username)
+}
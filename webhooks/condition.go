@@ -0,0 +1,346 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+	the conditions rules are written in, eg:
+
+		subreddit == golang and (score >= 500 or delta > 50) and age < 6h
+		flair =~ "^(news|release)$" and not subreddit == "wallstreetbets"
+
+	a condition compares a post's fields to values, joined with and/or (also
+	&& and ||), not (also !) and parentheses. The fields are:
+
+		score       upvotes
+		delta       upvotes gained since the post's previous snapshot (0 when it's first tracked)
+		comments    number of comments
+		age         how long ago it was posted, compared to durations (90m, 6h, 2h30m) or seconds
+		subreddit   without the r/
+		flair       the post's flair as text, empty if it has none
+
+	numbers compare with == != < <= > >=. Text compares with == and != (ignoring
+	case), and =~ / !~ to match a regular expression (https://pkg.go.dev/regexp/syntax).
+	Text can be quoted with " or ', and has to be if it isn't one word
+*/
+
+// a post's fields, as a condition sees them
+type fields struct {
+	score     float64
+	delta     float64
+	comments  float64
+	age       float64 // seconds
+	subreddit string
+	flair     string
+}
+
+var numberFields = map[string]func(f fields) float64{
+	"score":    func(f fields) float64 { return f.score },
+	"delta":    func(f fields) float64 { return f.delta },
+	"comments": func(f fields) float64 { return f.comments },
+	"age":      func(f fields) float64 { return f.age },
+}
+
+var textFields = map[string]func(f fields) string{
+	"subreddit": func(f fields) string { return f.subreddit },
+	"flair":     func(f fields) string { return f.flair },
+}
+
+// a parsed condition
+type condition interface {
+	matches(f fields) bool
+}
+
+type andCondition struct{ left, right condition }
+type orCondition struct{ left, right condition }
+type notCondition struct{ inner condition }
+
+func (c andCondition) matches(f fields) bool { return c.left.matches(f) && c.right.matches(f) }
+func (c orCondition) matches(f fields) bool  { return c.left.matches(f) || c.right.matches(f) }
+func (c notCondition) matches(f fields) bool { return !c.inner.matches(f) }
+
+type numberComparison struct {
+	field    func(f fields) float64
+	operator string
+	value    float64
+}
+
+func (c numberComparison) matches(f fields) bool {
+	field := c.field(f)
+	switch c.operator {
+	case "==":
+		return field == c.value
+	case "!=":
+		return field != c.value
+	case "<":
+		return field < c.value
+	case "<=":
+		return field <= c.value
+	case ">":
+		return field > c.value
+	case ">=":
+		return field >= c.value
+	}
+	return false
+}
+
+type textComparison struct {
+	field    func(f fields) string
+	operator string
+	value    string
+	pattern  *regexp.Regexp // for =~ and !~
+}
+
+func (c textComparison) matches(f fields) bool {
+	field := c.field(f)
+	switch c.operator {
+	case "==":
+		return strings.EqualFold(field, c.value)
+	case "!=":
+		return !strings.EqualFold(field, c.value)
+	case "=~":
+		return c.pattern.MatchString(field)
+	case "!~":
+		return !c.pattern.MatchString(field)
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	textToken           // quoted
+	operatorToken
+	openToken
+	closeToken
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!"}
+
+func tokenize(text string) ([]token, error) {
+	tokens := make([]token, 0)
+	for idx := 0; idx < len(text); {
+		char := rune(text[idx])
+		switch {
+		case unicode.IsSpace(char):
+			idx += 1
+			continue
+		case char == '(':
+			tokens = append(tokens, token{openToken, "("})
+			idx += 1
+			continue
+		case char == ')':
+			tokens = append(tokens, token{closeToken, ")"})
+			idx += 1
+			continue
+		case char == '"' || char == '\'':
+			end := strings.IndexRune(text[idx+1:], char)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated text starting at %s", text[idx:])
+			}
+			tokens = append(tokens, token{textToken, text[idx+1 : idx+1+end]})
+			idx += end + 2
+			continue
+		}
+
+		operator := ""
+		for _, candidate := range operators {
+			if strings.HasPrefix(text[idx:], candidate) {
+				operator = candidate
+				break
+			}
+		}
+		if operator != "" {
+			tokens = append(tokens, token{operatorToken, operator})
+			idx += len(operator)
+			continue
+		}
+
+		end := idx
+		for end < len(text) && !unicode.IsSpace(rune(text[end])) && !strings.ContainsRune("()\"'=!<>&|", rune(text[end])) {
+			end += 1
+		}
+		if end == idx {
+			return nil, fmt.Errorf("unexpected %q", text[idx:idx+1])
+		}
+		tokens = append(tokens, token{wordToken, text[idx:end]})
+		idx = end
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// parses a condition, see the top of this file
+func parseCondition(text string) (condition, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty condition")
+	}
+
+	p := &parser{tokens: tokens}
+	result, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.next < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.next].value)
+	}
+	return result, nil
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.next >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.next], true
+}
+
+// whether the next token is one of a keyword's spellings (eg: and, &&), consuming it if it is
+func (p *parser) accept(spellings ...string) bool {
+	next, ok := p.peek()
+	if !ok || next.kind == textToken {
+		return false
+	}
+	for _, spelling := range spellings {
+		if strings.EqualFold(next.value, spelling) {
+			p.next += 1
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or", "||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and", "&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (condition, error) {
+	if p.accept("not", "!") {
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{inner}, nil
+	}
+
+	next, ok := p.peek()
+	if !ok {
+		return nil, errors.New("condition ends too early")
+	}
+	if next.kind == openToken {
+		p.next += 1
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.kind != closeToken {
+			return nil, errors.New("missing )")
+		}
+		p.next += 1
+		return inner, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (condition, error) {
+	if len(p.tokens)-p.next < 3 {
+		return nil, errors.New("condition ends too early, expected <field> <operator> <value>")
+	}
+	field, operator, value := p.tokens[p.next], p.tokens[p.next+1], p.tokens[p.next+2]
+	p.next += 3
+
+	if field.kind != wordToken {
+		return nil, fmt.Errorf("expected a field, got %q", field.value)
+	}
+	if operator.kind != operatorToken {
+		return nil, fmt.Errorf("expected an operator after %s, got %q", field.value, operator.value)
+	}
+	if value.kind != wordToken && value.kind != textToken {
+		return nil, fmt.Errorf("expected a value after %s %s, got %q", field.value, operator.value, value.value)
+	}
+	name := strings.ToLower(field.value)
+
+	if getter, exists := numberFields[name]; exists {
+		switch operator.value {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("%s is a number, it can't be compared with %s", name, operator.value)
+		}
+
+		number, err := strconv.ParseFloat(value.value, 64)
+		if err != nil && name == "age" {
+			var duration time.Duration
+			duration, err = time.ParseDuration(value.value)
+			number = duration.Seconds()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s %s: %q isn't a number", name, operator.value, value.value, value.value)
+		}
+		return numberComparison{getter, operator.value, number}, nil
+	}
+
+	if getter, exists := textFields[name]; exists {
+		comparison := textComparison{field: getter, operator: operator.value, value: value.value}
+		switch operator.value {
+		case "==", "!=":
+		case "=~", "!~":
+			pattern, err := regexp.Compile(value.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s %s:\n%s", name, operator.value, value.value, err)
+			}
+			comparison.pattern = pattern
+		default:
+			return nil, fmt.Errorf("%s is text, it can't be compared with %s", name, operator.value)
+		}
+		return comparison, nil
+	}
+
+	return nil, fmt.Errorf("unknown field %q, expected score, delta, comments, age, subreddit or flair", field.value)
+}
@@ -0,0 +1,126 @@
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens []token
+	}{
+		{"", []token{}},
+		{"score >= 500", []token{{wordToken, "score"}, {operatorToken, ">="}, {wordToken, "500"}}},
+		{"score>=500", []token{{wordToken, "score"}, {operatorToken, ">="}, {wordToken, "500"}}},
+		{"(a||b)&&!c", []token{
+			{openToken, "("}, {wordToken, "a"}, {operatorToken, "||"}, {wordToken, "b"}, {closeToken, ")"},
+			{operatorToken, "&&"}, {operatorToken, "!"}, {wordToken, "c"},
+		}},
+		{`flair =~ "^(news|release)$"`, []token{{wordToken, "flair"}, {operatorToken, "=~"}, {textToken, "^(news|release)$"}}},
+		{`flair != 'two words'`, []token{{wordToken, "flair"}, {operatorToken, "!="}, {textToken, "two words"}}},
+		{`flair == "it's"`, []token{{wordToken, "flair"}, {operatorToken, "=="}, {textToken, "it's"}}},
+		{"flair == ''", []token{{wordToken, "flair"}, {operatorToken, "=="}, {textToken, ""}}},
+		{"age < 2h30m", []token{{wordToken, "age"}, {operatorToken, "<"}, {wordToken, "2h30m"}}},
+		{"flair !~ x", []token{{wordToken, "flair"}, {operatorToken, "!~"}, {wordToken, "x"}}},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenize(test.text)
+		if err != nil {
+			t.Errorf("tokenize(%q): %s", test.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("tokenize(%q) = %v, expected %v", test.text, tokens, test.tokens)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, text := range []string{
+		`flair == "news`,
+		"flair == 'news",
+		"score & 5",
+		"score = 5",
+	} {
+		if _, err := tokenize(text); err == nil {
+			t.Errorf("tokenize(%q) didn't fail", text)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	post := fields{score: 600, delta: 40, comments: 12, age: 2 * 3600, subreddit: "golang", flair: "News"}
+
+	tests := []struct {
+		text    string
+		matches bool
+	}{
+		{"score >= 500", true},
+		{"score > 600", false},
+		{"score == 600", true},
+		{"score != 600", false},
+		{"score <= 600", true},
+		{"comments < 12", false},
+		{"delta > 50", false},
+		{"age < 6h", true},
+		{"age < 90m", false},
+		{"age >= 7200", true},
+		{"subreddit == golang", true},
+		{"subreddit == GoLang", true},
+		{"subreddit != golang", false},
+		{`flair == "news"`, true},
+		{`flair =~ "^(news|release)$"`, false}, // regular expressions are case sensitive
+		{`flair =~ "^(News|Release)$"`, true},
+		{"flair !~ ^N", false},
+		{"SCORE >= 500 AND Subreddit == golang", true},
+
+		// and binds tighter than or
+		{"score > 1000 and delta > 50 or subreddit == golang", true},
+		{"subreddit == golang or score > 1000 and delta > 50", true},
+		{"(subreddit == golang or score > 1000) and delta > 50", false},
+		{"subreddit == golang and (score >= 500 or delta > 50) and age < 6h", true},
+		{"score >= 500 && !(delta > 50) || comments > 100", true},
+
+		{"not subreddit == wallstreetbets", true},
+		{"not not subreddit == golang", true},
+		{"! score >= 500", false},
+	}
+
+	for _, test := range tests {
+		parsed, err := parseCondition(test.text)
+		if err != nil {
+			t.Errorf("parseCondition(%q): %s", test.text, err)
+			continue
+		}
+		if matches := parsed.matches(post); matches != test.matches {
+			t.Errorf("%q matched: %t, expected %t", test.text, matches, test.matches)
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"   ",
+		"score",
+		"score >=",
+		"score >= 500 and",
+		"(score >= 500",
+		"score >= 500)",
+		"score >= 500 delta > 5",
+		"upvotes > 5",
+		"score =~ 5",
+		"score > lots",
+		"age < soon",
+		"subreddit > golang",
+		`flair =~ "("`,
+		"== 5 score",
+		"score == (",
+	} {
+		if _, err := parseCondition(text); err == nil {
+			t.Errorf("parseCondition(%q) didn't fail", text)
+		}
+	}
+}
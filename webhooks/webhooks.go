@@ -0,0 +1,408 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtyrmn/reddit-votewatch/reddit"
+	"github.com/jtyrmn/reddit-votewatch/scheduler"
+	"github.com/jtyrmn/reddit-votewatch/util"
+)
+
+/*
+	json POSTed to any url when a tracked post matches a rule. The rules are in
+	the file at WEBHOOK_RULES_PATH (see webhooks.json.template):
+
+		{
+			"rules": [
+				{"name": "hot in golang", "when": "subreddit == golang and score >= 500", "url": "https://example.com/hook"}
+			]
+		}
+
+	"when" is a condition over the post (see condition.go), checked each time
+	the post is updated. A post only matches each rule once. "secret" signs a
+	rule's requests instead of WEBHOOK_SECRET.
+
+	the request body is a Payload. When there's a secret, the body's
+	HMAC-SHA256 is in the X-Votewatch-Signature header as sha256=<hex>, the
+	same way github signs its webhooks. X-Votewatch-Delivery is the same for
+	every attempt at one request, to tell retries apart from new ones.
+
+	requests that fail (couldn't connect, 5xx, 429) are retried up to
+	WEBHOOK_RETRIES times (3 by default), waiting 1s, 2s, 4s... in between, or
+	as long as a Retry-After header asks. Each rule sends its requests in order
+	in the background, and drops them if it falls too far behind. A dropped
+	request isn't counted as a match, so the post can match again on its next
+	update. Shutdown stops waiting between retries right away
+*/
+
+const (
+	requestTimeout = 10 * time.Second
+	maxQueued      = 64
+	maxRetryDelay  = time.Minute
+)
+
+type Rule struct {
+	Name   string `json:"name"`
+	When   string `json:"when"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"` // optional, WEBHOOK_SECRET if it isn't set
+}
+
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// what's POSTed
+type Payload struct {
+	Rule string `json:"rule"`
+	Time int64  `json:"time"` // unix time the post matched
+	Post Post   `json:"post"`
+}
+
+type Post struct {
+	Id        reddit.Fullname `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Title     string          `json:"title"`
+	Author    string          `json:"author"`
+	Url       string          `json:"url"`
+	Link      string          `json:"link"` // https://redd.it/<id>
+	Flair     string          `json:"flair"`
+	Created   int64           `json:"created"`
+	Score     int             `json:"score"`
+	Delta     int             `json:"delta"`
+	Comments  int             `json:"comments"`
+	Age       int64           `json:"age"` // seconds
+}
+
+type rule struct {
+	Rule
+	condition condition
+	client    *http.Client
+	retries   int
+	queue     chan Payload
+	done      chan struct{} // closed when run returns, see Shutdown
+
+	mu      sync.Mutex
+	matched map[reddit.Fullname]uint64 // the posts that matched and were queued, and when they were posted
+}
+
+// the running rules, and how to stop them. See Setup and Shutdown
+var (
+	activeMu sync.Mutex
+	active   []*rule
+	stop     context.CancelFunc
+)
+
+// reads the rules at WEBHOOK_RULES_PATH. Empty if it isn't set
+func loadRules() ([]*rule, error) {
+	path := strings.TrimSpace(os.Getenv("WEBHOOK_RULES_PATH"))
+	if path == "" {
+		return nil, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("error reading WEBHOOK_RULES_PATH:\n" + err.Error())
+	}
+	var file rulesFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %s:\n%s", path, err)
+	}
+
+	retries := 3
+	if value := strings.TrimSpace(os.Getenv("WEBHOOK_RETRIES")); value != "" {
+		retries, err = strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return nil, fmt.Errorf("WEBHOOK_RETRIES=%s should be a whole number", value)
+		}
+	}
+	secret := os.Getenv("WEBHOOK_SECRET")
+
+	rules := make([]*rule, 0, len(file.Rules))
+	names := make(map[string]bool)
+	for idx, r := range file.Rules {
+		if r.Name == "" {
+			r.Name = "rule " + strconv.Itoa(idx+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("%s: there's more than one rule named %q", path, r.Name)
+		}
+		names[r.Name] = true
+
+		parsed, err := url.Parse(r.Url)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%s: %s: %q should be an http(s) url", path, r.Name, r.Url)
+		}
+		condition, err := parseCondition(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: error parsing \"when\":\n%s", path, r.Name, err)
+		}
+		if r.Secret == "" {
+			r.Secret = secret
+		}
+
+		rules = append(rules, &rule{
+			Rule:      r,
+			condition: condition,
+			client:    &http.Client{Timeout: requestTimeout},
+			retries:   retries,
+			queue:     make(chan Payload, maxQueued),
+			done:      make(chan struct{}),
+			matched:   make(map[reddit.Fullname]uint64),
+		})
+	}
+	return rules, nil
+}
+
+// the problems with the rules file, for the config check
+func CheckConfig() []error {
+	if _, err := loadRules(); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// starts checking the rules at WEBHOOK_RULES_PATH, if it's set. Must be called before the scheduler starts (see scheduler.OnEvent)
+func Setup() error {
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	activeMu.Lock()
+	active, stop = rules, cancel
+	activeMu.Unlock()
+
+	for _, r := range rules {
+		go r.run(ctx)
+		scheduler.OnEvent(scheduler.PostTracked, r.onEvent)
+		scheduler.OnEvent(scheduler.PostUpdated, r.onEvent)
+	}
+	return nil
+}
+
+// stops sending webhooks, giving up on the ones being retried and the ones still queued. Waits for requests in flight
+// to be cancelled
+func Shutdown() {
+	activeMu.Lock()
+	rules, cancel := active, stop
+	active, stop = nil, nil
+	activeMu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	for _, r := range rules {
+		<-r.done
+	}
+}
+
+func (r *rule) onEvent(event scheduler.Event) {
+	post := event.Post
+	delta := 0
+	if event.Kind == scheduler.PostUpdated {
+		delta = post.Upvotes - event.PreviousUpvotes
+	}
+	age := int64(0)
+	if post.QueryDate > post.Date {
+		age = int64(post.QueryDate - post.Date)
+	}
+
+	matches := r.condition.matches(fields{
+		score:     float64(post.Upvotes),
+		delta:     float64(delta),
+		comments:  float64(post.Comments),
+		age:       float64(age),
+		subreddit: post.Subreddit,
+		flair:     post.Flair,
+	})
+	if !matches {
+		return
+	}
+
+	//a post only matches once it's queued, so that one dropped below can still match on its next update
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hasMatched(post) {
+		return
+	}
+
+	payload := Payload{
+		Rule: r.Name,
+		Time: event.Time.Unix(),
+		Post: Post{
+			Id:        post.FullId(),
+			Subreddit: post.Subreddit,
+			Title:     post.Title,
+			Author:    post.Author,
+			Url:       post.Url,
+			Link:      "https://redd.it/" + post.Id,
+			Flair:     post.Flair,
+			Created:   int64(post.Date),
+			Score:     post.Upvotes,
+			Delta:     delta,
+			Comments:  post.Comments,
+			Age:       age,
+		},
+	}
+
+	select {
+	case r.queue <- payload:
+		r.markMatched(post)
+	default:
+		fmt.Printf("warning: too many webhooks queued for %s, dropped one (%s)\n", r.Name, payload.Post.Id)
+	}
+}
+
+// whether a post already matched the rule. r.mu must be held
+func (r *rule) hasMatched(post reddit.RedditContent) bool {
+	_, matched := r.matched[post.FullId()]
+	return matched
+}
+
+// records that a post matched the rule, so that it doesn't again. Posts past MAX_TRACKING_AGE are forgotten, they
+// aren't updated anymore. r.mu must be held
+func (r *rule) markMatched(post reddit.RedditContent) {
+	maxAge := uint64(util.GetEnvIntDefault("MAX_TRACKING_AGE", 86400))
+	now := uint64(time.Now().Unix())
+	for ID, posted := range r.matched {
+		if posted+maxAge < now {
+			delete(r.matched, ID)
+		}
+	}
+
+	r.matched[post.FullId()] = post.Date
+}
+
+// sends the rule's queued requests until ctx is cancelled (see Shutdown)
+func (r *rule) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		select {
+		case payload := <-r.queue:
+			err := r.deliver(ctx, payload)
+			if err != nil {
+				fmt.Printf("warning: error sending webhook for %s (%s):\n%s\n", r.Name, payload.Post.Id, err)
+			}
+		case <-ctx.Done():
+			if len(r.queue) > 0 {
+				fmt.Printf("warning: shutting down, dropped %d webhooks queued for %s\n", len(r.queue), r.Name)
+			}
+			return
+		}
+	}
+}
+
+// POSTs a payload, retrying as described at the top of this file. Gives up when ctx is cancelled
+func (r *rule) deliver(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	delivery := deliveryId()
+
+	delay := time.Second
+	for attempt := 0; ; attempt += 1 {
+		retryAfter, err := r.post(ctx, body, delivery)
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if attempt >= r.retries || errors.As(err, &permanent) {
+			return err
+		}
+
+		wait := delay
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if wait > maxRetryDelay {
+			wait = maxRetryDelay
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("gave up retrying, shutting down:\n%s", err)
+		}
+		delay *= 2
+	}
+}
+
+// a response that retrying won't change, eg: 404
+type permanentError struct {
+	status string
+}
+
+func (e permanentError) Error() string {
+	return e.status
+}
+
+// POSTs body once, returning how long the server asked to wait before retrying, if it did
+func (r *rule) post(ctx context.Context, body []byte, delivery string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanentError{err.Error()}
+	}
+	request.Header.Set("content-type", "application/json")
+	request.Header.Set("user-agent", "votewatch")
+	request.Header.Set("X-Votewatch-Delivery", delivery)
+	if r.Secret != "" {
+		request.Header.Set("X-Votewatch-Signature", Sign(r.Secret, body))
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 300 {
+		return 0, nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	status := fmt.Sprintf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		seconds, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, errors.New(status)
+	case response.StatusCode == http.StatusRequestTimeout:
+		return 0, errors.New(status)
+	}
+	return 0, permanentError{status}
+}
+
+// the X-Votewatch-Signature of a body: sha256=<hex of its HMAC-SHA256 with secret>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryId() string {
+	buffer := make([]byte, 16)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}